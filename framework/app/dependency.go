@@ -0,0 +1,138 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrDuplicateApp 存在同名应用
+	ErrDuplicateApp = errors.New("app: duplicate app name")
+	// ErrUnknownDependency 依赖的应用未注册
+	ErrUnknownDependency = errors.New("app: unknown dependency")
+	// ErrDependencyCycle 应用依赖存在环
+	ErrDependencyCycle = errors.New("app: dependency cycle detected")
+)
+
+// appEntry 已注册应用及其名称与依赖
+type appEntry struct {
+	name      string
+	dependsOn []string
+
+	mu       sync.RWMutex
+	app      IApp // 监管模式下重启可能替换为新实例，读取请使用 current
+	restarts atomic.Int64
+}
+
+// current 返回当前应用实例
+func (e *appEntry) current() IApp {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.app
+}
+
+// replace 替换应用实例（监管重启时使用）
+func (e *appEntry) replace(app IApp) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.app = app
+}
+
+// newAppEntry 创建应用条目。
+// name 为空时依次尝试 Namer 接口与 "app-<index>"；
+// dependsOn 为空时尝试 DependencyDeclarer 接口。
+func newAppEntry(index int, name string, app IApp, dependsOn []string) *appEntry {
+	if name == "" {
+		if n, ok := app.(Namer); ok {
+			name = n.Name()
+		}
+	}
+	if name == "" {
+		name = fmt.Sprintf("app-%d", index)
+	}
+	if len(dependsOn) == 0 {
+		if d, ok := app.(DependencyDeclarer); ok {
+			dependsOn = d.DependsOn()
+		}
+	}
+	return &appEntry{name: name, app: app, dependsOn: dependsOn}
+}
+
+// resolveStages 按依赖关系将应用划分为拓扑启动阶段。
+// 同一阶段内的应用互不依赖，并保持注册顺序；
+// 第 N 阶段的应用只依赖前 N-1 阶段中的应用。
+func resolveStages(entries []*appEntry) ([][]*appEntry, error) {
+	index := make(map[string]int, len(entries))
+	for i, e := range entries {
+		if _, ok := index[e.name]; ok {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateApp, e.name)
+		}
+		index[e.name] = i
+	}
+
+	inDegree := make([]int, len(entries))
+	dependents := make([][]int, len(entries))
+	for i, e := range entries {
+		seen := make(map[string]struct{}, len(e.dependsOn))
+		for _, dep := range e.dependsOn {
+			if _, dup := seen[dep]; dup {
+				continue
+			}
+			seen[dep] = struct{}{}
+
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q depends on %q", ErrUnknownDependency, e.name, dep)
+			}
+			if j == i {
+				return nil, fmt.Errorf("%w: %q depends on itself", ErrDependencyCycle, e.name)
+			}
+			inDegree[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	current := make([]int, 0, len(entries))
+	for i := range entries {
+		if inDegree[i] == 0 {
+			current = append(current, i)
+		}
+	}
+
+	var stages [][]*appEntry
+	resolved := 0
+	for len(current) > 0 {
+		stage := make([]*appEntry, 0, len(current))
+		next := make([]int, 0)
+		for _, i := range current {
+			stage = append(stage, entries[i])
+			for _, d := range dependents[i] {
+				inDegree[d]--
+				if inDegree[d] == 0 {
+					next = append(next, d)
+				}
+			}
+		}
+		resolved += len(current)
+		stages = append(stages, stage)
+		// 保证阶段内按注册顺序启动
+		slices.Sort(next)
+		current = next
+	}
+
+	if resolved < len(entries) {
+		var names []string
+		for i, e := range entries {
+			if inDegree[i] > 0 {
+				names = append(names, e.name)
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(names, ", "))
+	}
+
+	return stages, nil
+}
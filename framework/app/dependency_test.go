@@ -0,0 +1,197 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderApp 记录启动与关闭顺序
+type orderApp struct {
+	name     string
+	deps     []string
+	startErr error
+	mu       *sync.Mutex
+	events   *[]string
+}
+
+func (a *orderApp) Name() string        { return a.name }
+func (a *orderApp) DependsOn() []string { return a.deps }
+func (a *orderApp) Start(_ context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	*a.events = append(*a.events, "start:"+a.name)
+	return a.startErr
+}
+func (a *orderApp) Shutdown(_ context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	*a.events = append(*a.events, "shutdown:"+a.name)
+	return nil
+}
+
+func stageNames(stages [][]*appEntry) [][]string {
+	out := make([][]string, 0, len(stages))
+	for _, stage := range stages {
+		names := make([]string, 0, len(stage))
+		for _, e := range stage {
+			names = append(names, e.name)
+		}
+		out = append(out, names)
+	}
+	return out
+}
+
+func TestResolveStages_NoDependencies(t *testing.T) {
+	entries := []*appEntry{
+		newAppEntry(0, "", &noHealthApp{}, nil),
+		newAppEntry(1, "", &noHealthApp{}, nil),
+	}
+	stages, err := resolveStages(entries)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"app-0", "app-1"}}, stageNames(stages))
+}
+
+func TestResolveStages_Ordered(t *testing.T) {
+	entries := []*appEntry{
+		newAppEntry(0, "http", &noHealthApp{}, []string{"kafka", "db"}),
+		newAppEntry(1, "kafka", &noHealthApp{}, nil),
+		newAppEntry(2, "db", &noHealthApp{}, nil),
+		newAppEntry(3, "cron", &noHealthApp{}, []string{"db"}),
+	}
+	stages, err := resolveStages(entries)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"kafka", "db"}, {"http", "cron"}}, stageNames(stages))
+}
+
+func TestResolveStages_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []*appEntry
+		wantErr error
+	}{
+		{
+			name: "cycle",
+			entries: []*appEntry{
+				newAppEntry(0, "a", &noHealthApp{}, []string{"b"}),
+				newAppEntry(1, "b", &noHealthApp{}, []string{"c"}),
+				newAppEntry(2, "c", &noHealthApp{}, []string{"a"}),
+			},
+			wantErr: ErrDependencyCycle,
+		},
+		{
+			name: "self",
+			entries: []*appEntry{
+				newAppEntry(0, "a", &noHealthApp{}, []string{"a"}),
+			},
+			wantErr: ErrDependencyCycle,
+		},
+		{
+			name: "unknown",
+			entries: []*appEntry{
+				newAppEntry(0, "a", &noHealthApp{}, []string{"missing"}),
+			},
+			wantErr: ErrUnknownDependency,
+		},
+		{
+			name: "duplicate",
+			entries: []*appEntry{
+				newAppEntry(0, "a", &noHealthApp{}, nil),
+				newAppEntry(1, "a", &noHealthApp{}, nil),
+			},
+			wantErr: ErrDuplicateApp,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveStages(tt.entries)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewAppEntry_InterfaceFallback(t *testing.T) {
+	app := &orderApp{name: "svc", deps: []string{"db"}}
+	e := newAppEntry(3, "", app, nil)
+	assert.Equal(t, "svc", e.name)
+	assert.Equal(t, []string{"db"}, e.dependsOn)
+
+	// 显式声明优先于接口
+	e = newAppEntry(3, "custom", app, []string{"cache"})
+	assert.Equal(t, "custom", e.name)
+	assert.Equal(t, []string{"cache"}, e.dependsOn)
+}
+
+func TestManager_Run_DependencyCycle(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+
+	m := NewManager()
+	m.Register(&orderApp{name: "a", deps: []string{"b"}, mu: &mu, events: &events})
+	m.Register(&orderApp{name: "b", deps: []string{"a"}, mu: &mu, events: &events})
+
+	err := m.Run(context.Background())
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+	assert.Empty(t, events, "no app should start when dependencies are invalid")
+}
+
+func TestManager_Run_DependencyOrder(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	newApp := func(name string) *orderApp {
+		return &orderApp{name: name, mu: &mu, events: &events}
+	}
+
+	m := NewManager(WithNamedApp("http", newApp("http"), "producer", "db"))
+	m.RegisterNamed("producer", newApp("producer"), "db")
+	m.RegisterNamed("db", newApp("db"))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(os.Interrupt)
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not complete within timeout")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"start:db", "start:producer", "start:http",
+		"shutdown:http", "shutdown:producer", "shutdown:db",
+	}, events)
+}
+
+func TestManager_Run_DependencyStartFailure(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+
+	m := NewManager()
+	m.RegisterNamed("http", &orderApp{name: "http", mu: &mu, events: &events}, "producer")
+	m.RegisterNamed("producer", &orderApp{name: "producer", startErr: errors.New("broker down"), mu: &mu, events: &events}, "db")
+	m.RegisterNamed("db", &orderApp{name: "db", mu: &mu, events: &events})
+
+	err := m.Run(context.Background())
+	require.Error(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"start:db", "start:producer", "shutdown:db"}, events,
+		"dependents must not start and started apps must be shut down")
+}
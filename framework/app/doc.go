@@ -2,4 +2,20 @@
 //
 // 通过 IManager 管理多个 IApp 实例的启动与优雅关闭，
 // 并支持基于 HealthChecker 接口的健康检查。
+//
+// 应用可通过 RegisterNamed / WithNamedApp 或 DependencyDeclarer 接口声明依赖，
+// Manager 按拓扑阶段依次启动，依赖缺失或存在环时 Run 直接返回错误，
+// 关闭时按启动顺序的逆序执行。
+//
+// 通过 WithHealthServer 可启用管理端 HTTP 服务，暴露 /livez、/readyz、/healthz 端点，
+// 以 JSON 返回每个应用的健康状态；收到关闭信号后 /readyz 立即返回 503，便于负载均衡摘流。
+//
+// 关闭分为三个阶段，各有独立超时：标记未就绪并等待排空宽限期（WithDrainGracePeriod），
+// 逆序调用 PreStopper 钩子停止接收新流量（WithPreStopTimeout），最后逆序调用 Shutdown（WithShutdownTimeout）。
+//
+// 通过 WithSupervisor 可启用监管模式，定期检查应用健康状态，
+// 连续失败后按 RestartPolicy 不处理、退避重启或触发整个进程关闭。
+//
+// 通过 WithReloadSignals(syscall.SIGHUP) 或调用 IManager.Reload 可在不重启进程的情况下
+// 按启动顺序调用 Reloader 钩子刷新配置，最近一次结果记录在健康报告的 last_reload 字段中。
 package app
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// PreStopper 可选接口，实现此接口的 IApp 在排空阶段被调用，
+// 用于停止接收新流量（如停止拉取消息），并等待处理中的请求完成。
+// 调用顺序与 Shutdown 一致，均为启动顺序的逆序。
+type PreStopper interface {
+	PreStop(ctx context.Context) error
+}
+
+// drain 执行关闭前的排空阶段：
+//  1. 等待 drainGracePeriod，期间 /readyz 已返回 503，负载均衡摘除流量；
+//     再次收到退出信号时跳过等待
+//  2. 在 preStopTimeout 内按启动顺序的逆序调用 PreStop 钩子
+//
+// 返回所有 PreStop 钩子的错误，排空失败不阻止后续 Shutdown。
+func (m *manager) drain(started []*appEntry, quit <-chan os.Signal) []error {
+	if m.drainGracePeriod > 0 {
+		m.log.Info("Server draining...", slog.String("component", "app"), slog.Duration("grace_period", m.drainGracePeriod))
+		timer := time.NewTimer(m.drainGracePeriod)
+		select {
+		case <-timer.C:
+		case <-quit:
+			timer.Stop()
+			m.log.Warn("Server drain interrupted by signal", slog.String("component", "app"))
+		}
+	}
+
+	preStopCtx, cancel := context.WithTimeout(context.Background(), m.preStopTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		ps, ok := started[i].current().(PreStopper)
+		if !ok {
+			continue
+		}
+		if err := ps.PreStop(preStopCtx); err != nil {
+			m.log.Error("Server app pre-stop failed", slog.String("component", "app"), slog.String("app", started[i].name), slog.String("error", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
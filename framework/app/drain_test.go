@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// preStopApp 记录 PreStop 与 Shutdown 的调用顺序
+type preStopApp struct {
+	name       string
+	preStopErr error
+	mu         *sync.Mutex
+	events     *[]string
+	deadline   *time.Time
+}
+
+func (a *preStopApp) Start(_ context.Context) error { return nil }
+func (a *preStopApp) PreStop(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	*a.events = append(*a.events, "prestop:"+a.name)
+	if a.deadline != nil {
+		*a.deadline, _ = ctx.Deadline()
+	}
+	return a.preStopErr
+}
+func (a *preStopApp) Shutdown(_ context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	*a.events = append(*a.events, "shutdown:"+a.name)
+	return nil
+}
+
+func TestManager_Run_DrainPhases(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	var deadline time.Time
+	newApp := func(name string) *preStopApp {
+		return &preStopApp{name: name, mu: &mu, events: &events, deadline: &deadline}
+	}
+
+	m := NewManager(
+		WithDrainGracePeriod(150*time.Millisecond),
+		WithPreStopTimeout(time.Minute),
+		WithHealthCacheTTL(0),
+	)
+	m.RegisterNamed("db", newApp("db"))
+	m.RegisterNamed("consumer", newApp("consumer"), "db")
+	m.RegisterNamed("plain", &noHealthApp{})
+	mgr := m.(*manager)
+
+	done := runManager(m)
+	require.Eventually(t, func() bool { return mgr.phase.Load() == phaseRunning }, time.Second, 5*time.Millisecond)
+
+	signaledAt := time.Now()
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(os.Interrupt)
+
+	// 宽限期内：已标记为未就绪，但尚未调用 PreStop
+	require.Eventually(t, func() bool { return mgr.phase.Load() == phaseShuttingDown }, time.Second, time.Millisecond)
+	assert.False(t, m.Health(context.Background()).Ready)
+	mu.Lock()
+	assert.Empty(t, events, "pre-stop hooks must wait for the grace period")
+	mu.Unlock()
+
+	require.NoError(t, waitRun(t, done))
+	assert.GreaterOrEqual(t, time.Since(signaledAt), 150*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"prestop:consumer", "prestop:db",
+		"shutdown:consumer", "shutdown:db",
+	}, events)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second, "pre-stop should use its own timeout")
+}
+
+func TestManager_Run_PreStopErrorStillShutsDown(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	preStopErr := errors.New("drain failed")
+
+	m := NewManager()
+	m.Register(&preStopApp{name: "a", preStopErr: preStopErr, mu: &mu, events: &events})
+
+	done := runManager(m)
+	time.Sleep(100 * time.Millisecond)
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(os.Interrupt)
+
+	err := waitRun(t, done)
+	assert.ErrorIs(t, err, preStopErr)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"prestop:a", "shutdown:a"}, events)
+}
+
+func TestWithDrainOptions(t *testing.T) {
+	mgr := NewManager(WithDrainGracePeriod(5*time.Second), WithPreStopTimeout(0)).(*manager)
+	assert.Equal(t, 5*time.Second, mgr.drainGracePeriod)
+	assert.Equal(t, 10*time.Second, mgr.preStopTimeout, "zero pre-stop timeout should keep default")
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 管理器运行阶段
+const (
+	phaseIdle         int32 = iota // 未启动
+	phaseStarting                  // 启动中
+	phaseRunning                   // 运行中
+	phaseShuttingDown              // 关闭中
+	phaseStopped                   // 已关闭
+)
+
+// phaseName 返回运行阶段的可读名称
+func phaseName(phase int32) string {
+	switch phase {
+	case phaseIdle:
+		return "idle"
+	case phaseStarting:
+		return "starting"
+	case phaseRunning:
+		return "running"
+	case phaseShuttingDown:
+		return "shutting_down"
+	case phaseStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// HealthStatus 健康状态
+type HealthStatus string
+
+const (
+	// HealthStatusUp 健康
+	HealthStatusUp HealthStatus = "up"
+	// HealthStatusDown 不健康
+	HealthStatusDown HealthStatus = "down"
+)
+
+// AppHealth 单个应用的健康检查结果
+type AppHealth struct {
+	Name      string       `json:"name"`
+	Status    HealthStatus `json:"status"`
+	Error     string       `json:"error,omitempty"`
+	LatencyMs int64        `json:"latency_ms"`
+	Restarts  int64        `json:"restarts"`
+}
+
+// HealthReport 全部应用的健康检查报告
+type HealthReport struct {
+	Status    HealthStatus `json:"status"`
+	Ready     bool         `json:"ready"`
+	Phase     string       `json:"phase"`
+	CheckedAt time.Time    `json:"checked_at"`
+	Apps      []AppHealth  `json:"apps"`
+
+	LastReload *ReloadReport `json:"last_reload,omitempty"`
+}
+
+// healthChecker 并发执行各应用健康检查，并在 ttl 内缓存结果
+type healthChecker struct {
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	cached    []AppHealth
+	errs      []error
+	checkedAt time.Time
+}
+
+// check 返回各应用的健康检查结果，ttl 内复用缓存
+func (c *healthChecker) check(ctx context.Context, entries []*appEntry) ([]AppHealth, []error, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl > 0 && !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.ttl {
+		return c.cached, c.errs, c.checkedAt
+	}
+
+	c.cached, c.errs = checkApps(ctx, entries, c.timeout)
+	c.checkedAt = time.Now()
+	return c.cached, c.errs, c.checkedAt
+}
+
+// checkApps 并发执行所有应用的健康检查。
+// timeout > 0 时为每个检查单独附加超时；未实现 HealthChecker 的应用视为健康。
+func checkApps(ctx context.Context, entries []*appEntry, timeout time.Duration) ([]AppHealth, []error) {
+	results := make([]AppHealth, len(entries))
+	errs := make([]error, len(entries))
+
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		results[i] = AppHealth{Name: e.name, Status: HealthStatusUp, Restarts: e.restarts.Load()}
+		hc, ok := e.current().(HealthChecker)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, name string, hc HealthChecker) {
+			defer wg.Done()
+
+			checkCtx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				checkCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			begin := time.Now()
+			err := runHealthCheck(checkCtx, hc)
+			results[i].LatencyMs = time.Since(begin).Milliseconds()
+			if err != nil {
+				results[i].Status = HealthStatusDown
+				results[i].Error = err.Error()
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+			}
+		}(i, e.name, hc)
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
+// runHealthCheck 执行健康检查，检查未在 ctx 截止前返回时视为失败
+func runHealthCheck(ctx context.Context, hc HealthChecker) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- hc.HealthCheck(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Health 返回所有已注册应用的健康报告，结果在缓存有效期内复用
+func (m *manager) Health(ctx context.Context) HealthReport {
+	apps, errs, checkedAt := m.health.check(ctx, m.apps)
+
+	phase := m.phase.Load()
+	report := HealthReport{
+		Status:    HealthStatusUp,
+		Phase:     phaseName(phase),
+		CheckedAt: checkedAt,
+		Apps:      apps,
+
+		LastReload: m.reload.lastReport(),
+	}
+	if errors.Join(errs...) != nil {
+		report.Status = HealthStatusDown
+	}
+	report.Ready = phase == phaseRunning && report.Status == HealthStatusUp
+	return report
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// 健康检查端点路径
+const (
+	LivezPath   = "/livez"
+	ReadyzPath  = "/readyz"
+	HealthzPath = "/healthz"
+)
+
+// healthServer 管理端 HTTP 服务，暴露存活、就绪与健康检查端点
+type healthServer struct {
+	addr     string
+	srv      *http.Server
+	listener net.Listener
+	log      *slog.Logger
+}
+
+// newHealthServer 创建管理端 HTTP 服务
+func newHealthServer(addr string, handler http.Handler, log *slog.Logger) *healthServer {
+	return &healthServer{
+		addr: addr,
+		srv: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		log: log,
+	}
+}
+
+// Start 监听地址并在后台提供服务，监听失败时返回错误
+func (s *healthServer) Start(_ context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = ln
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("Health server stopped", slog.String("component", "app"), slog.String("error", err.Error()))
+		}
+	}()
+	s.log.Info("Health server listening", slog.String("component", "app"), slog.String("addr", ln.Addr().String()))
+	return nil
+}
+
+// Shutdown 优雅关闭管理端 HTTP 服务
+func (s *healthServer) Shutdown(ctx context.Context) error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.srv.Shutdown(ctx)
+}
+
+// Addr 返回实际监听地址，未启动时返回配置地址
+func (s *healthServer) Addr() string {
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.addr
+}
+
+// healthHandler 构建健康检查端点路由：
+//   - /livez：进程存活即返回 200，管理器关闭完成后返回 503
+//   - /readyz：全部应用已启动、未进入关闭流程且健康检查通过时返回 200
+//   - /healthz：全部应用健康检查通过时返回 200
+//
+// 三个端点均返回 JSON 格式的 HealthReport。
+func (m *manager) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(LivezPath, func(w http.ResponseWriter, r *http.Request) {
+		phase := m.phase.Load()
+		status := HealthStatusUp
+		if phase == phaseStopped {
+			status = HealthStatusDown
+		}
+		writeHealth(w, status == HealthStatusUp, map[string]any{
+			"status": status,
+			"phase":  phaseName(phase),
+		})
+	})
+	mux.HandleFunc(ReadyzPath, func(w http.ResponseWriter, r *http.Request) {
+		// 进入关闭流程后立即返回失败，无需等待健康检查
+		if phase := m.phase.Load(); phase != phaseRunning {
+			writeHealth(w, false, HealthReport{
+				Status:    HealthStatusDown,
+				Phase:     phaseName(phase),
+				CheckedAt: time.Now(),
+				Apps:      []AppHealth{},
+			})
+			return
+		}
+		report := m.Health(r.Context())
+		writeHealth(w, report.Ready, report)
+	})
+	mux.HandleFunc(HealthzPath, func(w http.ResponseWriter, r *http.Request) {
+		report := m.Health(r.Context())
+		writeHealth(w, report.Status == HealthStatusUp, report)
+	})
+	return mux
+}
+
+// writeHealth 输出 JSON 响应，ok 为 false 时返回 503
+func writeHealth(w http.ResponseWriter, ok bool, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingHealthApp struct {
+	noHealthApp
+	calls atomic.Int32
+	err   error
+	delay time.Duration
+}
+
+func (c *countingHealthApp) HealthCheck(ctx context.Context) error {
+	c.calls.Add(1)
+	if c.delay > 0 {
+		select {
+		case <-time.After(c.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return c.err
+}
+
+func doHealthRequest(t *testing.T, h http.Handler, path string) (int, HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report HealthReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return rec.Code, report
+}
+
+func TestManager_Health_ReportsEveryApp(t *testing.T) {
+	m := NewManager(WithHealthCacheTTL(0))
+	m.RegisterNamed("ok", &healthyApp{})
+	m.RegisterNamed("bad", &unhealthyApp{})
+	m.RegisterNamed("plain", &noHealthApp{})
+
+	report := m.Health(context.Background())
+	assert.Equal(t, HealthStatusDown, report.Status)
+	assert.False(t, report.Ready)
+	require.Len(t, report.Apps, 3)
+	assert.Equal(t, AppHealth{Name: "ok", Status: HealthStatusUp, LatencyMs: report.Apps[0].LatencyMs}, report.Apps[0])
+	assert.Equal(t, HealthStatusDown, report.Apps[1].Status)
+	assert.Equal(t, "unhealthy", report.Apps[1].Error)
+	assert.Equal(t, HealthStatusUp, report.Apps[2].Status)
+}
+
+func TestManager_IsHealthy_JoinsAllErrors(t *testing.T) {
+	errA := errors.New("a down")
+	errB := errors.New("b down")
+	m := NewManager()
+	m.RegisterNamed("a", &countingHealthApp{err: errA})
+	m.RegisterNamed("b", &countingHealthApp{err: errB})
+
+	err := m.IsHealthy(context.Background())
+	assert.ErrorIs(t, err, errA)
+	assert.ErrorIs(t, err, errB)
+}
+
+func TestManager_Health_CheckTimeout(t *testing.T) {
+	m := NewManager(WithHealthCheckTimeout(20*time.Millisecond), WithHealthCacheTTL(0))
+	m.RegisterNamed("slow", &countingHealthApp{delay: time.Second})
+
+	begin := time.Now()
+	report := m.Health(context.Background())
+	assert.Less(t, time.Since(begin), 500*time.Millisecond)
+	assert.Equal(t, HealthStatusDown, report.Status)
+	assert.Contains(t, report.Apps[0].Error, context.DeadlineExceeded.Error())
+}
+
+func TestManager_Health_Cached(t *testing.T) {
+	app := &countingHealthApp{}
+	m := NewManager(WithHealthCacheTTL(time.Hour))
+	m.Register(app)
+
+	m.Health(context.Background())
+	m.Health(context.Background())
+	assert.Equal(t, int32(1), app.calls.Load(), "second call should reuse cached result")
+
+	// IsHealthy 不使用缓存
+	require.NoError(t, m.IsHealthy(context.Background()))
+	assert.Equal(t, int32(2), app.calls.Load())
+}
+
+func TestManager_HealthHandler_Phases(t *testing.T) {
+	m := NewManager(WithHealthCacheTTL(0))
+	m.Register(&healthyApp{})
+	mgr := m.(*manager)
+	h := mgr.healthHandler()
+
+	// 启动完成前：存活但未就绪
+	mgr.phase.Store(phaseStarting)
+	code, _ := doHealthRequest(t, h, LivezPath)
+	assert.Equal(t, http.StatusOK, code)
+	code, report := doHealthRequest(t, h, ReadyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "starting", report.Phase)
+
+	mgr.phase.Store(phaseRunning)
+	code, report = doHealthRequest(t, h, ReadyzPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Ready)
+	code, _ = doHealthRequest(t, h, HealthzPath)
+	assert.Equal(t, http.StatusOK, code)
+
+	// 进入关闭流程：就绪检查立即失败
+	mgr.phase.Store(phaseShuttingDown)
+	code, report = doHealthRequest(t, h, ReadyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting_down", report.Phase)
+	code, _ = doHealthRequest(t, h, LivezPath)
+	assert.Equal(t, http.StatusOK, code)
+
+	mgr.phase.Store(phaseStopped)
+	code, _ = doHealthRequest(t, h, LivezPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestManager_HealthHandler_Unhealthy(t *testing.T) {
+	m := NewManager(WithHealthCacheTTL(0))
+	m.Register(&unhealthyApp{})
+	mgr := m.(*manager)
+	mgr.phase.Store(phaseRunning)
+	h := mgr.healthHandler()
+
+	code, report := doHealthRequest(t, h, HealthzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusDown, report.Status)
+	code, _ = doHealthRequest(t, h, ReadyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+// blockingShutdownApp 关闭时阻塞，用于观察关闭过程中的就绪状态
+type blockingShutdownApp struct {
+	noHealthApp
+	shuttingDown chan struct{}
+	release      chan struct{}
+}
+
+func (b *blockingShutdownApp) Shutdown(_ context.Context) error {
+	close(b.shuttingDown)
+	<-b.release
+	return nil
+}
+
+func TestManager_Run_HealthServer(t *testing.T) {
+	app := &blockingShutdownApp{
+		shuttingDown: make(chan struct{}),
+		release:      make(chan struct{}),
+	}
+	m := NewManager(WithHealthServer("127.0.0.1:0"), WithHealthCacheTTL(0), WithApp(app))
+	mgr := m.(*manager)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run(context.Background())
+	}()
+
+	require.Eventually(t, func() bool {
+		return mgr.phase.Load() == phaseRunning
+	}, 2*time.Second, 10*time.Millisecond)
+
+	url := "http://" + mgr.healthSrv.Addr()
+	resp, err := http.Get(url + ReadyzPath)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(os.Interrupt)
+	<-app.shuttingDown
+
+	resp, err = http.Get(url + ReadyzPath)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "readiness must fail once shutdown begins")
+
+	close(app.release)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not complete within timeout")
+	}
+}
+
+func TestManager_Run_HealthServerListenError(t *testing.T) {
+	m := NewManager(WithHealthServer("256.0.0.1:bad"))
+	m.Register(&noHealthApp{})
+	assert.Error(t, m.Run(context.Background()))
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -17,20 +18,46 @@ import (
 
 // managerOption Manager 选项的中间结构体
 type managerOption struct {
-	apps            []IApp
+	apps            []*appEntry
 	log             *slog.Logger
 	shutdownTimeout time.Duration
 	startupTimeout  time.Duration
+
+	healthAddr    string
+	healthTimeout time.Duration
+	healthTTL     time.Duration
+
+	drainGracePeriod time.Duration
+	preStopTimeout   time.Duration
+
+	reloadSignals []os.Signal
+	reloadTimeout time.Duration
+
+	superviseInterval    time.Duration
+	failureThreshold     int
+	restartPolicies      map[string]RestartPolicy
+	defaultRestartPolicy RestartPolicy
 }
 
 // ManagerOption Manager 选项函数
 type ManagerOption func(*managerOption)
 
 type manager struct {
-	apps            []IApp
-	log             *slog.Logger
-	shutdownTimeout time.Duration
-	startupTimeout  time.Duration
+	apps             []*appEntry
+	log              *slog.Logger
+	shutdownTimeout  time.Duration
+	startupTimeout   time.Duration
+	drainGracePeriod time.Duration
+	preStopTimeout   time.Duration
+
+	phase         atomic.Int32
+	started       []*appEntry // 实际启动顺序，进入运行阶段前写入
+	health        *healthChecker
+	healthSrv     *healthServer
+	supervisor    *supervisor
+	reload        *reloader
+	reloadSignals []os.Signal
+	stopCh        chan error
 }
 
 var _ IManager = (*manager)(nil)
@@ -39,6 +66,10 @@ var _ IManager = (*manager)(nil)
 func NewManager(opts ...ManagerOption) IManager {
 	cnf := &managerOption{
 		shutdownTimeout: 30 * time.Second,
+		preStopTimeout:  10 * time.Second,
+		reloadTimeout:   30 * time.Second,
+		healthTimeout:   3 * time.Second,
+		healthTTL:       time.Second,
 	}
 
 	for _, opt := range opts {
@@ -46,9 +77,18 @@ func NewManager(opts ...ManagerOption) IManager {
 	}
 
 	m := &manager{
-		apps:            cnf.apps,
-		shutdownTimeout: cnf.shutdownTimeout,
-		startupTimeout:  cnf.startupTimeout,
+		apps:             cnf.apps,
+		shutdownTimeout:  cnf.shutdownTimeout,
+		startupTimeout:   cnf.startupTimeout,
+		drainGracePeriod: cnf.drainGracePeriod,
+		preStopTimeout:   cnf.preStopTimeout,
+		health: &healthChecker{
+			timeout: cnf.healthTimeout,
+			ttl:     cnf.healthTTL,
+		},
+		reload:        &reloader{timeout: cnf.reloadTimeout},
+		reloadSignals: cnf.reloadSignals,
+		stopCh:        make(chan error, 1),
 	}
 	if cnf.log != nil {
 		m.log = cnf.log
@@ -57,7 +97,23 @@ func NewManager(opts ...ManagerOption) IManager {
 		m.log = logger.NewConsoleLogger()
 	}
 	if m.apps == nil {
-		m.apps = make([]IApp, 0)
+		m.apps = make([]*appEntry, 0)
+	}
+	if cnf.healthAddr != "" {
+		m.healthSrv = newHealthServer(cnf.healthAddr, m.healthHandler(), m.log)
+	}
+	if cnf.superviseInterval > 0 {
+		threshold := cnf.failureThreshold
+		if threshold <= 0 {
+			threshold = 3
+		}
+		m.supervisor = &supervisor{
+			m:         m,
+			interval:  cnf.superviseInterval,
+			threshold: threshold,
+			policies:  cnf.restartPolicies,
+			fallback:  cnf.defaultRestartPolicy,
+		}
 	}
 
 	return m
@@ -65,37 +121,70 @@ func NewManager(opts ...ManagerOption) IManager {
 
 // Register 注册应用
 func (m *manager) Register(app IApp) {
-	m.apps = append(m.apps, app)
+	m.apps = append(m.apps, newAppEntry(len(m.apps), "", app, nil))
+}
+
+// RegisterNamed 以指定名称注册应用，并声明其依赖的应用名称
+func (m *manager) RegisterNamed(name string, app IApp, dependsOn ...string) {
+	m.apps = append(m.apps, newAppEntry(len(m.apps), name, app, dependsOn))
 }
 
 // Run 启动应用，返回启动或关闭错误
 func (m *manager) Run(ctx context.Context) error {
 	m.log.Info("Server startup...")
+	m.phase.Store(phaseStarting)
 
-	// 启动 app
-	for i := range m.apps {
-		startCtx := ctx
-		var startCancel context.CancelFunc
-		if m.startupTimeout > 0 {
-			startCtx, startCancel = context.WithTimeout(ctx, m.startupTimeout)
-		}
-		err := m.apps[i].Start(startCtx)
-		if startCancel != nil {
-			startCancel()
+	// 按依赖关系划分启动阶段，依赖缺失或存在环时拒绝启动
+	stages, err := resolveStages(m.apps)
+	if err != nil {
+		m.log.Error("Server app dependency resolve failed", slog.String("component", "app"), slog.String("error", err.Error()))
+		m.phase.Store(phaseStopped)
+		return xerror.WrapWithXCode(err, xcode.InternalServerError)
+	}
+
+	// 管理端健康检查服务先于所有 app 启动，启动期间即可响应探针
+	if m.healthSrv != nil {
+		if err := m.healthSrv.Start(ctx); err != nil {
+			m.log.Error("Health server start failed", slog.String("component", "app"), slog.String("error", err.Error()))
+			m.phase.Store(phaseStopped)
+			return xerror.WrapWithXCode(err, xcode.InternalServerError)
 		}
-		if err != nil {
-			m.log.Error("Server app start failed", slog.String("component", "app"), slog.Int("index", i), slog.String("error", err.Error()))
-			// 逆序关闭已启动的 app（使用 shutdownTimeout 防止阻塞）
-			cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
-			for j := i - 1; j >= 0; j-- {
-				if err := m.apps[j].Shutdown(cleanupCtx); err != nil {
-					m.log.Error("Server app shutdown failed", slog.String("component", "app"), slog.Int("index", j), slog.String("error", err.Error()))
-				}
+	}
+
+	// 逐阶段启动 app，started 记录实际启动顺序，关闭时逆序执行
+	started := make([]*appEntry, 0, len(m.apps))
+	for stage, entries := range stages {
+		for _, e := range entries {
+			if err := m.startApp(ctx, e); err != nil {
+				m.log.Error("Server app start failed", slog.String("component", "app"), slog.String("app", e.name), slog.Int("stage", stage), slog.String("error", err.Error()))
+				// 逆序关闭已启动的 app（使用 shutdownTimeout 防止阻塞）
+				cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+				m.phase.Store(phaseShuttingDown)
+				m.shutdownApps(cleanupCtx, started)
+				m.shutdownHealthServer(cleanupCtx)
+				m.phase.Store(phaseStopped)
+				cleanupCancel()
+				return xerror.WrapWithXCode(err, xcode.InternalServerError)
 			}
-			cleanupCancel()
-			return xerror.WrapWithXCode(err, xcode.InternalServerError)
+			m.log.Debug("Server app started", slog.String("component", "app"), slog.String("app", e.name), slog.Int("stage", stage))
+			started = append(started, e)
 		}
 	}
+	// 进入运行阶段前注册重新加载信号，避免信号默认行为终止进程
+	var reloadSig chan os.Signal
+	if len(m.reloadSignals) > 0 {
+		reloadSig = make(chan os.Signal, 1)
+		signal.Notify(reloadSig, m.reloadSignals...)
+		defer signal.Stop(reloadSig)
+	}
+
+	m.started = started
+	m.phase.Store(phaseRunning)
+
+	// 监管模式：定期检查应用健康状态，按重启策略处理失败
+	if m.supervisor != nil {
+		m.supervisor.start(ctx, started)
+	}
 
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 30 seconds.
@@ -104,30 +193,95 @@ func (m *manager) Run(ctx context.Context) error {
 	// kill -2 is syscall.SIGINT
 	// kill -9 is syscall.SIGKILL but can't be catch, so don't need add it
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
 	// 仅监听 SIGINT/SIGTERM：覆盖 kill 和 Ctrl+C 的常见场景。
-	// SIGHUP 等自定义信号需在外部 signal.Notify 处理。
-	<-quit
+	// 重新加载信号（如 SIGHUP）需通过 WithReloadSignals 显式开启，已在进入运行阶段前注册。
+	// 监管策略要求整体退出时，通过 stopCh 触发同样的关闭流程。
+	var stopErr error
+wait:
+	for {
+		select {
+		case <-quit:
+			break wait
+		case stopErr = <-m.stopCh:
+			break wait
+		case <-reloadSig:
+			// 异步执行，避免阻塞退出信号；Reload 内部串行化
+			go func() { _ = m.Reload(context.Background()) }()
+		}
+	}
+	// 先切换阶段，使就绪检查立即失败，负载均衡摘除流量
+	m.phase.Store(phaseShuttingDown)
 	m.log.Info("Server shutting down...")
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
-	defer cancel()
+	// 等待进行中的重新加载结束，之后的重新加载请求会被拒绝
+	m.reload.wait()
 
-	var shutdownErrs []error
-	for i := len(m.apps) - 1; i >= 0; i-- {
-		if err := m.apps[i].Shutdown(shutdownCtx); err != nil {
-			m.log.Error("Server app shutdown failed", slog.String("component", "app"), slog.Int("index", i), slog.String("error", err.Error()))
-			shutdownErrs = append(shutdownErrs, err)
-		}
+	// 先停止监管，避免关闭期间触发重启
+	if m.supervisor != nil {
+		m.supervisor.stop()
 	}
 
+	// 排空阶段：等待宽限期并调用 PreStop 钩子
+	shutdownErrs := m.drain(started, quit)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	shutdownErrs = append(shutdownErrs, m.shutdownApps(shutdownCtx, started)...)
+	m.phase.Store(phaseStopped)
+	m.shutdownHealthServer(shutdownCtx)
 	if len(shutdownErrs) > 0 {
 		m.log.Error("Server shutdown completed with errors", slog.String("component", "app"))
 	}
 
 	m.log.Info("Server exiting")
+	if stopErr != nil {
+		return errors.Join(xerror.WrapWithXCode(stopErr, xcode.InternalServerError), errors.Join(shutdownErrs...))
+	}
 	return errors.Join(shutdownErrs...)
 }
 
+// requestStop 请求触发优雅关闭流程，重复请求只保留第一个原因
+func (m *manager) requestStop(cause error) {
+	select {
+	case m.stopCh <- cause:
+	default:
+	}
+}
+
+// startApp 启动单个 app，startupTimeout > 0 时附加启动超时
+func (m *manager) startApp(ctx context.Context, e *appEntry) error {
+	if m.startupTimeout <= 0 {
+		return e.current().Start(ctx)
+	}
+	startCtx, cancel := context.WithTimeout(ctx, m.startupTimeout)
+	defer cancel()
+	return e.current().Start(startCtx)
+}
+
+// shutdownApps 按启动顺序的逆序关闭 app，返回所有关闭错误
+func (m *manager) shutdownApps(ctx context.Context, started []*appEntry) []error {
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := started[i].current().Shutdown(ctx); err != nil {
+			m.log.Error("Server app shutdown failed", slog.String("component", "app"), slog.String("app", started[i].name), slog.String("error", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// shutdownHealthServer 关闭管理端健康检查服务
+func (m *manager) shutdownHealthServer(ctx context.Context) {
+	if m.healthSrv == nil {
+		return
+	}
+	if err := m.healthSrv.Shutdown(ctx); err != nil {
+		m.log.Error("Health server shutdown failed", slog.String("component", "app"), slog.String("error", err.Error()))
+	}
+}
+
 // MustRun 启动应用，失败时直接 os.Exit(1)
 func (m *manager) MustRun(ctx context.Context) {
 	if err := m.Run(ctx); err != nil {
@@ -136,15 +290,8 @@ func (m *manager) MustRun(ctx context.Context) {
 	}
 }
 
-// IsHealthy 检查所有已注册应用的健康状态
+// IsHealthy 检查所有已注册应用的健康状态，不使用缓存结果
 func (m *manager) IsHealthy(ctx context.Context) error {
-	var errs []error
-	for _, app := range m.apps {
-		if hc, ok := app.(HealthChecker); ok {
-			if err := hc.HealthCheck(ctx); err != nil {
-				errs = append(errs, err)
-			}
-		}
-	}
+	_, errs := checkApps(ctx, m.apps, m.health.timeout)
 	return errors.Join(errs...)
-}
\ No newline at end of file
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"log/slog"
+	"os"
 	"time"
 )
 
@@ -15,7 +16,14 @@ func WithLogger(log *slog.Logger) ManagerOption {
 // WithApp 注册一个应用实例到管理器
 func WithApp(app IApp) ManagerOption {
 	return func(o *managerOption) {
-		o.apps = append(o.apps, app)
+		o.apps = append(o.apps, newAppEntry(len(o.apps), "", app, nil))
+	}
+}
+
+// WithNamedApp 以指定名称注册应用实例，并声明其依赖的应用名称
+func WithNamedApp(name string, app IApp, dependsOn ...string) ManagerOption {
+	return func(o *managerOption) {
+		o.apps = append(o.apps, newAppEntry(len(o.apps), name, app, dependsOn))
 	}
 }
 
@@ -28,9 +36,96 @@ func WithShutdownTimeout(d time.Duration) ManagerOption {
 	}
 }
 
+// WithDrainGracePeriod 设置关闭前的排空宽限期，默认 0（不等待）。
+// 收到退出信号后 /readyz 立即返回 503，等待宽限期后再调用 PreStop 与 Shutdown。
+func WithDrainGracePeriod(d time.Duration) ManagerOption {
+	return func(o *managerOption) {
+		if d >= 0 {
+			o.drainGracePeriod = d
+		}
+	}
+}
+
+// WithPreStopTimeout 设置全部 PreStop 钩子的总超时时间，默认 10 秒
+func WithPreStopTimeout(d time.Duration) ManagerOption {
+	return func(o *managerOption) {
+		if d > 0 {
+			o.preStopTimeout = d
+		}
+	}
+}
+
 // WithStartupTimeout 设置单个应用启动超时时间，0 表示不限制
 func WithStartupTimeout(d time.Duration) ManagerOption {
 	return func(o *managerOption) {
 		o.startupTimeout = d
 	}
-}
\ No newline at end of file
+}
+
+// WithHealthServer 启用管理端 HTTP 服务，在 addr 上暴露 /livez、/readyz、/healthz 端点
+func WithHealthServer(addr string) ManagerOption {
+	return func(o *managerOption) {
+		o.healthAddr = addr
+	}
+}
+
+// WithHealthCheckTimeout 设置单个应用健康检查的超时时间，默认 3 秒，0 表示不限制
+func WithHealthCheckTimeout(d time.Duration) ManagerOption {
+	return func(o *managerOption) {
+		if d >= 0 {
+			o.healthTimeout = d
+		}
+	}
+}
+
+// WithHealthCacheTTL 设置健康检查结果的缓存时间，默认 1 秒，0 表示不缓存
+func WithHealthCacheTTL(d time.Duration) ManagerOption {
+	return func(o *managerOption) {
+		if d >= 0 {
+			o.healthTTL = d
+		}
+	}
+}
+
+// WithSupervisor 启用监管模式：每隔 interval 检查一次实现了 HealthChecker 的应用，
+// 连续失败 failureThreshold 次（<= 0 时默认 3）后按其 RestartPolicy 处理
+func WithSupervisor(interval time.Duration, failureThreshold int) ManagerOption {
+	return func(o *managerOption) {
+		o.superviseInterval = interval
+		o.failureThreshold = failureThreshold
+	}
+}
+
+// WithRestartPolicy 为指定名称的应用设置监管重启策略
+func WithRestartPolicy(name string, policy RestartPolicy) ManagerOption {
+	return func(o *managerOption) {
+		if o.restartPolicies == nil {
+			o.restartPolicies = make(map[string]RestartPolicy)
+		}
+		o.restartPolicies[name] = policy
+	}
+}
+
+// WithDefaultRestartPolicy 设置未单独配置策略的应用所使用的监管重启策略，默认 RestartNever
+func WithDefaultRestartPolicy(policy RestartPolicy) ManagerOption {
+	return func(o *managerOption) {
+		o.defaultRestartPolicy = policy
+	}
+}
+
+// WithReloadSignals 设置触发重新加载的信号，通常传入 syscall.SIGHUP。
+// 未设置时不监听任何重新加载信号，仍可通过 IManager.Reload 以编程方式触发。
+func WithReloadSignals(sigs ...os.Signal) ManagerOption {
+	return func(o *managerOption) {
+		o.reloadSignals = append(o.reloadSignals, sigs...)
+	}
+}
+
+// WithReloadTimeout 设置单次重新加载的总超时时间，默认 30 秒
+func WithReloadTimeout(d time.Duration) ManagerOption {
+	return func(o *managerOption) {
+		if d > 0 {
+			o.reloadTimeout = d
+		}
+	}
+}
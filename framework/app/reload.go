@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrReloadNotRunning 管理器未处于运行阶段，拒绝重新加载
+var ErrReloadNotRunning = errors.New("app: reload requires running manager")
+
+// Reloader 可选接口，实现此接口的 IApp 在收到重新加载信号或调用 IManager.Reload 时被调用，
+// 用于在不重启进程的情况下刷新配置（如日志等级、限流参数、CORS 白名单）。
+type Reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// AppReload 单个应用的重新加载结果
+type AppReload struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReloadReport 一次重新加载的结果
+type ReloadReport struct {
+	At   time.Time   `json:"at"`
+	Apps []AppReload `json:"apps"`
+}
+
+// reloader 串行执行重新加载，并记录最近一次结果
+type reloader struct {
+	timeout time.Duration
+
+	mu     sync.Mutex // 串行化重新加载，关闭流程也会获取以等待进行中的重新加载
+	last   sync.Mutex // 保护 report，避免健康检查读取时等待整个重新加载
+	report *ReloadReport
+}
+
+// lastReport 返回最近一次重新加载的结果，从未执行时返回 nil
+func (r *reloader) lastReport() *ReloadReport {
+	r.last.Lock()
+	defer r.last.Unlock()
+	return r.report
+}
+
+// wait 等待进行中的重新加载结束
+func (r *reloader) wait() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+}
+
+// Reload 按启动顺序调用已启动应用的 Reloader 钩子。
+// 多次触发时串行执行；单个应用失败不影响其他应用，返回合并后的错误。
+func (m *manager) Reload(ctx context.Context) error {
+	m.reload.mu.Lock()
+	defer m.reload.mu.Unlock()
+
+	if m.phase.Load() != phaseRunning {
+		return ErrReloadNotRunning
+	}
+
+	if m.reload.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.reload.timeout)
+		defer cancel()
+	}
+
+	m.log.Info("Server reloading...", slog.String("component", "app"))
+	report := &ReloadReport{At: time.Now(), Apps: make([]AppReload, 0)}
+	var errs []error
+	for _, e := range m.started {
+		r, ok := e.current().(Reloader)
+		if !ok {
+			continue
+		}
+		result := AppReload{Name: e.name}
+		if err := r.Reload(ctx); err != nil {
+			m.log.Error("Server app reload failed", slog.String("component", "app"), slog.String("app", e.name), slog.String("error", err.Error()))
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", e.name, err))
+		}
+		report.Apps = append(report.Apps, result)
+	}
+
+	m.reload.last.Lock()
+	m.reload.report = report
+	m.reload.last.Unlock()
+
+	if len(errs) > 0 {
+		m.log.Error("Server reload completed with errors", slog.String("component", "app"))
+	} else {
+		m.log.Info("Server reloaded", slog.String("component", "app"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reloadApp 记录 Reload 调用，并检测并发调用
+type reloadApp struct {
+	noHealthApp
+	err      error
+	delay    time.Duration
+	calls    atomic.Int32
+	inflight atomic.Int32
+	overlap  atomic.Bool
+}
+
+func (r *reloadApp) Reload(_ context.Context) error {
+	if r.inflight.Add(1) > 1 {
+		r.overlap.Store(true)
+	}
+	defer r.inflight.Add(-1)
+	time.Sleep(r.delay)
+	r.calls.Add(1)
+	return r.err
+}
+
+func TestManager_Reload_NotRunning(t *testing.T) {
+	m := NewManager()
+	m.Register(&reloadApp{})
+	assert.ErrorIs(t, m.Reload(context.Background()), ErrReloadNotRunning)
+}
+
+func TestManager_Reload_ReportsPerAppErrors(t *testing.T) {
+	ok := &reloadApp{}
+	bad := &reloadApp{err: errors.New("bad config")}
+
+	m := NewManager(WithHealthCacheTTL(0))
+	m.RegisterNamed("ok", ok)
+	m.RegisterNamed("bad", bad)
+	m.RegisterNamed("plain", &noHealthApp{})
+	done := runManager(m)
+	mgr := m.(*manager)
+	require.Eventually(t, func() bool { return mgr.phase.Load() == phaseRunning }, time.Second, 5*time.Millisecond)
+
+	assert.Nil(t, m.Health(context.Background()).LastReload)
+
+	err := m.Reload(context.Background())
+	assert.ErrorIs(t, err, bad.err)
+	assert.Equal(t, int32(1), ok.calls.Load())
+
+	last := m.Health(context.Background()).LastReload
+	require.NotNil(t, last)
+	assert.WithinDuration(t, time.Now(), last.At, time.Second)
+	assert.Equal(t, []AppReload{{Name: "ok"}, {Name: "bad", Error: "bad config"}}, last.Apps)
+
+	// 健康检查端点输出最近一次重新加载结果
+	mgr.phase.Store(phaseRunning)
+	rec := httptest.NewRecorder()
+	mgr.healthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HealthzPath, nil))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Contains(t, body, "last_reload")
+
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(os.Interrupt)
+	require.NoError(t, waitRun(t, done))
+	assert.ErrorIs(t, m.Reload(context.Background()), ErrReloadNotRunning)
+}
+
+func TestManager_Reload_Serialized(t *testing.T) {
+	app := &reloadApp{delay: 20 * time.Millisecond}
+
+	m := NewManager()
+	m.Register(app)
+	done := runManager(m)
+	mgr := m.(*manager)
+	require.Eventually(t, func() bool { return mgr.phase.Load() == phaseRunning }, time.Second, 5*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = m.Reload(context.Background())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(5), app.calls.Load())
+	assert.False(t, app.overlap.Load(), "reloads must not run concurrently")
+
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(os.Interrupt)
+	require.NoError(t, waitRun(t, done))
+}
+
+func TestManager_Reload_Signal(t *testing.T) {
+	app := &reloadApp{}
+
+	m := NewManager(WithReloadSignals(syscall.SIGHUP))
+	m.Register(app)
+	done := runManager(m)
+	mgr := m.(*manager)
+	require.Eventually(t, func() bool { return mgr.phase.Load() == phaseRunning }, time.Second, 5*time.Millisecond)
+
+	p, _ := os.FindProcess(os.Getpid())
+	// 等待信号监听注册完成
+	require.Eventually(t, func() bool {
+		_ = p.Signal(syscall.SIGHUP)
+		return app.calls.Load() > 0
+	}, 2*time.Second, 50*time.Millisecond)
+
+	_ = p.Signal(os.Interrupt)
+	require.NoError(t, waitRun(t, done))
+}
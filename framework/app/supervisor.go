@@ -0,0 +1,222 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+)
+
+// ErrAppFailed 应用运行期间失败且监管策略要求整体退出
+var ErrAppFailed = errors.New("app: supervised app failed")
+
+// RestartMode 应用失败后的处理方式
+type RestartMode int
+
+const (
+	// RestartNever 仅记录日志，不做处理
+	RestartNever RestartMode = iota
+	// RestartOnFailure 按退避策略重启应用
+	RestartOnFailure
+	// RestartEscalate 触发整个进程的优雅关闭
+	RestartEscalate
+)
+
+// String 返回重启模式的可读名称
+func (m RestartMode) String() string {
+	switch m {
+	case RestartNever:
+		return "never"
+	case RestartOnFailure:
+		return "on_failure"
+	case RestartEscalate:
+		return "escalate"
+	default:
+		return "unknown"
+	}
+}
+
+// RestartPolicy 单个应用的监管重启策略
+type RestartPolicy struct {
+	// Mode 失败后的处理方式，默认 RestartNever
+	Mode RestartMode
+	// MaxAttempts 单次失败后最多尝试重启的次数，0 表示默认 5 次
+	MaxAttempts uint
+	// Backoff 重启尝试之间的退避策略，默认指数退避（1s 起，最长 1 分钟）
+	Backoff retry.BackoffStrategy
+	// Factory 可选，每次重启前创建新的应用实例。
+	// 已关闭后无法再次 Start 的应用（如 mq 消费者）必须提供。
+	Factory func() (IApp, error)
+	// EscalateOnGiveUp 重启尝试全部失败后是否触发整个进程的优雅关闭
+	EscalateOnGiveUp bool
+}
+
+// supervisor 监管运行中的应用：定期执行健康检查，
+// 连续失败达到阈值后按各应用的 RestartPolicy 处理。
+type supervisor struct {
+	m         *manager
+	interval  time.Duration
+	threshold int
+	policies  map[string]RestartPolicy
+	fallback  RestartPolicy
+
+	appCtx context.Context // 传给 IApp.Start 的上下文，与 Run 的 ctx 一致
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// policyFor 返回应用的监管策略，未单独配置时使用默认策略
+func (s *supervisor) policyFor(name string) RestartPolicy {
+	if p, ok := s.policies[name]; ok {
+		return p
+	}
+	return s.fallback
+}
+
+// start 为每个实现了 HealthChecker 的应用启动监管 goroutine。
+// appCtx 用于重启时调用 IApp.Start，与首次启动保持一致。
+func (s *supervisor) start(appCtx context.Context, entries []*appEntry) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.appCtx = appCtx
+	s.cancel = cancel
+
+	for _, e := range entries {
+		if _, ok := e.current().(HealthChecker); !ok {
+			continue
+		}
+		s.wg.Add(1)
+		go func(e *appEntry) {
+			defer s.wg.Done()
+			s.watch(ctx, e, s.policyFor(e.name))
+		}(e)
+	}
+}
+
+// stop 停止监管并等待进行中的重启结束
+func (s *supervisor) stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// watch 定期检查单个应用，连续失败 threshold 次后执行监管策略
+func (s *supervisor) watch(ctx context.Context, e *appEntry, policy RestartPolicy) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		hc, ok := e.current().(HealthChecker)
+		if !ok {
+			continue
+		}
+		err := s.check(ctx, hc)
+		if err == nil {
+			failures = 0
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		failures++
+		s.m.log.Warn("Server app unhealthy", slog.String("component", "app"), slog.String("app", e.name),
+			slog.Int("failures", failures), slog.String("error", err.Error()))
+		if failures < s.threshold {
+			continue
+		}
+		failures = 0
+
+		switch policy.Mode {
+		case RestartOnFailure:
+			if rerr := s.restart(ctx, e, policy); rerr != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				s.m.log.Error("Server app restart gave up", slog.String("component", "app"), slog.String("app", e.name),
+					slog.String("error", rerr.Error()))
+				if policy.EscalateOnGiveUp {
+					s.escalate(e, rerr)
+					return
+				}
+			}
+		case RestartEscalate:
+			s.escalate(e, err)
+			return
+		default:
+			s.m.log.Error("Server app failed, restart policy is never", slog.String("component", "app"), slog.String("app", e.name),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+// check 执行一次健康检查，沿用管理器的健康检查超时
+func (s *supervisor) check(ctx context.Context, hc HealthChecker) error {
+	if s.m.health.timeout <= 0 {
+		return runHealthCheck(ctx, hc)
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, s.m.health.timeout)
+	defer cancel()
+	return runHealthCheck(checkCtx, hc)
+}
+
+// restart 关闭失败的应用实例并按退避策略重新启动
+func (s *supervisor) restart(ctx context.Context, e *appEntry, policy RestartPolicy) error {
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = &retry.ExponentialDelay{Base: time.Second, Max: time.Minute}
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = 5
+	}
+
+	return retry.Do(ctx, retry.Config{MaxAttempts: maxAttempts, Strategy: backoff}, func(attempt uint) error {
+		s.m.log.Info("Server app restarting", slog.String("component", "app"), slog.String("app", e.name),
+			slog.Uint64("attempt", uint64(attempt+1)))
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.m.shutdownTimeout)
+		if err := e.current().Shutdown(shutdownCtx); err != nil {
+			s.m.log.Warn("Server app shutdown before restart failed", slog.String("component", "app"), slog.String("app", e.name),
+				slog.String("error", err.Error()))
+		}
+		cancel()
+
+		if policy.Factory != nil {
+			app, err := policy.Factory()
+			if err != nil {
+				return fmt.Errorf("create app %q: %w", e.name, err)
+			}
+			e.replace(app)
+		}
+
+		if err := s.m.startApp(s.appCtx, e); err != nil {
+			s.m.log.Warn("Server app restart failed", slog.String("component", "app"), slog.String("app", e.name),
+				slog.Uint64("attempt", uint64(attempt+1)), slog.String("error", err.Error()))
+			return err
+		}
+
+		restarts := e.restarts.Add(1)
+		s.m.log.Info("Server app restarted", slog.String("component", "app"), slog.String("app", e.name),
+			slog.Int64("restarts", restarts))
+		return nil
+	})
+}
+
+// escalate 请求整个进程优雅关闭
+func (s *supervisor) escalate(e *appEntry, cause error) {
+	s.m.log.Error("Server app failed, escalating to shutdown", slog.String("component", "app"), slog.String("app", e.name),
+		slog.String("error", cause.Error()))
+	s.m.requestStop(fmt.Errorf("%w: %s: %w", ErrAppFailed, e.name, cause))
+}
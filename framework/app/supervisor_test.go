@@ -0,0 +1,191 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyApp 健康状态可由测试控制的应用
+type flakyApp struct {
+	healthy   atomic.Bool
+	starts    atomic.Int32
+	shutdowns atomic.Int32
+	startErr  error
+}
+
+func newFlakyApp() *flakyApp {
+	a := &flakyApp{}
+	a.healthy.Store(true)
+	return a
+}
+
+func (f *flakyApp) Start(_ context.Context) error {
+	f.starts.Add(1)
+	return f.startErr
+}
+
+func (f *flakyApp) Shutdown(_ context.Context) error {
+	f.shutdowns.Add(1)
+	return nil
+}
+
+func (f *flakyApp) HealthCheck(_ context.Context) error {
+	if f.healthy.Load() {
+		return nil
+	}
+	return errors.New("not running")
+}
+
+func runManager(m IManager) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run(context.Background())
+	}()
+	return done
+}
+
+func waitRun(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not complete within timeout")
+		return nil
+	}
+}
+
+func TestRestartMode_String(t *testing.T) {
+	assert.Equal(t, "never", RestartNever.String())
+	assert.Equal(t, "on_failure", RestartOnFailure.String())
+	assert.Equal(t, "escalate", RestartEscalate.String())
+	assert.Equal(t, "unknown", RestartMode(99).String())
+}
+
+func TestSupervisor_RestartOnFailure_WithFactory(t *testing.T) {
+	first := newFlakyApp()
+	second := newFlakyApp()
+
+	m := NewManager(
+		WithSupervisor(10*time.Millisecond, 2),
+		WithRestartPolicy("worker", RestartPolicy{
+			Mode:    RestartOnFailure,
+			Backoff: &retry.FixedDelay{Wait: time.Millisecond},
+			Factory: func() (IApp, error) { return second, nil },
+		}),
+	)
+	m.RegisterNamed("worker", first)
+	done := runManager(m)
+
+	require.Eventually(t, func() bool { return first.starts.Load() == 1 }, time.Second, 5*time.Millisecond)
+	first.healthy.Store(false)
+
+	require.Eventually(t, func() bool { return second.starts.Load() == 1 }, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), first.shutdowns.Load(), "failed instance should be shut down before restart")
+
+	report := m.Health(context.Background())
+	require.Len(t, report.Apps, 1)
+	assert.Equal(t, int64(1), report.Apps[0].Restarts)
+
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(os.Interrupt)
+	require.NoError(t, waitRun(t, done))
+	assert.Equal(t, int32(1), second.shutdowns.Load(), "restarted instance should be shut down on exit")
+}
+
+func TestSupervisor_RestartOnFailure_ReusesInstance(t *testing.T) {
+	app := newFlakyApp()
+
+	m := NewManager(
+		WithSupervisor(10*time.Millisecond, 1),
+		WithDefaultRestartPolicy(RestartPolicy{
+			Mode:    RestartOnFailure,
+			Backoff: &retry.FixedDelay{Wait: time.Millisecond},
+		}),
+	)
+	m.Register(app)
+	done := runManager(m)
+
+	require.Eventually(t, func() bool { return app.starts.Load() == 1 }, time.Second, 5*time.Millisecond)
+	app.healthy.Store(false)
+	require.Eventually(t, func() bool { return app.starts.Load() >= 2 }, 2*time.Second, 5*time.Millisecond)
+	app.healthy.Store(true)
+
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(os.Interrupt)
+	require.NoError(t, waitRun(t, done))
+}
+
+func TestSupervisor_Escalate(t *testing.T) {
+	app := newFlakyApp()
+	other := newFlakyApp()
+
+	m := NewManager(
+		WithSupervisor(10*time.Millisecond, 1),
+		WithRestartPolicy("critical", RestartPolicy{Mode: RestartEscalate}),
+	)
+	m.RegisterNamed("critical", app)
+	m.RegisterNamed("other", other)
+	done := runManager(m)
+
+	require.Eventually(t, func() bool { return app.starts.Load() == 1 }, time.Second, 5*time.Millisecond)
+	app.healthy.Store(false)
+
+	err := waitRun(t, done)
+	assert.ErrorIs(t, err, ErrAppFailed)
+	assert.Equal(t, int32(1), app.shutdowns.Load())
+	assert.Equal(t, int32(1), other.shutdowns.Load(), "escalation should shut down every app")
+}
+
+func TestSupervisor_RestartGiveUpEscalates(t *testing.T) {
+	app := newFlakyApp()
+
+	m := NewManager(
+		WithSupervisor(10*time.Millisecond, 1),
+		WithRestartPolicy("worker", RestartPolicy{
+			Mode:        RestartOnFailure,
+			MaxAttempts: 2,
+			Backoff:     &retry.FixedDelay{Wait: time.Millisecond},
+			Factory: func() (IApp, error) {
+				return nil, errors.New("cannot build")
+			},
+			EscalateOnGiveUp: true,
+		}),
+	)
+	m.RegisterNamed("worker", app)
+	done := runManager(m)
+
+	require.Eventually(t, func() bool { return app.starts.Load() == 1 }, time.Second, 5*time.Millisecond)
+	app.healthy.Store(false)
+
+	err := waitRun(t, done)
+	assert.ErrorIs(t, err, ErrAppFailed)
+	assert.Equal(t, int64(0), m.(*manager).apps[0].restarts.Load())
+}
+
+func TestSupervisor_NeverKeepsRunning(t *testing.T) {
+	app := newFlakyApp()
+
+	m := NewManager(WithSupervisor(10*time.Millisecond, 1))
+	m.Register(app)
+	done := runManager(m)
+
+	require.Eventually(t, func() bool { return app.starts.Load() == 1 }, time.Second, 5*time.Millisecond)
+	app.healthy.Store(false)
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, int32(1), app.starts.Load(), "never policy must not restart")
+	assert.Equal(t, int32(0), app.shutdowns.Load())
+
+	p, _ := os.FindProcess(os.Getpid())
+	_ = p.Signal(os.Interrupt)
+	require.NoError(t, waitRun(t, done))
+}
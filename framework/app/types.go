@@ -14,14 +14,23 @@ type IApp interface {
 type IManager interface {
 	// Register 注册应用
 	Register(app IApp)
+	// RegisterNamed 以指定名称注册应用，并声明其依赖的应用名称。
+	// 被依赖的应用先于本应用启动、晚于本应用关闭。
+	RegisterNamed(name string, app IApp, dependsOn ...string)
 	// Run 启动应用，返回启动或关闭错误
 	Run(ctx context.Context) error
 	// MustRun 启动应用，失败时直接 os.Exit(1)
 	MustRun(ctx context.Context)
 	// IsHealthy 检查所有已注册应用的健康状态。
-	// 返回 nil 表示全部健康；返回第一个非 nil 错误。
+	// 返回 nil 表示全部健康；否则返回合并了所有应用错误的 error。
 	// 未实现 HealthChecker 的 IApp 视为健康。
 	IsHealthy(ctx context.Context) error
+	// Health 返回每个已注册应用的健康检查结果及管理器运行阶段。
+	// 结果在缓存有效期内复用（见 WithHealthCacheTTL）。
+	Health(ctx context.Context) HealthReport
+	// Reload 调用已启动应用的 Reloader 钩子，多次调用串行执行。
+	// 管理器未处于运行阶段时返回 ErrReloadNotRunning。
+	Reload(ctx context.Context) error
 }
 
 // HealthChecker 可选接口，实现此接口的 IApp 将被 Manager 自动纳入健康检查。
@@ -29,3 +38,15 @@ type IManager interface {
 type HealthChecker interface {
 	HealthCheck(ctx context.Context) error
 }
+
+// Namer 可选接口，实现此接口的 IApp 使用 Name 作为在 Manager 中的名称。
+// 未实现此接口且未通过 RegisterNamed 指定名称时，使用 "app-<序号>"。
+type Namer interface {
+	Name() string
+}
+
+// DependencyDeclarer 可选接口，实现此接口的 IApp 可声明其依赖的应用名称。
+// 通过 RegisterNamed 显式声明的依赖优先于此接口。
+type DependencyDeclarer interface {
+	DependsOn() []string
+}
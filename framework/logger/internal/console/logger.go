@@ -17,7 +17,7 @@ func New(opts ...func(*types.Option)) *slog.Logger {
 	}
 
 	var handler slog.Handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: cnf.Level,
+		Level: cnf.Leveler(),
 	})
 
 	// 采样（在 trace.Injector 之前，避免对将被丢弃的日志注入 trace）
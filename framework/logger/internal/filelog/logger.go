@@ -53,7 +53,7 @@ func NewWith(logPath string, opts ...func(*types.Option)) *slog.Logger {
 
 	// 构建 handler
 	handlerOpts := &slog.HandlerOptions{
-		Level: cnf.Level,
+		Level: cnf.Leveler(),
 	}
 
 	var handler slog.Handler
@@ -10,6 +10,9 @@ type Option struct {
 	Level  Level     // 日志等级
 	Format LogFormat // 日志格式
 
+	// LevelVar 动态日志等级，非 nil 时优先于 Level，可在运行期间调整
+	LevelVar *slog.LevelVar
+
 	StdPrint bool // 是否在控制台输出
 
 	// Sampling 采样配置，nil 表示不启用采样
@@ -29,6 +32,14 @@ type SamplingConfig struct {
 	SummaryInterval time.Duration
 }
 
+// Leveler 返回 handler 使用的日志等级，LevelVar 非 nil 时优先
+func (o *Option) Leveler() slog.Leveler {
+	if o.LevelVar != nil {
+		return o.LevelVar
+	}
+	return o.Level
+}
+
 func DefaultOption() *Option {
 	return &Option{
 		Stack:  DailyStack,
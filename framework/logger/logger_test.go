@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -123,3 +124,15 @@ func TestNewFileLogger_WithSampling(t *testing.T) {
 	assert.NotNil(t, l)
 	l.Info("sampling file log test")
 }
+
+func TestWithLevelVar(t *testing.T) {
+	var level slog.LevelVar
+	level.Set(types.ErrorLevel)
+
+	l := NewConsoleLogger(WithLevelString("debug"), WithLevelVar(&level))
+	assert.False(t, l.Enabled(context.Background(), slog.LevelInfo), "LevelVar should take precedence over Level")
+
+	// 运行期间调整等级立即生效
+	level.Set(types.ParseLevel("debug"))
+	assert.True(t, l.Enabled(context.Background(), slog.LevelDebug))
+}
@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"log/slog"
+
 	"github.com/gomooth/pkg/framework/logger/internal/types"
 )
 
@@ -28,6 +30,14 @@ func WithLevelString(levelText string) func(*types.Option) {
 	}
 }
 
+// WithLevelVar 设置动态日志等级，优先于 WithLevel / WithLevelString。
+// 运行期间调用 v.Set 即可调整等级，适用于配置热加载（见 app.Reloader）。
+func WithLevelVar(v *slog.LevelVar) func(*types.Option) {
+	return func(o *types.Option) {
+		o.LevelVar = v
+	}
+}
+
 // WithFormat 设置日志格式
 func WithFormat(format types.LogFormat) func(*types.Option) {
 	return func(o *types.Option) {
@@ -0,0 +1,582 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"sync"
+	"time"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeBroker 进程内的 AMQP 0-9-1 替身，覆盖本包使用的语义：
+// 默认 / direct / fanout 交换机、队列参数校验、预取、手动确认、死信交换机、队列 TTL 与发布确认。
+type fakeBroker struct {
+	mu        sync.Mutex
+	exchanges map[string]*fakeExchange
+	queues    map[string]*fakeQueue
+	conns     []*fakeConn
+	dials     int
+	dialErr   error
+	nackAll   bool // 发布确认全部返回 nack
+}
+
+type fakeExchange struct {
+	kind     string
+	bindings []fakeBinding
+}
+
+type fakeBinding struct {
+	queue string
+	key   string
+}
+
+type fakeQueue struct {
+	name      string
+	args      amqp091.Table
+	ready     []*fakeMsg
+	consumers []*fakeConsumer
+	next      int // 轮询分发位置
+}
+
+type fakeMsg struct {
+	pub         amqp091.Publishing
+	exchange    string
+	key         string
+	redelivered bool
+}
+
+type fakeConsumer struct {
+	ch         *fakeChannel
+	tag        string
+	deliveries chan amqp091.Delivery
+}
+
+type fakeUnacked struct {
+	queue *fakeQueue
+	msg   *fakeMsg
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		exchanges: map[string]*fakeExchange{},
+		queues:    map[string]*fakeQueue{},
+	}
+}
+
+// dial 实现 dialFunc
+func (b *fakeBroker) dial(_ string, _ amqp091.Config) (amqpConnection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dials++
+	if b.dialErr != nil {
+		return nil, b.dialErr
+	}
+	c := &fakeConn{broker: b}
+	b.conns = append(b.conns, c)
+	return c, nil
+}
+
+func (b *fakeBroker) setDialErr(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dialErr = err
+}
+
+func (b *fakeBroker) setNackAll(v bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nackAll = v
+}
+
+func (b *fakeBroker) dialCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dials
+}
+
+// dropConnections 模拟网络中断：关闭全部连接，未确认的消息重新入队
+func (b *fakeBroker) dropConnections() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.conns {
+		c.closeLocked()
+	}
+	b.conns = nil
+}
+
+// publish 由测试直接向 broker 发布消息
+func (b *fakeBroker) publish(exchange, key string, pub amqp091.Publishing) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.routeLocked(exchange, key, &fakeMsg{pub: pub, exchange: exchange, key: key})
+}
+
+// get 取出队列中的一条就绪消息（basic.get 且自动确认）
+func (b *fakeBroker) get(queue string) (amqp091.Publishing, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q := b.queues[queue]
+	if q == nil || len(q.ready) == 0 {
+		return amqp091.Publishing{}, false
+	}
+	m := q.ready[0]
+	q.ready = q.ready[1:]
+	return m.pub, true
+}
+
+// ready 队列中等待投递的消息数
+func (b *fakeBroker) ready(queue string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if q := b.queues[queue]; q != nil {
+		return len(q.ready)
+	}
+	return 0
+}
+
+// unacked 全部通道上已投递未确认的消息数
+func (b *fakeBroker) unacked() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for _, c := range b.conns {
+		for _, ch := range c.channels {
+			n += len(ch.unacked)
+		}
+	}
+	return n
+}
+
+func (b *fakeBroker) hasQueue(name string) (amqp091.Table, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[name]
+	if !ok {
+		return nil, false
+	}
+	return q.args, true
+}
+
+func (b *fakeBroker) routeLocked(exchange, key string, m *fakeMsg) error {
+	if exchange == "" {
+		if q := b.queues[key]; q != nil {
+			b.enqueueLocked(q, m, false)
+		}
+		return nil
+	}
+	ex, ok := b.exchanges[exchange]
+	if !ok {
+		return fmt.Errorf("NOT_FOUND - no exchange '%s'", exchange)
+	}
+	for _, bd := range ex.bindings {
+		if ex.kind == amqp091.ExchangeFanout || bd.key == key {
+			if q := b.queues[bd.queue]; q != nil {
+				cp := *m
+				b.enqueueLocked(q, &cp, false)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *fakeBroker) enqueueLocked(q *fakeQueue, m *fakeMsg, front bool) {
+	if front {
+		q.ready = append([]*fakeMsg{m}, q.ready...)
+	} else {
+		q.ready = append(q.ready, m)
+	}
+	if ttl, ok := q.args[argMessageTTL].(int64); ok && !front {
+		time.AfterFunc(time.Duration(ttl)*time.Millisecond, func() { b.expire(q, m) })
+	}
+	b.dispatchLocked(q)
+}
+
+// expire 队列 TTL 到期：消息仍在队列中时转发到死信交换机
+func (b *fakeBroker) expire(q *fakeQueue, m *fakeMsg) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, r := range q.ready {
+		if r == m {
+			q.ready = append(q.ready[:i:i], q.ready[i+1:]...)
+			b.deadLetterLocked(q, m, "expired")
+			return
+		}
+	}
+}
+
+// deadLetterLocked 按队列的 x-dead-letter-* 参数转发消息，并追加 x-death 消息头
+func (b *fakeBroker) deadLetterLocked(q *fakeQueue, m *fakeMsg, reason string) {
+	dlx, ok := q.args[argDeadLetterExchange].(string)
+	if !ok {
+		return
+	}
+	key := m.key
+	if rk, ok := q.args[argDeadLetterRoutingKey].(string); ok {
+		key = rk
+	}
+	pub := m.pub
+	pub.Headers = maps.Clone(pub.Headers)
+	if pub.Headers == nil {
+		pub.Headers = amqp091.Table{}
+	}
+	pub.Headers["x-death"] = []any{amqp091.Table{"queue": q.name, "reason": reason}}
+	_ = b.routeLocked(dlx, key, &fakeMsg{pub: pub, exchange: dlx, key: key})
+}
+
+// dispatchLocked 将就绪消息轮询投递给未达预取上限的消费者
+func (b *fakeBroker) dispatchLocked(q *fakeQueue) {
+	for len(q.ready) > 0 && len(q.consumers) > 0 {
+		var target *fakeConsumer
+		for i := range q.consumers {
+			c := q.consumers[(q.next+i)%len(q.consumers)]
+			if c.ch.prefetch == 0 || len(c.ch.unacked) < c.ch.prefetch {
+				target = c
+				q.next = (q.next + i + 1) % len(q.consumers)
+				break
+			}
+		}
+		if target == nil {
+			return
+		}
+		m := q.ready[0]
+		q.ready = q.ready[1:]
+
+		ch := target.ch
+		ch.tag++
+		ch.unacked[ch.tag] = fakeUnacked{queue: q, msg: m}
+		target.deliveries <- amqp091.Delivery{
+			Acknowledger: ch,
+			Headers:      maps.Clone(m.pub.Headers),
+			MessageId:    m.pub.MessageId,
+			Timestamp:    m.pub.Timestamp,
+			DeliveryMode: m.pub.DeliveryMode,
+			ConsumerTag:  target.tag,
+			DeliveryTag:  ch.tag,
+			Redelivered:  m.redelivered,
+			Exchange:     m.exchange,
+			RoutingKey:   m.key,
+			Body:         m.pub.Body,
+		}
+	}
+}
+
+// ==================== 连接 ====================
+
+type fakeConn struct {
+	broker   *fakeBroker
+	closed   bool
+	channels []*fakeChannel
+}
+
+func (c *fakeConn) Channel() (amqpChannel, error) {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	if c.closed {
+		return nil, amqp091.ErrClosed
+	}
+	ch := &fakeChannel{broker: c.broker, conn: c, unacked: map[uint64]fakeUnacked{}}
+	c.channels = append(c.channels, ch)
+	return ch, nil
+}
+
+func (c *fakeConn) IsClosed() bool {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConn) Close() error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	if c.closed {
+		return amqp091.ErrClosed
+	}
+	c.closeLocked()
+	return nil
+}
+
+func (c *fakeConn) closeLocked() {
+	c.closed = true
+	for _, ch := range c.channels {
+		ch.closeLocked()
+	}
+	c.channels = nil
+}
+
+// ==================== 通道 ====================
+
+type fakeChannel struct {
+	broker *fakeBroker
+	conn   *fakeConn
+
+	closed    bool
+	prefetch  int
+	tag       uint64
+	unacked   map[uint64]fakeUnacked
+	consumers []*fakeConsumer
+	confirm   bool
+	seq       uint64
+	notify    []chan amqp091.Confirmation
+}
+
+var _ amqpChannel = (*fakeChannel)(nil)
+var _ amqp091.Acknowledger = (*fakeChannel)(nil)
+
+func (ch *fakeChannel) Qos(prefetchCount, _ int, _ bool) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return amqp091.ErrClosed
+	}
+	ch.prefetch = prefetchCount
+	return nil
+}
+
+func (ch *fakeChannel) ExchangeDeclare(name, kind string, _, _, _, _ bool, _ amqp091.Table) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return amqp091.ErrClosed
+	}
+	if ex, ok := ch.broker.exchanges[name]; ok {
+		if ex.kind != kind {
+			return ch.failLocked(fmt.Errorf("PRECONDITION_FAILED - inequivalent arg 'type' for exchange '%s'", name))
+		}
+		return nil
+	}
+	ch.broker.exchanges[name] = &fakeExchange{kind: kind}
+	return nil
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, args amqp091.Table) (amqp091.Queue, error) {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return amqp091.Queue{}, amqp091.ErrClosed
+	}
+	if q, ok := ch.broker.queues[name]; ok {
+		if !sameArgs(q.args, args) {
+			return amqp091.Queue{}, ch.failLocked(fmt.Errorf("PRECONDITION_FAILED - inequivalent args for queue '%s'", name))
+		}
+		return amqp091.Queue{Name: name, Messages: len(q.ready)}, nil
+	}
+	ch.broker.queues[name] = &fakeQueue{name: name, args: maps.Clone(args)}
+	return amqp091.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) QueueBind(name, key, exchange string, _ bool, _ amqp091.Table) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return amqp091.ErrClosed
+	}
+	ex, ok := ch.broker.exchanges[exchange]
+	if !ok {
+		return ch.failLocked(fmt.Errorf("NOT_FOUND - no exchange '%s'", exchange))
+	}
+	if _, ok := ch.broker.queues[name]; !ok {
+		return ch.failLocked(fmt.Errorf("NOT_FOUND - no queue '%s'", name))
+	}
+	for _, bd := range ex.bindings {
+		if bd.queue == name && bd.key == key {
+			return nil
+		}
+	}
+	ex.bindings = append(ex.bindings, fakeBinding{queue: name, key: key})
+	return nil
+}
+
+func (ch *fakeChannel) Consume(queue, consumer string, autoAck, _, _, _ bool, _ amqp091.Table) (<-chan amqp091.Delivery, error) {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return nil, amqp091.ErrClosed
+	}
+	if autoAck {
+		return nil, errors.New("fake broker supports manual ack only")
+	}
+	q, ok := ch.broker.queues[queue]
+	if !ok {
+		return nil, ch.failLocked(fmt.Errorf("NOT_FOUND - no queue '%s'", queue))
+	}
+	c := &fakeConsumer{ch: ch, tag: consumer, deliveries: make(chan amqp091.Delivery, 1024)}
+	q.consumers = append(q.consumers, c)
+	ch.consumers = append(ch.consumers, c)
+	ch.broker.dispatchLocked(q)
+	return c.deliveries, nil
+}
+
+func (ch *fakeChannel) Cancel(consumer string, _ bool) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return amqp091.ErrClosed
+	}
+	for i, c := range ch.consumers {
+		if c.tag == consumer {
+			ch.removeConsumerLocked(c)
+			ch.consumers = append(ch.consumers[:i], ch.consumers[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (ch *fakeChannel) Confirm(_ bool) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return amqp091.ErrClosed
+	}
+	ch.confirm = true
+	return nil
+}
+
+func (ch *fakeChannel) NotifyPublish(confirm chan amqp091.Confirmation) chan amqp091.Confirmation {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		close(confirm)
+		return confirm
+	}
+	ch.notify = append(ch.notify, confirm)
+	return confirm
+}
+
+func (ch *fakeChannel) PublishWithContext(ctx context.Context, exchange, key string, _, _ bool, msg amqp091.Publishing) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return amqp091.ErrClosed
+	}
+	if err := ch.broker.routeLocked(exchange, key, &fakeMsg{pub: msg, exchange: exchange, key: key}); err != nil {
+		return ch.failLocked(err)
+	}
+	if ch.confirm {
+		ch.seq++
+		for _, n := range ch.notify {
+			n <- amqp091.Confirmation{DeliveryTag: ch.seq, Ack: !ch.broker.nackAll}
+		}
+	}
+	return nil
+}
+
+func (ch *fakeChannel) Close() error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	if ch.closed {
+		return amqp091.ErrClosed
+	}
+	ch.closeLocked()
+	return nil
+}
+
+// failLocked 通道级错误：与真实 broker 一致，出错后通道关闭
+func (ch *fakeChannel) failLocked(err error) error {
+	ch.closeLocked()
+	return err
+}
+
+func (ch *fakeChannel) closeLocked() {
+	if ch.closed {
+		return
+	}
+	ch.closed = true
+	for _, c := range ch.consumers {
+		ch.removeConsumerLocked(c)
+	}
+	ch.consumers = nil
+	for _, n := range ch.notify {
+		close(n)
+	}
+	ch.notify = nil
+
+	// 未确认的消息按投递顺序重新入队
+	touched := map[*fakeQueue]bool{}
+	for tag := uint64(1); tag <= ch.tag; tag++ {
+		u, ok := ch.unacked[tag]
+		if !ok {
+			continue
+		}
+		u.msg.redelivered = true
+		u.queue.ready = append(u.queue.ready, u.msg)
+		touched[u.queue] = true
+	}
+	clear(ch.unacked)
+	for q := range touched {
+		ch.broker.dispatchLocked(q)
+	}
+}
+
+func (ch *fakeChannel) removeConsumerLocked(c *fakeConsumer) {
+	for _, q := range ch.broker.queues {
+		for i, qc := range q.consumers {
+			if qc == c {
+				q.consumers = append(q.consumers[:i], q.consumers[i+1:]...)
+				q.next = 0
+				break
+			}
+		}
+	}
+	close(c.deliveries)
+}
+
+// ==================== 确认（amqp091.Acknowledger） ====================
+
+func (ch *fakeChannel) Ack(tag uint64, _ bool) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	u, err := ch.settleLocked(tag)
+	if err != nil {
+		return err
+	}
+	ch.broker.dispatchLocked(u.queue)
+	return nil
+}
+
+func (ch *fakeChannel) Nack(tag uint64, _ bool, requeue bool) error {
+	ch.broker.mu.Lock()
+	defer ch.broker.mu.Unlock()
+	u, err := ch.settleLocked(tag)
+	if err != nil {
+		return err
+	}
+	if requeue {
+		u.msg.redelivered = true
+		ch.broker.enqueueLocked(u.queue, u.msg, true)
+	} else {
+		ch.broker.deadLetterLocked(u.queue, u.msg, "rejected")
+	}
+	ch.broker.dispatchLocked(u.queue)
+	return nil
+}
+
+func (ch *fakeChannel) Reject(tag uint64, requeue bool) error {
+	return ch.Nack(tag, false, requeue)
+}
+
+func (ch *fakeChannel) settleLocked(tag uint64) (fakeUnacked, error) {
+	if ch.closed {
+		return fakeUnacked{}, amqp091.ErrClosed
+	}
+	u, ok := ch.unacked[tag]
+	if !ok {
+		return fakeUnacked{}, fmt.Errorf("PRECONDITION_FAILED - unknown delivery tag %d", tag)
+	}
+	delete(ch.unacked, tag)
+	return u, nil
+}
+
+func sameArgs(a, b amqp091.Table) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
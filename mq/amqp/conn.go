@@ -0,0 +1,107 @@
+package amqp
+
+import (
+	"context"
+	"sync"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// amqpConnection AMQP 连接中本包使用的部分，测试中以进程内实现替换
+type amqpConnection interface {
+	Channel() (amqpChannel, error)
+	IsClosed() bool
+	Close() error
+}
+
+// amqpChannel AMQP 通道中本包使用的部分，*amqp091.Channel 直接实现该接口
+type amqpChannel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp091.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp091.Table) (amqp091.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp091.Table) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp091.Table) (<-chan amqp091.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp091.Confirmation) chan amqp091.Confirmation
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp091.Publishing) error
+	Close() error
+}
+
+// dialFunc 建立 AMQP 连接
+type dialFunc func(url string, cfg amqp091.Config) (amqpConnection, error)
+
+// dialAMQP 通过 amqp091-go 建立连接
+func dialAMQP(url string, cfg amqp091.Config) (amqpConnection, error) {
+	conn, err := amqp091.DialConfig(url, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &connAdapter{Connection: conn}, nil
+}
+
+// connAdapter 将 *amqp091.Connection 适配为 amqpConnection
+type connAdapter struct {
+	*amqp091.Connection
+}
+
+func (c *connAdapter) Channel() (amqpChannel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// connector 管理引擎共享的 AMQP 连接。
+// 连接断开后，下次获取通道时重新建立连接；各消费循环与发布者各自持有独立通道。
+type connector struct {
+	url  string
+	cfg  amqp091.Config
+	dial dialFunc
+
+	mu   sync.Mutex
+	conn amqpConnection
+}
+
+func newConnector(url string, cfg amqp091.Config, dial dialFunc) *connector {
+	if dial == nil {
+		dial = dialAMQP
+	}
+	return &connector{url: url, cfg: cfg, dial: dial}
+}
+
+// channel 打开新通道，连接不存在或已断开时先重新连接
+func (c *connector) channel() (amqpChannel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || c.conn.IsClosed() {
+		conn, err := c.dial(c.url, c.cfg)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+	return c.conn.Channel()
+}
+
+// connected 报告当前连接是否可用
+func (c *connector) connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil && !c.conn.IsClosed()
+}
+
+// close 关闭当前连接，之后再获取通道会重新连接
+func (c *connector) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
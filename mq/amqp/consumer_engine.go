@@ -0,0 +1,426 @@
+package amqp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/logutil"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/middleware"
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// queueConsumer 单个队列的消费者
+type queueConsumer struct {
+	queue    string
+	handler  types.IHandler // 已组装中间件链
+	strategy mqretry.RetryStrategy
+}
+
+// consumerEngine 消费者生命周期引擎（未导出）
+type consumerEngine struct {
+	engine.Base
+	opt *consumerConfig
+
+	conn *connector
+	pub  *publisher // 再入队重试使用的发布通道
+
+	registrations []queueConsumer
+	regMu         sync.Mutex
+}
+
+// 编译时接口检查
+var _ types.IConsumeServer = (*consumerEngine)(nil)
+
+func newConsumerEngine(url string, cfg *consumerConfig) *consumerEngine {
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	conn := newConnector(url, cfg.amqpConfig, cfg.dial)
+	eng := &consumerEngine{
+		Base: engine.Base{
+			Logger:       logger,
+			Metrics:      metrics.NewConsumerMetrics("amqp"),
+			PanicHandler: cfg.panicHandler,
+		},
+		opt:  cfg,
+		conn: conn,
+		pub:  newPublisher(conn, true),
+	}
+
+	// 预注册配置中的消费者
+	for _, reg := range cfg.consumers {
+		eng.createRegistration(reg.Queue, reg.Handler, nil)
+	}
+
+	return eng
+}
+
+// Register 注册队列消费者，队列在 Start 时自动声明。
+// 同一队列注册多次时，各注册以竞争消费者的方式分摊消息。
+func (e *consumerEngine) Register(queue string, handler types.IHandler, opts ...types.RegisterOption) error {
+	e.regMu.Lock()
+	defer e.regMu.Unlock()
+
+	if e.State.Load() != engine.Idle {
+		return xerror.NewXCode(xcode.ErrMQConsume, "cannot register after consumer started")
+	}
+
+	cfg := types.ApplyRegisterOptions(opts)
+	e.createRegistration(queue, handler, cfg.Middlewares)
+	return nil
+}
+
+func (e *consumerEngine) Count() uint {
+	e.regMu.Lock()
+	defer e.regMu.Unlock()
+	return uint(len(e.registrations))
+}
+
+func (e *consumerEngine) createRegistration(queue string, handler types.IHandler, mws []types.Middleware) {
+	if len(queue) == 0 {
+		e.Logger.Error("queue name must not be empty")
+		return
+	}
+	if handler == nil {
+		e.Logger.Error("handler must not be nil", "queue", queue)
+		return
+	}
+
+	backoff := e.opt.backoff
+	if backoff == nil {
+		backoff = &retry.ExponentialDelay{Base: time.Second, Max: 5 * time.Minute}
+	}
+
+	m := e.Metrics.(*metrics.ConsumerMetrics)
+
+	// 注入默认 failedHandler（若用户未设置）
+	failedHandler := e.opt.failedHandler
+	if failedHandler == nil {
+		failedHandler = DefaultFailedHandlerFunc(logutil.NewSlogLogger(e.Logger))
+	}
+	userDeadLetter, _ := handler.(types.DeadLetterHandler)
+	deadLetter := &deadLetterRouter{
+		handler: userDeadLetter,
+		dlx:     hasDeadLetterExchange(e.opt),
+		failed:  failedHandler,
+		logger:  e.Logger,
+	}
+
+	var strategy mqretry.RetryStrategy
+	switch e.opt.retryMode {
+	case types.RetryModeRequeue:
+		// 退避由延迟队列的 TTL 实现，不在 worker 中等待
+		strategy = mqretry.NewRequeueStrategy(mqretry.RequeueConfig{
+			MaxRetry:      e.opt.maxRetry,
+			Metrics:       m,
+			FailedHandler: failedHandler,
+			DeadLetter:    deadLetter,
+			Timeout:       e.opt.handlerTimeout,
+			RetryIf:       e.opt.retryIf,
+			Logger:        logutil.NewSlogLogger(e.Logger),
+			Requeue: func(ctx context.Context, msg types.Message) error {
+				return e.requeue(ctx, queue, msg, backoff.Delay(uint(msg.Attempt-2)))
+			},
+		})
+	default: // RetryModeSync
+		strategy = mqretry.NewSyncStrategy(mqretry.SyncConfig{
+			MaxRetry: e.opt.maxRetry,
+			Backoff: mqretry.BackoffDelayFunc(func(attempt uint) time.Duration {
+				return backoff.Delay(attempt)
+			}),
+			Metrics:       m,
+			FailedHandler: failedHandler,
+			DeadLetter:    deadLetter,
+			Timeout:       e.opt.handlerTimeout,
+			RetryIf:       e.opt.retryIf,
+			Logger:        logutil.NewSlogLogger(e.Logger),
+		})
+	}
+
+	// 重试策略调用组装好中间件链的 handler；死信接口仍在原始 handler 上识别
+	e.registrations = append(e.registrations, queueConsumer{
+		queue:    queue,
+		handler:  e.wrapHandler(handler, mws),
+		strategy: strategy,
+	})
+}
+
+// wrapHandler 按 内置 → 服务级 → 注册级 的顺序为 handler 组装中间件链
+func (e *consumerEngine) wrapHandler(handler types.IHandler, mws []types.Middleware) types.IHandler {
+	server := middleware.Server(e.opt.noDefaultMiddlewares, e.Logger, e.opt.panicHandler, e.opt.middlewares)
+	return middleware.Wrap(handler, server, mws)
+}
+
+// requeue 将消息发布到延迟队列，过期后经默认交换机回到原队列；delay 不大于 0 时直接发回原队列
+func (e *consumerEngine) requeue(ctx context.Context, queue string, msg types.Message, delay time.Duration) error {
+	key := queue
+	if delay > 0 {
+		q := retryQueue(queue, delay)
+		if err := e.pub.declare(q); err != nil {
+			return err
+		}
+		key = q.Name
+	}
+	return e.pub.publish(ctx, "", key, toPublishing(msg))
+}
+
+// hasDeadLetterExchange 注册消费的队列是否配置了死信交换机
+func hasDeadLetterExchange(cfg *consumerConfig) bool {
+	if cfg.deadLetterExchange != nil {
+		return true
+	}
+	_, ok := cfg.queueArgs[argDeadLetterExchange]
+	return ok
+}
+
+// topology 消费者需要声明的交换机、队列与绑定，注册消费的队列排在额外队列之前
+func (e *consumerEngine) topology(regs []queueConsumer) topology {
+	t := topology{exchanges: e.opt.exchanges, bindings: e.opt.bindings}
+	seen := make(map[string]bool, len(regs))
+	for _, reg := range regs {
+		if seen[reg.queue] {
+			continue
+		}
+		seen[reg.queue] = true
+		t.queues = append(t.queues, consumeQueue(reg.queue, e.opt))
+	}
+	t.queues = append(t.queues, e.opt.queues...)
+	return t
+}
+
+func (e *consumerEngine) Start(ctx context.Context) error {
+	if !e.TryStart() {
+		if e.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(xcode.ErrMQConsume, "consumer already closed")
+	}
+
+	engineCtx, cancel := context.WithCancel(ctx)
+	e.CancelFunc = cancel
+
+	e.regMu.Lock()
+	regs := make([]queueConsumer, len(e.registrations))
+	copy(regs, e.registrations)
+	e.regMu.Unlock()
+
+	if len(regs) == 0 {
+		cancel()
+		e.State.Store(engine.Idle)
+		return xerror.NewXCode(xcode.ErrMQConsume, "no consumers registered")
+	}
+
+	// 声明拓扑（同时验证连接与参数）
+	topo := e.topology(regs)
+	if err := e.declare(topo); err != nil {
+		cancel()
+		_ = e.conn.close()
+		e.State.Store(engine.Idle)
+		return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+
+	// 启动消费循环
+	for _, reg := range regs {
+		r := reg
+		e.WG.Add(1)
+		e.SafeGo(fmt.Sprintf("consume-%s", r.queue), func() {
+			defer e.WG.Done()
+			e.consumeLoop(engineCtx, topo, r)
+		}, e.opt.panicHandler)
+	}
+
+	return nil
+}
+
+// declare 在临时通道上声明拓扑
+func (e *consumerEngine) declare(topo topology) error {
+	ch, err := e.conn.channel()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ch.Close() }()
+	return topo.declare(ch)
+}
+
+func (e *consumerEngine) Shutdown(ctx context.Context) error {
+	if !e.RequestShutdown() {
+		if e.State.Load() == engine.Idle {
+			e.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	// 取消后 worker 不再接收新消息；处理中断的消息 nack 后重新入队
+	if e.CancelFunc != nil {
+		e.CancelFunc()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.WG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	// 关闭连接后，未确认的消息由 broker 重新投递
+	e.pub.close()
+	err := e.conn.close()
+
+	e.State.Store(engine.Closed)
+	if err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	return nil
+}
+
+// HealthCheck 检查消费者处于运行状态且 AMQP 连接可用
+func (e *consumerEngine) HealthCheck(ctx context.Context) error {
+	if err := e.Base.HealthCheck(ctx); err != nil {
+		return err
+	}
+	if !e.conn.connected() {
+		return xerror.NewXCode(xcode.ErrMQConsume, "amqp connection closed")
+	}
+	return nil
+}
+
+// consumeLoop 单个队列的消费循环：通道或连接断开后按退避间隔重新订阅
+func (e *consumerEngine) consumeLoop(ctx context.Context, topo topology, qc queueConsumer) {
+	backoff := &retry.ExponentialDelay{Base: e.opt.reconnectDelay, Max: 30 * time.Second, Jitter: true}
+	attempt := uint(0)
+
+	for {
+		subscribed, err := e.consumeSession(ctx, topo, qc)
+		if err == nil {
+			return
+		}
+		if subscribed {
+			attempt = 0
+		}
+
+		delay := backoff.Delay(attempt)
+		attempt++
+		e.Logger.Warn("amqp consume interrupted, resubscribing",
+			"queue", qc.queue, "error", err, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.Draining():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// consumeSession 在新通道上订阅队列并启动 worker，直到引擎停止（返回 nil）或通道断开（返回错误）。
+// subscribed 表示本次是否订阅成功，用于重置重连退避。
+func (e *consumerEngine) consumeSession(ctx context.Context, topo topology, qc queueConsumer) (subscribed bool, err error) {
+	if ctx.Err() != nil || e.draining() {
+		return false, nil
+	}
+
+	ch, err := e.conn.channel()
+	if err != nil {
+		return false, err
+	}
+	// 关闭通道时，已预取但未确认的消息由 broker 重新投递
+	defer func() { _ = ch.Close() }()
+
+	if err := ch.Qos(e.opt.prefetch, 0, false); err != nil {
+		return false, err
+	}
+	if err := topo.declare(ch); err != nil {
+		return false, err
+	}
+
+	tag := e.opt.consumerTag + "-" + qc.queue
+	deliveries, err := ch.Consume(qc.queue, tag, false, false, false, false, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range e.opt.concurrency {
+		wg.Add(1)
+		e.SafeGo(fmt.Sprintf("consume-%s-%d", qc.queue, i), func() {
+			defer wg.Done()
+			e.work(ctx, qc, deliveries)
+		}, e.opt.panicHandler)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil || e.draining() {
+		_ = ch.Cancel(tag, false)
+		return true, nil
+	}
+	return true, errChannelClosed
+}
+
+// work 逐条处理投递的消息，引擎停止、排空或投递通道关闭时返回
+func (e *consumerEngine) work(ctx context.Context, qc queueConsumer, deliveries <-chan amqp091.Delivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.Draining():
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			e.handleDelivery(ctx, qc, d)
+		}
+	}
+}
+
+// handleDelivery 处理单条消息并确认：
+// 成功、再入队或交给死信处理后 ack；需要由死信交换机接收时 nack 且不重新入队；
+// 处理因引擎停止而中断时 nack 并重新入队。
+func (e *consumerEngine) handleDelivery(ctx context.Context, qc queueConsumer, d amqp091.Delivery) {
+	msg := fromDelivery(qc.queue, d)
+	s := &settlement{}
+
+	m := e.Metrics.(*metrics.ConsumerMetrics)
+	m.AddInFlight(qc.queue, 1)
+	err := qc.strategy.OnMessage(withSettlement(ctx, s), msg, qc.handler.Handle)
+	m.AddInFlight(qc.queue, -1)
+
+	var ackErr error
+	switch {
+	case err != nil && ctx.Err() != nil:
+		ackErr = d.Nack(false, true)
+	case s.reject:
+		ackErr = d.Nack(false, false)
+	default:
+		ackErr = d.Ack(false)
+	}
+	if ackErr != nil {
+		e.Logger.Warn("mq ack failed", "system", "amqp", "queue", qc.queue, "error", ackErr)
+	}
+}
+
+func (e *consumerEngine) draining() bool {
+	select {
+	case <-e.Draining():
+		return true
+	default:
+		return false
+	}
+}
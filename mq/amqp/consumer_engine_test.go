@@ -0,0 +1,455 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	amqp091 "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector 记录已处理消息的测试 handler，err 非 nil 时每次处理都返回该错误
+type collector struct {
+	mu   sync.Mutex
+	msgs []types.Message
+	err  error
+}
+
+func (c *collector) Handle(_ context.Context, msg types.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, msg)
+	return c.err
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.msgs)
+}
+
+func (c *collector) get(i int) types.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.msgs[i]
+}
+
+// deadLetterCollector 实现 DeadLetterHandler 的失败 handler
+type deadLetterCollector struct {
+	collector
+	dlMu    sync.Mutex
+	dead    []types.Message
+	lastErr error
+	dlErr   error
+}
+
+func (d *deadLetterCollector) OnDeadLetter(_ context.Context, msg types.Message, lastErr error) error {
+	d.dlMu.Lock()
+	defer d.dlMu.Unlock()
+	d.dead = append(d.dead, msg)
+	d.lastErr = lastErr
+	return d.dlErr
+}
+
+func (d *deadLetterCollector) deadCount() int {
+	d.dlMu.Lock()
+	defer d.dlMu.Unlock()
+	return len(d.dead)
+}
+
+func startConsumer(t *testing.T, b *fakeBroker, opts ...ConsumerOption) *consumerEngine {
+	t.Helper()
+	opts = append([]ConsumerOption{
+		WithBackoff(&retry.FixedDelay{Wait: time.Millisecond}),
+		WithReconnectDelay(10 * time.Millisecond),
+		func(c *consumerConfig) { c.dial = b.dial },
+	}, opts...)
+	c := NewConsumer("amqp://fake", opts...).(*consumerEngine)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Shutdown(context.Background()) })
+	return c
+}
+
+func TestConsumer_Register(t *testing.T) {
+	c := NewConsumer("amqp://fake", WithConsumer("q1", &collector{}))
+	require.NoError(t, c.Register("q2", &collector{}))
+	assert.Equal(t, uint(2), c.Count())
+
+	b := newFakeBroker()
+	empty := NewConsumer("amqp://fake", func(c *consumerConfig) { c.dial = b.dial })
+	assert.Error(t, empty.Start(context.Background()), "no consumers registered")
+}
+
+func TestConsumer_ConsumeAndAck(t *testing.T) {
+	b := newFakeBroker()
+	h := &collector{}
+	startConsumer(t, b, WithConsumer("orders", h))
+
+	ts := time.Unix(1700000000, 0)
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{
+		ID: "id-1", Key: "user-1", Timestamp: ts, Data: []byte("hello"),
+		Headers: map[string]string{"traceparent": "00-abc"},
+	})))
+	require.Eventually(t, func() bool { return h.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	msg := h.get(0)
+	assert.True(t, msg.IsAMQP())
+	assert.Equal(t, "orders", msg.Queue)
+	assert.Equal(t, "hello", string(msg.Data))
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "user-1", msg.Key)
+	assert.Equal(t, ts, msg.Timestamp)
+	assert.Equal(t, 1, msg.Attempt)
+	assert.Equal(t, "00-abc", msg.Headers["traceparent"])
+
+	assert.Eventually(t, func() bool { return b.unacked() == 0 && b.ready("orders") == 0 },
+		time.Second, 10*time.Millisecond, "processed message should be acknowledged")
+}
+
+func TestConsumer_ForeignMessage(t *testing.T) {
+	b := newFakeBroker()
+	h := &collector{}
+	startConsumer(t, b, WithConsumer("orders", h))
+
+	// 非本包发送的消息：没有信封字段，消息头含非字符串值
+	require.NoError(t, b.publish("", "orders", amqp091.Publishing{
+		Headers: amqp091.Table{"count": int32(3), "source": "legacy"},
+		Body:    []byte("raw"),
+	}))
+	require.Eventually(t, func() bool { return h.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	msg := h.get(0)
+	assert.Equal(t, "raw", string(msg.Data))
+	assert.Equal(t, 1, msg.Attempt)
+	assert.Equal(t, map[string]string{"source": "legacy"}, msg.Headers)
+}
+
+func TestConsumer_SyncRetryThenDeadLetterHandler(t *testing.T) {
+	b := newFakeBroker()
+	boom := errors.New("boom")
+	h := &deadLetterCollector{collector: collector{err: boom}}
+	startConsumer(t, b, WithConsumer("orders", h), WithMaxRetry(3))
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{ID: "m1", Data: []byte("x")})))
+	require.Eventually(t, func() bool { return h.deadCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 4, h.count(), "first attempt plus 3 retries")
+	assert.ErrorIs(t, h.lastErr, boom)
+	assert.Eventually(t, func() bool { return b.unacked() == 0 && b.ready("orders") == 0 },
+		time.Second, 10*time.Millisecond, "dead-lettered message should be acknowledged")
+}
+
+func TestConsumer_DeadLetterExchange(t *testing.T) {
+	b := newFakeBroker()
+	h := &collector{err: errors.New("boom")}
+	var failed atomic.Int32
+	startConsumer(t, b,
+		WithConsumer("orders", h),
+		WithMaxRetry(2),
+		WithExchanges(Exchange{Name: "dlx", Durable: true}),
+		WithQueues(Queue{Name: "orders.dlq", Durable: true}),
+		WithBindings(Binding{Queue: "orders.dlq", Exchange: "dlx", RoutingKey: "orders.dead"}),
+		WithDeadLetterExchange("dlx", "orders.dead"),
+		WithFailedHandler(func(context.Context, types.Message, error) { failed.Add(1) }),
+	)
+
+	args, ok := b.hasQueue("orders")
+	require.True(t, ok)
+	assert.Equal(t, "dlx", args[argDeadLetterExchange])
+	assert.Equal(t, "orders.dead", args[argDeadLetterRoutingKey])
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{ID: "m1", Data: []byte("x")})))
+	require.Eventually(t, func() bool { return b.ready("orders.dlq") == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	pub, _ := b.get("orders.dlq")
+	assert.Equal(t, "m1", pub.MessageId)
+	assert.Equal(t, []byte("x"), pub.Body)
+	assert.Equal(t, 3, h.count())
+	assert.Zero(t, failed.Load(), "broker dead-lettering replaces the failed handler")
+	assert.Zero(t, b.unacked())
+}
+
+func TestConsumer_DeadLetterHandlerErrorFallsBackToExchange(t *testing.T) {
+	b := newFakeBroker()
+	h := &deadLetterCollector{collector: collector{err: errors.New("boom")}, dlErr: errors.New("store down")}
+	startConsumer(t, b,
+		WithConsumer("orders", h),
+		WithMaxRetry(1),
+		WithQueues(Queue{Name: "orders.dlq", Durable: true}),
+		WithDeadLetterExchange("", "orders.dlq"),
+	)
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{ID: "m1", Data: []byte("x")})))
+	require.Eventually(t, func() bool { return b.ready("orders.dlq") == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, h.deadCount())
+}
+
+func TestConsumer_PermanentErrorSkipsRetries(t *testing.T) {
+	b := newFakeBroker()
+	h := &collector{err: types.Permanent(errors.New("bad payload"))}
+	startConsumer(t, b,
+		WithConsumer("orders", h),
+		WithMaxRetry(5),
+		WithQueues(Queue{Name: "orders.dlq", Durable: true}),
+		WithDeadLetterExchange("", "orders.dlq"),
+	)
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("x")})))
+	require.Eventually(t, func() bool { return b.ready("orders.dlq") == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, h.count())
+}
+
+func TestConsumer_FailedHandlerWithoutDeadLetter(t *testing.T) {
+	b := newFakeBroker()
+	h := &collector{err: errors.New("boom")}
+	var failed atomic.Int32
+	startConsumer(t, b,
+		WithConsumer("orders", h),
+		WithMaxRetry(2),
+		WithFailedHandler(func(context.Context, types.Message, error) { failed.Add(1) }),
+	)
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("x")})))
+	require.Eventually(t, func() bool { return failed.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return b.unacked() == 0 && b.ready("orders") == 0 },
+		time.Second, 10*time.Millisecond)
+}
+
+func TestConsumer_RequeueThroughDelayQueue(t *testing.T) {
+	b := newFakeBroker()
+	h := &deadLetterCollector{collector: collector{err: errors.New("boom")}}
+	startConsumer(t, b,
+		WithConsumer("orders", h),
+		WithRetryMode(RetryModeRequeue),
+		WithMaxRetry(3),
+		WithBackoff(&retry.FixedDelay{Wait: 20 * time.Millisecond}),
+	)
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{ID: "m1", Data: []byte("x")})))
+	require.Eventually(t, func() bool { return h.deadCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, 3, h.count())
+	for i := range 3 {
+		msg := h.get(i)
+		assert.Equal(t, i+1, msg.Attempt, "attempt travels in the envelope")
+		assert.Equal(t, "m1", msg.ID)
+		assert.Equal(t, "orders", msg.Queue)
+	}
+
+	args, ok := b.hasQueue("orders.retry.20")
+	require.True(t, ok, "delay queue declared per backoff duration")
+	assert.Equal(t, int64(20), args[argMessageTTL])
+	assert.Equal(t, "orders", args[argDeadLetterRoutingKey])
+	assert.Zero(t, b.unacked())
+}
+
+func TestConsumer_RequeueWithoutDelay(t *testing.T) {
+	b := newFakeBroker()
+	var calls atomic.Int32
+	h := types.FuncHandler(func(_ context.Context, msg types.Message) error {
+		if calls.Add(1) < 2 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	startConsumer(t, b,
+		WithConsumer("orders", h),
+		WithRetryMode(RetryModeRequeue),
+		WithBackoff(&retry.FixedDelay{}),
+	)
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("x")})))
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return b.unacked() == 0 && b.ready("orders") == 0 },
+		time.Second, 10*time.Millisecond)
+	_, ok := b.hasQueue("orders.retry.0")
+	assert.False(t, ok, "zero backoff requeues straight to the source queue")
+}
+
+func TestConsumer_RequeueJitteredBackoffReusesTiers(t *testing.T) {
+	b := newFakeBroker()
+	h := &deadLetterCollector{collector: collector{err: errors.New("boom")}}
+	startConsumer(t, b,
+		WithConsumer("orders", h),
+		WithRetryMode(RetryModeRequeue),
+		WithMaxRetry(2),
+		WithBackoff(&retry.ExponentialDelay{Base: 45 * time.Millisecond, Max: 45 * time.Millisecond, Jitter: true}),
+	)
+
+	for i := range 20 {
+		require.NoError(t, b.publish("", "orders", toPublishing(types.Message{ID: fmt.Sprint(i), Data: []byte("x")})))
+	}
+	require.Eventually(t, func() bool { return h.deadCount() == 20 }, 5*time.Second, 10*time.Millisecond)
+
+	// 抖动后的 22.5ms ~ 45ms 全部取整到 50ms 档位，只声明一个延迟队列
+	var retryQueues []string
+	b.mu.Lock()
+	for name := range b.queues {
+		if strings.HasPrefix(name, "orders.retry.") {
+			retryQueues = append(retryQueues, name)
+		}
+	}
+	b.mu.Unlock()
+	assert.Equal(t, []string{"orders.retry.50"}, retryQueues)
+}
+
+func TestRetryDelayTier(t *testing.T) {
+	assert.Equal(t, 10*time.Millisecond, retryDelayTier(time.Millisecond))
+	assert.Equal(t, time.Second, retryDelayTier(time.Second))
+	assert.Equal(t, 2*time.Second, retryDelayTier(1001*time.Millisecond))
+	assert.Equal(t, 5*time.Minute, retryDelayTier(3*time.Minute))
+	assert.Equal(t, 24*time.Hour, retryDelayTier(48*time.Hour))
+
+	q := retryQueue("orders", 1234*time.Millisecond)
+	assert.Equal(t, "orders.retry.2000", q.Name)
+	assert.Equal(t, int64(2000), q.Args[argMessageTTL])
+}
+
+func TestConsumer_Prefetch(t *testing.T) {
+	b := newFakeBroker()
+	release := make(chan struct{})
+	var started atomic.Int32
+	h := types.FuncHandler(func(context.Context, types.Message) error {
+		started.Add(1)
+		<-release
+		return nil
+	})
+	startConsumer(t, b, WithConsumer("orders", h), WithPrefetch(2))
+
+	for range 5 {
+		require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("x")})))
+	}
+	require.Eventually(t, func() bool { return started.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, b.unacked(), "broker delivers at most prefetch unacked messages")
+	assert.Equal(t, 3, b.ready("orders"))
+
+	close(release)
+	assert.Eventually(t, func() bool { return started.Load() == 5 && b.unacked() == 0 },
+		2*time.Second, 10*time.Millisecond)
+}
+
+func TestConsumer_Concurrency(t *testing.T) {
+	b := newFakeBroker()
+	var active, peak atomic.Int32
+	var done atomic.Int32
+	h := types.FuncHandler(func(context.Context, types.Message) error {
+		n := active.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		active.Add(-1)
+		done.Add(1)
+		return nil
+	})
+	startConsumer(t, b, WithConsumer("orders", h), WithConcurrency(4), WithPrefetch(8))
+
+	for range 8 {
+		require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("x")})))
+	}
+	require.Eventually(t, func() bool { return done.Load() == 8 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(4), peak.Load())
+}
+
+func TestConsumer_ExchangeBinding(t *testing.T) {
+	b := newFakeBroker()
+	a, c := &collector{}, &collector{}
+	startConsumer(t, b,
+		WithConsumer("billing", a),
+		WithConsumer("audit", c),
+		WithExchanges(Exchange{Name: "events", Kind: amqp091.ExchangeFanout, Durable: true}),
+		WithBindings(
+			Binding{Queue: "billing", Exchange: "events"},
+			Binding{Queue: "audit", Exchange: "events"},
+		),
+	)
+
+	require.NoError(t, b.publish("events", "order.created", toPublishing(types.Message{Data: []byte("x")})))
+	assert.Eventually(t, func() bool { return a.count() == 1 && c.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestConsumer_Reconnect(t *testing.T) {
+	b := newFakeBroker()
+	h := &collector{}
+	c := startConsumer(t, b, WithConsumer("orders", h))
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("1")})))
+	require.Eventually(t, func() bool { return h.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	b.dropConnections()
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("2")})))
+	require.Eventually(t, func() bool { return h.count() == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, b.dialCount(), 2)
+	assert.NoError(t, c.HealthCheck(context.Background()))
+}
+
+func TestConsumer_ShutdownRequeuesInFlight(t *testing.T) {
+	b := newFakeBroker()
+	started := make(chan struct{})
+	h := types.FuncHandler(func(ctx context.Context, _ types.Message) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c := startConsumer(t, b, WithConsumer("orders", h))
+
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("x")})))
+	<-started
+	require.NoError(t, c.Shutdown(context.Background()))
+
+	assert.Equal(t, 1, b.ready("orders"), "interrupted message goes back to the queue")
+	assert.Zero(t, b.unacked())
+}
+
+func TestConsumer_PreStopStopsDelivery(t *testing.T) {
+	b := newFakeBroker()
+	h := &collector{}
+	c := startConsumer(t, b, WithConsumer("orders", h))
+
+	require.NoError(t, c.PreStop(context.Background()))
+	require.NoError(t, b.publish("", "orders", toPublishing(types.Message{Data: []byte("x")})))
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, h.count())
+	assert.Equal(t, 1, b.ready("orders"))
+}
+
+func TestConsumer_StartFailure(t *testing.T) {
+	b := newFakeBroker()
+	b.setDialErr(errors.New("connection refused"))
+	c := NewConsumer("amqp://fake", WithConsumer("orders", &collector{}),
+		func(c *consumerConfig) { c.dial = b.dial })
+	require.Error(t, c.Start(context.Background()))
+
+	// 队列参数与已存在的队列不一致时声明失败
+	b.setDialErr(nil)
+	startConsumer(t, b, WithConsumer("orders", &collector{}))
+	c = NewConsumer("amqp://fake", WithConsumer("orders", &collector{}),
+		WithQueueArgs(amqp091.Table{"x-queue-type": "quorum"}),
+		func(c *consumerConfig) { c.dial = b.dial })
+	assert.Error(t, c.Start(context.Background()))
+}
+
+func TestConsumer_HealthCheck(t *testing.T) {
+	b := newFakeBroker()
+	c := NewConsumer("amqp://fake", WithConsumer("orders", &collector{}),
+		func(c *consumerConfig) { c.dial = b.dial }).(*consumerEngine)
+	assert.Error(t, c.HealthCheck(context.Background()), "not started")
+
+	require.NoError(t, c.Start(context.Background()))
+	assert.NoError(t, c.HealthCheck(context.Background()))
+
+	require.NoError(t, c.Shutdown(context.Background()))
+	assert.Error(t, c.HealthCheck(context.Background()))
+}
@@ -0,0 +1,16 @@
+// Package amqp 提供基于 AMQP 0-9-1（RabbitMQ）的消费者和生产者实现。
+//
+// 消费者为每个注册的队列打开独立通道，以 basic.qos 限制未确认消息数（预取），
+// 手动确认：处理成功、再入队或交给死信处理后 ack，需要由死信交换机接收时 nack 且不重新入队，
+// 处理因关闭而中断时 nack 并重新入队。连接或通道断开后按退避间隔自动重连并重新订阅。
+//
+// 交换机、队列与绑定通过选项声明，启动及每次重新订阅时执行。
+// 重试耗尽或不可重试的消息优先交给 handler 实现的 DeadLetterHandler；
+// 未实现（或其返回错误）且队列配置了死信交换机时，由 broker 转发到死信交换机。
+// 再入队重试将消息发布到按退避档位划分的延迟队列（x-message-ttl），过期后回到原队列。
+//
+// 生产者默认开启发布确认（publisher confirms），Produce 在 broker 确认后返回；不支持延迟投递。
+//
+// Consumer 和 Producer 均实现 app.IApp 接口，可通过 app.Manager 统一管理生命周期。
+// Consumer 同时实现 app.PreStopper 接口，排空阶段停止接收新消息并等待处理中的消息完成。
+package amqp
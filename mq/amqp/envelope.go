@@ -0,0 +1,89 @@
+package amqp
+
+import (
+	"github.com/gomooth/pkg/mq/internal/types"
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// 消息信封中没有对应 AMQP 属性的字段，以保留前缀的消息头传递
+const (
+	headerKey     = "x-mq-key"
+	headerAttempt = "x-mq-attempt"
+)
+
+// toPublishing 将消息信封映射为 AMQP 消息属性：
+// ID → message-id，Timestamp → timestamp（秒级精度），Headers → headers，
+// Key 与 Attempt 写入保留消息头。消息均以持久化模式发送。
+func toPublishing(msg types.Message) amqp091.Publishing {
+	headers := make(amqp091.Table, len(msg.Headers)+2)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	if msg.Key != "" {
+		headers[headerKey] = msg.Key
+	}
+	if msg.Attempt > 1 {
+		headers[headerAttempt] = int32(msg.Attempt)
+	}
+	return amqp091.Publishing{
+		Headers:      headers,
+		MessageId:    msg.ID,
+		Timestamp:    msg.Timestamp,
+		DeliveryMode: amqp091.Persistent,
+		Body:         msg.Data,
+	}
+}
+
+// fromDelivery 将投递的 AMQP 消息还原为统一消息。
+// 非本包发送的消息同样可以处理：缺少信封字段时为零值，Attempt 缺省为 1；
+// 非字符串类型的消息头（如 broker 写入的 x-death）不会出现在 Message.Headers 中。
+func fromDelivery(queue string, d amqp091.Delivery) types.Message {
+	msg := types.NewAMQPMessage(queue, d.Body)
+	msg.ID = d.MessageId
+	msg.Timestamp = d.Timestamp
+	msg.Attempt = 1
+
+	for k, v := range d.Headers {
+		switch k {
+		case headerKey:
+			msg.Key, _ = v.(string)
+		case headerAttempt:
+			if n := headerInt(v); n > 0 {
+				msg.Attempt = n
+			}
+		default:
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+			if msg.Headers == nil {
+				msg.Headers = make(map[string]string, len(d.Headers))
+			}
+			msg.Headers[k] = s
+		}
+	}
+	return msg
+}
+
+// headerInt 读取整数类型的消息头，broker 可能以不同宽度的整数返回
+func headerInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int8:
+		return int(n)
+	case int16:
+		return int(n)
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case uint8:
+		return int(n)
+	case uint16:
+		return int(n)
+	case uint32:
+		return int(n)
+	}
+	return 0
+}
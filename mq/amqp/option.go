@@ -0,0 +1,286 @@
+package amqp
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// ==================== Consumer 选项 ====================
+
+// ConsumerOption 消费者配置选项
+type ConsumerOption func(*consumerConfig)
+
+// deadLetterConfig 注册队列的死信交换机配置
+type deadLetterConfig struct {
+	exchange   string
+	routingKey string
+}
+
+// consumerConfig 消费者引擎配置（未导出）
+type consumerConfig struct {
+	logger               *slog.Logger
+	amqpConfig           amqp091.Config
+	dial                 dialFunc // 测试替换
+	maxRetry             int
+	backoff              retry.BackoffStrategy
+	retryMode            types.RetryMode
+	retryIf              func(error) bool
+	handlerTimeout       time.Duration
+	failedHandler        types.FailedHandlerFunc
+	panicHandler         func(any)
+	middlewares          []types.Middleware
+	noDefaultMiddlewares bool
+	consumers            []ConsumerRegistration
+	prefetch             int
+	concurrency          int
+	consumerTag          string
+	reconnectDelay       time.Duration
+	exchanges            []Exchange
+	queues               []Queue
+	bindings             []Binding
+	queueArgs            amqp091.Table
+	deadLetterExchange   *deadLetterConfig
+}
+
+// WithConsumerLogger 设置消费者日志器
+func WithConsumerLogger(l *slog.Logger) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.logger = l
+	}
+}
+
+// WithConsumerAMQPConfig 设置连接配置（TLS、心跳、vhost 等），默认使用 amqp091-go 的默认配置
+func WithConsumerAMQPConfig(cfg amqp091.Config) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.amqpConfig = cfg
+	}
+}
+
+// WithMaxRetry 设置最大重试次数（默认 3，0=不重试）
+func WithMaxRetry(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.maxRetry = n
+	}
+}
+
+// WithBackoff 设置退避策略（默认 ExponentialDelay{Base:1s, Max:5min}）。
+// 再入队模式下退避时长向上取整到固定档位（1-2-5 序列，最大 24h），决定消息在延迟队列中停留的时间。
+func WithBackoff(b retry.BackoffStrategy) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.backoff = b
+	}
+}
+
+// WithRetryMode 设置重试模式（默认 RetryModeSync）
+func WithRetryMode(mode types.RetryMode) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryMode = mode
+	}
+}
+
+// WithRetryIf 设置错误重试谓词：返回 false 的错误不再重试，直接进入死信处理（默认所有错误都重试）。
+// mq.Permanent 包装的错误与解码失败始终不重试。
+func WithRetryIf(fn func(error) bool) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryIf = fn
+	}
+}
+
+// WithHandlerTimeout 设置单次 handler 调用的超时时间（默认 0，不限）
+func WithHandlerTimeout(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.handlerTimeout = d
+	}
+}
+
+// WithFailedHandler 设置重试耗尽后的失败处理回调。
+// handler 未实现 DeadLetterHandler 且未配置死信交换机时调用。
+func WithFailedHandler(fn types.FailedHandlerFunc) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.failedHandler = fn
+	}
+}
+
+// WithPanicHandler 设置 panic 恢复后的回调函数（handler panic 由内置 Recover 中间件恢复后回调）
+func WithPanicHandler(fn func(any)) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.panicHandler = fn
+	}
+}
+
+// WithMiddleware 追加服务级 handler 中间件，作用于该消费者注册的所有 handler。
+// 服务级中间件位于内置中间件（Trace → Recover）之内、注册级中间件（mq.WithMiddleware）之外；
+// 先传入的中间件位于外层。
+func WithMiddleware(mws ...types.Middleware) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithoutDefaultMiddlewares 禁用内置的 Trace 与 Recover 中间件，
+// 可通过 WithMiddleware 以 mq.Trace / mq.Recover 自定义顺序或替换实现
+func WithoutDefaultMiddlewares() ConsumerOption {
+	return func(c *consumerConfig) {
+		c.noDefaultMiddlewares = true
+	}
+}
+
+// WithConsumers 批量预注册消费者
+func WithConsumers(regs ...ConsumerRegistration) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.consumers = append(c.consumers, regs...)
+	}
+}
+
+// WithConsumer 预注册单个消费者
+func WithConsumer(queue string, handler types.IHandler) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.consumers = append(c.consumers, ConsumerRegistration{
+			Queue:   queue,
+			Handler: handler,
+		})
+	}
+}
+
+// WithPrefetch 设置每个队列通道的预取数量（basic.qos，默认 10），即未确认消息的上限
+func WithPrefetch(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		if n > 0 {
+			c.prefetch = n
+		}
+	}
+}
+
+// WithConcurrency 设置每个队列并行处理消息的 worker 数（默认 1）。
+// 并行处理时同一队列的消息不再保证按顺序处理；预取数量应不小于 worker 数。
+func WithConcurrency(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithConsumerTag 设置消费者标签前缀（默认 "<hostname>-<pid>"），实际标签为 "<前缀>-<队列名>"
+func WithConsumerTag(tag string) ConsumerOption {
+	return func(c *consumerConfig) {
+		if tag != "" {
+			c.consumerTag = tag
+		}
+	}
+}
+
+// WithReconnectDelay 设置连接或通道断开后重新订阅的基础等待时间（默认 1s，按指数退避增长，最长 30s）
+func WithReconnectDelay(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		if d > 0 {
+			c.reconnectDelay = d
+		}
+	}
+}
+
+// WithExchanges 声明交换机，启动及每次重新订阅时执行（幂等）
+func WithExchanges(exchanges ...Exchange) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.exchanges = append(c.exchanges, exchanges...)
+	}
+}
+
+// WithQueues 声明额外的队列（如死信队列）。注册消费的队列会自动声明，无需在此列出。
+func WithQueues(queues ...Queue) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.queues = append(c.queues, queues...)
+	}
+}
+
+// WithBindings 声明队列绑定，在交换机与队列声明之后执行
+func WithBindings(bindings ...Binding) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.bindings = append(c.bindings, bindings...)
+	}
+}
+
+// WithQueueArgs 设置注册消费的队列的声明参数（如 x-queue-type: quorum）。
+// 队列已存在且参数不一致时 broker 拒绝声明，启动失败。
+func WithQueueArgs(args amqp091.Table) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.queueArgs = args
+	}
+}
+
+// WithDeadLetterExchange 为注册消费的队列设置死信交换机（x-dead-letter-exchange），
+// routingKey 为空时保留消息原路由键。
+// 重试耗尽或不可重试的消息在 handler 未实现 DeadLetterHandler（或其返回错误）时被拒绝（nack，不重新入队），
+// 由 broker 转发到死信交换机。
+func WithDeadLetterExchange(exchange, routingKey string) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.deadLetterExchange = &deadLetterConfig{exchange: exchange, routingKey: routingKey}
+	}
+}
+
+// ==================== Producer 选项 ====================
+
+// ProducerOption 生产者配置选项
+type ProducerOption func(*producerConfig)
+
+// producerConfig 生产者引擎配置（未导出）
+type producerConfig struct {
+	logger     *slog.Logger
+	amqpConfig amqp091.Config
+	dial       dialFunc // 测试替换
+	exchange   string
+	exchanges  []Exchange
+	noConfirm  bool
+}
+
+// WithProducerLogger 设置生产者日志器
+func WithProducerLogger(l *slog.Logger) ProducerOption {
+	return func(c *producerConfig) {
+		c.logger = l
+	}
+}
+
+// WithProducerAMQPConfig 设置连接配置（TLS、心跳、vhost 等），默认使用 amqp091-go 的默认配置
+func WithProducerAMQPConfig(cfg amqp091.Config) ProducerOption {
+	return func(c *producerConfig) {
+		c.amqpConfig = cfg
+	}
+}
+
+// WithProducerExchange 设置发布消息的交换机（默认 "" 即默认交换机），Produce 的 dest 作为路由键。
+// 使用默认交换机时 dest 即队列名。
+func WithProducerExchange(exchange string) ProducerOption {
+	return func(c *producerConfig) {
+		c.exchange = exchange
+	}
+}
+
+// WithProducerExchanges 声明交换机，Start 时执行（幂等）
+func WithProducerExchanges(exchanges ...Exchange) ProducerOption {
+	return func(c *producerConfig) {
+		c.exchanges = append(c.exchanges, exchanges...)
+	}
+}
+
+// WithoutPublisherConfirms 关闭发布确认（publisher confirms）。
+// 默认开启：Produce 等待 broker 确认后返回，被拒绝（nack）时返回错误。
+func WithoutPublisherConfirms() ProducerOption {
+	return func(c *producerConfig) {
+		c.noConfirm = true
+	}
+}
+
+// defaultConsumerTag 生成默认的消费者标签前缀
+func defaultConsumerTag() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
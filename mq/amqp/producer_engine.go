@@ -0,0 +1,178 @@
+package amqp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/gomooth/pkg/framework/telemetry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	mqtraceutil "github.com/gomooth/pkg/mq/internal/traceutil"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	amqp091 "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// producerEngine 生产者生命周期引擎（未导出）
+type producerEngine struct {
+	engine.Base
+	opt *producerConfig
+
+	conn *connector
+	pub  *publisher
+}
+
+// 编译时接口检查
+var _ types.IProducer = (*producerEngine)(nil)
+
+func newProducerEngine(url string, cfg *producerConfig) *producerEngine {
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	conn := newConnector(url, cfg.amqpConfig, cfg.dial)
+	return &producerEngine{
+		Base: engine.Base{
+			Logger:  logger,
+			Metrics: metrics.NewProducerMetrics("amqp"),
+		},
+		opt:  cfg,
+		conn: conn,
+		pub:  newPublisher(conn, !cfg.noConfirm),
+	}
+}
+
+// Start 建立连接并声明 WithProducerExchanges 配置的交换机
+func (e *producerEngine) Start(_ context.Context) error {
+	if !e.TryStart() {
+		if e.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer already closed")
+	}
+
+	ch, err := e.conn.channel()
+	if err == nil {
+		err = topology{exchanges: e.opt.exchanges}.declare(ch)
+		_ = ch.Close()
+	}
+	if err != nil {
+		_ = e.conn.close()
+		e.State.Store(engine.Idle)
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	return nil
+}
+
+func (e *producerEngine) Shutdown(_ context.Context) error {
+	if !e.RequestShutdown() {
+		if e.State.Load() == engine.Idle {
+			e.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	e.pub.close()
+	err := e.conn.close()
+
+	e.State.Store(engine.Closed)
+	if err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	return nil
+}
+
+// HealthCheck 检查生产者处于运行状态且 AMQP 连接可用
+func (e *producerEngine) HealthCheck(_ context.Context) error {
+	if state := e.State.Load(); state != engine.Running {
+		return xerror.NewXCode(xcode.ErrMQPublish,
+			fmt.Sprintf("producer not running (state=%d)", state))
+	}
+	if !e.conn.connected() {
+		return xerror.NewXCode(xcode.ErrMQPublish, "amqp connection closed")
+	}
+	return nil
+}
+
+// Produce 以 dest 为路由键发布消息到 WithProducerExchange 配置的交换机（默认交换机时 dest 即队列名）。
+// 开启发布确认时等待 broker 确认后返回。不支持延迟投递，设置 WithDelay / WithDeliverAt 时返回 ErrDelayNotSupported。
+func (e *producerEngine) Produce(ctx context.Context, dest string, message []byte, opts ...types.ProduceOption) error {
+	return e.produce(ctx, dest, [][]byte{message}, opts, false)
+}
+
+// ProduceBatch 连续发布多条消息后统一等待确认，每条消息独立生成消息 ID；
+// 任一消息被 broker 拒绝时返回错误，其余消息可能已投递
+func (e *producerEngine) ProduceBatch(ctx context.Context, dest string, messages [][]byte, opts ...types.ProduceOption) error {
+	if len(messages) == 0 {
+		return xerror.NewXCode(xcode.ErrMQPublish, "no messages")
+	}
+	return e.produce(ctx, dest, messages, opts, true)
+}
+
+func (e *producerEngine) produce(ctx context.Context, dest string, messages [][]byte, opts []types.ProduceOption, batch bool) error {
+	if err := ctx.Err(); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	if state := e.State.Load(); state != engine.Running {
+		return xerror.NewXCode(xcode.ErrMQPublish,
+			fmt.Sprintf("producer not running (state=%d)", state))
+	}
+
+	produceCfg := types.ApplyProduceOptions(opts)
+	if _, delayed := produceCfg.DeliveryTime(); delayed {
+		return xerror.WrapWithXCode(types.ErrDelayNotSupported, xcode.ErrMQPublish)
+	}
+	if batch {
+		produceCfg.MessageID = ""
+	}
+
+	spanName := fmt.Sprintf("%s produce", dest)
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "amqp"),
+		attribute.String("messaging.destination", dest),
+	}
+	if e.opt.exchange != "" {
+		attrs = append(attrs, attribute.String("messaging.rabbitmq.exchange", e.opt.exchange))
+	}
+	if batch {
+		spanName = fmt.Sprintf("%s produce batch", dest)
+		attrs = append(attrs, attribute.Int("messaging.batch.size", len(messages)))
+	}
+
+	// Create producer Span
+	tracer := telemetry.Tracer("mq.amqp.producer")
+	ctx, span := tracer.Start(ctx, spanName,
+		trace.WithAttributes(attrs...),
+		trace.WithSpanKind(trace.SpanKindProducer),
+	)
+	defer span.End()
+
+	pubs := make([]amqp091.Publishing, len(messages))
+	for i, data := range messages {
+		msg := types.NewAMQPMessage(dest, data)
+		produceCfg.Stamp(&msg)
+		mqtraceutil.InjectHeaders(ctx, msg.Headers)
+		pubs[i] = toPublishing(msg)
+	}
+
+	if err := e.pub.publish(ctx, e.opt.exchange, dest, pubs...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if m, ok := e.Metrics.(*metrics.ProducerMetrics); ok && m != nil {
+			m.OnError()
+		}
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	if m, ok := e.Metrics.(*metrics.ProducerMetrics); ok && m != nil {
+		m.OnProduce(len(messages))
+	}
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
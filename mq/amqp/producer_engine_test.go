@@ -0,0 +1,192 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+	amqp091 "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func startProducer(t *testing.T, b *fakeBroker, opts ...ProducerOption) *producerEngine {
+	t.Helper()
+	opts = append([]ProducerOption{func(c *producerConfig) { c.dial = b.dial }}, opts...)
+	p := NewProducer("amqp://fake", opts...).(*producerEngine)
+	require.NoError(t, p.Start(context.Background()))
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+	return p
+}
+
+// declareQueue 通过 broker 通道声明队列，模拟消费方已建立的拓扑
+func declareQueue(t *testing.T, b *fakeBroker, name string) {
+	t.Helper()
+	conn, err := b.dial("", amqp091.Config{})
+	require.NoError(t, err)
+	ch, err := conn.Channel()
+	require.NoError(t, err)
+	_, err = ch.QueueDeclare(name, true, false, false, false, nil)
+	require.NoError(t, err)
+}
+
+func TestProducer_Produce(t *testing.T) {
+	b := newFakeBroker()
+	declareQueue(t, b, "orders")
+	p := startProducer(t, b)
+
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("hello"),
+		types.WithMessageID("id-1"), types.WithOrderKey("user-1"), types.WithHeaders(map[string]string{"source": "api"})))
+
+	pub, ok := b.get("orders")
+	require.True(t, ok)
+	assert.Equal(t, "hello", string(pub.Body))
+	assert.Equal(t, "id-1", pub.MessageId)
+	assert.Equal(t, amqp091.Persistent, pub.DeliveryMode)
+	assert.Equal(t, "user-1", pub.Headers[headerKey])
+	assert.Equal(t, "api", pub.Headers["source"])
+	assert.False(t, pub.Timestamp.IsZero())
+}
+
+func TestProducer_InjectsTraceContext(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	b := newFakeBroker()
+	declareQueue(t, b, "orders")
+	require.NoError(t, startProducer(t, b).Produce(ctx, "orders", []byte("x")))
+
+	pub, ok := b.get("orders")
+	require.True(t, ok)
+	assert.Contains(t, pub.Headers["traceparent"], "4bf92f3577b34da6a3ce929d0e0e4736")
+}
+
+func TestProducer_Exchange(t *testing.T) {
+	b := newFakeBroker()
+	declareQueue(t, b, "billing")
+	p := startProducer(t, b,
+		WithProducerExchanges(Exchange{Name: "events", Durable: true}),
+		WithProducerExchange("events"),
+	)
+	conn, _ := b.dial("", amqp091.Config{})
+	ch, _ := conn.Channel()
+	require.NoError(t, ch.QueueBind("billing", "order.created", "events", false, nil))
+
+	require.NoError(t, p.Produce(context.Background(), "order.created", []byte("x")))
+	assert.Equal(t, 1, b.ready("billing"))
+}
+
+func TestProducer_ProduceBatch(t *testing.T) {
+	b := newFakeBroker()
+	declareQueue(t, b, "orders")
+	p := startProducer(t, b)
+
+	msgs := make([][]byte, confirmBatch+10)
+	for i := range msgs {
+		msgs[i] = []byte("x")
+	}
+	require.NoError(t, p.ProduceBatch(context.Background(), "orders", msgs, types.WithMessageID("ignored")))
+	assert.Equal(t, len(msgs), b.ready("orders"))
+
+	first, _ := b.get("orders")
+	second, _ := b.get("orders")
+	assert.NotEqual(t, "ignored", first.MessageId)
+	assert.NotEqual(t, first.MessageId, second.MessageId)
+
+	assert.Error(t, p.ProduceBatch(context.Background(), "orders", nil))
+}
+
+func TestProducer_NackedByBroker(t *testing.T) {
+	b := newFakeBroker()
+	p := startProducer(t, b)
+
+	b.setNackAll(true)
+	err := p.Produce(context.Background(), "orders", []byte("x"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nacked")
+
+	// 关闭发布确认后不等待 broker 确认
+	unconfirmed := startProducer(t, b, WithoutPublisherConfirms())
+	assert.NoError(t, unconfirmed.Produce(context.Background(), "orders", []byte("x")))
+}
+
+func TestProducer_DelayNotSupported(t *testing.T) {
+	b := newFakeBroker()
+	p := startProducer(t, b)
+	err := p.Produce(context.Background(), "orders", []byte("x"), types.WithDelay(time.Second))
+	assert.ErrorIs(t, err, types.ErrDelayNotSupported)
+}
+
+func TestProducer_RecoversFromConnectionLoss(t *testing.T) {
+	b := newFakeBroker()
+	declareQueue(t, b, "orders")
+	p := startProducer(t, b)
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("1")))
+
+	b.dropConnections()
+	assert.Error(t, p.HealthCheck(context.Background()))
+
+	// 第一次发布发现通道已关闭并丢弃，下一次发布重新连接
+	assert.Error(t, p.Produce(context.Background(), "orders", []byte("2")))
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("2")))
+	assert.NoError(t, p.HealthCheck(context.Background()))
+	assert.Equal(t, 2, b.ready("orders"))
+}
+
+func TestProducer_Lifecycle(t *testing.T) {
+	b := newFakeBroker()
+	p := NewProducer("amqp://fake", func(c *producerConfig) { c.dial = b.dial }).(*producerEngine)
+
+	assert.Error(t, p.Produce(context.Background(), "orders", []byte("x")), "not started")
+	assert.Error(t, p.HealthCheck(context.Background()))
+
+	require.NoError(t, p.Start(context.Background()))
+	require.NoError(t, p.Start(context.Background()), "start is idempotent")
+	assert.NoError(t, p.HealthCheck(context.Background()))
+
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.Error(t, p.Produce(context.Background(), "orders", []byte("x")))
+	assert.Error(t, p.Start(context.Background()), "closed producer cannot restart")
+}
+
+func TestProducer_StartFailure(t *testing.T) {
+	b := newFakeBroker()
+	b.setDialErr(errors.New("connection refused"))
+	p := NewProducer("amqp://fake", func(c *producerConfig) { c.dial = b.dial })
+	require.Error(t, p.Start(context.Background()))
+
+	b.setDialErr(nil)
+	require.NoError(t, p.Start(context.Background()), "failed start can be retried")
+	_ = p.Shutdown(context.Background())
+}
+
+func TestProducerConsumer_RoundTrip(t *testing.T) {
+	b := newFakeBroker()
+	h := &collector{}
+	startConsumer(t, b, WithConsumer("orders", h))
+	p := startProducer(t, b)
+
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("hello"),
+		types.WithMessageID("id-1"), types.WithOrderKey("k"), types.WithHeaders(map[string]string{"h": "v"})))
+	require.Eventually(t, func() bool { return h.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	msg := h.get(0)
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "k", msg.Key)
+	assert.Equal(t, "v", msg.Headers["h"])
+	assert.Equal(t, 1, msg.Attempt)
+}
@@ -0,0 +1,126 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// confirmBatch 等待确认前最多连续发布的消息数，同时作为确认通道的缓冲大小
+const confirmBatch = 256
+
+var errChannelClosed = errors.New("amqp channel closed")
+
+// publisher 在独立通道上发布消息，启用确认模式（publisher confirms）时等待 broker 确认。
+// 发布与等待确认串行执行；通道出错后丢弃，下次发布时重新打开。
+type publisher struct {
+	conn    *connector
+	confirm bool
+
+	mu       sync.Mutex
+	ch       amqpChannel
+	confirms chan amqp091.Confirmation
+	declared map[string]bool
+}
+
+func newPublisher(conn *connector, confirm bool) *publisher {
+	return &publisher{conn: conn, confirm: confirm, declared: make(map[string]bool)}
+}
+
+// publish 发布消息并在确认模式下等待全部确认，任一消息被 broker 拒绝（nack）时返回错误
+func (p *publisher) publish(ctx context.Context, exchange, key string, msgs ...amqp091.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.open(); err != nil {
+		return err
+	}
+
+	var nacked error
+	for start := 0; start < len(msgs); start += confirmBatch {
+		end := min(start+confirmBatch, len(msgs))
+		for _, msg := range msgs[start:end] {
+			if err := p.ch.PublishWithContext(ctx, exchange, key, false, false, msg); err != nil {
+				p.reset()
+				return err
+			}
+		}
+		if !p.confirm {
+			continue
+		}
+		for i := start; i < end; i++ {
+			select {
+			case c, ok := <-p.confirms:
+				if !ok {
+					p.reset()
+					return errChannelClosed
+				}
+				if !c.Ack && nacked == nil {
+					nacked = fmt.Errorf("message %d nacked by broker", i)
+				}
+			case <-ctx.Done():
+				// 未收到的确认会与后续发布错位，丢弃该通道
+				p.reset()
+				return ctx.Err()
+			}
+		}
+	}
+	return nacked
+}
+
+// declare 在发布通道上声明队列，同名队列只声明一次
+func (p *publisher) declare(q Queue) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.declared[q.Name] {
+		return nil
+	}
+	if err := p.open(); err != nil {
+		return err
+	}
+	if err := (topology{queues: []Queue{q}}).declare(p.ch); err != nil {
+		p.reset()
+		return err
+	}
+	p.declared[q.Name] = true
+	return nil
+}
+
+func (p *publisher) open() error {
+	if p.ch != nil {
+		return nil
+	}
+	ch, err := p.conn.channel()
+	if err != nil {
+		return err
+	}
+	if p.confirm {
+		if err := ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			return err
+		}
+		p.confirms = ch.NotifyPublish(make(chan amqp091.Confirmation, confirmBatch))
+	}
+	p.ch = ch
+	return nil
+}
+
+// reset 关闭当前通道；broker 可能已重启，已声明的队列需要重新声明
+func (p *publisher) reset() {
+	if p.ch != nil {
+		_ = p.ch.Close()
+	}
+	p.ch = nil
+	p.confirms = nil
+	clear(p.declared)
+}
+
+func (p *publisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+}
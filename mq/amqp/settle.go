@@ -0,0 +1,61 @@
+package amqp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// settlement 记录单条投递的最终确认方式，由死信路由在处理过程中设置
+type settlement struct {
+	reject bool // 拒绝且不重新入队，由 broker 转发到死信交换机
+}
+
+type settlementKey struct{}
+
+func withSettlement(ctx context.Context, s *settlement) context.Context {
+	return context.WithValue(ctx, settlementKey{}, s)
+}
+
+func settlementFrom(ctx context.Context) *settlement {
+	s, _ := ctx.Value(settlementKey{}).(*settlement)
+	return s
+}
+
+// deadLetterRouter 作为重试策略的 DeadLetterHandler，决定重试耗尽或不可重试的消息去向：
+//   - handler 实现了 DeadLetterHandler 时优先调用，成功即确认消息；
+//   - 否则（或其返回错误时）队列配置了死信交换机则拒绝消息，由 broker 转发；
+//   - 都没有时调用 FailedHandlerFunc 后确认消息。
+type deadLetterRouter struct {
+	handler types.DeadLetterHandler
+	dlx     bool
+	failed  types.FailedHandlerFunc
+	logger  *slog.Logger
+}
+
+var _ types.DeadLetterHandler = (*deadLetterRouter)(nil)
+
+func (r *deadLetterRouter) OnDeadLetter(ctx context.Context, msg types.Message, lastErr error) error {
+	if r.handler != nil {
+		err := r.handler.OnDeadLetter(ctx, msg, lastErr)
+		if err == nil {
+			return nil
+		}
+		if !r.dlx {
+			r.logger.Warn("dead letter handler failed",
+				"queue", msg.Queue, "message_id", msg.ID, "error", err)
+			return err
+		}
+	}
+	if r.dlx {
+		if s := settlementFrom(ctx); s != nil {
+			s.reject = true
+		}
+		return nil
+	}
+	if r.failed != nil {
+		r.failed(ctx, msg, lastErr)
+	}
+	return nil
+}
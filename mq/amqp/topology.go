@@ -0,0 +1,124 @@
+package amqp
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// 死信相关的队列参数
+const (
+	argDeadLetterExchange   = "x-dead-letter-exchange"
+	argDeadLetterRoutingKey = "x-dead-letter-routing-key"
+	argMessageTTL           = "x-message-ttl"
+)
+
+// Exchange 交换机声明
+type Exchange struct {
+	Name       string
+	Kind       string // direct / fanout / topic / headers，为空时使用 direct
+	Durable    bool
+	AutoDelete bool
+	Args       amqp091.Table
+}
+
+// Queue 额外声明的队列（如死信队列）。注册消费的队列由消费者自动声明，无需在此列出。
+type Queue struct {
+	Name       string
+	Durable    bool
+	AutoDelete bool
+	Args       amqp091.Table
+}
+
+// Binding 队列绑定声明
+type Binding struct {
+	Queue      string
+	Exchange   string
+	RoutingKey string
+	Args       amqp091.Table
+}
+
+// topology 待声明的交换机、队列与绑定。声明是幂等的，每次（重新）建立通道时都会执行；
+// 已存在的同名交换机 / 队列参数不一致时 broker 返回 PRECONDITION_FAILED。
+type topology struct {
+	exchanges []Exchange
+	queues    []Queue
+	bindings  []Binding
+}
+
+// declare 依次声明交换机、队列与绑定
+func (t topology) declare(ch amqpChannel) error {
+	for _, ex := range t.exchanges {
+		kind := ex.Kind
+		if kind == "" {
+			kind = amqp091.ExchangeDirect
+		}
+		if err := ch.ExchangeDeclare(ex.Name, kind, ex.Durable, ex.AutoDelete, false, false, ex.Args); err != nil {
+			return fmt.Errorf("declare exchange %q: %w", ex.Name, err)
+		}
+	}
+	for _, q := range t.queues {
+		if _, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, false, false, q.Args); err != nil {
+			return fmt.Errorf("declare queue %q: %w", q.Name, err)
+		}
+	}
+	for _, b := range t.bindings {
+		if err := ch.QueueBind(b.Queue, b.RoutingKey, b.Exchange, false, b.Args); err != nil {
+			return fmt.Errorf("bind queue %q to exchange %q: %w", b.Queue, b.Exchange, err)
+		}
+	}
+	return nil
+}
+
+// consumeQueue 注册消费的队列：持久化，附加 WithQueueArgs 与死信交换机参数
+func consumeQueue(name string, cfg *consumerConfig) Queue {
+	args := amqp091.Table{}
+	maps.Copy(args, cfg.queueArgs)
+	if cfg.deadLetterExchange != nil {
+		args[argDeadLetterExchange] = cfg.deadLetterExchange.exchange
+		if cfg.deadLetterExchange.routingKey != "" {
+			args[argDeadLetterRoutingKey] = cfg.deadLetterExchange.routingKey
+		}
+	}
+	return Queue{Name: name, Durable: true, Args: args}
+}
+
+// retryDelayTiers 延迟队列的固定档位（1-2-5 序列）。退避时长向上取整到档位，
+// 带抖动的退避不会为每个时长声明新的延迟队列，每个队列的延迟队列数量有上限。
+var retryDelayTiers = []time.Duration{
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second,
+	10 * time.Second, 20 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute,
+	10 * time.Minute, 20 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 5 * time.Hour,
+	12 * time.Hour, 24 * time.Hour,
+}
+
+// retryDelayTier 将退避时长向上取整到最近的档位，超过最大档位时使用最大档位
+func retryDelayTier(delay time.Duration) time.Duration {
+	for _, tier := range retryDelayTiers {
+		if delay <= tier {
+			return tier
+		}
+	}
+	return retryDelayTiers[len(retryDelayTiers)-1]
+}
+
+// retryQueue 再入队重试使用的延迟队列：消息在队列中停留 delay（取整到档位）后过期，经默认交换机回到原队列。
+// 每个档位使用独立的队列，避免按消息设置 TTL 时队首消息阻塞较短延迟的消息。
+func retryQueue(queue string, delay time.Duration) Queue {
+	ttl := retryDelayTier(delay).Milliseconds()
+	return Queue{
+		Name:    fmt.Sprintf("%s.retry.%d", queue, ttl),
+		Durable: true,
+		Args: amqp091.Table{
+			argMessageTTL:           ttl,
+			argDeadLetterExchange:   "",
+			argDeadLetterRoutingKey: queue,
+		},
+	}
+}
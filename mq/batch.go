@@ -0,0 +1,22 @@
+package mq
+
+import "github.com/gomooth/pkg/mq/internal/types"
+
+// 批量消费 re-export
+type IBatchHandler = types.IBatchHandler
+type FuncBatchHandler = types.FuncBatchHandler
+type BatchError = types.BatchError
+type BatchConfig = types.BatchConfig
+type BatchOption = types.BatchOption
+
+const (
+	DefaultBatchSize    = types.DefaultBatchSize
+	DefaultBatchMaxWait = types.DefaultBatchMaxWait
+)
+
+var NewBatchHandler = types.NewBatchHandler
+var NewBatchError = types.NewBatchError
+var BatchItemError = types.BatchItemError
+var BatchItemContext = types.BatchItemContext
+var WithBatchSize = types.WithBatchSize
+var WithBatchMaxWait = types.WithBatchMaxWait
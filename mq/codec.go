@@ -0,0 +1,35 @@
+package mq
+
+import (
+	"context"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// 编解码 re-export
+type Codec = types.Codec
+type JSONCodec = types.JSONCodec
+type MsgpackCodec = types.MsgpackCodec
+type ProtobufCodec = types.ProtobufCodec
+type CodecOption = types.CodecOption
+type DecodeError = types.DecodeError
+
+const HeaderCodec = types.HeaderCodec
+
+var RegisterCodec = types.RegisterCodec
+var LookupCodec = types.LookupCodec
+var WithCodec = types.WithCodec
+
+// 类型化 handler / producer re-export
+type TypedHandler[T any] = types.TypedHandler[T]
+type TypedProducer[T any] = types.TypedProducer[T]
+
+// NewTypedHandler 创建类型化 handler，消息体按编解码器解码为 T 后调用 fn
+func NewTypedHandler[T any](fn func(ctx context.Context, msg Message, v T) error, opts ...CodecOption) *TypedHandler[T] {
+	return types.NewTypedHandler(fn, opts...)
+}
+
+// NewTypedProducer 基于 IProducer 创建类型化生产者
+func NewTypedProducer[T any](producer IProducer, opts ...CodecOption) *TypedProducer[T] {
+	return types.NewTypedProducer[T](producer, opts...)
+}
@@ -0,0 +1,135 @@
+package dedup
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// handler 去重装饰器
+type handler struct {
+	next    types.IHandler
+	store   Store
+	cfg     config
+	metrics *metrics.DedupMetrics
+}
+
+// deadLetterHandler 被装饰的 handler 同时实现 DeadLetterHandler 时使用，保留死信接口
+type deadLetterHandler struct {
+	*handler
+	types.DeadLetterHandler
+}
+
+// NewHandler 返回按消息去重的 IHandler 装饰器。
+//
+// 处理前以去重键在 store 中获取处理权：
+//   - 获取成功：调用 h，成功后标记为已完成，失败或 panic 时删除处理中标记，重新投递的消息可再次处理；
+//   - 已完成：视为重复消息，直接返回 nil（消息被确认）；
+//   - 处理中：按 WithInProgressPoll 间隔轮询，处理者完成后视为重复消息返回 nil，
+//     处理中标记被删除或过期（处理失败或处理者崩溃）后获取处理权重新处理；
+//     等待期间 ctx 结束（关闭或 handler 超时）时返回 ErrInProgress，消息按处理失败进入重试 / 死信流程。
+//
+// 去重键默认为消息 ID，实际存储键为 [命名空间:]队列[:消费者组]:键，不同队列或消费者组互不影响。
+// h 实现 DeadLetterHandler 时装饰器同样实现。批量 handler（mq.NewBatchHandler）包装后退化为逐条处理，不应使用。
+func NewHandler(h types.IHandler, store Store, opts ...Option) types.IHandler {
+	cfg := config{
+		keyFunc:        func(msg types.Message) string { return msg.ID },
+		processingTTL:  DefaultProcessingTTL,
+		doneTTL:        DefaultDoneTTL,
+		inProgressPoll: DefaultInProgressPoll,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.logger == nil {
+		cfg.logger = slog.Default()
+	}
+
+	d := &handler{next: h, store: store, cfg: cfg, metrics: metrics.NewDedupMetrics()}
+	if dl, ok := h.(types.DeadLetterHandler); ok {
+		return &deadLetterHandler{handler: d, DeadLetterHandler: dl}
+	}
+	return d
+}
+
+func (d *handler) Handle(ctx context.Context, msg types.Message) error {
+	key := d.cfg.keyFunc(msg)
+	if key == "" {
+		return d.next.Handle(ctx, msg)
+	}
+	key = d.storeKey(msg, key)
+
+	for waiting := false; ; waiting = true {
+		acquired, state, err := d.store.Acquire(ctx, key, d.cfg.processingTTL)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			break
+		}
+		if state == StateDone {
+			d.metrics.OnDuplicate(msg.Queue)
+			d.cfg.logger.Debug("duplicate message suppressed", "queue", msg.Queue, "key", key)
+			return nil
+		}
+		// 处理中：在 handler 内等待处理者完成或处理中标记过期，等待不消耗重试次数
+		if !waiting {
+			d.metrics.OnInProgress(msg.Queue)
+		}
+		if !d.wait(ctx) {
+			return ErrInProgress
+		}
+	}
+
+	// 状态更新不受 handler ctx 取消影响
+	storeCtx := context.WithoutCancel(ctx)
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		if relErr := d.store.Release(storeCtx, key); relErr != nil {
+			d.cfg.logger.Warn("release dedup key failed", "key", key, "error", relErr)
+		}
+	}()
+
+	if err := d.next.Handle(ctx, msg); err != nil {
+		return err
+	}
+	completed = true
+
+	// 标记失败时消息已处理，处理中标记过期前的重复投递返回 ErrInProgress，过期后可能被再次处理
+	if err := d.store.Complete(storeCtx, key, d.cfg.doneTTL); err != nil {
+		d.cfg.logger.Warn("complete dedup key failed", "key", key, "error", err)
+	}
+	return nil
+}
+
+// wait 等待一个轮询间隔，ctx 结束时返回 false
+func (d *handler) wait(ctx context.Context) bool {
+	timer := time.NewTimer(d.cfg.inProgressPoll)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// storeKey 返回存储键：[命名空间:]队列[:消费者组]:去重键
+func (d *handler) storeKey(msg types.Message, key string) string {
+	parts := make([]string, 0, 4)
+	if d.cfg.namespace != "" {
+		parts = append(parts, d.cfg.namespace)
+	}
+	parts = append(parts, msg.Queue)
+	if msg.Group != "" {
+		parts = append(parts, msg.Group)
+	}
+	return strings.Join(append(parts, key), ":")
+}
@@ -0,0 +1,155 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingHandler 统计调用次数，err 非空时返回错误
+type countingHandler struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (h *countingHandler) Handle(context.Context, types.Message) error {
+	h.calls.Add(1)
+	return h.err
+}
+
+func message(id string) types.Message {
+	msg := types.NewMemoryMessage("orders", []byte("x"))
+	msg.ID = id
+	return msg
+}
+
+func TestHandler_SuppressesDuplicates(t *testing.T) {
+	inner := &countingHandler{}
+	h := NewHandler(inner, NewMemoryStore())
+	ctx := context.Background()
+
+	require.NoError(t, h.Handle(ctx, message("id-1")))
+	require.NoError(t, h.Handle(ctx, message("id-1")))
+	require.NoError(t, h.Handle(ctx, message("id-2")))
+	assert.Equal(t, int32(2), inner.calls.Load())
+}
+
+func TestHandler_FailureAllowsRedelivery(t *testing.T) {
+	inner := &countingHandler{err: errors.New("boom")}
+	h := NewHandler(inner, NewMemoryStore())
+	ctx := context.Background()
+
+	assert.Error(t, h.Handle(ctx, message("id-1")))
+	inner.err = nil
+	assert.NoError(t, h.Handle(ctx, message("id-1")))
+	assert.NoError(t, h.Handle(ctx, message("id-1")))
+	assert.Equal(t, int32(2), inner.calls.Load())
+}
+
+func TestHandler_InProgress(t *testing.T) {
+	store := NewMemoryStore()
+	inner := &countingHandler{}
+	h := NewHandler(inner, store)
+	ctx := context.Background()
+
+	// 模拟其他消费者正在处理（或处理中崩溃）
+	acquired, _, err := store.Acquire(ctx, "orders:id-1", DefaultProcessingTTL)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// 等待期间 ctx 结束：仍在处理中，返回 ErrInProgress
+	waitCtx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, h.Handle(waitCtx, message("id-1")), ErrInProgress)
+	assert.Zero(t, inner.calls.Load())
+}
+
+func TestHandler_InProgressWaitsForCompletion(t *testing.T) {
+	store := NewMemoryStore()
+	inner := &countingHandler{}
+	h := NewHandler(inner, store, WithInProgressPoll(5*time.Millisecond))
+	ctx := context.Background()
+
+	acquired, _, err := store.Acquire(ctx, "orders:id-1", DefaultProcessingTTL)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	time.AfterFunc(30*time.Millisecond, func() { _ = store.Complete(ctx, "orders:id-1", DefaultDoneTTL) })
+
+	// 处理者完成后视为重复消息
+	assert.NoError(t, h.Handle(ctx, message("id-1")))
+	assert.Zero(t, inner.calls.Load())
+}
+
+func TestHandler_InProgressTakesOverAfterRelease(t *testing.T) {
+	store := NewMemoryStore()
+	inner := &countingHandler{}
+	h := NewHandler(inner, store, WithInProgressPoll(5*time.Millisecond))
+	ctx := context.Background()
+
+	acquired, _, err := store.Acquire(ctx, "orders:id-1", DefaultProcessingTTL)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	time.AfterFunc(30*time.Millisecond, func() { _ = store.Release(ctx, "orders:id-1") })
+
+	// 处理者失败释放处理权后由等待方处理
+	assert.NoError(t, h.Handle(ctx, message("id-1")))
+	assert.Equal(t, int32(1), inner.calls.Load())
+}
+
+func TestHandler_PanicReleases(t *testing.T) {
+	store := NewMemoryStore()
+	h := NewHandler(types.FuncHandler(func(context.Context, types.Message) error {
+		panic("boom")
+	}), store)
+
+	assert.Panics(t, func() { _ = h.Handle(context.Background(), message("id-1")) })
+
+	acquired, _, err := store.Acquire(context.Background(), "orders:id-1", DefaultProcessingTTL)
+	require.NoError(t, err)
+	assert.True(t, acquired, "processing mark should be released after panic")
+}
+
+func TestHandler_KeyScoping(t *testing.T) {
+	inner := &countingHandler{}
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	byOrder := NewHandler(inner, store, WithKeyFunc(func(msg types.Message) string {
+		return msg.Headers["order-no"]
+	}))
+	a := message("id-1")
+	a.Headers = map[string]string{"order-no": "o-1"}
+	b := message("id-2")
+	b.Headers = map[string]string{"order-no": "o-1"}
+	require.NoError(t, byOrder.Handle(ctx, a))
+	require.NoError(t, byOrder.Handle(ctx, b))
+	assert.Equal(t, int32(1), inner.calls.Load(), "same extracted key is a duplicate")
+
+	// 其他命名空间独立去重
+	other := NewHandler(inner, store, WithNamespace("audit"))
+	require.NoError(t, other.Handle(ctx, message("id-1")))
+	assert.Equal(t, int32(2), inner.calls.Load())
+
+	// 无去重键时直接处理
+	require.NoError(t, byOrder.Handle(ctx, message("id-3")))
+	require.NoError(t, byOrder.Handle(ctx, message("id-3")))
+	assert.Equal(t, int32(4), inner.calls.Load())
+}
+
+// dlHandler 同时实现 DeadLetterHandler
+type dlHandler struct{ countingHandler }
+
+func (h *dlHandler) OnDeadLetter(context.Context, types.Message, error) error { return nil }
+
+func TestHandler_PreservesDeadLetter(t *testing.T) {
+	_, ok := NewHandler(&countingHandler{}, NewMemoryStore()).(types.DeadLetterHandler)
+	assert.False(t, ok)
+	_, ok = NewHandler(&dlHandler{}, NewMemoryStore()).(types.DeadLetterHandler)
+	assert.True(t, ok)
+}
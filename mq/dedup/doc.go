@@ -0,0 +1,10 @@
+// Package dedup 提供幂等消费去重装饰器。
+//
+// redis 备份队列恢复、kafka 水位线提交等至少一次投递机制会重复投递消息，
+// NewHandler 以消息 ID（或自定义 KeyFunc 提取的业务键）在 Store 中记录处理状态：
+// 已完成的重复消息直接确认，处理中的重复消息在 handler 内等待处理结果（不消耗重试次数），
+// 处理失败或 handler 崩溃后处理中标记被删除或过期，消息可被重新处理。
+//
+// 内置三种存储：MemoryStore（进程内）、RedisStore（SET NX）与 GormStore（数据库唯一主键），
+// 被丢弃与推迟的重复消息分别计入 mq.dedup.duplicates 与 mq.dedup.in_progress 指标。
+package dedup
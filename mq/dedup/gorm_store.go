@@ -0,0 +1,143 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomooth/xerror"
+	"github.com/gomooth/xerror/xcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 编译时接口检查
+var _ Store = (*GormStore)(nil)
+
+// DefaultGormTable GORM 去重存储默认表名
+const DefaultGormTable = "mq_dedup"
+
+// gormRecord 去重表的一行
+type gormRecord struct {
+	DedupKey  string    `gorm:"primaryKey;size:255"`
+	State     State     `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UpdatedAt time.Time
+}
+
+// GormStoreOption GORM 去重存储配置选项
+type GormStoreOption func(*gormStoreConfig)
+
+type gormStoreConfig struct {
+	table string
+}
+
+// WithGormTable 设置去重表名（默认 DefaultGormTable）
+func WithGormTable(table string) GormStoreOption {
+	return func(c *gormStoreConfig) {
+		if table != "" {
+			c.table = table
+		}
+	}
+}
+
+// GormStore 基于数据库唯一主键的去重存储，适用于多实例部署且不引入 Redis 的场景。
+// 过期记录在 Acquire 时被抢占复用，可定期调用 Cleanup 删除。
+type GormStore struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewGormStore 创建 GORM 去重存储
+func NewGormStore(db *gorm.DB, opts ...GormStoreOption) *GormStore {
+	cfg := gormStoreConfig{table: DefaultGormTable}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &GormStore{db: db, table: cfg.table}
+}
+
+// Migrate 创建或更新去重表
+func (s *GormStore) Migrate(ctx context.Context) error {
+	if err := s.db.WithContext(ctx).Table(s.table).AutoMigrate(&gormRecord{}); err != nil {
+		return xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	return nil
+}
+
+// Acquire 以插入唯一主键将 key 标记为处理中；主键冲突时抢占已过期的记录
+func (s *GormStore) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, State, error) {
+	now := time.Now()
+	res := s.db.WithContext(ctx).Table(s.table).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&gormRecord{DedupKey: key, State: StateProcessing, ExpiresAt: now.Add(ttl)})
+	if res.Error != nil {
+		return false, 0, xerror.WrapWithXCode(res.Error, xcode.DBFailed)
+	}
+	if res.RowsAffected == 1 {
+		return true, StateProcessing, nil
+	}
+
+	res = s.db.WithContext(ctx).Table(s.table).
+		Where("dedup_key = ? AND expires_at <= ?", key, now).
+		Updates(map[string]any{"state": StateProcessing, "expires_at": now.Add(ttl)})
+	if res.Error != nil {
+		return false, 0, xerror.WrapWithXCode(res.Error, xcode.DBFailed)
+	}
+	if res.RowsAffected == 1 {
+		return true, StateProcessing, nil
+	}
+
+	var existing gormRecord
+	err := s.db.WithContext(ctx).Table(s.table).Where("dedup_key = ?", key).Take(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 记录恰好被删除，按处理中返回，重新投递时再次获取
+		return false, StateProcessing, nil
+	}
+	if err != nil {
+		return false, 0, xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	return false, existing.State, nil
+}
+
+// Complete 将 key 标记为已完成
+func (s *GormStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	expiresAt := time.Now().Add(ttl)
+	res := s.db.WithContext(ctx).Table(s.table).Where("dedup_key = ?", key).
+		Updates(map[string]any{"state": StateDone, "expires_at": expiresAt})
+	if res.Error != nil {
+		return xerror.WrapWithXCode(res.Error, xcode.DBFailed)
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	err := s.db.WithContext(ctx).Table(s.table).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&gormRecord{DedupKey: key, State: StateDone, ExpiresAt: expiresAt}).Error
+	if err != nil {
+		return xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	return nil
+}
+
+// Release 删除 key 的处理中标记，已完成的记录保留
+func (s *GormStore) Release(ctx context.Context, key string) error {
+	err := s.db.WithContext(ctx).Table(s.table).
+		Where("dedup_key = ? AND state = ?", key, StateProcessing).
+		Delete(&gormRecord{}).Error
+	if err != nil {
+		return xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	return nil
+}
+
+// Cleanup 删除已过期的记录，返回删除条数
+func (s *GormStore) Cleanup(ctx context.Context) (int64, error) {
+	res := s.db.WithContext(ctx).Table(s.table).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&gormRecord{})
+	if res.Error != nil {
+		return 0, xerror.WrapWithXCode(res.Error, xcode.DBFailed)
+	}
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,76 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 编译时接口检查
+var _ Store = (*MemoryStore)(nil)
+
+// memorySweepInterval 过期记录清理间隔
+const memorySweepInterval = time.Minute
+
+// memoryEntry 内存去重记录
+type memoryEntry struct {
+	state     State
+	expiresAt time.Time
+}
+
+// MemoryStore 进程内去重存储，适用于单实例部署与测试；进程重启后记录丢失。
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryStore 创建进程内去重存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry), lastSweep: time.Now()}
+}
+
+// Acquire 尝试将 key 标记为处理中
+func (s *MemoryStore) Acquire(_ context.Context, key string, ttl time.Duration) (bool, State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		return false, e.state, nil
+	}
+	s.entries[key] = memoryEntry{state: StateProcessing, expiresAt: now.Add(ttl)}
+	return true, StateProcessing, nil
+}
+
+// Complete 将 key 标记为已完成
+func (s *MemoryStore) Complete(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{state: StateDone, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release 删除 key 的处理中标记，已完成的记录保留
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && e.state == StateProcessing {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// sweep 定期删除过期记录，调用方须持有锁
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for k, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package dedup
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// 默认有效期
+const (
+	// DefaultProcessingTTL 处理中标记默认有效期，应大于 handler 的最长处理时间
+	DefaultProcessingTTL = 5 * time.Minute
+	// DefaultDoneTTL 已完成记录默认保留时长，应覆盖 MQ 可能重复投递的时间窗口
+	DefaultDoneTTL = 24 * time.Hour
+	// DefaultInProgressPoll 处理中的重复消息等待时的默认轮询间隔
+	DefaultInProgressPoll = 200 * time.Millisecond
+)
+
+// KeyFunc 从消息中提取去重键，返回空字符串时不去重
+type KeyFunc func(msg types.Message) string
+
+// Option 去重配置选项
+type Option func(*config)
+
+// config 去重配置（未导出）
+type config struct {
+	keyFunc        KeyFunc
+	namespace      string
+	processingTTL  time.Duration
+	doneTTL        time.Duration
+	inProgressPoll time.Duration
+	logger         *slog.Logger
+}
+
+// WithKeyFunc 设置去重键提取函数（默认使用消息 ID）
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.keyFunc = fn
+		}
+	}
+}
+
+// WithNamespace 设置去重键命名空间，多个 handler 共用存储且需要各自处理同一消息时使用
+func WithNamespace(ns string) Option {
+	return func(c *config) {
+		c.namespace = ns
+	}
+}
+
+// WithProcessingTTL 设置处理中标记有效期（默认 DefaultProcessingTTL）
+func WithProcessingTTL(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.processingTTL = d
+		}
+	}
+}
+
+// WithDoneTTL 设置已完成记录保留时长（默认 DefaultDoneTTL）
+func WithDoneTTL(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.doneTTL = d
+		}
+	}
+}
+
+// WithInProgressPoll 设置处理中的重复消息等待处理结果时的轮询间隔（默认 DefaultInProgressPoll）
+func WithInProgressPoll(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.inProgressPoll = d
+		}
+	}
+}
+
+// WithLogger 设置日志器
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
@@ -0,0 +1,102 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/xerror"
+	"github.com/redis/go-redis/v9"
+)
+
+// 编译时接口检查
+var _ Store = (*RedisStore)(nil)
+
+// redis 中记录的状态值
+const (
+	redisProcessing = "processing"
+	redisDone       = "done"
+)
+
+// releaseScript 仅删除处理中标记，避免误删已完成记录
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisStoreOption Redis 去重存储配置选项
+type RedisStoreOption func(*redisStoreConfig)
+
+type redisStoreConfig struct {
+	keyPrefix string
+}
+
+// WithRedisKeyPrefix 设置 Redis key 前缀（默认 "mq:dedup"）
+func WithRedisKeyPrefix(prefix string) RedisStoreOption {
+	return func(c *redisStoreConfig) {
+		c.keyPrefix = prefix
+	}
+}
+
+// RedisStore 基于 Redis SET NX 的去重存储，适用于多实例部署，记录由 Redis 过期自动清理。
+type RedisStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisStore 创建 Redis 去重存储
+func NewRedisStore(client redis.UniversalClient, opts ...RedisStoreOption) *RedisStore {
+	cfg := redisStoreConfig{keyPrefix: "mq:dedup"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &RedisStore{client: client, keyPrefix: cfg.keyPrefix}
+}
+
+func (s *RedisStore) key(key string) string {
+	return s.keyPrefix + ":" + key
+}
+
+// Acquire 以 SET NX 将 key 标记为处理中
+func (s *RedisStore) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, State, error) {
+	k := s.key(key)
+	ok, err := s.client.SetNX(ctx, k, redisProcessing, ttl).Result()
+	if err != nil {
+		return false, 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	if ok {
+		return true, StateProcessing, nil
+	}
+
+	val, err := s.client.Get(ctx, k).Result()
+	if errors.Is(err, redis.Nil) {
+		// 记录恰好过期，按处理中返回，重新投递时再次获取
+		return false, StateProcessing, nil
+	}
+	if err != nil {
+		return false, 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	if val == redisDone {
+		return false, StateDone, nil
+	}
+	return false, StateProcessing, nil
+}
+
+// Complete 将 key 标记为已完成
+func (s *RedisStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.key(key), redisDone, ttl).Err(); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	return nil
+}
+
+// Release 删除 key 的处理中标记，已完成的记录保留
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	if err := releaseScript.Run(ctx, s.client, []string{s.key(key)}, redisProcessing).Err(); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	return nil
+}
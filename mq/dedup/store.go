@@ -0,0 +1,47 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// State 去重记录状态
+type State int
+
+const (
+	// StateProcessing 消息正在处理，到期前重复投递的消息等待处理结果
+	StateProcessing State = iota + 1
+	// StateDone 消息已处理完成，保留期内重复投递的消息被丢弃
+	StateDone
+)
+
+// String 返回状态的可读名称
+func (s State) String() string {
+	switch s {
+	case StateProcessing:
+		return "processing"
+	case StateDone:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrInProgress 表示等待期间 ctx 结束时，同一消息仍在被其他消费者处理（或处理者崩溃且处理中标记尚未过期）。
+// 消息按 handler 失败进入重试流程，重试耗尽时与其他失败一样进入死信 / 失败回调。
+var ErrInProgress = errors.New("mq: duplicate message in progress")
+
+// Store 去重状态存储接口。
+//
+// 同一 key 的 Acquire 须互斥：只有一个调用方能成功获取处理权。
+// 记录均带有效期，处理中记录过期后视为不存在，崩溃中断的消息可被重新处理。
+type Store interface {
+	// Acquire 尝试将 key 标记为处理中，有效期 ttl。
+	// key 不存在或已过期时获取成功；否则返回 acquired=false 与现有记录的状态。
+	Acquire(ctx context.Context, key string, ttl time.Duration) (acquired bool, state State, err error)
+	// Complete 将 key 标记为已完成，有效期 ttl
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+	// Release 删除 key 的处理中标记，处理失败后重新投递的消息可立即处理
+	Release(ctx context.Context, key string) error
+}
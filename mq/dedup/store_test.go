@@ -0,0 +1,84 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testStoreContract 验证 Store 的公共语义，expire 使已写入的记录过期
+func testStoreContract(t *testing.T, store Store, expire func()) {
+	ctx := context.Background()
+	ttl := 200 * time.Millisecond
+
+	acquired, state, err := store.Acquire(ctx, "k1", ttl)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, StateProcessing, state)
+
+	acquired, state, err = store.Acquire(ctx, "k1", ttl)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, StateProcessing, state)
+
+	// 处理失败：释放后可再次获取
+	require.NoError(t, store.Release(ctx, "k1"))
+	acquired, _, err = store.Acquire(ctx, "k1", ttl)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// 处理成功：已完成记录不可获取，也不会被 Release 删除
+	require.NoError(t, store.Complete(ctx, "k1", ttl))
+	require.NoError(t, store.Release(ctx, "k1"))
+	acquired, state, err = store.Acquire(ctx, "k1", ttl)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, StateDone, state)
+
+	// 处理中崩溃：标记过期后可重新获取
+	acquired, _, err = store.Acquire(ctx, "k2", ttl)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	expire()
+	acquired, _, err = store.Acquire(ctx, "k2", ttl)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStoreContract(t, NewMemoryStore(), func() { time.Sleep(250 * time.Millisecond) })
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	store := NewRedisStore(client, WithRedisKeyPrefix("test:dedup"))
+	testStoreContract(t, store, func() { mr.FastForward(time.Second) })
+
+	assert.True(t, mr.Exists("test:dedup:k2"))
+}
+
+func TestGormStore(t *testing.T) {
+	dsn := fmt.Sprintf("file:dedup_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+
+	store := NewGormStore(db)
+	require.NoError(t, store.Migrate(context.Background()))
+	testStoreContract(t, store, func() { time.Sleep(250 * time.Millisecond) })
+
+	time.Sleep(250 * time.Millisecond)
+	n, err := store.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
@@ -11,6 +11,7 @@ func NewConsumer(opts ...ConsumerOption) types.IConsumeServer {
 	cfg := consumerConfig{
 		maxRetry:        3,
 		emptyQueueSleep: time.Second,
+		concurrency:     1,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
@@ -15,6 +15,7 @@ import (
 	"github.com/gomooth/pkg/mq/internal/engine"
 	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/middleware"
 	"github.com/gomooth/pkg/mq/internal/types"
 	"github.com/gomooth/xerror"
 )
@@ -25,6 +26,7 @@ const maxConsumeErrors = 50
 type queueConsumer struct {
 	queueName string
 	handler   types.IHandler
+	mws       []types.Middleware // 注册级中间件，批量调用组装中间件链时使用
 	client    httpsqs.IClient
 	strategy  retryStrategy
 }
@@ -98,7 +100,7 @@ func newConsumerEngine(cfg *consumerConfig) *consumerEngine {
 
 	// 预注册配置中的消费者
 	for _, reg := range cfg.consumers {
-		eng.createRegistration(reg.Queue, reg.Handler, reg.Opts)
+		eng.createRegistration(reg.Queue, reg.Handler, reg.Opts, nil)
 	}
 
 	return eng
@@ -118,7 +120,7 @@ func (e *consumerEngine) Register(queue string, handler types.IHandler, opts ...
 		return xerror.NewXCode(xcode.ErrMQConsume, "httpsqs does not support WithGroup option")
 	}
 
-	e.createRegistration(queue, handler, cfg.QueueOpts)
+	e.createRegistration(queue, handler, cfg.QueueOpts, cfg.Middlewares)
 	return nil
 }
 
@@ -128,7 +130,7 @@ func (e *consumerEngine) Count() uint {
 	return uint(len(e.registrations))
 }
 
-func (e *consumerEngine) createRegistration(queueName string, handler types.IHandler, opts []types.QueueOption) {
+func (e *consumerEngine) createRegistration(queueName string, handler types.IHandler, opts []types.QueueOption, mws []types.Middleware) {
 	if len(queueName) == 0 {
 		e.Logger.Error("queue name must not be empty")
 		return
@@ -195,36 +197,54 @@ func (e *consumerEngine) createRegistration(queueName string, handler types.IHan
 	intLogger := logutil.NewSlogLogger(e.Logger)
 	m := e.Metrics.(*metrics.ConsumerMetrics)
 
+	// 重试策略调用组装好中间件链的 handler；死信与批量接口仍在原始 handler 上识别
+	wrapped := e.wrapHandler(handler, mws)
+
 	// 创建重试策略
 	var strategy retryStrategy
 
 	switch retryMode {
 	case types.RetryModeRequeue:
-		s := newRequeueRetryStrategy(handler, maxRetry, backoff, client, queueName, intLogger, m)
+		s := newRequeueRetryStrategy(wrapped, maxRetry, backoff, client, queueName, intLogger, m)
 		s.SetFailedHandler(effectiveFailedFn)
 		if dl, ok := handler.(types.DeadLetterHandler); ok {
 			s.SetDeadLetterHandler(dl)
 		}
 		s.SetTimeout(e.opt.handlerTimeout)
+		s.SetRetryIf(e.opt.retryIf)
 		strategy = s
 	default: // RetryModeSync
-		s := newSyncRetryStrategy(handler, maxRetry, backoff, intLogger, m)
+		s := newSyncRetryStrategy(wrapped, maxRetry, backoff, intLogger, m)
 		s.SetFailedHandler(effectiveFailedFn)
 		if dl, ok := handler.(types.DeadLetterHandler); ok {
 			s.SetDeadLetterHandler(dl)
 		}
 		s.SetTimeout(e.opt.handlerTimeout)
+		s.SetRetryIf(e.opt.retryIf)
 		strategy = s
 	}
 
 	e.registrations = append(e.registrations, queueConsumer{
 		queueName: queueName,
 		handler:   handler,
+		mws:       mws,
 		client:    client,
 		strategy:  strategy,
 	})
 }
 
+// wrapHandler 按 内置 → 服务级 → 注册级 的顺序为 handler 组装中间件链
+func (e *consumerEngine) wrapHandler(handler types.IHandler, mws []types.Middleware) types.IHandler {
+	server := middleware.Server(e.opt.noDefaultMiddlewares, e.Logger, e.opt.panicHandler, e.opt.middlewares)
+	return middleware.Wrap(handler, server, mws)
+}
+
+// wrapBatchHandler 为整批 HandleBatch 调用组装与单条处理相同的中间件链
+func (e *consumerEngine) wrapBatchHandler(bh types.IBatchHandler, mws []types.Middleware) types.IBatchHandler {
+	server := middleware.Server(e.opt.noDefaultMiddlewares, e.Logger, e.opt.panicHandler, e.opt.middlewares)
+	return middleware.WrapBatch(bh, append(server, mws...), e.Logger, e.opt.panicHandler)
+}
+
 func (e *consumerEngine) Start(ctx context.Context) error {
 	if !e.TryStart() {
 		if e.State.Load() == engine.Running {
@@ -253,14 +273,16 @@ func (e *consumerEngine) Start(ctx context.Context) error {
 		return xerror.NewXCode(xcode.ErrMQConsume, "no consumers registered")
 	}
 
-	// 启动消费循环
+	// 启动消费循环（每个队列 concurrency 个 worker）
 	for _, reg := range regs {
 		r := reg
-		e.WG.Add(1)
-		e.SafeGo(fmt.Sprintf("consume-%s", r.queueName), func() {
-			defer e.WG.Done()
-			e.consumeLoop(engineCtx, r)
-		}, e.opt.panicHandler)
+		for i := range e.opt.concurrency {
+			e.WG.Add(1)
+			e.SafeGo(fmt.Sprintf("consume-%s-%d", r.queueName, i), func() {
+				defer e.WG.Done()
+				e.consumeLoop(engineCtx, r)
+			}, e.opt.panicHandler)
+		}
 	}
 
 	return nil
@@ -285,17 +307,6 @@ func (e *consumerEngine) Shutdown(ctx context.Context) error {
 		qw.Shutdown()
 	}
 
-	// 3. 关闭 requeueRetryStrategy 的 AttemptTracker
-	e.regMu.Lock()
-	regs := make([]queueConsumer, len(e.registrations))
-	copy(regs, e.registrations)
-	e.regMu.Unlock()
-	for _, reg := range regs {
-		if closer, ok := reg.strategy.(interface{ Close() }); ok {
-			closer.Close()
-		}
-	}
-
 	done := make(chan struct{})
 	go func() {
 		e.WG.Wait()
@@ -321,7 +332,24 @@ func (e *consumerEngine) consumeLoop(ctx context.Context, qc queueConsumer) {
 		EmptySleep: e.opt.emptyQueueSleep,
 		MaxErrors:  maxConsumeErrors,
 		Tracer:     telemetry.Tracer("mq.httpsqs.consumer"),
+		Logger:     e.Logger,
+		Drain:      e.Draining(),
+		Metrics:    e.Metrics.(*metrics.ConsumerMetrics),
+	}
+
+	// 批量 handler 使用批量消费循环，失败的消息交给单条重试策略
+	if bh, batchCfg, ok := types.BatchOf(qc.handler); ok {
+		strategy := consume.NewBatchStrategy(e.wrapBatchHandler(bh, qc.mws), decodeMessage, qc.strategy, e.opt.handlerTimeout)
+		consume.ConsumeBatchLoop(ctx, cfg, batchCfg, fetcher, strategy)
+		return
 	}
 
 	consume.ConsumeLoop(ctx, cfg, fetcher, qc.strategy)
+}
+
+// decodeMessage 将线上数据解析为 httpsqs 消息
+func decodeMessage(queue string, data []byte) types.Message {
+	msg := types.NewHttpsqSMessage(queue, nil, 0)
+	types.UnmarshalWire(&msg, data)
+	return msg
 }
\ No newline at end of file
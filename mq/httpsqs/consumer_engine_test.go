@@ -339,4 +339,92 @@ func TestConsumer_ShutdownIdempotent(t *testing.T) {
 		_ = consumer.Shutdown(context.Background())
 		_ = consumer.Shutdown(context.Background())
 	})
-}
\ No newline at end of file
+}
+func TestConsumer_Concurrency(t *testing.T) {
+	client := &mockGetClient{
+		results: []mockGetResult{
+			{data: "m1", pos: 1},
+			{data: "m2", pos: 2},
+			{data: "m3", pos: 3},
+			{data: "m4", pos: 4},
+		},
+	}
+
+	const workers = 2
+	var inFlight, peak, consumed atomic.Int32
+	release := make(chan struct{})
+	consumer := NewConsumer(
+		WithHTTPSQSClient(client),
+		WithConsumer("concurrent-queue", types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			inFlight.Add(-1)
+			consumed.Add(1)
+			return nil
+		})),
+		WithConcurrency(workers),
+		WithEmptyQueueSleep(10*time.Millisecond),
+	)
+
+	ctx := context.Background()
+	require.NoError(t, consumer.Start(ctx))
+
+	require.Eventually(t, func() bool { return inFlight.Load() == workers }, 3*time.Second, 10*time.Millisecond)
+	close(release)
+	require.Eventually(t, func() bool { return consumed.Load() == 4 }, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(workers), peak.Load(), "in-flight messages must not exceed concurrency")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, consumer.Shutdown(shutdownCtx))
+}
+
+func TestConsumer_BatchHandler(t *testing.T) {
+	client := &mockGetClient{
+		results: []mockGetResult{
+			{data: "a", pos: 1},
+			{data: "b", pos: 2},
+			{data: "c", pos: 3},
+		},
+	}
+
+	batches := make(chan []string, 4)
+	consumer := NewConsumer(
+		WithHTTPSQSClient(client),
+		WithConsumer("batch-queue", types.NewBatchHandler(
+			types.FuncBatchHandler(func(ctx context.Context, msgs []types.Message) error {
+				batch := make([]string, len(msgs))
+				for i, m := range msgs {
+					batch[i] = string(m.Data)
+				}
+				batches <- batch
+				return nil
+			}),
+			types.WithBatchSize(2),
+			types.WithBatchMaxWait(20*time.Millisecond),
+		)),
+		WithEmptyQueueSleep(10*time.Millisecond),
+	)
+
+	ctx := context.Background()
+	require.NoError(t, consumer.Start(ctx))
+
+	for _, want := range [][]string{{"a", "b"}, {"c"}} {
+		select {
+		case got := <-batches:
+			assert.Equal(t, want, got)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("batch %v not consumed", want)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, consumer.Shutdown(shutdownCtx))
+}
@@ -1,7 +1,12 @@
-// Package httpsqs 提供统一的 HTTPSQS 队列消费者实现。
+// Package httpsqs 提供统一的 HTTPSQS 队列消费者与生产者实现。
 //
 // 消费者支持同步重试和再入队重试两种模式，通过可配置的退避策略控制重试节奏。
 // 支持 per-queue 配置覆盖全局默认值（QueueOption）。
+// 通过 mq.NewBatchHandler 注册的批量 handler 按条数或等待时间凑批处理，失败的消息逐条进入重试流程。
 //
-// Consumer 实现 app.IApp 接口，可通过 app.Manager 统一管理生命周期。
+// 生产者以与 mq/redis 相同的版本化线上格式写入消息，trace context 通过消息头传播；
+// HTTPSQS 不支持延迟投递，设置 WithDelay / WithDeliverAt 时返回 ErrDelayNotSupported。
+//
+// Consumer 和 Producer 均实现 app.IApp 与 app.HealthChecker 接口，可通过 app.Manager 统一管理生命周期。
+// Consumer 同时实现 app.PreStopper 接口，排空阶段停止拉取新消息并等待处理中的消息完成。
 package httpsqs
@@ -30,4 +30,24 @@ func (f *httpsqsFetcher) Fetch(ctx context.Context) consume.FetchResult {
 		return consume.FetchResult{Empty: true}
 	}
 	return consume.FetchResult{Data: data}
-}
\ No newline at end of file
+}
+
+// FetchBatch 逐条拉取至多 max 条消息，队列为空时提前返回。
+// HTTPSQS 协议不支持批量出队，已拉取的消息在拉取出错时仍会返回，避免丢失。
+func (f *httpsqsFetcher) FetchBatch(ctx context.Context, max int) ([]consume.FetchResult, error) {
+	var results []consume.FetchResult
+	for len(results) < max {
+		data, pos, err := f.client.Get(ctx, f.queueName)
+		if err != nil {
+			if ctx.Err() != nil || len(results) > 0 {
+				return results, nil
+			}
+			return nil, err
+		}
+		if pos == -1 {
+			break
+		}
+		results = append(results, consume.FetchResult{Data: data})
+	}
+	return results, nil
+}
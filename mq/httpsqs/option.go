@@ -16,16 +16,20 @@ type ConsumerOption func(*consumerConfig)
 
 // consumerConfig 消费者引擎配置（未导出）
 type consumerConfig struct {
-	logger          *slog.Logger
-	client          httpsqs.IClient // 全局 HTTPSQS 客户端（必填）
-	maxRetry        int
-	backoff         retry.BackoffStrategy
-	retryMode       types.RetryMode
-	handlerTimeout  time.Duration
-	emptyQueueSleep time.Duration
-	failedHandler   types.FailedHandlerFunc
-	panicHandler    func(any)
-	consumers       []ConsumerRegistration
+	logger               *slog.Logger
+	client               httpsqs.IClient // 全局 HTTPSQS 客户端（必填）
+	maxRetry             int
+	backoff              retry.BackoffStrategy
+	retryMode            types.RetryMode
+	retryIf              func(error) bool
+	handlerTimeout       time.Duration
+	emptyQueueSleep      time.Duration
+	concurrency          int
+	failedHandler        types.FailedHandlerFunc
+	panicHandler         func(any)
+	middlewares          []types.Middleware
+	noDefaultMiddlewares bool
+	consumers            []ConsumerRegistration
 }
 
 // WithHTTPSQSClient 设置全局 HTTPSQS 客户端（必填，Start 时校验）
@@ -56,6 +60,14 @@ func WithRetryMode(mode types.RetryMode) ConsumerOption {
 	}
 }
 
+// WithRetryIf 设置错误重试谓词：返回 false 的错误不再重试，直接进入死信处理器或失败回调（默认所有错误都重试）。
+// mq.Permanent 包装的错误与解码失败始终不重试。
+func WithRetryIf(fn func(error) bool) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryIf = fn
+	}
+}
+
 // WithHandlerTimeout 设置单次 handler 调用的超时时间（默认 0，不限）
 func WithHandlerTimeout(d time.Duration) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -63,13 +75,40 @@ func WithHandlerTimeout(d time.Duration) ConsumerOption {
 	}
 }
 
-// WithPanicHandler 设置 panic 恢复后的回调函数
+// WithPanicHandler 设置 panic 恢复后的回调函数（handler panic 由内置 Recover 中间件恢复后回调）
 func WithPanicHandler(fn func(any)) ConsumerOption {
 	return func(c *consumerConfig) {
 		c.panicHandler = fn
 	}
 }
 
+// WithMiddleware 追加服务级 handler 中间件，作用于该消费者注册的所有 handler。
+// 服务级中间件位于内置中间件（Trace → Recover）之内、注册级中间件（mq.WithMiddleware）之外；
+// 先传入的中间件位于外层。
+func WithMiddleware(mws ...types.Middleware) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithoutDefaultMiddlewares 禁用内置的 Trace 与 Recover 中间件，
+// 可通过 WithMiddleware 以 mq.Trace / mq.Recover 自定义顺序或替换实现
+func WithoutDefaultMiddlewares() ConsumerOption {
+	return func(c *consumerConfig) {
+		c.noDefaultMiddlewares = true
+	}
+}
+
+// WithConcurrency 设置每个队列并行处理消息的 worker 数（默认 1）。
+// 并行处理时同一队列的消息不再保证按顺序处理。
+func WithConcurrency(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
 // WithEmptyQueueSleep 设置队列为空时的休眠时间（默认 1s）
 func WithEmptyQueueSleep(d time.Duration) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -109,4 +148,49 @@ func WithConsumerLogger(l *slog.Logger) ConsumerOption {
 	return func(c *consumerConfig) {
 		c.logger = l
 	}
-}
\ No newline at end of file
+}
+
+// ==================== Producer 选项 ====================
+
+// ProducerOption 生产者配置选项
+type ProducerOption func(*producerConfig)
+
+// producerConfig 生产者引擎配置（未导出）
+type producerConfig struct {
+	logger      *slog.Logger
+	client      httpsqs.IClient // HTTPSQS 客户端（必填）
+	healthQueue string
+	legacyWire  bool
+}
+
+// WithProducerHTTPSQSClient 设置生产者使用的 HTTPSQS 客户端（必填，Start 时校验）
+func WithProducerHTTPSQSClient(client httpsqs.IClient) ProducerOption {
+	return func(c *producerConfig) {
+		c.client = client
+	}
+}
+
+// WithProducerLogger 设置生产者日志器
+func WithProducerLogger(l *slog.Logger) ProducerOption {
+	return func(c *producerConfig) {
+		c.logger = l
+	}
+}
+
+// WithProducerHealthQueue 设置健康检查查询状态的队列名（默认 "mq-health"）。
+// 查询状态不会创建队列或写入消息。
+func WithProducerHealthQueue(queue string) ProducerOption {
+	return func(c *producerConfig) {
+		if queue != "" {
+			c.healthQueue = queue
+		}
+	}
+}
+
+// WithProducerLegacyPayload 不使用消息信封，直接写入原始消息体（trace context 注入 JSON 消息体）。
+// 用于滚动升级期间兼容尚未升级的消费者，此时消息头、消息 ID 等信封字段不会被传递。
+func WithProducerLegacyPayload() ProducerOption {
+	return func(c *producerConfig) {
+		c.legacyWire = true
+	}
+}
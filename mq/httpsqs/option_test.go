@@ -24,6 +24,14 @@ func TestWithHandlerTimeout(t *testing.T) {
 	assert.Equal(t, 5*time.Second, cfg.handlerTimeout)
 }
 
+func TestWithConcurrency(t *testing.T) {
+	cfg := &consumerConfig{concurrency: 1}
+	WithConcurrency(-1)(cfg)
+	assert.Equal(t, 1, cfg.concurrency, "non-positive value should be ignored")
+	WithConcurrency(8)(cfg)
+	assert.Equal(t, 8, cfg.concurrency)
+}
+
 func TestWithPanicHandler(t *testing.T) {
 	var panicVal any
 	cfg := &consumerConfig{}
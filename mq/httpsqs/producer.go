@@ -0,0 +1,17 @@
+package httpsqs
+
+import (
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// NewProducer 创建生产者实例，须通过 WithProducerHTTPSQSClient 设置 HTTPSQS 客户端。
+// 返回值同时实现 app.HealthChecker 接口。
+func NewProducer(opts ...ProducerOption) types.IProducer {
+	cfg := producerConfig{
+		healthQueue: "mq-health",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return newProducerEngine(&cfg)
+}
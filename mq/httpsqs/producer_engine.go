@@ -0,0 +1,176 @@
+package httpsqs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/gomooth/pkg/framework/telemetry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	mqtraceutil "github.com/gomooth/pkg/mq/internal/traceutil"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// producerEngine 生产者生命周期引擎（未导出）
+type producerEngine struct {
+	engine.Base
+	opt *producerConfig
+}
+
+// 编译时接口检查
+var _ types.IProducer = (*producerEngine)(nil)
+
+func newProducerEngine(cfg *producerConfig) *producerEngine {
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &producerEngine{
+		Base: engine.Base{
+			Logger:  logger,
+			Metrics: metrics.NewProducerMetrics("httpsqs"),
+		},
+		opt: cfg,
+	}
+}
+
+// Start 校验客户端配置并查询一次队列状态，确认 HTTPSQS 服务可用
+func (e *producerEngine) Start(ctx context.Context) error {
+	if !e.TryStart() {
+		if e.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer already closed")
+	}
+
+	if e.opt.client == nil {
+		e.State.Store(engine.Idle)
+		return xerror.NewXCode(xcode.ErrMQPublish, "httpsqs client is required, use WithProducerHTTPSQSClient()")
+	}
+	if _, err := e.opt.client.Status(ctx, e.opt.healthQueue); err != nil {
+		e.State.Store(engine.Idle)
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	return nil
+}
+
+// Shutdown 停止生产者。HTTPSQS 客户端由调用方创建，不在此关闭。
+func (e *producerEngine) Shutdown(_ context.Context) error {
+	if !e.RequestShutdown() {
+		if e.State.Load() == engine.Idle {
+			e.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	e.State.Store(engine.Closed)
+	return nil
+}
+
+// HealthCheck 检查生产者处于运行状态，且 HTTPSQS 服务可查询队列状态
+func (e *producerEngine) HealthCheck(ctx context.Context) error {
+	if state := e.State.Load(); state != engine.Running {
+		return xerror.NewXCode(xcode.ErrMQPublish,
+			fmt.Sprintf("producer not running (state=%d)", state))
+	}
+	if _, err := e.opt.client.Status(ctx, e.opt.healthQueue); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	return nil
+}
+
+// Produce 将消息写入队列尾部（Put）。不支持延迟投递，设置 WithDelay / WithDeliverAt 时返回 ErrDelayNotSupported。
+func (e *producerEngine) Produce(ctx context.Context, queue string, message []byte, opts ...types.ProduceOption) error {
+	return e.produce(ctx, queue, [][]byte{message}, opts, false)
+}
+
+// ProduceBatch 逐条写入消息，每条消息独立生成消息 ID。
+// HTTPSQS 协议不支持批量入队，某条消息写入失败时停止并返回错误，此前的消息已写入。
+func (e *producerEngine) ProduceBatch(ctx context.Context, queue string, messages [][]byte, opts ...types.ProduceOption) error {
+	if len(messages) == 0 {
+		return xerror.NewXCode(xcode.ErrMQPublish, "no messages")
+	}
+	return e.produce(ctx, queue, messages, opts, true)
+}
+
+func (e *producerEngine) produce(ctx context.Context, queue string, messages [][]byte, opts []types.ProduceOption, batch bool) error {
+	if err := ctx.Err(); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	produceCfg := types.ApplyProduceOptions(opts)
+	if _, delayed := produceCfg.DeliveryTime(); delayed {
+		return xerror.WrapWithXCode(types.ErrDelayNotSupported, xcode.ErrMQPublish)
+	}
+	if batch {
+		produceCfg.MessageID = ""
+	}
+
+	spanName := fmt.Sprintf("%s produce", queue)
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "httpsqs"),
+		attribute.String("messaging.destination", queue),
+	}
+	if batch {
+		spanName = fmt.Sprintf("%s produce batch", queue)
+		attrs = append(attrs, attribute.Int("messaging.batch.size", len(messages)))
+	}
+
+	// Create producer Span
+	tracer := telemetry.Tracer("mq.httpsqs.producer")
+	ctx, span := tracer.Start(ctx, spanName,
+		trace.WithAttributes(attrs...),
+		trace.WithSpanKind(trace.SpanKindProducer),
+	)
+	defer span.End()
+
+	if e.State.Load() != engine.Running {
+		span.RecordError(fmt.Errorf("producer not connected"))
+		span.SetStatus(codes.Error, "producer not connected")
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer not connected")
+	}
+
+	m, _ := e.Metrics.(*metrics.ProducerMetrics)
+	for i, data := range messages {
+		if _, err := e.opt.client.Put(ctx, queue, e.encode(ctx, queue, data, produceCfg)); err != nil {
+			if batch {
+				err = fmt.Errorf("produce message %d: %w", i, err)
+			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			if m != nil {
+				if i > 0 {
+					m.OnProduce(i)
+				}
+				m.OnError()
+			}
+			return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+		}
+	}
+
+	if m != nil {
+		m.OnProduce(len(messages))
+	}
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
+
+// encode 生成写入队列的数据：填充消息信封并注入 trace context，编码为线上格式。
+// 启用 WithProducerLegacyPayload 时写入原始消息体，trace context 注入 JSON 消息体。
+func (e *producerEngine) encode(ctx context.Context, queue string, data []byte, cfg *types.ProduceConfig) string {
+	if e.opt.legacyWire {
+		return mqtraceutil.InjectTraceContext(ctx, string(data))
+	}
+
+	msg := types.NewHttpsqSMessage(queue, data, 0)
+	cfg.Stamp(&msg)
+	mqtraceutil.InjectHeaders(ctx, msg.Headers)
+	return string(types.MarshalWire(msg))
+}
@@ -0,0 +1,263 @@
+package httpsqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomooth/httpsqs"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeHTTPSQS httptest 实现的 HTTPSQS 服务，支持 put / get / status_json，maxQueue 为 0 时不限长度
+type fakeHTTPSQS struct {
+	mu       sync.Mutex
+	queues   map[string][]string
+	maxQueue int
+	down     bool
+}
+
+func newFakeHTTPSQS(t *testing.T) (*fakeHTTPSQS, httpsqs.IClient) {
+	t.Helper()
+	f := &fakeHTTPSQS{queues: make(map[string][]string)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	client := httpsqs.NewClient(&httpsqs.Config{Addr: strings.TrimPrefix(srv.URL, "http://")})
+	return f, client
+}
+
+func (f *fakeHTTPSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down {
+		_, _ = fmt.Fprint(w, "HTTPSQS_ERROR")
+		return
+	}
+	q := r.URL.Query()
+	name := q.Get("name")
+	switch q.Get("opt") {
+	case "put":
+		if f.maxQueue > 0 && len(f.queues[name]) >= f.maxQueue {
+			_, _ = fmt.Fprint(w, "HTTPSQS_PUT_END")
+			return
+		}
+		f.queues[name] = append(f.queues[name], q.Get("data"))
+		w.Header().Set("Pos", fmt.Sprint(len(f.queues[name])))
+		_, _ = fmt.Fprint(w, "HTTPSQS_PUT_OK")
+	case "get":
+		if len(f.queues[name]) == 0 {
+			_, _ = fmt.Fprint(w, "HTTPSQS_GET_END")
+			return
+		}
+		data := f.queues[name][0]
+		f.queues[name] = f.queues[name][1:]
+		w.Header().Set("Pos", "1")
+		_, _ = fmt.Fprint(w, data)
+	case "status_json":
+		_, _ = fmt.Fprintf(w, `{"name":%q,"maxqueue":1000000,"unread":%d}`, name, len(f.queues[name]))
+	default:
+		_, _ = fmt.Fprint(w, "HTTPSQS_ERROR")
+	}
+}
+
+func (f *fakeHTTPSQS) messages(queue string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.queues[queue]...)
+}
+
+func (f *fakeHTTPSQS) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func startProducer(t *testing.T, client httpsqs.IClient, opts ...ProducerOption) types.IProducer {
+	t.Helper()
+	p := NewProducer(append([]ProducerOption{WithProducerHTTPSQSClient(client)}, opts...)...)
+	require.NoError(t, p.Start(context.Background()))
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+	return p
+}
+
+func decodeWire(t *testing.T, raw string) types.Message {
+	t.Helper()
+	msg := types.NewHttpsqSMessage("", nil, 0)
+	types.UnmarshalWire(&msg, []byte(raw))
+	return msg
+}
+
+func TestProducer_Produce(t *testing.T) {
+	f, client := newFakeHTTPSQS(t)
+	p := startProducer(t, client)
+
+	err := p.Produce(context.Background(), "orders", []byte("hello"),
+		types.WithMessageID("id-1"), types.WithOrderKey("k"), types.WithHeaders(map[string]string{"tenant": "t1"}))
+	require.NoError(t, err)
+
+	got := f.messages("orders")
+	require.Len(t, got, 1)
+	msg := decodeWire(t, got[0])
+	assert.Equal(t, []byte("hello"), msg.Data)
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "k", msg.Key)
+	assert.Equal(t, "t1", msg.Header("tenant"))
+	assert.False(t, msg.Timestamp.IsZero())
+}
+
+func TestProducer_InjectsTraceContext(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	f, client := newFakeHTTPSQS(t)
+	require.NoError(t, startProducer(t, client).Produce(ctx, "orders", []byte("x")))
+	msg := decodeWire(t, f.messages("orders")[0])
+	assert.Contains(t, msg.Header("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	legacy := startProducer(t, client, WithProducerLegacyPayload())
+	require.NoError(t, legacy.Produce(ctx, "legacy", []byte(`{"a":1}`)))
+	assert.Contains(t, f.messages("legacy")[0], `"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736`)
+}
+
+func TestProducer_LegacyPayload(t *testing.T) {
+	f, client := newFakeHTTPSQS(t)
+	p := startProducer(t, client, WithProducerLegacyPayload())
+
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("hello")))
+	assert.Equal(t, []string{"hello"}, f.messages("orders"))
+}
+
+func TestProducer_ProduceBatch(t *testing.T) {
+	f, client := newFakeHTTPSQS(t)
+	p := startProducer(t, client)
+	ctx := context.Background()
+
+	require.NoError(t, p.ProduceBatch(ctx, "orders", [][]byte{[]byte("a"), []byte("b"), []byte("c")}))
+	got := f.messages("orders")
+	require.Len(t, got, 3)
+	ids := map[string]bool{}
+	for i, raw := range got {
+		msg := decodeWire(t, raw)
+		assert.Equal(t, string(rune('a'+i)), string(msg.Data))
+		ids[msg.ID] = true
+	}
+	assert.Len(t, ids, 3, "each message gets its own ID")
+
+	assert.Error(t, p.ProduceBatch(ctx, "orders", nil))
+}
+
+func TestProducer_ProduceBatchStopsWhenQueueFull(t *testing.T) {
+	f, client := newFakeHTTPSQS(t)
+	f.maxQueue = 2
+	p := startProducer(t, client)
+
+	err := p.ProduceBatch(context.Background(), "orders", [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "produce message 2")
+	assert.Len(t, f.messages("orders"), 2)
+}
+
+func TestProducer_DelayNotSupported(t *testing.T) {
+	f, client := newFakeHTTPSQS(t)
+	p := startProducer(t, client)
+
+	err := p.Produce(context.Background(), "orders", []byte("x"), types.WithDelay(time.Second))
+	assert.True(t, errors.Is(err, types.ErrDelayNotSupported))
+	err = p.ProduceBatch(context.Background(), "orders", [][]byte{[]byte("x")}, types.WithDeliverAt(time.Now().Add(time.Minute)))
+	assert.True(t, errors.Is(err, types.ErrDelayNotSupported))
+	assert.Empty(t, f.messages("orders"))
+}
+
+func TestProducer_Lifecycle(t *testing.T) {
+	_, client := newFakeHTTPSQS(t)
+	ctx := context.Background()
+
+	assert.Error(t, NewProducer().Start(ctx), "client is required")
+
+	p := NewProducer(WithProducerHTTPSQSClient(client))
+	assert.Error(t, p.Produce(ctx, "q", []byte("x")), "not started")
+	require.NoError(t, p.Start(ctx))
+	require.NoError(t, p.Start(ctx), "double start is a no-op")
+	require.NoError(t, p.Shutdown(ctx))
+	assert.Error(t, p.Produce(ctx, "q", []byte("x")), "shut down")
+	assert.Error(t, p.Start(ctx))
+}
+
+func TestProducer_HealthCheck(t *testing.T) {
+	f, client := newFakeHTTPSQS(t)
+	p := NewProducer(WithProducerHTTPSQSClient(client), WithProducerHealthQueue("probe"))
+	hc, ok := p.(interface{ HealthCheck(context.Context) error })
+	require.True(t, ok, "producer implements app.HealthChecker")
+	ctx := context.Background()
+
+	assert.Error(t, hc.HealthCheck(ctx), "not started")
+	require.NoError(t, p.Start(ctx))
+	assert.NoError(t, hc.HealthCheck(ctx))
+
+	f.setDown(true)
+	assert.Error(t, hc.HealthCheck(ctx))
+	f.setDown(false)
+
+	require.NoError(t, p.Shutdown(ctx))
+	assert.Error(t, hc.HealthCheck(ctx))
+}
+
+func TestProducer_StartFailsWhenServerDown(t *testing.T) {
+	f, client := newFakeHTTPSQS(t)
+	f.setDown(true)
+	p := NewProducer(WithProducerHTTPSQSClient(client))
+
+	assert.Error(t, p.Start(context.Background()))
+	f.setDown(false)
+	assert.NoError(t, p.Start(context.Background()), "start can be retried after failure")
+}
+
+// TestProducer_RoundTrip 验证生产者写入的消息可被消费者解析（信封字段保留）
+func TestProducer_RoundTrip(t *testing.T) {
+	_, client := newFakeHTTPSQS(t)
+	p := startProducer(t, client)
+	ctx := context.Background()
+
+	received := make(chan types.Message, 1)
+	c := NewConsumer(
+		WithHTTPSQSClient(client),
+		WithEmptyQueueSleep(10*time.Millisecond),
+		WithConsumer("orders", types.FuncHandler(func(_ context.Context, msg types.Message) error {
+			received <- msg
+			return nil
+		})),
+	)
+	require.NoError(t, c.Start(ctx))
+	t.Cleanup(func() { _ = c.Shutdown(context.Background()) })
+
+	require.NoError(t, p.Produce(ctx, "orders", []byte("hello"), types.WithMessageID("id-1")))
+	select {
+	case msg := <-received:
+		assert.Equal(t, "hello", string(msg.Data))
+		assert.Equal(t, "id-1", msg.ID)
+		assert.Equal(t, 1, msg.Attempt)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not consumed")
+	}
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/gomooth/httpsqs"
 	"github.com/gomooth/pkg/framework/retry"
-	"github.com/gomooth/pkg/mq/internal/attempt_tracker"
 	mqretry "github.com/gomooth/pkg/mq/internal/retry"
 	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
@@ -25,17 +24,15 @@ func newRequeueRetryStrategy(
 	backoff retry.BackoffStrategy,
 	client httpsqs.IClient,
 	queueName string,
-	_ logutil.Logger,
+	logger logutil.Logger,
 	m *metrics.ConsumerMetrics,
 ) *requeueRetryStrategy {
 	backoffFn := mqretry.BackoffDelayFunc(func(attempt uint) time.Duration {
 		return backoff.Delay(attempt)
 	})
 
-	tracker := attempt_tracker.NewAttemptTracker()
-
 	requeueFn := func(ctx context.Context, msg types.Message) error {
-		_, pushErr := client.Put(ctx, queueName, string(msg.Data))
+		_, pushErr := client.Put(ctx, queueName, string(types.MarshalWire(msg)))
 		return pushErr
 	}
 
@@ -44,8 +41,8 @@ func newRequeueRetryStrategy(
 		inner: mqretry.NewRequeueStrategy(mqretry.RequeueConfig{
 			MaxRetry: maxRetry,
 			Backoff:  backoffFn,
-			Tracker:  tracker,
 			Metrics:  m,
+			Logger:   logger,
 			Requeue:  requeueFn,
 		}),
 	}
@@ -63,8 +60,13 @@ func (s *requeueRetryStrategy) SetTimeout(d time.Duration) {
 	s.inner.SetTimeout(d)
 }
 
+func (s *requeueRetryStrategy) SetRetryIf(fn func(error) bool) {
+	s.inner.SetRetryIf(fn)
+}
+
 func (s *requeueRetryStrategy) OnMessage(ctx context.Context, queue string, data []byte) error {
-	msg := types.NewHttpsqSMessage(queue, data, 0)
+	msg := types.NewHttpsqSMessage(queue, nil, 0)
+	types.UnmarshalWire(&msg, data)
 	err := s.inner.OnMessage(ctx, msg, s.handler.Handle)
 	// 兼容旧行为：上下文取消时返回错误，其他情况返回 nil
 	if err != nil && ctx.Err() != nil {
@@ -72,8 +74,3 @@ func (s *requeueRetryStrategy) OnMessage(ctx context.Context, queue string, data
 	}
 	return nil
 }
-
-// Close 停止 AttemptTracker 的后台清理 goroutine
-func (s *requeueRetryStrategy) Close() {
-	s.inner.Close()
-}
\ No newline at end of file
@@ -20,6 +20,7 @@ type mockHTTPSQSClient struct {
 	getPos    int64
 	getErr    error
 	putCalled atomic.Int32
+	putData   string
 	putErr    error
 }
 
@@ -27,8 +28,9 @@ func (m *mockHTTPSQSClient) Get(_ context.Context, _ string) (string, int64, err
 	return m.getResult, m.getPos, m.getErr
 }
 
-func (m *mockHTTPSQSClient) Put(_ context.Context, _ string, _ string) (int64, error) {
+func (m *mockHTTPSQSClient) Put(_ context.Context, _ string, data string) (int64, error) {
 	m.putCalled.Add(1)
+	m.putData = data
 	return 1, m.putErr
 }
 
@@ -88,6 +90,12 @@ func TestRequeueRetryStrategy_RequeueOnFailure(t *testing.T) {
 	err := strategy.OnMessage(context.Background(), "test", []byte("hello"))
 	assert.NoError(t, err)
 	assert.Equal(t, int32(1), client.putCalled.Load(), "failure should requeue")
+
+	// 旧格式消息重新入队时写入信封，记录处理次数
+	requeued := types.NewHttpsqSMessage("test", nil, 0)
+	types.UnmarshalWire(&requeued, []byte(client.putData))
+	assert.Equal(t, []byte("hello"), requeued.Data)
+	assert.Equal(t, 2, requeued.Attempt)
 }
 
 func TestRequeueRetryStrategy_Exhausted(t *testing.T) {
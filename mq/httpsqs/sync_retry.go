@@ -26,7 +26,7 @@ func newSyncRetryStrategy(
 	handler types.IHandler,
 	maxRetry int,
 	backoff retry.BackoffStrategy,
-	_ logutil.Logger,
+	logger logutil.Logger,
 	m *metrics.ConsumerMetrics,
 ) *syncRetryStrategy {
 	backoffFn := mqretry.BackoffDelayFunc(func(attempt uint) time.Duration {
@@ -39,6 +39,7 @@ func newSyncRetryStrategy(
 			MaxRetry: maxRetry,
 			Backoff:  backoffFn,
 			Metrics:  m,
+			Logger:   logger,
 		}),
 	}
 }
@@ -55,8 +56,13 @@ func (s *syncRetryStrategy) SetTimeout(d time.Duration) {
 	s.inner.SetTimeout(d)
 }
 
+func (s *syncRetryStrategy) SetRetryIf(fn func(error) bool) {
+	s.inner.SetRetryIf(fn)
+}
+
 func (s *syncRetryStrategy) OnMessage(ctx context.Context, queue string, data []byte) error {
-	msg := types.NewHttpsqSMessage(queue, data, 0)
+	msg := types.NewHttpsqSMessage(queue, nil, 0)
+	types.UnmarshalWire(&msg, data)
 	err := s.inner.OnMessage(ctx, msg, s.handler.Handle)
 	// 兼容旧行为：上下文取消时返回错误，耗尽时返回 nil
 	if err != nil && ctx.Err() != nil {
@@ -15,6 +15,12 @@ type DeadLetterHandler = types.DeadLetterHandler
 // FuncHandler 函数适配器，将函数转换为 IHandler
 type FuncHandler = types.FuncHandler
 
+// IBatchHandler 批量消息处理器接口，通过 mq.NewBatchHandler 包装后注册
+type IBatchHandler = types.IBatchHandler
+
+// FuncBatchHandler 函数适配器，将函数转换为 IBatchHandler
+type FuncBatchHandler = types.FuncBatchHandler
+
 // IConsumeServer 消费者服务接口
 type IConsumeServer = types.IConsumeServer
 
@@ -25,6 +31,11 @@ type ConsumerRegistration struct {
 	Opts    []QueueOption
 }
 
+// ==================== Producer ====================
+
+// IProducer 生产者接口
+type IProducer = types.IProducer
+
 // ==================== 重试模式 ====================
 
 // RetryMode 重试模式
@@ -52,5 +63,8 @@ type Message = types.Message
 // RegisterOption 注册消费者时的配置选项
 type RegisterOption = types.RegisterOption
 
+// ProduceOption 生产消息时的配置选项
+type ProduceOption = types.ProduceOption
+
 // QueueOption 单队列级别配置（覆盖全局默认值）
 type QueueOption = types.QueueOption
\ No newline at end of file
@@ -0,0 +1,241 @@
+package consume
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/framework/telemetry"
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BatchFetcher 批量拉取接口，由支持批量消费的 MQ 实现提供。
+// FetchBatch 不阻塞等待新消息，最多返回 max 条；队列为空时返回空切片。
+type BatchFetcher interface {
+	FetchBatch(ctx context.Context, max int) ([]FetchResult, error)
+}
+
+// BatchStrategy 批量处理策略接口（消费循环层面）
+type BatchStrategy interface {
+	OnBatch(ctx context.Context, queue string, data [][]byte) error
+}
+
+// batchStrategy 调用 IBatchHandler 后，将每条消息连同其批量处理结果交给单条重试策略，
+// 成功的消息由单条策略记为消费成功，失败的消息进入既有的重试/死信流程
+type batchStrategy struct {
+	handler types.IBatchHandler
+	decode  func(queue string, data []byte) types.Message
+	single  RetryStrategy
+	timeout time.Duration
+}
+
+// NewBatchStrategy 创建批量处理策略。
+// decode 将线上数据解析为各 MQ 的消息类型；single 为该队列的单条重试策略，
+// 其 handler 必须是 types.NewBatchHandler 创建的适配器。
+func NewBatchStrategy(
+	handler types.IBatchHandler,
+	decode func(queue string, data []byte) types.Message,
+	single RetryStrategy,
+	timeout time.Duration,
+) BatchStrategy {
+	return &batchStrategy{handler: handler, decode: decode, single: single, timeout: timeout}
+}
+
+func (s *batchStrategy) OnBatch(ctx context.Context, queue string, data [][]byte) error {
+	msgs := make([]types.Message, len(data))
+	for i, d := range data {
+		msgs[i] = s.decode(queue, d)
+	}
+
+	err := mqretry.ApplyTimeout(ctx, s.timeout, func(ctx context.Context) error {
+		return s.handler.HandleBatch(ctx, msgs)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	for i := range data {
+		itemCtx := types.WithBatchResult(ctx, types.BatchItemError(err, i))
+		if itemErr := s.single.OnMessage(itemCtx, queue, data[i]); itemErr != nil && ctx.Err() != nil {
+			return itemErr
+		}
+	}
+	return err
+}
+
+// ConsumeBatchLoop 批量消费循环，适用于 redis/httpsqs 的主动拉取模式。
+// 收到批内第一条消息后最多等待 batch.MaxWait 凑满 batch.Size 条，随后整批交给 strategy 处理，
+// 处理结束后逐条调用 Ack；处理因 ctx 取消而中断时不确认。
+func ConsumeBatchLoop(
+	ctx context.Context,
+	cfg LoopConfig,
+	batch types.BatchConfig,
+	fetcher BatchFetcher,
+	strategy BatchStrategy,
+) {
+	backoff := cfg.Backoff
+	if backoff == nil {
+		backoff = &retry.ExponentialDelay{Base: time.Second, Max: 30 * time.Second, Jitter: true}
+	}
+
+	pauseDuration := cfg.PauseDuration
+	if pauseDuration == 0 {
+		pauseDuration = 5 * time.Minute
+	}
+	maxErrors := cfg.MaxErrors
+	if maxErrors == 0 {
+		maxErrors = 50
+	}
+	emptySleep := cfg.EmptySleep
+	if emptySleep == 0 {
+		emptySleep = time.Second
+	}
+	size := batch.Size
+	if size <= 0 {
+		size = types.DefaultBatchSize
+	}
+
+	tracer := cfg.Tracer
+	if tracer == nil {
+		tracer = telemetry.Tracer(fmt.Sprintf("mq.%s.consumer", cfg.MQSystem))
+	}
+
+	// fetchCtx 控制拉取与等待，排空时取消；已拉取的消息仍使用 ctx 处理完成
+	fetchCtx, cancelFetch := context.WithCancel(ctx)
+	defer cancelFetch()
+	if cfg.Drain != nil {
+		go func() {
+			select {
+			case <-cfg.Drain:
+				cancelFetch()
+			case <-fetchCtx.Done():
+			}
+		}()
+	}
+
+	sleep := func(d time.Duration) bool {
+		select {
+		case <-fetchCtx.Done():
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+
+	attempt := uint(0)
+	items := make([]FetchResult, 0, size)
+	var deadline time.Time
+
+	for {
+		// 凑批：满批、等待超时或停止拉取时提交
+		full := len(items) >= size
+		expired := len(items) > 0 && !time.Now().Before(deadline)
+		stopping := fetchCtx.Err() != nil
+		if len(items) > 0 && (full || expired || stopping) {
+			if !processBatch(ctx, cfg, tracer, strategy, items) {
+				return
+			}
+			items = items[:0]
+			continue
+		}
+		if stopping {
+			return
+		}
+
+		results, err := fetcher.FetchBatch(fetchCtx, size-len(items))
+		if err != nil {
+			if fetchCtx.Err() != nil {
+				continue
+			}
+			if len(items) > 0 {
+				// 先提交已拉取的消息，下一轮再处理拉取错误
+				deadline = time.Now()
+				continue
+			}
+
+			delay := backoff.Delay(attempt)
+			attempt++
+
+			if attempt >= maxErrors {
+				if !sleep(pauseDuration) {
+					return
+				}
+				attempt = 0
+				continue
+			}
+			if !sleep(delay) {
+				return
+			}
+			continue
+		}
+		attempt = 0
+
+		if len(results) == 0 {
+			wait := emptySleep
+			if len(items) > 0 {
+				wait = min(wait, time.Until(deadline))
+			}
+			if wait > 0 {
+				sleep(wait)
+			}
+			continue
+		}
+
+		if len(items) == 0 {
+			deadline = time.Now().Add(batch.MaxWait)
+		}
+		items = append(items, results...)
+	}
+}
+
+// processBatch 处理一批消息并确认，处理因 ctx 取消而中断时返回 false
+func processBatch(
+	ctx context.Context,
+	cfg LoopConfig,
+	tracer trace.Tracer,
+	strategy BatchStrategy,
+	items []FetchResult,
+) bool {
+	data := make([][]byte, len(items))
+	for i, item := range items {
+		data[i] = []byte(item.Data)
+	}
+
+	batchCtx, span := tracer.Start(ctx, fmt.Sprintf("%s consume batch", cfg.QueueName),
+		trace.WithAttributes(
+			attribute.String("messaging.system", cfg.MQSystem),
+			attribute.String("messaging.destination", cfg.QueueName),
+			attribute.Int("messaging.batch.size", len(items)),
+		),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+	)
+	defer span.End()
+
+	cfg.Metrics.AddInFlight(cfg.QueueName, int64(len(items)))
+	err := strategy.OnBatch(batchCtx, cfg.QueueName, data)
+	cfg.Metrics.AddInFlight(cfg.QueueName, -int64(len(items)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if ctx.Err() != nil {
+			return false
+		}
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+
+	for _, item := range items {
+		if item.Ack == nil {
+			continue
+		}
+		if err := item.Ack(ctx); err != nil {
+			span.RecordError(err)
+		}
+	}
+	return true
+}
@@ -0,0 +1,243 @@
+package consume
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBatchFetcher implements BatchFetcher for testing
+type testBatchFetcher struct {
+	mu      sync.Mutex
+	pending []string
+	acked   []string
+	maxSeen []int
+}
+
+func (f *testBatchFetcher) push(data ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending = append(f.pending, data...)
+}
+
+func (f *testBatchFetcher) FetchBatch(_ context.Context, max int) ([]FetchResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maxSeen = append(f.maxSeen, max)
+	n := min(max, len(f.pending))
+	results := make([]FetchResult, n)
+	for i := range n {
+		data := f.pending[i]
+		results[i] = FetchResult{Data: data, Ack: func(context.Context) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.acked = append(f.acked, data)
+			return nil
+		}}
+	}
+	f.pending = f.pending[n:]
+	return results, nil
+}
+
+func (f *testBatchFetcher) getAcked() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acked...)
+}
+
+// testBatchStrategy implements BatchStrategy for testing
+type testBatchStrategy struct {
+	mu      sync.Mutex
+	batches [][]string
+	block   chan struct{}
+}
+
+func (s *testBatchStrategy) OnBatch(ctx context.Context, _ string, data [][]byte) error {
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	batch := make([]string, len(data))
+	for i, d := range data {
+		batch[i] = string(d)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *testBatchStrategy) getBatches() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.batches...)
+}
+
+func runBatchLoop(ctx context.Context, batch types.BatchConfig, fetcher BatchFetcher, strategy BatchStrategy) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ConsumeBatchLoop(ctx, LoopConfig{
+			MQSystem:   "redis",
+			QueueName:  "q",
+			EmptySleep: 5 * time.Millisecond,
+		}, batch, fetcher, strategy)
+	}()
+	return done
+}
+
+func TestConsumeBatchLoop_FullBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetcher := &testBatchFetcher{}
+	fetcher.push("a", "b", "c", "d", "e")
+	strategy := &testBatchStrategy{}
+	done := runBatchLoop(ctx, types.BatchConfig{Size: 2, MaxWait: time.Hour}, fetcher, strategy)
+
+	require.Eventually(t, func() bool { return len(strategy.getBatches()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}}, strategy.getBatches())
+	assert.Equal(t, []string{"a", "b", "c", "d"}, fetcher.getAcked())
+
+	// 不足一批的消息在 MaxWait 内不提交，停止时提交
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(t, strategy.getBatches(), 2)
+	cancel()
+	<-done
+}
+
+func TestConsumeBatchLoop_MaxWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetcher := &testBatchFetcher{}
+	strategy := &testBatchStrategy{}
+	done := runBatchLoop(ctx, types.BatchConfig{Size: 10, MaxWait: 50 * time.Millisecond}, fetcher, strategy)
+
+	fetcher.push("a")
+	time.Sleep(10 * time.Millisecond)
+	fetcher.push("b")
+
+	require.Eventually(t, func() bool { return len(strategy.getBatches()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, strategy.getBatches()[0])
+	assert.Equal(t, []string{"a", "b"}, fetcher.getAcked())
+
+	fetcher.mu.Lock()
+	for _, m := range fetcher.maxSeen {
+		assert.LessOrEqual(t, m, 10)
+	}
+	fetcher.mu.Unlock()
+
+	cancel()
+	<-done
+}
+
+func TestConsumeBatchLoop_DrainFlushesPartialBatch(t *testing.T) {
+	fetcher := &testBatchFetcher{}
+	fetcher.push("a")
+	strategy := &testBatchStrategy{}
+	drain := make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ConsumeBatchLoop(context.Background(), LoopConfig{
+			MQSystem:   "redis",
+			QueueName:  "q",
+			EmptySleep: 5 * time.Millisecond,
+			Drain:      drain,
+		}, types.BatchConfig{Size: 10, MaxWait: time.Hour}, fetcher, strategy)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(drain)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("loop did not stop after drain")
+	}
+	assert.Equal(t, [][]string{{"a"}}, strategy.getBatches())
+	assert.Equal(t, []string{"a"}, fetcher.getAcked())
+}
+
+func TestConsumeBatchLoop_NoAckWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fetcher := &testBatchFetcher{}
+	fetcher.push("a")
+	strategy := &testBatchStrategy{block: make(chan struct{})}
+	done := runBatchLoop(ctx, types.BatchConfig{Size: 1, MaxWait: time.Millisecond}, fetcher, strategy)
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+	assert.Empty(t, fetcher.getAcked())
+}
+
+// ---------------------------------------------------------------------------
+// batchStrategy
+// ---------------------------------------------------------------------------
+
+// resultStrategy 记录单条策略收到的批量处理结果
+type resultStrategy struct {
+	handler types.IHandler
+	results map[string]error
+}
+
+func (s *resultStrategy) OnMessage(ctx context.Context, queue string, data []byte) error {
+	s.results[string(data)] = s.handler.Handle(ctx, types.NewRedisMessage(queue, data))
+	return nil
+}
+
+func TestBatchStrategy_PartialFailure(t *testing.T) {
+	boom := errors.New("boom")
+	var batchCalls atomic.Int32
+	bh := types.FuncBatchHandler(func(_ context.Context, msgs []types.Message) error {
+		batchCalls.Add(1)
+		be := types.NewBatchError()
+		for i, m := range msgs {
+			if string(m.Data) == "bad" {
+				be.Fail(i, boom)
+			}
+		}
+		return be.Err()
+	})
+	single := &resultStrategy{handler: types.NewBatchHandler(bh), results: map[string]error{}}
+	decode := func(queue string, data []byte) types.Message {
+		msg := types.NewRedisMessage(queue, nil)
+		types.UnmarshalWire(&msg, data)
+		return msg
+	}
+
+	s := NewBatchStrategy(bh, decode, single, 0)
+	err := s.OnBatch(context.Background(), "q", [][]byte{[]byte("ok"), []byte("bad")})
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), batchCalls.Load(), "single strategy should reuse batch results")
+	assert.NoError(t, single.results["ok"])
+	assert.ErrorIs(t, single.results["bad"], boom)
+}
+
+func TestBatchStrategy_CanceledSkipsDispatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bh := types.FuncBatchHandler(func(ctx context.Context, _ []types.Message) error {
+		cancel()
+		return ctx.Err()
+	})
+	single := &resultStrategy{handler: types.NewBatchHandler(bh), results: map[string]error{}}
+	s := NewBatchStrategy(bh, func(q string, d []byte) types.Message { return types.NewRedisMessage(q, d) }, single, 0)
+
+	assert.ErrorIs(t, s.OnBatch(ctx, "q", [][]byte{[]byte("a")}), context.Canceled)
+	assert.Empty(t, single.results)
+}
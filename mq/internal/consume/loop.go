@@ -4,14 +4,11 @@ package consume
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/gomooth/pkg/framework/retry"
-	"github.com/gomooth/pkg/framework/telemetry"
-	mqtraceutil "github.com/gomooth/pkg/mq/internal/traceutil"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/codes"
+	"github.com/gomooth/pkg/mq/internal/metrics"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -20,6 +17,10 @@ type FetchResult struct {
 	Data  string // 消息内容
 	Empty bool   // 队列为空
 	Err   error  // 拉取错误
+
+	// Ack 可选：消息处理完成（成功、再入队或进入死信）后调用，用于确认消息（如 stream XACK）。
+	// 处理因 ctx 取消而中断时不调用，消息保留在待确认状态，由 MQ 重新投递。
+	Ack func(ctx context.Context) error
 }
 
 // Fetcher 消息拉取接口，由各 MQ 实现提供
@@ -35,10 +36,14 @@ type LoopConfig struct {
 	MaxErrors     uint
 	PauseDuration time.Duration
 	Backoff       retry.BackoffStrategy
-	Tracer        trace.Tracer
+	Tracer        trace.Tracer             // 批量消费 span 使用；单条消息的 span 由 Trace 中间件创建
+	Logger        *slog.Logger             // 可选：记录确认失败
+	Drain         <-chan struct{}          // 关闭后停止拉取新消息，处理中的消息继续完成
+	Metrics       *metrics.ConsumerMetrics // 可选：上报处理中的消息数
 }
 
-// RetryStrategy 重试策略接口（消费循环层面）
+// RetryStrategy 重试策略接口（消费循环层面）。
+// data 为拉取到的原始线上数据，由实现通过 types.UnmarshalWire 解析消息信封。
 type RetryStrategy interface {
 	OnMessage(ctx context.Context, queue string, data []byte) error
 }
@@ -65,23 +70,33 @@ func ConsumeLoop(
 		maxErrors = 50
 	}
 
-	tracer := cfg.Tracer
-	if tracer == nil {
-		tracer = telemetry.Tracer(fmt.Sprintf("mq.%s.consumer", cfg.MQSystem))
+	// fetchCtx 控制拉取与等待，排空时取消；消息处理仍使用 ctx，保证处理中的消息完成
+	fetchCtx, cancelFetch := context.WithCancel(ctx)
+	defer cancelFetch()
+	if cfg.Drain != nil {
+		go func() {
+			select {
+			case <-cfg.Drain:
+				cancelFetch()
+			case <-fetchCtx.Done():
+			}
+		}()
 	}
 
 	attempt := uint(0)
 
 	for {
 		select {
-		case <-ctx.Done():
+		case <-fetchCtx.Done():
+			return
+		case <-cfg.Drain:
 			return
 		default:
 		}
 
-		result := fetcher.Fetch(ctx)
+		result := fetcher.Fetch(fetchCtx)
 		if result.Err != nil {
-			if ctx.Err() != nil {
+			if fetchCtx.Err() != nil {
 				return
 			}
 
@@ -90,7 +105,7 @@ func ConsumeLoop(
 
 			if attempt >= maxErrors {
 				select {
-				case <-ctx.Done():
+				case <-fetchCtx.Done():
 					return
 				case <-time.After(pauseDuration):
 					attempt = 0
@@ -99,7 +114,7 @@ func ConsumeLoop(
 			}
 
 			select {
-			case <-ctx.Done():
+			case <-fetchCtx.Done():
 				return
 			case <-time.After(delay):
 			}
@@ -113,7 +128,7 @@ func ConsumeLoop(
 				sleepDur = time.Second
 			}
 			select {
-			case <-ctx.Done():
+			case <-fetchCtx.Done():
 				return
 			case <-time.After(sleepDur):
 			}
@@ -123,28 +138,17 @@ func ConsumeLoop(
 		// 成功获取消息，重置退避计数
 		attempt = 0
 
-		// Extract trace context
-		msgCtx := mqtraceutil.ExtractTraceContext(ctx, result.Data)
-
-		// Create consumer Span
-		msgCtx, span := tracer.Start(msgCtx, fmt.Sprintf("%s consume", cfg.QueueName),
-			trace.WithAttributes(
-				attribute.String("messaging.system", cfg.MQSystem),
-				attribute.String("messaging.destination", cfg.QueueName),
-			),
-			trace.WithSpanKind(trace.SpanKindConsumer),
-		)
-
-		if err := strategy.OnMessage(msgCtx, cfg.QueueName, []byte(result.Data)); err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, err.Error())
-			if ctx.Err() != nil {
-				span.End()
-				return
+		// 单条消息的 trace span 由 Trace 中间件在 handler 层创建
+		cfg.Metrics.AddInFlight(cfg.QueueName, 1)
+		err := strategy.OnMessage(ctx, cfg.QueueName, []byte(result.Data))
+		cfg.Metrics.AddInFlight(cfg.QueueName, -1)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		if result.Ack != nil {
+			if err := result.Ack(ctx); err != nil && cfg.Logger != nil {
+				cfg.Logger.Warn("mq ack failed", "system", cfg.MQSystem, "queue", cfg.QueueName, "error", err)
 			}
-		} else {
-			span.SetStatus(codes.Ok, "")
 		}
-		span.End()
 	}
 }
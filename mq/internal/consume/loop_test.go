@@ -416,3 +416,123 @@ func TestConsumeLoop_DefaultEmptySleep(t *testing.T) {
 	msgs := strategy.getMessages()
 	assert.Empty(t, msgs, "no messages should be processed on empty queue")
 }
+
+// blockingStrategy 处理消息时阻塞直到 release 关闭，记录处理时 ctx 是否已取消
+type blockingStrategy struct {
+	started  chan struct{}
+	release  chan struct{}
+	ctxErr   atomic.Value
+	finished atomic.Int32
+}
+
+func (s *blockingStrategy) OnMessage(ctx context.Context, _ string, _ []byte) error {
+	close(s.started)
+	<-s.release
+	if err := ctx.Err(); err != nil {
+		s.ctxErr.Store(err)
+	}
+	s.finished.Add(1)
+	return nil
+}
+
+func TestConsumeLoop_DrainFinishesInFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan FetchResult, 10)
+	fetcher := &testFetcher{dynamic: ch}
+	strategy := &blockingStrategy{started: make(chan struct{}), release: make(chan struct{})}
+	drain := make(chan struct{})
+
+	cfg := LoopConfig{
+		MQSystem:   "redis",
+		QueueName:  "test-queue",
+		EmptySleep: 10 * time.Millisecond,
+		Tracer:     noop.NewTracerProvider().Tracer("test"),
+		Drain:      drain,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ConsumeLoop(ctx, cfg, fetcher, strategy)
+		close(done)
+	}()
+
+	ch <- FetchResult{Data: "first"}
+	<-strategy.started
+
+	// 排空：已拉取的消息继续处理，之后不再拉取
+	close(drain)
+	ch <- FetchResult{Data: "second"}
+	close(strategy.release)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("loop did not exit after drain")
+	}
+	assert.Equal(t, int32(1), strategy.finished.Load())
+	assert.Nil(t, strategy.ctxErr.Load(), "in-flight message context must not be canceled by drain")
+	assert.Len(t, ch, 1, "no new message should be fetched after drain")
+}
+
+func TestConsumeLoop_AckAfterProcessing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan FetchResult, 10)
+	fetcher := &testFetcher{dynamic: ch}
+	strategy := &testStrategy{}
+
+	var acked atomic.Int32
+	ack := func(context.Context) error {
+		// 确认时消息必须已经交给策略处理
+		assert.Len(t, strategy.getMessages(), int(acked.Load())+1)
+		acked.Add(1)
+		return nil
+	}
+	ch <- FetchResult{Data: "a", Ack: ack}
+	ch <- FetchResult{Data: "b", Ack: ack}
+
+	cfg := LoopConfig{
+		MQSystem:   "redis",
+		QueueName:  "test-queue",
+		EmptySleep: 10 * time.Millisecond,
+		Tracer:     noop.NewTracerProvider().Tracer("test"),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ConsumeLoop(ctx, cfg, fetcher, strategy)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return acked.Load() == 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestConsumeLoop_NoAckWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan FetchResult, 1)
+	fetcher := &testFetcher{dynamic: ch}
+	strategy := &cancelingStrategy{cancel: cancel, err: errors.New("processing failed")}
+
+	var acked atomic.Bool
+	ch <- FetchResult{Data: "a", Ack: func(context.Context) error {
+		acked.Store(true)
+		return nil
+	}}
+
+	cfg := LoopConfig{
+		MQSystem:   "redis",
+		QueueName:  "test-queue",
+		EmptySleep: 10 * time.Millisecond,
+		Tracer:     noop.NewTracerProvider().Tracer("test"),
+	}
+	ConsumeLoop(ctx, cfg, fetcher, strategy)
+
+	assert.False(t, acked.Load(), "interrupted message must stay unacknowledged")
+}
@@ -28,6 +28,10 @@ type Base struct {
 	Logger      *slog.Logger
 	Metrics     interface{} // 使用 interface{} 避免循环导入 mq/internal/metrics
 	PanicHandler func(any)
+
+	drainInit sync.Once
+	drainOnce sync.Once
+	drainCh   chan struct{}
 }
 
 // HealthCheck 检查引擎是否处于运行状态
@@ -71,3 +75,30 @@ func (b *Base) TryStart() bool {
 func (b *Base) RequestShutdown() bool {
 	return b.State.CompareAndSwap(Running, ShuttingDown)
 }
+
+// Draining 返回排空信号通道，PreStop 调用后关闭。
+// 消费循环收到信号后停止拉取新消息，处理中的消息继续完成。
+func (b *Base) Draining() <-chan struct{} {
+	b.drainInit.Do(func() { b.drainCh = make(chan struct{}) })
+	return b.drainCh
+}
+
+// PreStop 停止拉取新消息，并等待处理中的消息完成或 ctx 结束。
+// 实现 app.PreStopper 接口，适用于基于 WG 跟踪消费循环的引擎。
+func (b *Base) PreStop(ctx context.Context) error {
+	b.Draining()
+	b.drainOnce.Do(func() { close(b.drainCh) })
+
+	done := make(chan struct{})
+	go func() {
+		b.WG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
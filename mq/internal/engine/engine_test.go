@@ -86,3 +86,24 @@ func TestBase_SafeGo_PanicWithLogger(t *testing.T) {
 	assert.Eventually(t, func() bool { return panicked.Load() }, time.Second, 10*time.Millisecond)
 	assert.Contains(t, buf.String(), "goroutine panic recovered")
 }
+
+func TestBase_PreStop(t *testing.T) {
+	b := &Base{}
+	b.State.Store(Running)
+
+	release := make(chan struct{})
+	b.WG.Add(1)
+	go func() {
+		defer b.WG.Done()
+		<-b.Draining()
+		<-release
+	}()
+
+	// 消费循环未退出时，PreStop 在 ctx 到期后返回错误
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.PreStop(ctx), context.DeadlineExceeded)
+
+	close(release)
+	assert.NoError(t, b.PreStop(context.Background()), "repeated PreStop must not panic")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/gomooth/pkg/framework/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
@@ -12,6 +13,8 @@ type ConsumerMetrics struct {
 	consumeCounter    metric.Int64Counter
 	retryCounter      metric.Int64Counter
 	deadLetterCounter metric.Int64Counter
+	inFlight          metric.Int64UpDownCounter
+	lag               metric.Int64Gauge
 }
 
 // NewConsumerMetrics 创建消费者指标收集器。
@@ -22,10 +25,14 @@ func NewConsumerMetrics(prefix string) *ConsumerMetrics {
 	consumeCounter, _ := m.Int64Counter(prefix+".consumer.messages", metric.WithDescription("Messages consumed successfully"))
 	retryCounter, _ := m.Int64Counter(prefix+".consumer.retries", metric.WithDescription("Message retry attempts"))
 	deadLetterCounter, _ := m.Int64Counter(prefix+".consumer.dead_letters", metric.WithDescription("Messages sent to dead letter"))
+	inFlight, _ := m.Int64UpDownCounter(prefix+".consumer.in_flight", metric.WithDescription("Messages currently being processed"))
+	lag, _ := m.Int64Gauge(prefix+".consumer.lag", metric.WithDescription("Messages behind the partition high watermark"))
 	return &ConsumerMetrics{
 		consumeCounter:    consumeCounter,
 		retryCounter:      retryCounter,
 		deadLetterCounter: deadLetterCounter,
+		inFlight:          inFlight,
+		lag:               lag,
 	}
 }
 
@@ -41,8 +48,44 @@ func (m *ConsumerMetrics) OnRetry() {
 	}
 }
 
+// 死信原因，作为 dead_letters 指标的 reason 标签
+const (
+	DeadLetterReasonExhausted = "exhausted" // 重试耗尽
+	DeadLetterReasonPermanent = "permanent" // 不可重试的错误，未经重试
+)
+
+// OnDeadLetter 记录重试耗尽进入死信的消息
 func (m *ConsumerMetrics) OnDeadLetter() {
+	m.onDeadLetter(DeadLetterReasonExhausted)
+}
+
+// OnPermanentFailure 记录因不可重试的错误直接进入死信的消息
+func (m *ConsumerMetrics) OnPermanentFailure() {
+	m.onDeadLetter(DeadLetterReasonPermanent)
+}
+
+func (m *ConsumerMetrics) onDeadLetter(reason string) {
 	if m != nil && m.deadLetterCounter != nil {
-		m.deadLetterCounter.Add(context.Background(), 1)
+		m.deadLetterCounter.Add(context.Background(), 1,
+			metric.WithAttributes(attribute.String("reason", reason)))
+	}
+}
+
+// AddInFlight 调整指定队列处理中的消息数，开始处理时 delta=1，处理结束时 delta=-1
+func (m *ConsumerMetrics) AddInFlight(queue string, delta int64) {
+	if m != nil && m.inFlight != nil {
+		m.inFlight.Add(context.Background(), delta,
+			metric.WithAttributes(attribute.String("messaging.destination", queue)))
+	}
+}
+
+// RecordLag 记录指定分区的消费延迟（高水位与已消费 offset 之间的消息数）
+func (m *ConsumerMetrics) RecordLag(queue string, partition int32, lag int64) {
+	if m != nil && m.lag != nil {
+		m.lag.Record(context.Background(), lag,
+			metric.WithAttributes(
+				attribute.String("messaging.destination", queue),
+				attribute.Int("messaging.partition", int(partition)),
+			))
 	}
 }
@@ -32,11 +32,28 @@ func TestConsumerMetrics_OnDeadLetter(t *testing.T) {
 	})
 }
 
+func TestConsumerMetrics_AddInFlight(t *testing.T) {
+	m := NewConsumerMetrics("test")
+	assert.NotPanics(t, func() {
+		m.AddInFlight("q", 1)
+		m.AddInFlight("q", -1)
+	})
+}
+
+func TestConsumerMetrics_RecordLag(t *testing.T) {
+	m := NewConsumerMetrics("test")
+	assert.NotPanics(t, func() {
+		m.RecordLag("q", 0, 42)
+	})
+}
+
 func TestConsumerMetrics_NilReceiver(t *testing.T) {
 	var m *ConsumerMetrics
 	assert.NotPanics(t, func() {
 		m.OnConsume()
 		m.OnRetry()
 		m.OnDeadLetter()
+		m.AddInFlight("q", 1)
+		m.RecordLag("q", 0, 1)
 	})
 }
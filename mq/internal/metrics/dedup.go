@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/gomooth/pkg/framework/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DedupMetrics 消费去重指标收集器
+type DedupMetrics struct {
+	duplicateCounter  metric.Int64Counter
+	inProgressCounter metric.Int64Counter
+}
+
+// NewDedupMetrics 创建消费去重指标收集器
+func NewDedupMetrics() *DedupMetrics {
+	m := telemetry.Meter("github.com/gomooth/pkg/mq/dedup")
+	duplicateCounter, _ := m.Int64Counter("mq.dedup.duplicates", metric.WithDescription("Duplicate messages suppressed"))
+	inProgressCounter, _ := m.Int64Counter("mq.dedup.in_progress", metric.WithDescription("Duplicate messages deferred while in progress"))
+	return &DedupMetrics{
+		duplicateCounter:  duplicateCounter,
+		inProgressCounter: inProgressCounter,
+	}
+}
+
+// OnDuplicate 记录一条已处理过而被丢弃的重复消息
+func (m *DedupMetrics) OnDuplicate(queue string) {
+	if m != nil && m.duplicateCounter != nil {
+		m.duplicateCounter.Add(context.Background(), 1,
+			metric.WithAttributes(attribute.String("messaging.destination", queue)))
+	}
+}
+
+// OnInProgress 记录一条因正在处理而推迟的重复消息
+func (m *DedupMetrics) OnInProgress(queue string) {
+	if m != nil && m.inProgressCounter != nil {
+		m.inProgressCounter.Add(context.Background(), 1,
+			metric.WithAttributes(attribute.String("messaging.destination", queue)))
+	}
+}
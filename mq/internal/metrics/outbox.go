@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomooth/pkg/framework/telemetry"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OutboxMetrics 发件箱中继指标收集器
+type OutboxMetrics struct {
+	relayCounter metric.Int64Counter
+	errorCounter metric.Int64Counter
+	pending      metric.Int64Gauge
+	lag          metric.Float64Gauge
+}
+
+// NewOutboxMetrics 创建发件箱中继指标收集器
+func NewOutboxMetrics() *OutboxMetrics {
+	m := telemetry.Meter("github.com/gomooth/pkg/mq/outbox")
+	relayCounter, _ := m.Int64Counter("outbox.relay.messages", metric.WithDescription("Outbox messages published successfully"))
+	errorCounter, _ := m.Int64Counter("outbox.relay.errors", metric.WithDescription("Outbox publish errors"))
+	pending, _ := m.Int64Gauge("outbox.pending", metric.WithDescription("Undelivered outbox messages"))
+	lag, _ := m.Float64Gauge("outbox.lag", metric.WithUnit("s"),
+		metric.WithDescription("Age of the oldest undelivered outbox message"))
+	return &OutboxMetrics{
+		relayCounter: relayCounter,
+		errorCounter: errorCounter,
+		pending:      pending,
+		lag:          lag,
+	}
+}
+
+func (m *OutboxMetrics) OnRelay(count int) {
+	if m != nil && m.relayCounter != nil {
+		m.relayCounter.Add(context.Background(), int64(count))
+	}
+}
+
+func (m *OutboxMetrics) OnError() {
+	if m != nil && m.errorCounter != nil {
+		m.errorCounter.Add(context.Background(), 1)
+	}
+}
+
+// RecordLag 记录未投递消息数与最早未投递消息的滞留时长
+func (m *OutboxMetrics) RecordLag(pending int64, lag time.Duration) {
+	if m == nil {
+		return
+	}
+	if m.pending != nil {
+		m.pending.Record(context.Background(), pending)
+	}
+	if m.lag != nil {
+		m.lag.Record(context.Background(), lag.Seconds())
+	}
+}
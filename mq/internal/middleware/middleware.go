@@ -0,0 +1,183 @@
+// Package middleware 提供 mq handler 的内置中间件，以及各 MQ 消费者组装中间件链的工具函数。
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/gomooth/pkg/framework/telemetry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/traceutil"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace 链路追踪中间件：从消息头提取 trace context（旧格式消息回退到 JSON 消息体），
+// 并为每次处理创建 SpanKindConsumer span，处理失败时记录错误。
+// 重试（包括 kafka 异步重试）同样从消息头恢复上游链路。
+func Trace() types.Middleware {
+	return func(next types.IHandler) types.IHandler {
+		return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			ctx = traceutil.ExtractMessage(ctx, msg.Headers, msg.Data)
+
+			attrs := []attribute.KeyValue{
+				attribute.String("messaging.system", msg.System()),
+				attribute.String("messaging.destination", msg.Queue),
+				attribute.String("messaging.message.id", msg.ID),
+				attribute.Int("messaging.attempt", msg.Attempt),
+			}
+			if msg.Group != "" {
+				attrs = append(attrs, attribute.String("messaging.consumer.group", msg.Group))
+			}
+			if msg.IsKafka() {
+				attrs = append(attrs,
+					attribute.Int("messaging.partition", int(msg.Partition)),
+					attribute.Int64("messaging.offset", msg.Offset),
+				)
+			}
+
+			tracer := telemetry.Tracer(fmt.Sprintf("mq.%s.consumer", msg.System()))
+			ctx, span := tracer.Start(ctx, fmt.Sprintf("%s consume", msg.Queue),
+				trace.WithAttributes(attrs...),
+				trace.WithSpanKind(trace.SpanKindConsumer),
+			)
+			defer span.End()
+
+			err := next.Handle(ctx, msg)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			} else {
+				span.SetStatus(codes.Ok, "")
+			}
+			return err
+		})
+	}
+}
+
+// Recover panic 恢复中间件：handler panic 时记录日志与堆栈，调用 onPanic（可为 nil），
+// 并将 panic 转换为 ErrMQConsume 错误，消息按处理失败进入重试 / 死信流程。
+func Recover(logger *slog.Logger, onPanic func(any)) types.Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next types.IHandler) types.IHandler {
+		return types.FuncHandler(func(ctx context.Context, msg types.Message) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				logger.Error("handler panic recovered",
+					"queue", msg.Queue,
+					"message_id", msg.ID,
+					"panic", r,
+					"stack", string(debug.Stack()),
+				)
+				if onPanic != nil {
+					onPanic(r)
+				}
+				err = xerror.NewXCode(xcode.ErrMQConsume, fmt.Sprintf("handler panic: %v", r))
+			}()
+			return next.Handle(ctx, msg)
+		})
+	}
+}
+
+// Server 返回消费者的服务级中间件：未禁用时内置中间件（Trace → Recover）在前，用户中间件在后
+func Server(disableDefaults bool, logger *slog.Logger, onPanic func(any), mws []types.Middleware) []types.Middleware {
+	out := make([]types.Middleware, 0, len(mws)+2)
+	if !disableDefaults {
+		out = append(out, Trace(), Recover(logger, onPanic))
+	}
+	return append(out, mws...)
+}
+
+// Wrap 按 服务级 → 注册级 的顺序为 handler 组装中间件链
+func Wrap(h types.IHandler, server []types.Middleware, registration []types.Middleware) types.IHandler {
+	mws := make([]types.Middleware, 0, len(server)+len(registration))
+	mws = append(mws, server...)
+	mws = append(mws, registration...)
+	return types.Chain(h, mws...)
+}
+
+// errBatchItemSkipped 中间件未调用 next 而返回时记录的结果：消息未交给 HandleBatch，
+// 批量处理不将其视为成功，由单条处理流程按中间件的决定处理
+var errBatchItemSkipped = xerror.NewXCode(xcode.ErrMQConsume, "message skipped by middleware before batch handling")
+
+// WrapBatch 为整批 HandleBatch 调用组装中间件链：批内每条消息各自独立经过 mws，
+// 链的末端仅记录中间件处理后的消息与 ctx；随后以到达末端的消息调用一次 HandleBatch，
+// 各消息的 ctx 可通过 types.BatchItemContext 取得。
+//
+//   - 任一消息的链返回错误时整批失败，不调用 HandleBatch，消息随后逐条进入重试流程；
+//   - 中间件未调用 next 而返回 nil 的消息不交给 HandleBatch，也不视为处理成功；
+//   - HandleBatch 的 panic 被恢复并转换为 ErrMQConsume 错误（批量调用不在任何中间件链内，始终恢复）。
+func WrapBatch(bh types.IBatchHandler, mws []types.Middleware, logger *slog.Logger, onPanic func(any)) types.IBatchHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return types.FuncBatchHandler(func(ctx context.Context, msgs []types.Message) error {
+		reached := make([]int, 0, len(msgs))
+		handled := make([]types.Message, 0, len(msgs))
+		ctxs := make([]context.Context, 0, len(msgs))
+		for i, msg := range msgs {
+			h := types.Chain(types.FuncHandler(func(itemCtx context.Context, m types.Message) error {
+				reached = append(reached, i)
+				handled = append(handled, m)
+				ctxs = append(ctxs, itemCtx)
+				return nil
+			}), mws...)
+			if err := h.Handle(ctx, msg); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if len(handled) > 0 {
+			err = handleBatch(types.WithBatchItemContexts(ctx, ctxs), bh, handled, logger, onPanic)
+		}
+		if len(reached) == len(msgs) {
+			return err
+		}
+
+		// 部分消息被中间件跳过：按原始下标重建结果，跳过的消息记为未处理
+		be := types.NewBatchError()
+		for i := range msgs {
+			be.Fail(i, errBatchItemSkipped)
+		}
+		for j, i := range reached {
+			if itemErr := types.BatchItemError(err, j); itemErr != nil {
+				be.Fail(i, itemErr)
+			} else {
+				delete(be.Failed, i)
+			}
+		}
+		return be.Err()
+	})
+}
+
+// handleBatch 调用 HandleBatch，panic 时记录日志与堆栈、调用 onPanic（可为 nil）并转换为错误
+func handleBatch(ctx context.Context, bh types.IBatchHandler, msgs []types.Message, logger *slog.Logger, onPanic func(any)) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		logger.Error("batch handler panic recovered",
+			"queue", msgs[0].Queue,
+			"batch_size", len(msgs),
+			"panic", r,
+			"stack", string(debug.Stack()),
+		)
+		if onPanic != nil {
+			onPanic(r)
+		}
+		err = xerror.NewXCode(xcode.ErrMQConsume, fmt.Sprintf("batch handler panic: %v", r))
+	}()
+	return bh.HandleBatch(ctx, msgs)
+}
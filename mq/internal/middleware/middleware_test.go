@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRecover_ConvertsPanicToError(t *testing.T) {
+	var recovered atomic.Value
+	h := Recover(nil, func(r any) { recovered.Store(r) })(types.FuncHandler(func(context.Context, types.Message) error {
+		panic("boom")
+	}))
+
+	err := h.Handle(context.Background(), types.NewMemoryMessage("q", nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "handler panic: boom")
+	assert.Equal(t, "boom", recovered.Load())
+}
+
+func TestRecover_PassesThroughError(t *testing.T) {
+	want := errors.New("fail")
+	h := Recover(nil, nil)(types.FuncHandler(func(context.Context, types.Message) error { return want }))
+	assert.ErrorIs(t, h.Handle(context.Background(), types.NewMemoryMessage("q", nil)), want)
+}
+
+func TestTrace_ExtractsParentFromHeaders(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	msg := types.NewMemoryMessage("q", []byte("x"))
+	msg.Headers = map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+
+	var got trace.SpanContext
+	h := Trace()(types.FuncHandler(func(ctx context.Context, _ types.Message) error {
+		got = trace.SpanContextFromContext(ctx)
+		return nil
+	}))
+	require.NoError(t, h.Handle(context.Background(), msg))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got.TraceID().String())
+}
+
+func TestServer_Defaults(t *testing.T) {
+	user := func(next types.IHandler) types.IHandler { return next }
+	assert.Len(t, Server(false, nil, nil, []types.Middleware{user}), 3)
+	assert.Len(t, Server(true, nil, nil, []types.Middleware{user}), 1)
+}
+
+func TestWrap_ServerOutsideRegistration(t *testing.T) {
+	var calls []string
+	mw := func(name string) types.Middleware {
+		return func(next types.IHandler) types.IHandler {
+			return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+				calls = append(calls, name)
+				return next.Handle(ctx, msg)
+			})
+		}
+	}
+	h := Wrap(types.FuncHandler(func(context.Context, types.Message) error {
+		calls = append(calls, "handler")
+		return nil
+	}), []types.Middleware{mw("server")}, []types.Middleware{mw("register")})
+
+	require.NoError(t, h.Handle(context.Background(), types.NewMemoryMessage("q", nil)))
+	assert.Equal(t, []string{"server", "register", "handler"}, calls)
+}
+
+func TestWrapBatch_RunsEachMessageThroughChain(t *testing.T) {
+	type tenantKey struct{}
+	var seen []string
+	tag := func(next types.IHandler) types.IHandler {
+		return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			seen = append(seen, string(msg.Data))
+			ctx = context.WithValue(ctx, tenantKey{}, "t-"+string(msg.Data))
+			msg.Data = append([]byte("mw:"), msg.Data...)
+			return next.Handle(ctx, msg)
+		})
+	}
+	var got, tenants []string
+	bh := WrapBatch(types.FuncBatchHandler(func(ctx context.Context, msgs []types.Message) error {
+		for i, m := range msgs {
+			got = append(got, string(m.Data))
+			tenants = append(tenants, types.BatchItemContext(ctx, i).Value(tenantKey{}).(string))
+		}
+		return nil
+	}), []types.Middleware{tag}, nil, nil)
+
+	require.NoError(t, bh.HandleBatch(context.Background(), []types.Message{
+		types.NewMemoryMessage("q", []byte("a")),
+		types.NewMemoryMessage("q", []byte("b")),
+	}))
+	assert.Equal(t, []string{"a", "b"}, seen)
+	assert.Equal(t, []string{"mw:a", "mw:b"}, got)
+	assert.Equal(t, []string{"t-a", "t-b"}, tenants)
+}
+
+func TestWrapBatch_ChainsAreIndependent(t *testing.T) {
+	depth, maxDepth := 0, 0
+	track := func(next types.IHandler) types.IHandler {
+		return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			depth++
+			maxDepth = max(maxDepth, depth)
+			defer func() { depth-- }()
+			return next.Handle(ctx, msg)
+		})
+	}
+	bh := WrapBatch(types.FuncBatchHandler(func(context.Context, []types.Message) error { return nil }),
+		[]types.Middleware{track}, nil, nil)
+
+	msgs := make([]types.Message, 10)
+	for i := range msgs {
+		msgs[i] = types.NewMemoryMessage("q", nil)
+	}
+	require.NoError(t, bh.HandleBatch(context.Background(), msgs))
+	assert.Equal(t, 1, maxDepth)
+}
+
+func TestWrapBatch_RejectedMessageFailsBatch(t *testing.T) {
+	deny := errors.New("denied")
+	reject := func(next types.IHandler) types.IHandler {
+		return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			if string(msg.Data) == "b" {
+				return deny
+			}
+			return next.Handle(ctx, msg)
+		})
+	}
+	called := false
+	bh := WrapBatch(types.FuncBatchHandler(func(context.Context, []types.Message) error {
+		called = true
+		return nil
+	}), []types.Middleware{reject}, nil, nil)
+
+	err := bh.HandleBatch(context.Background(), []types.Message{
+		types.NewMemoryMessage("q", []byte("a")),
+		types.NewMemoryMessage("q", []byte("b")),
+	})
+	assert.ErrorIs(t, err, deny)
+	assert.False(t, called)
+}
+
+func TestWrapBatch_DroppedMessageNotReportedSuccess(t *testing.T) {
+	drop := func(next types.IHandler) types.IHandler {
+		return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			if string(msg.Data) == "b" {
+				return nil
+			}
+			return next.Handle(ctx, msg)
+		})
+	}
+	bad := errors.New("bad row")
+	var got []string
+	bh := WrapBatch(types.FuncBatchHandler(func(_ context.Context, msgs []types.Message) error {
+		be := types.NewBatchError()
+		for i, m := range msgs {
+			got = append(got, string(m.Data))
+			if string(m.Data) == "c" {
+				be.Fail(i, bad)
+			}
+		}
+		return be.Err()
+	}), []types.Middleware{drop}, nil, nil)
+
+	err := bh.HandleBatch(context.Background(), []types.Message{
+		types.NewMemoryMessage("q", []byte("a")),
+		types.NewMemoryMessage("q", []byte("b")),
+		types.NewMemoryMessage("q", []byte("c")),
+	})
+	assert.Equal(t, []string{"a", "c"}, got)
+	assert.NoError(t, types.BatchItemError(err, 0))
+	assert.ErrorIs(t, types.BatchItemError(err, 1), errBatchItemSkipped)
+	assert.ErrorIs(t, types.BatchItemError(err, 2), bad)
+}
+
+func TestWrapBatch_AllDroppedSkipsHandleBatch(t *testing.T) {
+	drop := func(types.IHandler) types.IHandler {
+		return types.FuncHandler(func(context.Context, types.Message) error { return nil })
+	}
+	called := false
+	bh := WrapBatch(types.FuncBatchHandler(func(context.Context, []types.Message) error {
+		called = true
+		return nil
+	}), []types.Middleware{drop}, nil, nil)
+
+	err := bh.HandleBatch(context.Background(), []types.Message{types.NewMemoryMessage("q", nil)})
+	assert.False(t, called)
+	assert.ErrorIs(t, types.BatchItemError(err, 0), errBatchItemSkipped)
+}
+
+func TestWrapBatch_RecoversPanic(t *testing.T) {
+	var recovered atomic.Value
+	bh := WrapBatch(types.FuncBatchHandler(func(context.Context, []types.Message) error {
+		panic("boom")
+	}), nil, nil, func(r any) { recovered.Store(r) })
+
+	err := bh.HandleBatch(context.Background(), []types.Message{types.NewMemoryMessage("q", nil)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "batch handler panic: boom")
+	assert.Equal(t, "boom", recovered.Load())
+}
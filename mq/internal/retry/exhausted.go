@@ -3,10 +3,20 @@ package retry
 import (
 	"context"
 
+	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
 	"github.com/gomooth/pkg/mq/internal/types"
 )
 
+// Retryable 报告处理错误是否应当重试。
+// 不可重试的错误（types.Permanent、解码失败）始终不重试；retryIf 非 nil 时由其决定，否则所有错误都重试。
+func Retryable(err error, retryIf func(error) bool) bool {
+	if types.IsNonRetryable(err) {
+		return false
+	}
+	return retryIf == nil || retryIf(err)
+}
+
 // HandleExhausted 处理重试耗尽的消息。
 // 优先调用 DeadLetterHandler，若无则调用 FailedHandlerFunc。
 func HandleExhausted(
@@ -20,6 +30,38 @@ func HandleExhausted(
 	if m != nil {
 		m.OnDeadLetter()
 	}
+	deliver(ctx, deadLetter, failed, msg, lastErr)
+}
+
+// HandlePermanent 处理不可重试的消息：跳过剩余重试，以 permanent 原因记录指标与日志，
+// 再与重试耗尽相同地交给 DeadLetterHandler 或 FailedHandlerFunc。
+func HandlePermanent(
+	ctx context.Context,
+	m *metrics.ConsumerMetrics,
+	logger logutil.Logger,
+	deadLetter types.DeadLetterHandler,
+	failed types.FailedHandlerFunc,
+	msg types.Message,
+	err error,
+) {
+	if m != nil {
+		m.OnPermanentFailure()
+	}
+	if logger != nil {
+		logger.Warn("non-retryable error, skipping retries",
+			"queue", msg.Queue, "message_id", msg.ID, "attempt", msg.Attempt,
+			"reason", metrics.DeadLetterReasonPermanent, "error", err)
+	}
+	deliver(ctx, deadLetter, failed, msg, err)
+}
+
+func deliver(
+	ctx context.Context,
+	deadLetter types.DeadLetterHandler,
+	failed types.FailedHandlerFunc,
+	msg types.Message,
+	lastErr error,
+) {
 	if deadLetter != nil {
 		_ = deadLetter.OnDeadLetter(ctx, msg, lastErr)
 		return
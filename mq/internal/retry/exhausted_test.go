@@ -78,3 +78,23 @@ func TestHandleExhausted_NeitherSet(t *testing.T) {
 	// Should not panic
 	HandleExhausted(context.Background(), nil, nil, nil, types.NewRedisMessage("q", nil), errors.New("e"))
 }
+
+func TestRetryable(t *testing.T) {
+	plain := errors.New("fail")
+	assert.True(t, Retryable(plain, nil))
+	assert.False(t, Retryable(types.Permanent(plain), nil))
+	assert.False(t, Retryable(types.Permanent(plain), func(error) bool { return true }),
+		"permanent errors ignore the classifier")
+	assert.False(t, Retryable(plain, func(error) bool { return false }))
+}
+
+func TestHandlePermanent_DeliversToDeadLetter(t *testing.T) {
+	dl := &mockDeadLetterHandler{}
+	msg := types.NewRedisMessage("q1", []byte("hello"))
+	err := types.Permanent(errors.New("invalid"))
+
+	HandlePermanent(context.Background(), nil, nil, dl, nil, msg, err)
+
+	assert.True(t, dl.called)
+	assert.Equal(t, err, dl.err)
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"time"
 
-	"github.com/gomooth/pkg/mq/internal/attempt_tracker"
+	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
 	"github.com/gomooth/pkg/mq/internal/types"
 )
@@ -13,12 +13,14 @@ import (
 type RequeueConfig struct {
 	MaxRetry      int
 	Backoff       BackoffDelayFunc
-	Tracker       *attempt_tracker.AttemptTracker
 	Metrics       *metrics.ConsumerMetrics
 	FailedHandler types.FailedHandlerFunc
 	DeadLetter    types.DeadLetterHandler
 	Timeout       time.Duration
-	Requeue       func(ctx context.Context, msg types.Message) error
+	RetryIf       func(error) bool // 可选：返回 false 的错误不再重试，nil 时所有错误都重试
+	Logger        logutil.Logger   // 可选：记录不可重试的错误
+	// Requeue 携带递增后的 Attempt 重新入队；为 nil 时失败即视为重试耗尽
+	Requeue func(ctx context.Context, msg types.Message) error
 }
 
 // RequeueStrategy 再入队重试策略
@@ -46,9 +48,15 @@ func (s *RequeueStrategy) SetTimeout(d time.Duration) {
 	s.cfg.Timeout = d
 }
 
+// SetRetryIf 设置错误重试谓词
+func (s *RequeueStrategy) SetRetryIf(fn func(error) bool) {
+	s.cfg.RetryIf = fn
+}
+
 // OnMessage 对消息执行再入队重试策略。
-// 失败时通过 Tracker 跟踪重试次数，未达上限则重新入队；
-// 达到上限后调用 HandleExhausted。
+// 重试次数取自消息信封的 Attempt（随消息重新入队递增），重启与多实例消费下依然准确，
+// 且不同消息即使消息体相同也互不影响；未达上限则重新入队，达到上限后调用 HandleExhausted；
+// 不可重试的错误（见 Retryable）直接调用 HandlePermanent。
 func (s *RequeueStrategy) OnMessage(
 	ctx context.Context,
 	msg types.Message,
@@ -58,52 +66,46 @@ func (s *RequeueStrategy) OnMessage(
 		return handle(ctx, msg)
 	})
 	if err == nil {
-		if s.cfg.Tracker != nil {
-			key := attempt_tracker.MessageKey(string(msg.Data))
-			s.cfg.Tracker.Remove(key)
-		}
 		if s.cfg.Metrics != nil {
 			s.cfg.Metrics.OnConsume()
 		}
 		return nil
 	}
 
-	if s.cfg.Tracker == nil {
+	if !Retryable(err, s.cfg.RetryIf) {
+		HandlePermanent(ctx, s.cfg.Metrics, s.cfg.Logger, s.cfg.DeadLetter, s.cfg.FailedHandler, msg, err)
+		return nil
+	}
+
+	if s.cfg.Requeue == nil {
 		HandleExhausted(ctx, s.cfg.Metrics, s.cfg.DeadLetter, s.cfg.FailedHandler, msg, err)
 		return nil
 	}
 
-	key := attempt_tracker.MessageKey(string(msg.Data))
-	attempt := s.cfg.Tracker.Increment(key)
+	// 旧格式消息不携带信封，视为首次处理
+	attempt := max(msg.Attempt, 1)
+	if attempt >= s.cfg.MaxRetry {
+		HandleExhausted(ctx, s.cfg.Metrics, s.cfg.DeadLetter, s.cfg.FailedHandler, msg, err)
+		return nil
+	}
 
-	if attempt < s.cfg.MaxRetry {
-		if s.cfg.Backoff != nil {
-			delay := s.cfg.Backoff(uint(attempt - 1))
-			select {
-			case <-time.After(delay):
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-		}
-		if requeueErr := s.cfg.Requeue(ctx, msg); requeueErr != nil {
-			s.cfg.Tracker.Remove(key)
-			HandleExhausted(ctx, s.cfg.Metrics, s.cfg.DeadLetter, s.cfg.FailedHandler, msg, err)
-			return nil
-		}
-		if s.cfg.Metrics != nil {
-			s.cfg.Metrics.OnRetry()
+	if s.cfg.Backoff != nil {
+		delay := s.cfg.Backoff(uint(attempt - 1))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+	}
+	// 重新入队的消息保留信封，处理次数加一
+	next := msg
+	next.Attempt = attempt + 1
+	if requeueErr := s.cfg.Requeue(ctx, next); requeueErr != nil {
+		HandleExhausted(ctx, s.cfg.Metrics, s.cfg.DeadLetter, s.cfg.FailedHandler, msg, err)
 		return nil
 	}
-
-	s.cfg.Tracker.Remove(key)
-	HandleExhausted(ctx, s.cfg.Metrics, s.cfg.DeadLetter, s.cfg.FailedHandler, msg, err)
-	return nil
-}
-
-// Close 释放资源，停止 Tracker 后台清理 goroutine
-func (s *RequeueStrategy) Close() {
-	if s.cfg.Tracker != nil {
-		s.cfg.Tracker.Close()
+	if s.cfg.Metrics != nil {
+		s.cfg.Metrics.OnRetry()
 	}
+	return nil
 }
@@ -8,21 +8,13 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
-	"github.com/gomooth/pkg/mq/internal/attempt_tracker"
 	"github.com/gomooth/pkg/mq/internal/types"
 )
 
 func TestRequeueStrategy_Success(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-	defer tracker.Close()
-
 	s := NewRequeueStrategy(RequeueConfig{
 		MaxRetry: 3,
 		Backoff:  testBackoff,
-		Tracker:  tracker,
 	})
 
 	msg := types.NewRedisMessage("q", []byte("data"))
@@ -33,87 +25,99 @@ func TestRequeueStrategy_Success(t *testing.T) {
 }
 
 func TestRequeueStrategy_RequeueOnFailure(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-	defer tracker.Close()
-
 	var requeueCalls int
-	requeueFn := func(_ context.Context, _ types.Message) error {
+	var requeued types.Message
+	requeueFn := func(_ context.Context, msg types.Message) error {
 		requeueCalls++
+		requeued = msg
 		return nil
 	}
 
 	s := NewRequeueStrategy(RequeueConfig{
 		MaxRetry: 3,
 		Backoff:  testBackoff,
-		Tracker:  tracker,
 		Requeue:  requeueFn,
 	})
 
 	msg := types.NewRedisMessage("q", []byte("data"))
+	msg.ID = "id-1"
+	msg.Attempt = 1
 	err := s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
 		return errors.New("fail")
 	})
 	assert.NoError(t, err, "requeue strategy returns nil after requeue")
 	assert.Equal(t, 1, requeueCalls, "requeue should be called once")
+	assert.Equal(t, "id-1", requeued.ID, "requeued message keeps its envelope")
+	assert.Equal(t, 2, requeued.Attempt)
 }
 
 func TestRequeueStrategy_ExhaustedAfterMaxRetries(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-	defer tracker.Close()
-
 	var failedCalled bool
 	failedFn := func(_ context.Context, _ types.Message, _ error) {
 		failedCalled = true
 	}
 
-	var requeueCalls int
-	requeueFn := func(_ context.Context, _ types.Message) error {
-		requeueCalls++
+	var requeued []types.Message
+	requeueFn := func(_ context.Context, msg types.Message) error {
+		requeued = append(requeued, msg)
 		return nil
 	}
 
 	s := NewRequeueStrategy(RequeueConfig{
 		MaxRetry:      3,
 		Backoff:       testBackoff,
-		Tracker:       tracker,
 		Requeue:       requeueFn,
 		FailedHandler: failedFn,
 	})
 
 	msg := types.NewRedisMessage("q", []byte("data"))
 	handleErr := errors.New("fail")
+	fail := func(_ context.Context, _ types.Message) error { return handleErr }
 
-	// First attempt: increment tracker to 1, 1 < 3 → requeue
-	err := s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
-		return handleErr
-	})
-	assert.NoError(t, err)
-	assert.Equal(t, 1, requeueCalls)
+	// 第 1 次处理：1 < 3 → 以 Attempt=2 重新入队
+	assert.NoError(t, s.OnMessage(context.Background(), msg, fail))
+	assert.Len(t, requeued, 1)
+	assert.Equal(t, 2, requeued[0].Attempt)
 	assert.False(t, failedCalled, "should not be exhausted yet")
 
-	// Second attempt: increment tracker to 2, 2 < 3 → requeue
-	err = s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
-		return handleErr
-	})
-	assert.NoError(t, err)
-	assert.Equal(t, 2, requeueCalls)
+	// 第 2 次处理：2 < 3 → 以 Attempt=3 重新入队
+	assert.NoError(t, s.OnMessage(context.Background(), requeued[0], fail))
+	assert.Len(t, requeued, 2)
+	assert.Equal(t, 3, requeued[1].Attempt)
 	assert.False(t, failedCalled, "should not be exhausted yet")
 
-	// Third attempt: increment tracker to 3, 3 < 3 → false → exhausted
-	err = s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
-		return handleErr
-	})
-	assert.NoError(t, err)
+	// 第 3 次处理：达到上限 → 耗尽
+	assert.NoError(t, s.OnMessage(context.Background(), requeued[1], fail))
+	assert.Len(t, requeued, 2)
 	assert.True(t, failedCalled, "should be exhausted now")
 }
 
-func TestRequeueStrategy_NoTracker(t *testing.T) {
+// TestRequeueStrategy_AttemptFromEnvelope 验证重试次数只取决于消息信封：
+// 新的策略实例（模拟重启或其他消费实例）同样按信封判定耗尽，消息体相同的不同消息互不影响
+func TestRequeueStrategy_AttemptFromEnvelope(t *testing.T) {
+	var failed []string
+	var requeueCalls int
+	s := NewRequeueStrategy(RequeueConfig{
+		MaxRetry:      3,
+		Requeue:       func(context.Context, types.Message) error { requeueCalls++; return nil },
+		FailedHandler: func(_ context.Context, msg types.Message, _ error) { failed = append(failed, msg.ID) },
+	})
+	fail := func(context.Context, types.Message) error { return errors.New("fail") }
+
+	last := types.NewRedisMessage("q", []byte("same"))
+	last.ID = "last"
+	last.Attempt = 3
+	fresh := types.NewRedisMessage("q", []byte("same"))
+	fresh.ID = "fresh"
+	fresh.Attempt = 1
+
+	_ = s.OnMessage(context.Background(), last, fail)
+	_ = s.OnMessage(context.Background(), fresh, fail)
+	assert.Equal(t, []string{"last"}, failed)
+	assert.Equal(t, 1, requeueCalls)
+}
+
+func TestRequeueStrategy_NoRequeue(t *testing.T) {
 	var failedCalled bool
 	failedFn := func(_ context.Context, _ types.Message, _ error) {
 		failedCalled = true
@@ -129,16 +133,10 @@ func TestRequeueStrategy_NoTracker(t *testing.T) {
 		return errors.New("fail")
 	})
 	assert.NoError(t, err)
-	assert.True(t, failedCalled, "without tracker, should call FailedHandler immediately")
+	assert.True(t, failedCalled, "without Requeue, should call FailedHandler immediately")
 }
 
 func TestRequeueStrategy_RequeueFail(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-	defer tracker.Close()
-
 	var failedCalled bool
 	failedFn := func(_ context.Context, _ types.Message, _ error) {
 		failedCalled = true
@@ -150,7 +148,6 @@ func TestRequeueStrategy_RequeueFail(t *testing.T) {
 
 	s := NewRequeueStrategy(RequeueConfig{
 		MaxRetry:      3,
-		Tracker:       tracker,
 		Requeue:       requeueFn,
 		FailedHandler: failedFn,
 	})
@@ -163,31 +160,7 @@ func TestRequeueStrategy_RequeueFail(t *testing.T) {
 	assert.True(t, failedCalled, "should call FailedHandler when requeue fails")
 }
 
-func TestRequeueStrategy_Close(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-
-	s := NewRequeueStrategy(RequeueConfig{
-		MaxRetry: 3,
-		Tracker:  tracker,
-	})
-
-	// Close should not panic
-	s.Close()
-
-	// Double close should not panic
-	s.Close()
-}
-
 func TestRequeueStrategy_DeadLetterOnExhausted(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-	defer tracker.Close()
-
 	dl := &mockDeadLetterHandler{}
 
 	requeueFn := func(_ context.Context, _ types.Message) error {
@@ -196,7 +169,6 @@ func TestRequeueStrategy_DeadLetterOnExhausted(t *testing.T) {
 
 	s := NewRequeueStrategy(RequeueConfig{
 		MaxRetry:   2,
-		Tracker:    tracker,
 		Requeue:    requeueFn,
 		DeadLetter: dl,
 	})
@@ -209,18 +181,13 @@ func TestRequeueStrategy_DeadLetterOnExhausted(t *testing.T) {
 		_ = s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
 			return handleErr
 		})
+		msg.Attempt = max(msg.Attempt, 1) + 1 // 模拟重新入队后的信封
 	}
 	assert.True(t, dl.called, "DeadLetterHandler should be called on exhaustion")
 }
 
 // TestRequeueStrategy_SetFailedHandler 验证通过 SetFailedHandler 设置的回调在重试耗尽时被调用
 func TestRequeueStrategy_SetFailedHandler(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-	defer tracker.Close()
-
 	var failedCalled bool
 	failedFn := func(_ context.Context, _ types.Message, _ error) {
 		failedCalled = true
@@ -228,7 +195,6 @@ func TestRequeueStrategy_SetFailedHandler(t *testing.T) {
 
 	s := NewRequeueStrategy(RequeueConfig{
 		MaxRetry: 2,
-		Tracker:  tracker,
 		Requeue:  func(_ context.Context, _ types.Message) error { return nil },
 	})
 	s.SetFailedHandler(failedFn)
@@ -241,18 +207,13 @@ func TestRequeueStrategy_SetFailedHandler(t *testing.T) {
 		_ = s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
 			return handleErr
 		})
+		msg.Attempt = max(msg.Attempt, 1) + 1 // 模拟重新入队后的信封
 	}
 	assert.True(t, failedCalled, "SetFailedHandler 设置的回调应被调用")
 }
 
 // TestRequeueStrategy_SetDeadLetterHandler 验证死信处理器优先于 FailedHandler
 func TestRequeueStrategy_SetDeadLetterHandler(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-	defer tracker.Close()
-
 	dl := &mockDeadLetterHandler{}
 	var failedCalled bool
 	failedFn := func(_ context.Context, _ types.Message, _ error) {
@@ -261,7 +222,6 @@ func TestRequeueStrategy_SetDeadLetterHandler(t *testing.T) {
 
 	s := NewRequeueStrategy(RequeueConfig{
 		MaxRetry: 1,
-		Tracker:  tracker,
 		Requeue:  func(_ context.Context, _ types.Message) error { return nil },
 	})
 	s.SetDeadLetterHandler(dl)
@@ -275,6 +235,7 @@ func TestRequeueStrategy_SetDeadLetterHandler(t *testing.T) {
 		_ = s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
 			return handleErr
 		})
+		msg.Attempt = max(msg.Attempt, 1) + 1 // 模拟重新入队后的信封
 	}
 	assert.True(t, dl.called, "SetDeadLetterHandler 设置的处理器应被调用")
 	assert.False(t, failedCalled, "死信处理器应优先于 FailedHandler")
@@ -282,15 +243,8 @@ func TestRequeueStrategy_SetDeadLetterHandler(t *testing.T) {
 
 // TestRequeueStrategy_SetTimeout 验证超时生效，处理函数超过超时时间后返回 context.DeadlineExceeded
 func TestRequeueStrategy_SetTimeout(t *testing.T) {
-	tracker := attempt_tracker.NewAttemptTracker(
-		attempt_tracker.WithMaxAge(time.Minute),
-		attempt_tracker.WithCleanInterval(time.Hour),
-	)
-	defer tracker.Close()
-
 	s := NewRequeueStrategy(RequeueConfig{
 		MaxRetry: 0,
-		Tracker:  tracker,
 	})
 	s.SetTimeout(10 * time.Millisecond)
 
@@ -305,7 +259,7 @@ func TestRequeueStrategy_SetTimeout(t *testing.T) {
 		}
 	})
 	// RequeueStrategy 返回 nil（始终不返回错误），但超时应使 handler 收到 context.DeadlineExceeded
-	// 验证方式：无 tracker 且 MaxRetry=0 时直接走 HandleExhausted
+	// 验证方式：MaxRetry=0 时直接走 HandleExhausted
 	s2 := NewRequeueStrategy(RequeueConfig{
 		MaxRetry: 0,
 	})
@@ -326,3 +280,25 @@ func TestRequeueStrategy_SetTimeout(t *testing.T) {
 	})
 	assert.True(t, errors.Is(failedErr, context.DeadlineExceeded), "SetTimeout 设置的超时应生效")
 }
+
+func TestRequeueStrategy_NonRetryableSkipsRequeue(t *testing.T) {
+	var failedCalled bool
+	var requeueCalls int
+	s := NewRequeueStrategy(RequeueConfig{
+		MaxRetry: 3,
+		Backoff:  testBackoff,
+		Requeue: func(context.Context, types.Message) error {
+			requeueCalls++
+			return nil
+		},
+		FailedHandler: func(context.Context, types.Message, error) { failedCalled = true },
+	})
+
+	msg := types.NewRedisMessage("q", []byte("data"))
+	err := s.OnMessage(context.Background(), msg, func(context.Context, types.Message) error {
+		return &types.DecodeError{Codec: "json", Err: errors.New("bad")}
+	})
+	assert.NoError(t, err)
+	assert.Zero(t, requeueCalls)
+	assert.True(t, failedCalled)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
 	"github.com/gomooth/pkg/mq/internal/types"
 )
@@ -19,6 +20,8 @@ type SyncConfig struct {
 	FailedHandler types.FailedHandlerFunc
 	DeadLetter    types.DeadLetterHandler
 	Timeout       time.Duration
+	RetryIf       func(error) bool // 可选：返回 false 的错误不再重试，nil 时所有错误都重试
+	Logger        logutil.Logger   // 可选：记录不可重试的错误
 }
 
 // SyncStrategy 同步阻塞重试策略
@@ -46,13 +49,24 @@ func (s *SyncStrategy) SetTimeout(d time.Duration) {
 	s.cfg.Timeout = d
 }
 
+// SetRetryIf 设置错误重试谓词
+func (s *SyncStrategy) SetRetryIf(fn func(error) bool) {
+	s.cfg.RetryIf = fn
+}
+
 // OnMessage 对消息执行同步重试策略。
-// 首次执行 + 最多 MaxRetry 次重试；全部失败后调用 HandleExhausted。
+// 首次执行 + 最多 MaxRetry 次重试；全部失败后调用 HandleExhausted；
+// 不可重试的错误（见 Retryable）跳过剩余重试，调用 HandlePermanent。
 func (s *SyncStrategy) OnMessage(
 	ctx context.Context,
 	msg types.Message,
 	handle func(ctx context.Context, msg types.Message) error,
 ) error {
+	// 每次调用 handle 时 msg.Attempt 递增，反映当前是第几次处理
+	base := msg.Attempt
+	if base <= 0 {
+		base = 1
+	}
 	var lastErr error
 	for attempt := 0; attempt <= s.cfg.MaxRetry; attempt++ {
 		if ctx.Err() != nil {
@@ -66,6 +80,7 @@ func (s *SyncStrategy) OnMessage(
 				return ctx.Err()
 			}
 		}
+		msg.Attempt = base + attempt
 		err := ApplyTimeout(ctx, s.cfg.Timeout, func(ctx context.Context) error {
 			return handle(ctx, msg)
 		})
@@ -76,6 +91,11 @@ func (s *SyncStrategy) OnMessage(
 			return nil
 		}
 		lastErr = err
+		// 不可重试的错误跳过剩余重试，直接进入死信流程
+		if !Retryable(err, s.cfg.RetryIf) {
+			HandlePermanent(ctx, s.cfg.Metrics, s.cfg.Logger, s.cfg.DeadLetter, s.cfg.FailedHandler, msg, err)
+			return err
+		}
 		if attempt < s.cfg.MaxRetry {
 			if s.cfg.Metrics != nil {
 				s.cfg.Metrics.OnRetry()
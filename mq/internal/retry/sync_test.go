@@ -33,9 +33,11 @@ func TestSyncStrategy_RetryThenSuccess(t *testing.T) {
 		Backoff:  testBackoff,
 	})
 
+	var seen []int
 	msg := types.NewRedisMessage("q", []byte("data"))
-	err := s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
+	err := s.OnMessage(context.Background(), msg, func(_ context.Context, m types.Message) error {
 		attempts++
+		seen = append(seen, m.Attempt)
 		if attempts < 3 {
 			return errors.New("fail")
 		}
@@ -43,6 +45,7 @@ func TestSyncStrategy_RetryThenSuccess(t *testing.T) {
 	})
 	assert.NoError(t, err)
 	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []int{1, 2, 3}, seen, "msg.Attempt should reflect the current attempt")
 }
 
 func TestSyncStrategy_Exhausted(t *testing.T) {
@@ -168,3 +171,43 @@ func TestSyncStrategy_SetTimeout(t *testing.T) {
 	})
 	assert.True(t, errors.Is(err, context.DeadlineExceeded), "SetTimeout 设置的超时应生效")
 }
+
+func TestSyncStrategy_NonRetryableSkipsRetries(t *testing.T) {
+	dl := &mockDeadLetterHandler{}
+	s := NewSyncStrategy(SyncConfig{
+		MaxRetry:   3,
+		Backoff:    testBackoff,
+		DeadLetter: dl,
+	})
+
+	var attempts int
+	msg := types.NewRedisMessage("q", []byte("data"))
+	err := s.OnMessage(context.Background(), msg, func(_ context.Context, _ types.Message) error {
+		attempts++
+		return &types.DecodeError{Codec: "json", Err: errors.New("bad")}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts, "non-retryable error should not be retried")
+	assert.True(t, dl.called)
+}
+
+func TestSyncStrategy_RetryIfFalseSkipsRetries(t *testing.T) {
+	var failedCalled bool
+	notFound := errors.New("not found")
+	s := NewSyncStrategy(SyncConfig{
+		MaxRetry:      3,
+		Backoff:       testBackoff,
+		FailedHandler: func(context.Context, types.Message, error) { failedCalled = true },
+	})
+	s.SetRetryIf(func(err error) bool { return !errors.Is(err, notFound) })
+
+	var attempts int
+	err := s.OnMessage(context.Background(), types.NewRedisMessage("q", []byte("data")),
+		func(context.Context, types.Message) error {
+			attempts++
+			return notFound
+		})
+	assert.ErrorIs(t, err, notFound)
+	assert.Equal(t, 1, attempts)
+	assert.True(t, failedCalled)
+}
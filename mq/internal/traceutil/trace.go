@@ -65,3 +65,17 @@ func InjectTraceContext(ctx context.Context, msg string) string {
 	result, _ := json.Marshal(body)
 	return string(result)
 }
+
+// InjectHeaders 向消息头注入 trace context，适用于任意格式的消息体。
+func InjectHeaders(ctx context.Context, headers map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+}
+
+// ExtractMessage 从消息中提取 trace context。
+// 优先读取消息头；消息头中没有 traceparent 时（旧格式消息），回退到从 JSON 消息体中提取。
+func ExtractMessage(ctx context.Context, headers map[string]string, body []byte) context.Context {
+	if _, ok := headers["traceparent"]; ok {
+		return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+	}
+	return ExtractTraceContext(ctx, string(body))
+}
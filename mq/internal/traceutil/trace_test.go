@@ -176,3 +176,29 @@ func TestInjectTraceContext_CleansOldFormat(t *testing.T) {
 	// 新格式字段应存在
 	assert.Contains(t, injected, `"traceparent"`)
 }
+
+func TestInjectHeadersAndExtractMessage(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test-span")
+	defer span.End()
+
+	// 非 JSON 消息体也能通过消息头传播
+	headers := map[string]string{}
+	InjectHeaders(ctx, headers)
+	require.Contains(t, headers, "traceparent")
+
+	extracted := ExtractMessage(context.Background(), headers, []byte("binary payload"))
+	assert.Equal(t, span.SpanContext().TraceID(), trace.SpanContextFromContext(extracted).TraceID())
+
+	// 消息头缺失时回退到 JSON 消息体
+	legacy := InjectTraceContext(ctx, `{"key":"value"}`)
+	extracted = ExtractMessage(context.Background(), nil, []byte(legacy))
+	assert.Equal(t, span.SpanContext().TraceID(), trace.SpanContextFromContext(extracted).TraceID())
+}
@@ -0,0 +1,196 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// IBatchHandler 批量消息处理接口。
+//
+// HandleBatch 返回 nil 表示整批处理成功；返回 *BatchError 表示部分失败，
+// 仅其中列出的消息进入单条重试/死信流程，其余消息视为成功；
+// 返回其他 error 表示整批失败，批内每条消息都进入单条重试流程。
+// 重试阶段以单条消息的批次（len(msgs) == 1）再次调用 HandleBatch。
+type IBatchHandler interface {
+	HandleBatch(ctx context.Context, msgs []Message) error
+}
+
+// FuncBatchHandler 函数适配器，将函数转换为 IBatchHandler
+type FuncBatchHandler func(ctx context.Context, msgs []Message) error
+
+func (f FuncBatchHandler) HandleBatch(ctx context.Context, msgs []Message) error {
+	return f(ctx, msgs)
+}
+
+// BatchError 批量处理的部分失败结果，记录失败消息在批次中的下标及其错误
+type BatchError struct {
+	Failed map[int]error
+}
+
+// NewBatchError 创建空的部分失败结果
+func NewBatchError() *BatchError {
+	return &BatchError{Failed: make(map[int]error)}
+}
+
+// Fail 记录第 index 条消息处理失败，返回自身便于链式调用
+func (e *BatchError) Fail(index int, err error) *BatchError {
+	if err == nil {
+		err = errors.New("batch item failed")
+	}
+	e.Failed[index] = err
+	return e
+}
+
+// Err 无失败记录时返回 nil，便于 HandleBatch 直接 return be.Err()
+func (e *BatchError) Err() error {
+	if e == nil || len(e.Failed) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch: %d message(s) failed", len(e.Failed))
+}
+
+// BatchItemError 返回批次中第 index 条消息的处理结果。
+// err 为 *BatchError 时返回对应下标的错误（未列出则为 nil），其他非 nil 错误视为整批失败。
+func BatchItemError(err error, index int) error {
+	if err == nil {
+		return nil
+	}
+	var be *BatchError
+	if errors.As(err, &be) {
+		return be.Failed[index]
+	}
+	return err
+}
+
+// ==================== 批量配置 ====================
+
+const (
+	// DefaultBatchSize 默认批量大小
+	DefaultBatchSize = 100
+	// DefaultBatchMaxWait 默认凑批最长等待时间
+	DefaultBatchMaxWait = time.Second
+)
+
+// BatchConfig 批量消费配置
+type BatchConfig struct {
+	Size    int           // 单批最大消息数
+	MaxWait time.Duration // 收到批内第一条消息后最长等待时间，到期后不足 Size 也提交
+}
+
+// BatchOption 批量消费配置选项
+type BatchOption func(*BatchConfig)
+
+// WithBatchSize 设置单批最大消息数（默认 100）
+func WithBatchSize(n int) BatchOption {
+	return func(c *BatchConfig) {
+		if n > 0 {
+			c.Size = n
+		}
+	}
+}
+
+// WithBatchMaxWait 设置凑批最长等待时间（默认 1s）
+func WithBatchMaxWait(d time.Duration) BatchOption {
+	return func(c *BatchConfig) {
+		if d > 0 {
+			c.MaxWait = d
+		}
+	}
+}
+
+// batchHandler 将 IBatchHandler 适配为 IHandler（未导出），
+// 消费引擎通过 BatchOf 识别并切换到批量消费
+type batchHandler struct {
+	handler IBatchHandler
+	cfg     BatchConfig
+}
+
+// batchDeadLetterHandler 批量 handler 同时实现 DeadLetterHandler 时使用，保留死信接口
+type batchDeadLetterHandler struct {
+	*batchHandler
+	DeadLetterHandler
+}
+
+// NewBatchHandler 将批量 handler 包装为 IHandler，用于 Register / WithConsumer 注册批量消费。
+// 不支持批量消费的 MQ 实现按单条消息调用 HandleBatch。
+func NewBatchHandler(h IBatchHandler, opts ...BatchOption) IHandler {
+	cfg := BatchConfig{Size: DefaultBatchSize, MaxWait: DefaultBatchMaxWait}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	b := &batchHandler{handler: h, cfg: cfg}
+	if dl, ok := h.(DeadLetterHandler); ok {
+		return &batchDeadLetterHandler{batchHandler: b, DeadLetterHandler: dl}
+	}
+	return b
+}
+
+// Handle 返回批量处理时记录的结果；重试阶段以单条消息的批次调用 HandleBatch
+func (b *batchHandler) Handle(ctx context.Context, msg Message) error {
+	if err, ok := takeBatchResult(ctx); ok {
+		return err
+	}
+	return BatchItemError(b.handler.HandleBatch(ctx, []Message{msg}), 0)
+}
+
+// BatchOf 判断 handler 是否由 NewBatchHandler 创建，返回内部批量 handler 及配置
+func BatchOf(h IHandler) (IBatchHandler, BatchConfig, bool) {
+	switch b := h.(type) {
+	case *batchHandler:
+		return b.handler, b.cfg, true
+	case *batchDeadLetterHandler:
+		return b.handler, b.cfg, true
+	}
+	return nil, BatchConfig{}, false
+}
+
+// ==================== 批量结果传递 ====================
+
+type batchResultKey struct{}
+
+// batchResult 批量处理中单条消息的结果，仅被消费一次
+type batchResult struct {
+	err  error
+	used atomic.Bool
+}
+
+// WithBatchResult 将批量处理中单条消息的结果写入 ctx。
+// 消费引擎随后把消息交给单条重试策略，策略首次调用 handler 时直接得到该结果，
+// 后续重试才真正以单条批次调用 HandleBatch，从而复用既有的重试/死信流程。
+func WithBatchResult(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, batchResultKey{}, &batchResult{err: err})
+}
+
+func takeBatchResult(ctx context.Context) (error, bool) {
+	r, ok := ctx.Value(batchResultKey{}).(*batchResult)
+	if !ok || !r.used.CompareAndSwap(false, true) {
+		return nil, false
+	}
+	return r.err, true
+}
+
+// ==================== 批内消息上下文 ====================
+
+type batchItemContextsKey struct{}
+
+// WithBatchItemContexts 记录批内每条消息经中间件处理后的 ctx，下标与传给 HandleBatch 的 msgs 一致
+func WithBatchItemContexts(ctx context.Context, ctxs []context.Context) context.Context {
+	return context.WithValue(ctx, batchItemContextsKey{}, ctxs)
+}
+
+// BatchItemContext 返回批内第 index 条消息经中间件处理后的 ctx（携带租户、链路等单条消息的上下文）；
+// 未记录时返回 ctx 本身
+func BatchItemContext(ctx context.Context, index int) context.Context {
+	ctxs, _ := ctx.Value(batchItemContextsKey{}).([]context.Context)
+	if index < 0 || index >= len(ctxs) {
+		return ctx
+	}
+	return ctxs[index]
+}
\ No newline at end of file
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- BatchError ---
+
+func TestBatchError(t *testing.T) {
+	assert.NoError(t, NewBatchError().Err(), "empty BatchError should be nil")
+
+	boom := errors.New("boom")
+	be := NewBatchError().Fail(1, boom).Fail(3, nil)
+	require.Error(t, be.Err())
+	assert.Equal(t, "batch: 2 message(s) failed", be.Error())
+
+	assert.NoError(t, BatchItemError(be, 0))
+	assert.ErrorIs(t, BatchItemError(be, 1), boom)
+	assert.Error(t, BatchItemError(be, 3), "nil error should be replaced")
+}
+
+func TestBatchItemError_WholeBatch(t *testing.T) {
+	boom := errors.New("boom")
+	assert.NoError(t, BatchItemError(nil, 0))
+	assert.ErrorIs(t, BatchItemError(boom, 5), boom)
+
+	wrapped := fmt.Errorf("insert rows: %w", NewBatchError().Fail(2, boom))
+	assert.NoError(t, BatchItemError(wrapped, 0))
+	assert.ErrorIs(t, BatchItemError(wrapped, 2), boom)
+}
+
+// --- NewBatchHandler / BatchOf ---
+
+func TestNewBatchHandler_Config(t *testing.T) {
+	h := NewBatchHandler(FuncBatchHandler(func(context.Context, []Message) error { return nil }))
+	_, cfg, ok := BatchOf(h)
+	require.True(t, ok)
+	assert.Equal(t, DefaultBatchSize, cfg.Size)
+	assert.Equal(t, DefaultBatchMaxWait, cfg.MaxWait)
+
+	h = NewBatchHandler(FuncBatchHandler(func(context.Context, []Message) error { return nil }),
+		WithBatchSize(10), WithBatchMaxWait(50*time.Millisecond), WithBatchSize(0))
+	_, cfg, ok = BatchOf(h)
+	require.True(t, ok)
+	assert.Equal(t, 10, cfg.Size)
+	assert.Equal(t, 50*time.Millisecond, cfg.MaxWait)
+
+	_, _, ok = BatchOf(FuncHandler(func(context.Context, Message) error { return nil }))
+	assert.False(t, ok)
+}
+
+type dlBatchHandler struct{ dead []Message }
+
+func (h *dlBatchHandler) HandleBatch(context.Context, []Message) error { return nil }
+
+func (h *dlBatchHandler) OnDeadLetter(_ context.Context, msg Message, _ error) error {
+	h.dead = append(h.dead, msg)
+	return nil
+}
+
+func TestNewBatchHandler_KeepsDeadLetter(t *testing.T) {
+	inner := &dlBatchHandler{}
+	h := NewBatchHandler(inner)
+
+	dl, ok := h.(DeadLetterHandler)
+	require.True(t, ok, "dead letter interface should be preserved")
+	require.NoError(t, dl.OnDeadLetter(context.Background(), NewRedisMessage("q", nil), errors.New("x")))
+	assert.Len(t, inner.dead, 1)
+
+	got, _, ok := BatchOf(h)
+	require.True(t, ok)
+	assert.Same(t, inner, got)
+
+	_, ok = NewBatchHandler(FuncBatchHandler(nil)).(DeadLetterHandler)
+	assert.False(t, ok)
+}
+
+func TestBatchHandler_Handle(t *testing.T) {
+	boom := errors.New("boom")
+	var calls [][]Message
+	h := NewBatchHandler(FuncBatchHandler(func(_ context.Context, msgs []Message) error {
+		calls = append(calls, msgs)
+		return NewBatchError().Fail(0, boom)
+	}))
+
+	// 批量处理结果只被使用一次，之后以单条批次调用 HandleBatch
+	ctx := WithBatchResult(context.Background(), nil)
+	assert.NoError(t, h.Handle(ctx, NewRedisMessage("q", []byte("a"))))
+	assert.Empty(t, calls)
+
+	assert.ErrorIs(t, h.Handle(ctx, NewRedisMessage("q", []byte("a"))), boom)
+	require.Len(t, calls, 1)
+	assert.Len(t, calls[0], 1)
+	assert.Equal(t, "a", string(calls[0][0].Data))
+
+	ctx = WithBatchResult(context.Background(), boom)
+	assert.ErrorIs(t, h.Handle(ctx, NewRedisMessage("q", nil)), boom)
+	assert.Len(t, calls, 1)
+}
@@ -0,0 +1,95 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// HeaderCodec 记录消息体编解码器名称的消息头，消费端据此选择解码器
+const HeaderCodec = "mq-codec"
+
+// Codec 消息体编解码器接口。
+// Name 写入消息头 HeaderCodec，须在同一部署内唯一且保持稳定。
+type Codec interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec 默认的 JSON 编解码器
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string                       { return "json" }
+func (JSONCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// MsgpackCodec 基于 msgpack 的编解码器，比 JSON 更高效且体积更小
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Name() string                       { return "msgpack" }
+func (MsgpackCodec) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (MsgpackCodec) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+// ProtobufCodec 基于 protobuf 的编解码器，值须实现 proto.Message（如 *pb.Order）
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) Name() string { return "protobuf" }
+
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("mq: protobuf codec requires proto.Message, got %T", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal 支持 proto.Message，以及指向 proto.Message 指针的指针（为 nil 时自动分配）
+func (ProtobufCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer {
+		elem := rv.Elem()
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		if m, ok := elem.Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, m)
+		}
+	}
+	return fmt.Errorf("mq: protobuf codec requires proto.Message, got %T", v)
+}
+
+var (
+	codecMu sync.RWMutex
+	codecs  = map[string]Codec{
+		JSONCodec{}.Name():     JSONCodec{},
+		MsgpackCodec{}.Name():  MsgpackCodec{},
+		ProtobufCodec{}.Name(): ProtobufCodec{},
+	}
+)
+
+// RegisterCodec 注册自定义编解码器，同名时覆盖。
+// 消费端按消息头 HeaderCodec 查找已注册的编解码器，生产端更换编解码器前须先在消费端注册。
+func RegisterCodec(c Codec) {
+	if c == nil {
+		return
+	}
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codecs[c.Name()] = c
+}
+
+// LookupCodec 按名称查找已注册的编解码器，内置 json、msgpack、protobuf
+func LookupCodec(name string) (Codec, bool) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecOrder struct {
+	ID   int    `json:"id" msgpack:"id"`
+	Name string `json:"name" msgpack:"name"`
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	for _, c := range []Codec{JSONCodec{}, MsgpackCodec{}} {
+		t.Run(c.Name(), func(t *testing.T) {
+			data, err := c.Marshal(codecOrder{ID: 1, Name: "a"})
+			require.NoError(t, err)
+
+			var got codecOrder
+			require.NoError(t, c.Unmarshal(data, &got))
+			assert.Equal(t, codecOrder{ID: 1, Name: "a"}, got)
+		})
+	}
+}
+
+func TestProtobufCodec_RoundTrip(t *testing.T) {
+	c := ProtobufCodec{}
+	data, err := c.Marshal(wrapperspb.String("hello"))
+	require.NoError(t, err)
+
+	// 指向 nil 指针的指针：自动分配
+	var got *wrapperspb.StringValue
+	require.NoError(t, c.Unmarshal(data, &got))
+	assert.Equal(t, "hello", got.GetValue())
+
+	_, err = c.Marshal(codecOrder{})
+	assert.Error(t, err)
+	assert.Error(t, c.Unmarshal(data, &codecOrder{}))
+}
+
+type upperCodec struct{ JSONCodec }
+
+func (upperCodec) Name() string { return "test-upper" }
+
+func TestRegisterCodec_Lookup(t *testing.T) {
+	for _, name := range []string{"json", "msgpack", "protobuf"} {
+		_, ok := LookupCodec(name)
+		assert.True(t, ok, name)
+	}
+
+	_, ok := LookupCodec("test-upper")
+	assert.False(t, ok)
+	RegisterCodec(upperCodec{})
+	c, ok := LookupCodec("test-upper")
+	require.True(t, ok)
+	assert.Equal(t, "test-upper", c.Name())
+}
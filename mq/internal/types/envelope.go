@@ -0,0 +1,85 @@
+package types
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// wireMagic 版本化线上格式前缀（redis/httpsqs 使用）。
+// 格式：wireMagic + 信封 JSON + "\n" + 消息体。
+// 仅使用可打印字符，兼容以字符串存储消息的 httpsqs。
+const wireMagic = "#mq/1\n"
+
+// wireHeader 线上格式中的信封部分
+type wireHeader struct {
+	ID        string            `json:"id,omitempty"`
+	Key       string            `json:"key,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Timestamp int64             `json:"ts,omitempty"` // 毫秒时间戳
+	Attempt   int               `json:"attempt,omitempty"`
+}
+
+// MarshalWire 将消息信封与消息体编码为版本化线上格式。
+func MarshalWire(m Message) []byte {
+	h := wireHeader{
+		ID:      m.ID,
+		Key:     m.Key,
+		Headers: m.Headers,
+		Attempt: m.Attempt,
+	}
+	if !m.Timestamp.IsZero() {
+		h.Timestamp = m.Timestamp.UnixMilli()
+	}
+	header, _ := json.Marshal(h)
+
+	buf := make([]byte, 0, len(wireMagic)+len(header)+1+len(m.Data))
+	buf = append(buf, wireMagic...)
+	buf = append(buf, header...)
+	buf = append(buf, '\n')
+	buf = append(buf, m.Data...)
+	return buf
+}
+
+// UnmarshalWire 解析版本化线上格式，填充 m 的信封字段与 Data。
+// 非信封格式（如旧版本生产者写入的原始消息体）整体作为 Data，信封字段保持零值；
+// Attempt 缺省为 1。
+func UnmarshalWire(m *Message, raw []byte) {
+	m.Data = raw
+	if m.Attempt <= 0 {
+		m.Attempt = 1
+	}
+	if !bytes.HasPrefix(raw, []byte(wireMagic)) {
+		return
+	}
+
+	rest := raw[len(wireMagic):]
+	idx := bytes.IndexByte(rest, '\n')
+	if idx < 0 {
+		return
+	}
+	var h wireHeader
+	if err := json.Unmarshal(rest[:idx], &h); err != nil {
+		return
+	}
+
+	m.Data = rest[idx+1:]
+	m.ID = h.ID
+	m.Key = h.Key
+	m.Headers = h.Headers
+	if h.Timestamp > 0 {
+		m.Timestamp = time.UnixMilli(h.Timestamp)
+	}
+	if h.Attempt > 0 {
+		m.Attempt = h.Attempt
+	}
+}
+
+// NewMessageID 生成随机消息 ID（32 位十六进制字符串）
+func NewMessageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
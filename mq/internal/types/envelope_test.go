@@ -0,0 +1,48 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWire_RoundTrip(t *testing.T) {
+	at := time.UnixMilli(time.Now().UnixMilli())
+	msg := NewRedisMessage("q", []byte("hello\nworld"))
+	msg.ID = "id-1"
+	msg.Key = "order-1"
+	msg.Headers = map[string]string{"traceparent": "00-abc"}
+	msg.Timestamp = at
+	msg.Attempt = 3
+
+	got := NewRedisMessage("q", nil)
+	UnmarshalWire(&got, MarshalWire(msg))
+	assert.Equal(t, []byte("hello\nworld"), got.Data)
+	assert.Equal(t, "id-1", got.ID)
+	assert.Equal(t, "order-1", got.Key)
+	assert.Equal(t, "00-abc", got.Header("traceparent"))
+	assert.True(t, at.Equal(got.Timestamp))
+	assert.Equal(t, 3, got.Attempt)
+	assert.True(t, got.IsRedis())
+}
+
+func TestWire_LegacyRawBody(t *testing.T) {
+	// 旧版本生产者写入的原始消息体整体作为 Data
+	for _, raw := range []string{`{"traceparent":"x","a":1}`, "plain text", "", "#mq/1\nnot-json\nbody", "#mq/1\n{}"} {
+		var msg Message
+		UnmarshalWire(&msg, []byte(raw))
+		assert.Equal(t, raw, string(msg.Data))
+		assert.Empty(t, msg.ID)
+		assert.Nil(t, msg.Headers)
+		assert.True(t, msg.Timestamp.IsZero())
+		assert.Equal(t, 1, msg.Attempt)
+	}
+}
+
+func TestWire_EmptyEnvelope(t *testing.T) {
+	var msg Message
+	UnmarshalWire(&msg, MarshalWire(Message{Data: []byte("body")}))
+	assert.Equal(t, []byte("body"), msg.Data)
+	assert.Equal(t, 1, msg.Attempt)
+}
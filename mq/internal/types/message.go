@@ -1,11 +1,12 @@
 // Package types 定义 MQ 子系统共享的统一消息类型。
-// 外部包必须通过构造函数（NewRedisMessage / NewKafkaMessage / NewHttpsqSMessage）创建 Message，
+// 外部包必须通过构造函数（NewRedisMessage / NewKafkaMessage / NewHttpsqSMessage / NewMemoryMessage / NewAMQPMessage）创建 Message，
 // 以保证 MQ 类型与字段的一致性。
 package types
 
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // mqSystem 标识消息所属的 MQ 子系统。
@@ -15,6 +16,8 @@ const (
 	systemRedis   mqSystem = iota // Redis 队列
 	systemKafka                   // Kafka
 	systemHttpsqs                 // HTTPSQS
+	systemMemory                  // 进程内内存队列
+	systemAMQP                    // RabbitMQ / AMQP 0-9-1
 )
 
 // String 返回 MQ 子系统的可读名称。
@@ -26,6 +29,10 @@ func (s mqSystem) String() string {
 		return "kafka"
 	case systemHttpsqs:
 		return "httpsqs"
+	case systemMemory:
+		return "memory"
+	case systemAMQP:
+		return "amqp"
 	default:
 		return "unknown"
 	}
@@ -36,13 +43,24 @@ var ErrWrongMQType = errors.New("mq: accessor called on wrong message type")
 
 // Message 是跨 MQ 子系统的统一消息类型。
 // 私有 system 字段阻止外部直接构造不一致的组合；
-// 必须使用 NewRedisMessage / NewKafkaMessage / NewHttpsqSMessage 构造。
+// 必须使用 NewRedisMessage / NewKafkaMessage / NewHttpsqSMessage / NewMemoryMessage / NewAMQPMessage 构造。
 type Message struct {
 	system mqSystem // 私有：阻止外部构造无效组合
-	Queue  string   // 目标名称（redis/httpsqs=队列名，kafka=topic）
+	Queue  string   // 目标名称（redis/httpsqs/memory/amqp=队列名，kafka=topic）
 	Data   []byte   // 消息体
 	Group  string   // Kafka 专属：消费者组
 	Pos    int64    // HTTPSQS 专属：队列位置
+
+	Partition int32 // Kafka 专属：record 所在分区
+	Offset    int64 // Kafka 专属：record offset
+
+	// 消息信封：kafka 映射为原生 record key/headers/timestamp，
+	// redis/httpsqs 通过版本化线上格式传输（见 MarshalWire）。
+	ID        string            // 消息 ID，生产时自动生成
+	Key       string            // 业务键（kafka=record key）
+	Headers   map[string]string // 消息头，trace context 通过消息头传播
+	Timestamp time.Time         // 生产时间，旧格式消息为零值
+	Attempt   int               // 第几次处理，首次为 1
 }
 
 // NewRedisMessage 创建 Redis 队列消息。
@@ -74,6 +92,32 @@ func NewHttpsqSMessage(queue string, data []byte, pos int64) Message {
 	}
 }
 
+// NewMemoryMessage 创建进程内内存队列消息。
+func NewMemoryMessage(queue string, data []byte) Message {
+	return Message{
+		system: systemMemory,
+		Queue:  queue,
+		Data:   data,
+	}
+}
+
+// NewAMQPMessage 创建 AMQP 队列消息。
+func NewAMQPMessage(queue string, data []byte) Message {
+	return Message{
+		system: systemAMQP,
+		Queue:  queue,
+		Data:   data,
+	}
+}
+
+// Header 返回指定消息头的值，不存在时返回空字符串。
+func (m Message) Header(key string) string {
+	return m.Headers[key]
+}
+
+// System 返回消息所属 MQ 子系统的名称（redis / kafka / httpsqs / memory / amqp）。
+func (m Message) System() string { return m.system.String() }
+
 // IsRedis 报告消息是否来自 Redis 队列。
 func (m Message) IsRedis() bool { return m.system == systemRedis }
 
@@ -83,6 +127,12 @@ func (m Message) IsKafka() bool { return m.system == systemKafka }
 // IsHttpsqs 报告消息是否来自 HTTPSQS。
 func (m Message) IsHttpsqs() bool { return m.system == systemHttpsqs }
 
+// IsMemory 报告消息是否来自进程内内存队列。
+func (m Message) IsMemory() bool { return m.system == systemMemory }
+
+// IsAMQP 报告消息是否来自 AMQP 队列。
+func (m Message) IsAMQP() bool { return m.system == systemAMQP }
+
 // KafkaGroup 返回 Kafka 消费者组。
 // 若消息不是 Kafka 类型则返回错误。
 func (m Message) KafkaGroup() (string, error) {
@@ -112,6 +162,8 @@ func SetStrictMode(enabled bool) { strictMode = enabled }
 //   - Redis: Group 须为空，Pos 须为零
 //   - Kafka: Group 须非空，Pos 须为零
 //   - HTTPSQS: Group 须为空，Pos 须非零
+//   - Memory: Group 须为空，Pos 须为零
+//   - AMQP: Group 须为空，Pos 须为零
 //
 // 非严格模式始终返回 nil。
 func (m Message) Validate() error {
@@ -141,6 +193,20 @@ func (m Message) Validate() error {
 		if m.Pos == 0 {
 			return fmt.Errorf("mq: httpsqs message must have non-zero Pos")
 		}
+	case systemMemory:
+		if m.Group != "" {
+			return fmt.Errorf("mq: memory message must not have Group, got %q", m.Group)
+		}
+		if m.Pos != 0 {
+			return fmt.Errorf("mq: memory message must not have Pos, got %d", m.Pos)
+		}
+	case systemAMQP:
+		if m.Group != "" {
+			return fmt.Errorf("mq: amqp message must not have Group, got %q", m.Group)
+		}
+		if m.Pos != 0 {
+			return fmt.Errorf("mq: amqp message must not have Pos, got %d", m.Pos)
+		}
 	}
 	return nil
 }
@@ -40,6 +40,22 @@ func TestNewHttpsqSMessage(t *testing.T) {
 
 // --- 类型检查方法 ---
 
+func TestNewMemoryMessage(t *testing.T) {
+	msg := NewMemoryMessage("q", []byte("data"))
+	assert.Equal(t, "q", msg.Queue)
+	assert.True(t, msg.IsMemory())
+	assert.False(t, msg.IsRedis())
+	assert.False(t, NewRedisMessage("q", nil).IsMemory())
+}
+
+func TestNewAMQPMessage(t *testing.T) {
+	msg := NewAMQPMessage("q", []byte("data"))
+	assert.Equal(t, "q", msg.Queue)
+	assert.True(t, msg.IsAMQP())
+	assert.False(t, msg.IsMemory())
+	assert.Equal(t, "amqp", msg.System())
+}
+
 func TestMessage_IsRedis(t *testing.T) {
 	assert.True(t, NewRedisMessage("q", nil).IsRedis())
 	assert.False(t, NewKafkaMessage("g", "t", nil).IsRedis())
@@ -114,6 +130,8 @@ func TestMqSystem_String(t *testing.T) {
 	assert.Equal(t, "redis", systemRedis.String())
 	assert.Equal(t, "kafka", systemKafka.String())
 	assert.Equal(t, "httpsqs", systemHttpsqs.String())
+	assert.Equal(t, "memory", systemMemory.String())
+	assert.Equal(t, "amqp", systemAMQP.String())
 }
 
 // --- Validate 测试 ---
@@ -273,3 +291,14 @@ func TestMqSystem_String_Unknown(t *testing.T) {
 	unknown := mqSystem(99)
 	assert.Equal(t, "unknown", unknown.String())
 }
+
+func TestMessage_Validate_Strict_Memory(t *testing.T) {
+	strictMode = true
+	defer func() { strictMode = false }()
+
+	msg := NewMemoryMessage("q", []byte("data"))
+	assert.NoError(t, msg.Validate())
+
+	msg.Group = "g"
+	assert.Error(t, msg.Validate())
+}
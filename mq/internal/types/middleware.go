@@ -0,0 +1,15 @@
+package types
+
+// Middleware handler 中间件，包装 IHandler 以添加横切逻辑（日志、鉴权、租户提取、校验等）
+type Middleware func(IHandler) IHandler
+
+// Chain 按顺序组合中间件：mws[0] 位于最外层，最先执行。
+// mws 中的 nil 元素被忽略。
+func Chain(h IHandler, mws ...Middleware) IHandler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i] != nil {
+			h = mws[i](h)
+		}
+	}
+	return h
+}
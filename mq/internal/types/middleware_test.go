@@ -0,0 +1,46 @@
+package types
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next IHandler) IHandler {
+		return FuncHandler(func(ctx context.Context, msg Message) error {
+			*calls = append(*calls, name+":before")
+			err := next.Handle(ctx, msg)
+			*calls = append(*calls, name+":after")
+			return err
+		})
+	}
+}
+
+func TestChain_Order(t *testing.T) {
+	var calls []string
+	h := FuncHandler(func(context.Context, Message) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+
+	wrapped := Chain(h, recordingMiddleware("a", &calls), nil, recordingMiddleware("b", &calls))
+	require.NoError(t, wrapped.Handle(context.Background(), Message{}))
+	assert.Equal(t, []string{"a:before", "b:before", "handler", "b:after", "a:after"}, calls)
+}
+
+func TestChain_Empty(t *testing.T) {
+	h := FuncHandler(func(context.Context, Message) error { return nil })
+	assert.NotNil(t, Chain(h))
+}
+
+func TestWithMiddleware_Appends(t *testing.T) {
+	var calls []string
+	cfg := ApplyRegisterOptions([]RegisterOption{
+		WithMiddleware(recordingMiddleware("a", &calls)),
+		WithMiddleware(recordingMiddleware("b", &calls)),
+	})
+	assert.Len(t, cfg.Middlewares, 2)
+}
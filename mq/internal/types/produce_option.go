@@ -1,11 +1,24 @@
 package types
 
+import (
+	"errors"
+	"maps"
+	"time"
+)
+
+// ErrDelayNotSupported 表示当前 MQ 实现不支持延迟投递（WithDelay / WithDeliverAt）。
+var ErrDelayNotSupported = errors.New("mq: delayed delivery not supported")
+
 // ProduceOption 生产消息时的配置选项
 type ProduceOption func(*ProduceConfig)
 
 // ProduceConfig 生产配置
 type ProduceConfig struct {
-	OrderKey string // kafka 专有：有序生产的分区键
+	OrderKey  string            // kafka 专有：有序生产的分区键；其他实现写入消息信封 Key
+	MessageID string            // 消息 ID，为空时自动生成
+	Headers   map[string]string // 自定义消息头
+	Delay     time.Duration     // 延迟投递时长，相对于生产时刻
+	DeliverAt time.Time         // 定时投递时间，优先于 Delay
 }
 
 // ApplyProduceOptions 应用选项并返回解析后的配置
@@ -17,9 +30,65 @@ func ApplyProduceOptions(opts []ProduceOption) *ProduceConfig {
 	return cfg
 }
 
+// Stamp 按生产配置填充出站消息的信封字段：ID、Key、Headers 与生产时间。
+// Headers 会被复制，后续注入 trace context 不影响调用方传入的 map。
+func (c *ProduceConfig) Stamp(m *Message) {
+	m.ID = c.MessageID
+	if m.ID == "" {
+		m.ID = NewMessageID()
+	}
+	m.Key = c.OrderKey
+	m.Headers = make(map[string]string, len(c.Headers))
+	maps.Copy(m.Headers, c.Headers)
+	m.Timestamp = time.Now()
+}
+
+// DeliveryTime 返回延迟投递的目标时间。
+// 未设置延迟或目标时间不晚于当前时间时 delayed 为 false，应立即投递。
+func (c *ProduceConfig) DeliveryTime() (at time.Time, delayed bool) {
+	now := time.Now()
+	switch {
+	case !c.DeliverAt.IsZero():
+		at = c.DeliverAt
+	case c.Delay > 0:
+		at = now.Add(c.Delay)
+	default:
+		return time.Time{}, false
+	}
+	return at, at.After(now)
+}
+
 // WithOrderKey 设置 Kafka 有序生产的分区键。
 // 原 kafka.IProducer.ProduceOrdered 合并为此选项。
-// 非 Kafka 实现收到此选项时忽略。
+// 非 Kafka 实现将其写入消息信封的 Key。
 func WithOrderKey(key string) ProduceOption {
 	return func(c *ProduceConfig) { c.OrderKey = key }
 }
+
+// WithMessageID 指定消息 ID，未指定时自动生成。
+// 仅对 Produce 生效，ProduceBatch 中每条消息均自动生成 ID。
+func WithMessageID(id string) ProduceOption {
+	return func(c *ProduceConfig) { c.MessageID = id }
+}
+
+// WithHeaders 设置自定义消息头，可多次调用，同名消息头以后设置的为准。
+func WithHeaders(headers map[string]string) ProduceOption {
+	return func(c *ProduceConfig) {
+		if c.Headers == nil {
+			c.Headers = make(map[string]string, len(headers))
+		}
+		maps.Copy(c.Headers, headers)
+	}
+}
+
+// WithDelay 延迟 d 后投递消息，d <= 0 时立即投递。
+// 不支持延迟投递的实现返回 ErrDelayNotSupported。
+func WithDelay(d time.Duration) ProduceOption {
+	return func(c *ProduceConfig) { c.Delay = d }
+}
+
+// WithDeliverAt 在指定时间投递消息，时间已过时立即投递。
+// 同时设置 WithDelay 时以 WithDeliverAt 为准；不支持延迟投递的实现返回 ErrDelayNotSupported。
+func WithDeliverAt(at time.Time) ProduceOption {
+	return func(c *ProduceConfig) { c.DeliverAt = at }
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -42,3 +43,61 @@ func TestWithOrderKey_EmptyKey(t *testing.T) {
 	WithOrderKey("")(cfg)
 	assert.Empty(t, cfg.OrderKey)
 }
+
+// --- 信封选项 ---
+
+func TestWithHeaders_Merge(t *testing.T) {
+	cfg := ApplyProduceOptions([]ProduceOption{
+		WithHeaders(map[string]string{"a": "1", "b": "2"}),
+		WithHeaders(map[string]string{"b": "3"}),
+	})
+	assert.Equal(t, map[string]string{"a": "1", "b": "3"}, cfg.Headers)
+}
+
+func TestProduceConfig_Stamp(t *testing.T) {
+	headers := map[string]string{"tenant": "t1"}
+	cfg := ApplyProduceOptions([]ProduceOption{WithMessageID("id-1"), WithOrderKey("k"), WithHeaders(headers)})
+
+	msg := NewRedisMessage("q", []byte("x"))
+	cfg.Stamp(&msg)
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "k", msg.Key)
+	assert.Equal(t, "t1", msg.Header("tenant"))
+	assert.False(t, msg.Timestamp.IsZero())
+
+	// Headers 被复制，修改不影响调用方
+	msg.Headers["traceparent"] = "x"
+	assert.NotContains(t, headers, "traceparent")
+}
+
+func TestProduceConfig_Stamp_GeneratesID(t *testing.T) {
+	cfg := ApplyProduceOptions(nil)
+	var a, b Message
+	cfg.Stamp(&a)
+	cfg.Stamp(&b)
+	assert.Len(t, a.ID, 32)
+	assert.NotEqual(t, a.ID, b.ID)
+}
+
+// --- 延迟投递 ---
+
+func TestProduceConfig_DeliveryTime(t *testing.T) {
+	_, delayed := ApplyProduceOptions(nil).DeliveryTime()
+	assert.False(t, delayed)
+
+	at, delayed := ApplyProduceOptions([]ProduceOption{WithDelay(time.Minute)}).DeliveryTime()
+	assert.True(t, delayed)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), at, time.Second)
+
+	// WithDeliverAt 优先于 WithDelay
+	target := time.Now().Add(time.Hour)
+	at, delayed = ApplyProduceOptions([]ProduceOption{WithDelay(time.Minute), WithDeliverAt(target)}).DeliveryTime()
+	assert.True(t, delayed)
+	assert.Equal(t, target, at)
+
+	// 已过期的时间立即投递
+	_, delayed = ApplyProduceOptions([]ProduceOption{WithDeliverAt(time.Now().Add(-time.Second))}).DeliveryTime()
+	assert.False(t, delayed)
+	_, delayed = ApplyProduceOptions([]ProduceOption{WithDelay(-time.Second)}).DeliveryTime()
+	assert.False(t, delayed)
+}
@@ -8,6 +8,7 @@ type RegisterConfig struct {
 	Group       string        // kafka 专有：consumer group
 	ExtraTopics []string      // kafka 专有：额外 topic
 	QueueOpts   []QueueOption // httpsqs 专有：队列级别配置
+	Middlewares []Middleware  // 注册级中间件，位于服务级中间件之内
 }
 
 // ApplyRegisterOptions 应用选项并返回解析后的配置
@@ -29,6 +30,11 @@ func WithExtraTopics(topics ...string) RegisterOption {
 	return func(c *RegisterConfig) { c.ExtraTopics = append(c.ExtraTopics, topics...) }
 }
 
+// WithMiddleware 为本次注册的 handler 追加中间件，位于服务级中间件之内，按传入顺序由外到内执行。
+func WithMiddleware(mws ...Middleware) RegisterOption {
+	return func(c *RegisterConfig) { c.Middlewares = append(c.Middlewares, mws...) }
+}
+
 // WithQueueOptions 设置 HTTPSQS 队列级别配置。
 func WithQueueOptions(opts ...QueueOption) RegisterOption {
 	return func(c *RegisterConfig) { c.QueueOpts = append(c.QueueOpts, opts...) }
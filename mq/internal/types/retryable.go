@@ -0,0 +1,70 @@
+package types
+
+import "errors"
+
+// nonRetryable 由不可重试的错误实现。
+// 重试策略遇到此类错误时跳过剩余重试，直接进入死信 / 失败处理流程。
+type nonRetryable interface {
+	NonRetryable() bool
+}
+
+// IsNonRetryable 报告 err 链中是否包含不可重试的错误
+func IsNonRetryable(err error) bool {
+	var nr nonRetryable
+	return errors.As(err, &nr) && nr.NonRetryable()
+}
+
+// PermanentError 不可重试的错误，由 Permanent 创建
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	if e.Err == nil {
+		return "mq: permanent failure"
+	}
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// NonRetryable 永久失败不可重试
+func (e *PermanentError) NonRetryable() bool { return true }
+
+// Permanent 将 err 标记为不可重试：handler 返回此错误时，消息跳过剩余重试，
+// 直接进入死信处理器或失败回调。err 为 nil 时返回 nil。
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// TransientError 依赖服务暂时不可用等临时错误，由 Transient 创建。
+// 编解码器返回此错误时 TypedHandler 不将其视为解码失败，消息按普通处理失败进入重试流程。
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	if e.Err == nil {
+		return "mq: transient failure"
+	}
+	return e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// Transient 将 err 标记为临时错误（如编解码器访问外部服务失败）。err 为 nil 时返回 nil。
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TransientError{Err: err}
+}
+
+// IsTransient 报告 err 链中是否包含临时错误
+func IsTransient(err error) bool {
+	var te *TransientError
+	return errors.As(err, &te)
+}
\ No newline at end of file
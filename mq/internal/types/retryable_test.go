@@ -0,0 +1,22 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermanent(t *testing.T) {
+	assert.NoError(t, Permanent(nil))
+
+	base := errors.New("invalid order")
+	err := Permanent(base)
+	assert.EqualError(t, err, "invalid order")
+	assert.ErrorIs(t, err, base)
+	assert.True(t, IsNonRetryable(err))
+	assert.True(t, IsNonRetryable(fmt.Errorf("handle: %w", err)), "wrapped permanent error stays non-retryable")
+	assert.False(t, IsNonRetryable(base))
+	assert.False(t, IsNonRetryable(nil))
+}
@@ -0,0 +1,142 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"maps"
+)
+
+// DecodeError 消息体解码失败。
+// 解码失败不会因重试而成功，重试策略不再重试，直接进入死信 / 失败处理流程。
+type DecodeError struct {
+	Codec string // 解码使用的编解码器名称
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("mq: decode message with codec %q: %v", e.Codec, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// NonRetryable 解码错误不可重试
+func (e *DecodeError) NonRetryable() bool { return true }
+
+// CodecOption 类型化 handler / producer 的编解码配置选项
+type CodecOption func(*codecConfig)
+
+// codecConfig 编解码配置（未导出）
+type codecConfig struct {
+	codec Codec
+}
+
+// WithCodec 设置编解码器（默认 JSONCodec）。
+// 对 handler 而言仅在消息未携带 HeaderCodec 消息头时使用，携带时按消息头查找已注册的编解码器。
+func WithCodec(c Codec) CodecOption {
+	return func(cfg *codecConfig) {
+		if c != nil {
+			cfg.codec = c
+		}
+	}
+}
+
+func applyCodecOptions(opts []CodecOption) codecConfig {
+	cfg := codecConfig{codec: JSONCodec{}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// TypedHandler 类型化 handler：将消息体解码为 T 后调用处理函数。
+// 解码失败返回 *DecodeError，消息不经重试直接进入死信 / 失败处理流程。
+// 编解码器返回 Transient 标记的临时错误时原样返回，消息按普通处理失败重试。
+type TypedHandler[T any] struct {
+	fn    func(ctx context.Context, msg Message, v T) error
+	codec Codec
+}
+
+// NewTypedHandler 创建类型化 handler
+func NewTypedHandler[T any](fn func(ctx context.Context, msg Message, v T) error, opts ...CodecOption) *TypedHandler[T] {
+	cfg := applyCodecOptions(opts)
+	return &TypedHandler[T]{fn: fn, codec: cfg.codec}
+}
+
+func (h *TypedHandler[T]) Handle(ctx context.Context, msg Message) error {
+	v, err := h.Decode(msg)
+	if err != nil {
+		return err
+	}
+	return h.fn(ctx, msg, v)
+}
+
+// Decode 按消息头 HeaderCodec（缺省时为 handler 的编解码器）将消息体解码为 T
+func (h *TypedHandler[T]) Decode(msg Message) (T, error) {
+	var v T
+	c := h.codec
+	if name := msg.Header(HeaderCodec); name != "" && name != c.Name() {
+		found, ok := LookupCodec(name)
+		if !ok {
+			return v, &DecodeError{Codec: name, Err: fmt.Errorf("codec not registered")}
+		}
+		c = found
+	}
+	if err := c.Unmarshal(msg.Data, &v); err != nil {
+		// 临时错误（如 schema 注册中心不可用）与消息内容无关，保持可重试
+		if IsTransient(err) {
+			return v, err
+		}
+		return v, &DecodeError{Codec: c.Name(), Err: err}
+	}
+	return v, nil
+}
+
+// TypedProducer 类型化生产者：将 T 编码后发送，并在消息头 HeaderCodec 记录编解码器名称
+type TypedProducer[T any] struct {
+	producer IProducer
+	codec    Codec
+}
+
+// NewTypedProducer 基于 IProducer 创建类型化生产者，不接管 producer 的生命周期
+func NewTypedProducer[T any](producer IProducer, opts ...CodecOption) *TypedProducer[T] {
+	cfg := applyCodecOptions(opts)
+	return &TypedProducer[T]{producer: producer, codec: cfg.codec}
+}
+
+// Produce 编码并发送一条消息
+func (p *TypedProducer[T]) Produce(ctx context.Context, dest string, v T, opts ...ProduceOption) error {
+	data, err := p.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("mq: encode message with codec %q: %w", p.codec.Name(), err)
+	}
+	return p.producer.Produce(ctx, dest, data, p.withCodecHeader(opts)...)
+}
+
+// ProduceBatch 编码并批量发送消息，任一消息编码失败时不发送
+func (p *TypedProducer[T]) ProduceBatch(ctx context.Context, dest string, vs []T, opts ...ProduceOption) error {
+	messages := make([][]byte, len(vs))
+	for i, v := range vs {
+		data, err := p.codec.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("mq: encode message %d with codec %q: %w", i, p.codec.Name(), err)
+		}
+		messages[i] = data
+	}
+	return p.producer.ProduceBatch(ctx, dest, messages, p.withCodecHeader(opts)...)
+}
+
+// withCodecHeader 追加编解码器消息头；调用方显式设置的 HeaderCodec 不会被覆盖
+func (p *TypedProducer[T]) withCodecHeader(opts []ProduceOption) []ProduceOption {
+	header := func(c *ProduceConfig) {
+		if _, ok := c.Headers[HeaderCodec]; ok {
+			return
+		}
+		headers := make(map[string]string, len(c.Headers)+1)
+		maps.Copy(headers, c.Headers)
+		headers[HeaderCodec] = p.codec.Name()
+		c.Headers = headers
+	}
+	out := make([]ProduceOption, 0, len(opts)+1)
+	out = append(out, opts...)
+	return append(out, header)
+}
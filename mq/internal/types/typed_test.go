@@ -0,0 +1,126 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedHandler_Decodes(t *testing.T) {
+	var got codecOrder
+	h := NewTypedHandler(func(_ context.Context, _ Message, v codecOrder) error {
+		got = v
+		return nil
+	})
+
+	msg := NewMemoryMessage("orders", []byte(`{"id":7,"name":"x"}`))
+	require.NoError(t, h.Handle(context.Background(), msg))
+	assert.Equal(t, codecOrder{ID: 7, Name: "x"}, got)
+}
+
+func TestTypedHandler_CodecFromHeader(t *testing.T) {
+	data, err := MsgpackCodec{}.Marshal(codecOrder{ID: 3})
+	require.NoError(t, err)
+
+	var got codecOrder
+	h := NewTypedHandler(func(_ context.Context, _ Message, v codecOrder) error {
+		got = v
+		return nil
+	})
+	msg := NewMemoryMessage("orders", data)
+	msg.Headers = map[string]string{HeaderCodec: "msgpack"}
+	require.NoError(t, h.Handle(context.Background(), msg))
+	assert.Equal(t, 3, got.ID)
+}
+
+func TestTypedHandler_DecodeErrorIsNonRetryable(t *testing.T) {
+	called := false
+	h := NewTypedHandler(func(context.Context, Message, codecOrder) error {
+		called = true
+		return nil
+	})
+
+	err := h.Handle(context.Background(), NewMemoryMessage("orders", []byte("not json")))
+	var de *DecodeError
+	require.ErrorAs(t, err, &de)
+	assert.Equal(t, "json", de.Codec)
+	assert.True(t, IsNonRetryable(err))
+	assert.False(t, called)
+
+	msg := NewMemoryMessage("orders", []byte(`{}`))
+	msg.Headers = map[string]string{HeaderCodec: "unknown"}
+	err = h.Handle(context.Background(), msg)
+	require.ErrorAs(t, err, &de)
+	assert.Equal(t, "unknown", de.Codec)
+
+	assert.False(t, IsNonRetryable(errors.New("plain")))
+}
+
+// transientCodec 解码时返回临时错误的测试编解码器
+type transientCodec struct{ JSONCodec }
+
+func (transientCodec) Unmarshal([]byte, any) error {
+	return Transient(errors.New("registry unavailable"))
+}
+
+func TestTypedHandler_TransientErrorIsRetryable(t *testing.T) {
+	h := NewTypedHandler(func(context.Context, Message, codecOrder) error { return nil },
+		WithCodec(transientCodec{}))
+
+	err := h.Handle(context.Background(), NewMemoryMessage("orders", []byte(`{}`)))
+	require.Error(t, err)
+	var de *DecodeError
+	assert.False(t, errors.As(err, &de))
+	assert.True(t, IsTransient(err))
+	assert.False(t, IsNonRetryable(err))
+}
+
+// captureProducer 记录发送内容的测试 producer
+type captureProducer struct {
+	dest     string
+	messages [][]byte
+	cfg      *ProduceConfig
+}
+
+func (p *captureProducer) Start(context.Context) error    { return nil }
+func (p *captureProducer) Shutdown(context.Context) error { return nil }
+
+func (p *captureProducer) Produce(ctx context.Context, dest string, message []byte, opts ...ProduceOption) error {
+	return p.ProduceBatch(ctx, dest, [][]byte{message}, opts...)
+}
+
+func (p *captureProducer) ProduceBatch(_ context.Context, dest string, messages [][]byte, opts ...ProduceOption) error {
+	p.dest = dest
+	p.messages = messages
+	p.cfg = ApplyProduceOptions(opts)
+	return nil
+}
+
+func TestTypedProducer_EncodesWithHeader(t *testing.T) {
+	cp := &captureProducer{}
+	p := NewTypedProducer[codecOrder](cp, WithCodec(MsgpackCodec{}))
+
+	require.NoError(t, p.Produce(context.Background(), "orders", codecOrder{ID: 9},
+		WithHeaders(map[string]string{"tenant": "t1"})))
+	assert.Equal(t, "orders", cp.dest)
+	assert.Equal(t, "msgpack", cp.cfg.Headers[HeaderCodec])
+	assert.Equal(t, "t1", cp.cfg.Headers["tenant"])
+
+	var got codecOrder
+	require.NoError(t, MsgpackCodec{}.Unmarshal(cp.messages[0], &got))
+	assert.Equal(t, 9, got.ID)
+
+	require.NoError(t, p.ProduceBatch(context.Background(), "orders", []codecOrder{{ID: 1}, {ID: 2}}))
+	assert.Len(t, cp.messages, 2)
+	assert.Equal(t, "msgpack", cp.cfg.Headers[HeaderCodec])
+}
+
+func TestTypedProducer_EncodeError(t *testing.T) {
+	cp := &captureProducer{}
+	p := NewTypedProducer[codecOrder](cp, WithCodec(ProtobufCodec{}))
+	assert.Error(t, p.Produce(context.Background(), "orders", codecOrder{}))
+	assert.Nil(t, cp.messages)
+}
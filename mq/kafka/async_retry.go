@@ -11,6 +11,7 @@ import (
 	"github.com/gomooth/pkg/framework/retry"
 	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
 	"github.com/gomooth/pkg/mq/internal/types"
 )
 
@@ -49,6 +50,10 @@ type asyncRetryEngine struct {
 	metrics       *metrics.ConsumerMetrics
 	failedHandler types.FailedHandlerFunc
 	deadLetter    types.DeadLetterHandler
+	retryIf       func(error) bool
+
+	// 背压（可选）
+	backpressure *backpressure
 }
 
 const (
@@ -117,6 +122,29 @@ func (e *asyncRetryEngine) SetDeadLetterHandler(h types.DeadLetterHandler) {
 	e.deadLetter = h
 }
 
+// SetRetryIf 设置错误重试谓词
+func (e *asyncRetryEngine) SetRetryIf(fn func(error) bool) {
+	e.retryIf = fn
+}
+
+// SetBackpressure 设置背压控制：分区重试积压达到 high 时暂停拉取，回落到 low 及以下时恢复。
+// 存储未实现 BacklogStore 时返回 false。
+func (e *asyncRetryEngine) SetBackpressure(high, low int, flow *flowController) bool {
+	backlog, ok := e.store.(BacklogStore)
+	if !ok {
+		return false
+	}
+	e.backpressure = &backpressure{high: high, low: low, backlog: backlog, flow: flow}
+	return true
+}
+
+// checkBackpressure 分区积压变化后检查背压水位
+func (e *asyncRetryEngine) checkBackpressure(topic string, partition int32) {
+	if e.backpressure != nil {
+		e.backpressure.check(topic, partition)
+	}
+}
+
 func (e *asyncRetryEngine) SetSession(session sarama.ConsumerGroupSession) {
 	e.sessionMu.Lock()
 	e.session = session
@@ -188,8 +216,9 @@ func (e *asyncRetryEngine) OnMessage(ctx context.Context, session sarama.Consume
 	// 修复 P9：统一应用 handlerTimeout
 	msgCtx, cancel := e.applyHandlerTimeout(ctx)
 	defer cancel()
+	defer e.checkBackpressure(msg.Topic, msg.Partition)
 
-	kafkaMsg := types.NewKafkaMessage(e.consumerGroup, msg.Topic, msg.Value)
+	kafkaMsg := newKafkaMessage(e.consumerGroup, msg, 1)
 	err := e.handler.Handle(msgCtx, kafkaMsg)
 
 	if err == nil {
@@ -200,9 +229,19 @@ func (e *asyncRetryEngine) OnMessage(ctx context.Context, session sarama.Consume
 		return
 	}
 
+	// 不可重试的错误不进入 RetryStore，直接进入死信流程
+	if !mqretry.Retryable(err, e.retryIf) {
+		result := handlePermanent(ctx, kafkaMsg, err,
+			e.deadLetter, e.failedHandler, e.logger, e.metrics)
+		if result == exhaustedHandled {
+			e.strategy.OnExhausted(ctx, session, &RetryItem{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset})
+		}
+		return
+	}
+
 	// maxRetry == 0 表示不重试
 	if e.maxRetry == 0 {
-		result := handleExhausted(ctx, e.consumerGroup, msg.Topic, msg.Value, err,
+		result := handleExhausted(ctx, kafkaMsg, err,
 			e.deadLetter, e.failedHandler, e.logger, e.metrics)
 		if result == exhaustedHandled {
 			e.strategy.OnExhausted(ctx, session, &RetryItem{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset})
@@ -228,6 +267,7 @@ func (e *asyncRetryEngine) OnMessage(ctx context.Context, session sarama.Consume
 		Key:           msg.Key,
 		Value:         msg.Value,
 		Headers:       saramaHeadersToPublic(msg.Headers),
+		Timestamp:     msg.Timestamp,
 		Attempt:       1,
 		NextRetryAt:   nextRetryAt,
 		ConsumerGroup: e.consumerGroup,
@@ -239,7 +279,7 @@ func (e *asyncRetryEngine) OnMessage(ctx context.Context, session sarama.Consume
 				"topic", msg.Topic, "offset", msg.Offset, "error", storeErr)
 		}
 		// Schedule 失败意味着 pending 未被标记（或已满），无需 RemovePending
-		result := handleExhausted(ctx, e.consumerGroup, msg.Topic, msg.Value, err,
+		result := handleExhausted(ctx, kafkaMsg, err,
 			e.deadLetter, e.failedHandler, e.logger, e.metrics)
 		if result == exhaustedHandled {
 			e.strategy.OnScheduleFailed(ctx, session, &RetryItem{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset})
@@ -270,8 +310,9 @@ func (e *asyncRetryEngine) startWorkers(ctx context.Context) {
 func (e *asyncRetryEngine) processRetry(ctx context.Context, item *RetryItem) {
 	msgCtx, cancel := e.applyHandlerTimeout(ctx)
 	defer cancel()
+	defer e.checkBackpressure(item.Topic, item.Partition)
 
-	kafkaMsg := types.NewKafkaMessage(e.consumerGroup, item.Topic, item.Value)
+	kafkaMsg := retryItemMessage(e.consumerGroup, item)
 	err := e.handler.Handle(msgCtx, kafkaMsg)
 
 	if err == nil {
@@ -283,6 +324,15 @@ func (e *asyncRetryEngine) processRetry(ctx context.Context, item *RetryItem) {
 		return
 	}
 
+	if !mqretry.Retryable(err, e.retryIf) {
+		result := handlePermanent(ctx, kafkaMsg, err,
+			e.deadLetter, e.failedHandler, e.logger, e.metrics)
+		if result == exhaustedHandled {
+			e.strategy.OnExhausted(ctx, e.getSession(), item)
+		}
+		return
+	}
+
 	if item.Attempt < e.maxRetry {
 		if e.metrics != nil {
 			e.metrics.OnRetry()
@@ -294,6 +344,7 @@ func (e *asyncRetryEngine) processRetry(ctx context.Context, item *RetryItem) {
 			Key:           item.Key,
 			Value:         item.Value,
 			Headers:       item.Headers,
+			Timestamp:     item.Timestamp,
 			Attempt:       item.Attempt + 1,
 			NextRetryAt:   time.Now().Add(e.backoff.Delay(uint(item.Attempt))),
 			ConsumerGroup: item.ConsumerGroup,
@@ -308,7 +359,7 @@ func (e *asyncRetryEngine) processRetry(ctx context.Context, item *RetryItem) {
 	}
 
 	// 重试耗尽
-	result := handleExhausted(ctx, e.consumerGroup, item.Topic, item.Value, err,
+	result := handleExhausted(ctx, kafkaMsg, err,
 		e.deadLetter, e.failedHandler, e.logger, e.metrics)
 	if result == exhaustedHandled {
 		e.strategy.OnExhausted(ctx, e.getSession(), item)
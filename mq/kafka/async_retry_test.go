@@ -214,6 +214,34 @@ func TestAsyncRetry_OnMessageFail_WithRetry(t *testing.T) {
 	engine.ClearSession()
 }
 
+func TestAsyncRetry_OnMessageFail_NonRetryable(t *testing.T) {
+	var calls atomic.Int32
+	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+		calls.Add(1)
+		return &types.DecodeError{Codec: "json", Err: errors.New("bad")}
+	})
+
+	// 不可重试的错误不进入 RetryStore，直接耗尽处理
+	store := &nonWatermarkMockStore{}
+	engine := newAsyncRetryEngineWithStore("test-group", handler, 3,
+		&retry.ExponentialDelay{Base: time.Millisecond, Max: time.Second},
+		0, 1, store, nil, nil)
+
+	session := newMockSession()
+	engine.SetSession(session)
+
+	msg := &sarama.ConsumerMessage{
+		Topic: "test", Partition: 0, Offset: 1, Value: []byte("hello"),
+	}
+	engine.OnMessage(context.Background(), session, msg)
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Zero(t, store.scheduleCalled.Load())
+	assert.Len(t, session.marks, 1)
+
+	engine.ClearSession()
+}
+
 func TestAsyncRetry_OnMessageFail_ScheduleError(t *testing.T) {
 	var calls atomic.Int32
 	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
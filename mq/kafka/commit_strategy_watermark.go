@@ -9,6 +9,10 @@ import (
 	"github.com/gomooth/pkg/mq/internal/logutil"
 )
 
+// watermarkPollInterval 无新重试项通知时 worker 检查到期重试项的间隔。
+// 重试项按退避延迟入队，仅依赖入队通知时，分区暂停（无新消息）后已入队的重试将无法到期执行。
+const watermarkPollInterval = 100 * time.Millisecond
+
 // topicPartition 用于 watermarkStrategy 中 trackedParts map 的 key
 type topicPartition struct {
 	topic     string
@@ -64,6 +68,8 @@ func (s *watermarkStrategy) StartWorkers(ctx context.Context, wg *sync.WaitGroup
 		}()
 
 		notifyCh := s.wmStore.Notify()
+		poll := time.NewTicker(watermarkPollInterval)
+		defer poll.Stop()
 		for {
 			items, err := s.wmStore.Fetch(ctx, time.Now(), 1)
 			if ctx.Err() != nil {
@@ -79,6 +85,7 @@ func (s *watermarkStrategy) StartWorkers(ctx context.Context, wg *sync.WaitGroup
 
 			select {
 			case <-notifyCh:
+			case <-poll.C:
 			case <-ctx.Done():
 				return
 			}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,7 +12,9 @@ import (
 	"github.com/gomooth/pkg/framework/retry"
 	"github.com/gomooth/pkg/framework/xcode"
 	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/middleware"
 	"github.com/gomooth/pkg/mq/internal/types"
 	"github.com/gomooth/pkg/mq/kafka/internal"
 	"github.com/gomooth/xerror"
@@ -25,6 +28,7 @@ type consumerRegistration struct {
 	topics  []string
 	handler *groupHandler
 	cg      sarama.ConsumerGroup
+	flow    *flowController
 }
 
 // consumerEngine 消费者生命周期引擎（未导出）
@@ -35,10 +39,17 @@ type consumerEngine struct {
 
 	registrations []consumerRegistration
 	regMu         sync.Mutex
+
+	// 死信 topic 与重试 topic 链使用的生产者，Start 时创建
+	producerMu sync.RWMutex
+	producer   sarama.SyncProducer
 }
 
 // 编译时接口检查
-var _ types.IConsumeServer = (*consumerEngine)(nil)
+var (
+	_ types.IConsumeServer = (*consumerEngine)(nil)
+	_ TopicPauser          = (*consumerEngine)(nil)
+)
 
 func newConsumerEngine(brokers []string, cfg *consumerConfig) *consumerEngine {
 	logger := cfg.logger
@@ -49,14 +60,7 @@ func newConsumerEngine(brokers []string, cfg *consumerConfig) *consumerEngine {
 	// 初始化 sarama 全局日志器（仅首次调用生效）
 	internal.InitSaramaLogger(logger)
 
-	saramaConfig := cfg.saramaConfig
-	if saramaConfig == nil {
-		timeout := cfg.timeout
-		if timeout <= 0 {
-			timeout = 5 * time.Second
-		}
-		saramaConfig = internal.BuildConsumerConfig(timeout)
-	}
+	saramaConfig := cfg.consumerSaramaConfig()
 
 	m := metrics.NewConsumerMetrics("kafka")
 
@@ -72,7 +76,7 @@ func newConsumerEngine(brokers []string, cfg *consumerConfig) *consumerEngine {
 
 	// 预注册配置中的消费者
 	for _, reg := range cfg.consumers {
-		if err := eng.createRegistration(reg.Group, reg.Handler, reg.Topics, saramaConfig); err != nil {
+		if err := eng.createRegistration(reg.Group, reg.Handler, reg.Topics, nil, saramaConfig); err != nil {
 			logger.Error("failed to create consumer group registration", "group", reg.Group, "error", err)
 		}
 	}
@@ -96,16 +100,9 @@ func (e *consumerEngine) Register(dest string, handler types.IHandler, opts ...t
 
 	allTopics := append([]string{dest}, cfg.ExtraTopics...)
 
-	saramaConfig := e.config.saramaConfig
-	if saramaConfig == nil {
-		timeout := e.config.timeout
-		if timeout <= 0 {
-			timeout = 5 * time.Second
-		}
-		saramaConfig = internal.BuildConsumerConfig(timeout)
-	}
+	saramaConfig := e.config.consumerSaramaConfig()
 
-	if err := e.createRegistration(cfg.Group, handler, allTopics, saramaConfig); err != nil {
+	if err := e.createRegistration(cfg.Group, handler, allTopics, cfg.Middlewares, saramaConfig); err != nil {
 		return err
 	}
 	return nil
@@ -117,7 +114,7 @@ func (e *consumerEngine) Count() uint {
 	return uint(len(e.registrations))
 }
 
-func (e *consumerEngine) createRegistration(group string, handler types.IHandler, topics []string, saramaConfig *sarama.Config) error {
+func (e *consumerEngine) createRegistration(group string, handler types.IHandler, topics []string, mws []types.Middleware, saramaConfig *sarama.Config) error {
 	for _, t := range topics {
 		if len(t) == 0 {
 			return xerror.New("kafka: topic must not be empty")
@@ -137,32 +134,63 @@ func (e *consumerEngine) createRegistration(group string, handler types.IHandler
 		}
 	}
 
-	// 检查 handler 是否实现 DeadLetterHandler
+	// 检查 handler 是否实现 DeadLetterHandler，未实现时使用内置死信 topic（若启用）
 	var deadLetter types.DeadLetterHandler
 	if dl, ok := handler.(types.DeadLetterHandler); ok {
 		deadLetter = dl
+	} else if e.config.deadLetterTopic != nil {
+		deadLetter = &deadLetterPublisher{
+			group:   group,
+			topicOf: e.config.deadLetterTopic,
+			publish: e.publish,
+		}
 	}
 
+	// 中间件顺序：内置 → 服务级 → 注册级
+	middlewares := append(middleware.Server(e.config.noDefaultMiddlewares, e.Logger, e.config.panicHandler, e.config.middlewares), mws...)
+
+	flow := newFlowController(cg, logutil.NewSlogLogger(e.Logger))
+
 	gh := newGroupHandler(group, &groupHandlerConf{
 		Logger:                   e.Logger,
 		Handler:                  handler,
+		Middlewares:              middlewares,
+		PanicHandler:             e.config.panicHandler,
 		MaxRetry:                 e.config.maxRetry,
 		Backoff:                  e.config.backoff,
 		FailedHandler:            failedHandler,
 		DeadLetter:               deadLetter,
+		RetryIf:                  e.config.retryIf,
 		RetryMode:                e.config.retryMode,
 		RetryWorkers:             e.config.retryWorkers,
 		RetryStore:               e.config.retryStore,
 		Metrics:                  e.Metrics,
 		HandlerTimeout:           e.config.handlerTimeout,
 		SyncRetryMaxTotalTimeout: e.config.syncRetryMaxTotalTimeout,
+		Topics:                   topics,
+		RetryTopics:              e.config.retryTopics,
+		Publish:                  e.publish,
+		TransactionalIDPrefix:    e.config.transactionalIDPrefix,
+		DelayTopic:               e.config.delayTopic,
+		NewTxProducer:            e.newTxProducer,
+		Flow:                     flow,
+		BackpressureHigh:         e.config.backpressureHigh,
+		BackpressureLow:          e.config.backpressureLow,
+		KeyWorkers:               e.config.keyWorkers,
 	})
 
+	// 重试 topic 链：同一消费者组同时订阅各级重试 topic（exactly-once 模式不使用重试 topic）
+	subscribed := append([]string(nil), topics...)
+	if e.config.transactionalIDPrefix == "" {
+		subscribed = append(subscribed, retryTopicsOf(topics, group, e.config.retryTopics)...)
+	}
+
 	e.registrations = append(e.registrations, consumerRegistration{
 		group:   group,
-		topics:  topics,
+		topics:  subscribed,
 		handler: gh,
 		cg:      cg,
+		flow:    flow,
 	})
 	return nil
 }
@@ -189,6 +217,27 @@ func (e *consumerEngine) Start(ctx context.Context) error {
 		return xerror.NewXCode(xcode.ErrMQConsume, "no consumers registered")
 	}
 
+	if e.config.topicAdmin != nil {
+		if _, err := e.config.topicAdmin.Ensure(ctx); err != nil {
+			cancel()
+			e.State.Store(engine.Idle)
+			return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+		}
+	}
+
+	// exactly-once 模式的死信在分区事务 producer 内发布，无需共享生产者
+	if e.config.transactionalIDPrefix == "" && (e.config.deadLetterTopic != nil || len(e.config.retryTopics) > 0) {
+		producer, err := sarama.NewSyncProducer(e.brokers, e.producerConfig())
+		if err != nil {
+			cancel()
+			e.State.Store(engine.Idle)
+			return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+		}
+		e.producerMu.Lock()
+		e.producer = producer
+		e.producerMu.Unlock()
+	}
+
 	for _, reg := range regs {
 		e.WG.Add(1)
 		r := reg
@@ -244,6 +293,66 @@ func (e *consumerEngine) handle(ctx context.Context, cg sarama.ConsumerGroup, to
 	}
 }
 
+// PreStop 暂停所有消费者组的分区拉取，已拉取的消息继续处理。
+// 实现 app.PreStopper 接口；随后的 Shutdown 负责排空重试队列并关闭消费者组。
+func (e *consumerEngine) PreStop(_ context.Context) error {
+	if e.State.Load() != engine.Running {
+		return nil
+	}
+
+	e.regMu.Lock()
+	regs := make([]consumerRegistration, len(e.registrations))
+	copy(regs, e.registrations)
+	e.regMu.Unlock()
+
+	for _, reg := range regs {
+		if reg.cg != nil {
+			reg.cg.PauseAll()
+		}
+	}
+	return nil
+}
+
+// PauseTopics 暂停 topic 在所有消费者组中的拉取，已拉取的消息继续处理。
+// 暂停状态在 rebalance 后保持，直到 ResumeTopics。
+func (e *consumerEngine) PauseTopics(topics ...string) {
+	for _, reg := range e.snapshot() {
+		reg.flow.pauseTopics(topics...)
+	}
+}
+
+// ResumeTopics 恢复 topic 的拉取；因背压暂停的分区待重试积压回落后恢复
+func (e *consumerEngine) ResumeTopics(topics ...string) {
+	for _, reg := range e.snapshot() {
+		reg.flow.resumeTopics(topics...)
+	}
+}
+
+// PausedTopics 返回手动暂停的 topic
+func (e *consumerEngine) PausedTopics() []string {
+	seen := make(map[string]bool)
+	var topics []string
+	for _, reg := range e.snapshot() {
+		for _, t := range reg.flow.pausedTopics() {
+			if !seen[t] {
+				seen[t] = true
+				topics = append(topics, t)
+			}
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// snapshot 返回注册信息的副本
+func (e *consumerEngine) snapshot() []consumerRegistration {
+	e.regMu.Lock()
+	defer e.regMu.Unlock()
+	regs := make([]consumerRegistration, len(e.registrations))
+	copy(regs, e.registrations)
+	return regs
+}
+
 func (e *consumerEngine) Shutdown(ctx context.Context) error {
 	if !e.RequestShutdown() {
 		if e.State.Load() == engine.Idle {
@@ -290,6 +399,48 @@ func (e *consumerEngine) Shutdown(ctx context.Context) error {
 	case <-ctx.Done():
 	}
 
+	e.producerMu.Lock()
+	if e.producer != nil {
+		if err := e.producer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		e.producer = nil
+	}
+	e.producerMu.Unlock()
+
 	e.State.Store(engine.Closed)
 	return firstErr
+}
+
+// producerConfig 死信 / 重试 topic 生产者配置。
+// 自定义 sarama.Config 同时用于消费与发布，须开启 Producer.Return.Successes（SyncProducer 要求）。
+func (e *consumerEngine) producerConfig() *sarama.Config {
+	if e.config.saramaConfig != nil {
+		return e.config.saramaConfig
+	}
+	timeout := e.config.timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return internal.BuildProducerConfig(timeout)
+}
+
+// newTxProducer 为 exactly-once 消费创建分区专属的事务生产者
+func (e *consumerEngine) newTxProducer(transactionalID string) (sarama.SyncProducer, error) {
+	return sarama.NewSyncProducer(e.brokers, internal.TransactionalProducerConfig(e.producerConfig(), transactionalID))
+}
+
+// publish 同步发布死信 / 重试消息
+func (e *consumerEngine) publish(msg *sarama.ProducerMessage) error {
+	e.producerMu.RLock()
+	producer := e.producer
+	e.producerMu.RUnlock()
+
+	if producer == nil {
+		return xerror.NewXCode(xcode.ErrMQPublish, "dead letter producer not started")
+	}
+	if _, _, err := producer.SendMessage(msg); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	return nil
 }
\ No newline at end of file
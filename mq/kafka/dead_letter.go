@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+const (
+	// DefaultDeadLetterSuffix 死信 topic 默认后缀，死信写入 <topic>.DLT
+	DefaultDeadLetterSuffix = ".DLT"
+
+	// HeaderOriginalTopic 死信 / 重试消息的原始 topic
+	HeaderOriginalTopic = "mq-original-topic"
+	// HeaderOriginalPartition 死信 / 重试消息的原始分区
+	HeaderOriginalPartition = "mq-original-partition"
+	// HeaderOriginalOffset 死信 / 重试消息的原始 offset
+	HeaderOriginalOffset = "mq-original-offset"
+	// HeaderConsumerGroup 处理失败的消费者组
+	HeaderConsumerGroup = "mq-consumer-group"
+	// HeaderAttempt 死信消息为已处理次数，重试消息为下一次是第几次处理
+	HeaderAttempt = "mq-attempt"
+	// HeaderError 最后一次处理失败的错误信息
+	HeaderError = "mq-error"
+)
+
+// failureHeaders 死信 / 重试流程写入的 record headers，重新投递或交给 handler 前移除
+var failureHeaders = map[string]bool{
+	HeaderOriginalTopic:     true,
+	HeaderOriginalPartition: true,
+	HeaderOriginalOffset:    true,
+	HeaderConsumerGroup:     true,
+	HeaderAttempt:           true,
+	HeaderError:             true,
+	HeaderDeliverAt:         true,
+}
+
+// deadLetterTopicFunc 根据原始 topic 计算死信 topic
+type deadLetterTopicFunc func(topic string) string
+
+// suffixTopic 返回在原始 topic 后追加后缀的命名函数
+func suffixTopic(suffix string) deadLetterTopicFunc {
+	return func(topic string) string { return topic + suffix }
+}
+
+// deadLetterPublisher 内置死信 topic 发布器，实现 types.DeadLetterHandler。
+// 重试耗尽的消息连同原始位置、最后错误与处理次数写入死信 topic。
+type deadLetterPublisher struct {
+	group   string
+	topicOf deadLetterTopicFunc
+	publish func(msg *sarama.ProducerMessage) error
+}
+
+var _ types.DeadLetterHandler = (*deadLetterPublisher)(nil)
+
+// OnDeadLetter 发布死信，失败时返回错误（offset 不提交）。
+// exactly-once 消费时在当前事务内发布，死信与 offset 一并提交。
+func (p *deadLetterPublisher) OnDeadLetter(ctx context.Context, msg types.Message, lastErr error) error {
+	record := failureRecord(p.topicOf(msg.Queue), p.group, msg, lastErr, msg.Attempt)
+	if tx, ok := ctx.Value(txKey{}).(*producerTx); ok {
+		return tx.publish(record)
+	}
+	return p.publish(record)
+}
+
+// failureRecord 构造死信 / 重试 record：保留原始 key、value、timestamp 与消息信封，
+// 追加原始位置、消费者组、处理次数与错误信息。msg.Queue/Partition/Offset 须为原始位置。
+func failureRecord(topic, group string, msg types.Message, lastErr error, attempt int) *sarama.ProducerMessage {
+	out := &sarama.ProducerMessage{
+		Topic:     topic,
+		Value:     sarama.ByteEncoder(msg.Data),
+		Timestamp: msg.Timestamp,
+		Headers:   make([]sarama.RecordHeader, 0, len(msg.Headers)+7),
+	}
+	if msg.Key != "" {
+		out.Key = sarama.ByteEncoder(msg.Key)
+	}
+	if msg.ID != "" {
+		out.Headers = append(out.Headers, sarama.RecordHeader{Key: []byte(HeaderMessageID), Value: []byte(msg.ID)})
+	}
+	for k, v := range msg.Headers {
+		if failureHeaders[k] {
+			continue
+		}
+		out.Headers = append(out.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	errText := ""
+	if lastErr != nil {
+		errText = lastErr.Error()
+	}
+	out.Headers = append(out.Headers,
+		sarama.RecordHeader{Key: []byte(HeaderOriginalTopic), Value: []byte(msg.Queue)},
+		sarama.RecordHeader{Key: []byte(HeaderOriginalPartition), Value: []byte(strconv.FormatInt(int64(msg.Partition), 10))},
+		sarama.RecordHeader{Key: []byte(HeaderOriginalOffset), Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		sarama.RecordHeader{Key: []byte(HeaderConsumerGroup), Value: []byte(group)},
+		sarama.RecordHeader{Key: []byte(HeaderAttempt), Value: []byte(strconv.Itoa(attempt))},
+		sarama.RecordHeader{Key: []byte(HeaderError), Value: []byte(errText)},
+	)
+	return out
+}
@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureRecord(t *testing.T) {
+	ts := time.UnixMilli(1700000000000)
+	msg := newKafkaMessage("g", &sarama.ConsumerMessage{
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("k"),
+		Value:     []byte("hello"),
+		Timestamp: ts,
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(HeaderMessageID), Value: []byte("id-1")},
+			{Key: []byte("trace"), Value: []byte("t1")},
+			{Key: []byte(HeaderError), Value: []byte("stale")},
+		},
+	}, 3)
+
+	out := failureRecord("orders.DLT", "g", msg, errors.New("boom"), msg.Attempt)
+
+	assert.Equal(t, "orders.DLT", out.Topic)
+	assert.Equal(t, sarama.ByteEncoder("k"), out.Key)
+	assert.Equal(t, sarama.ByteEncoder("hello"), out.Value)
+	assert.Equal(t, ts, out.Timestamp)
+	assert.Equal(t, "id-1", headerValue(out.Headers, HeaderMessageID))
+	assert.Equal(t, "t1", headerValue(out.Headers, "trace"))
+	assert.Equal(t, "orders", headerValue(out.Headers, HeaderOriginalTopic))
+	assert.Equal(t, "2", headerValue(out.Headers, HeaderOriginalPartition))
+	assert.Equal(t, "42", headerValue(out.Headers, HeaderOriginalOffset))
+	assert.Equal(t, "g", headerValue(out.Headers, HeaderConsumerGroup))
+	assert.Equal(t, "3", headerValue(out.Headers, HeaderAttempt))
+	assert.Equal(t, "boom", headerValue(out.Headers, HeaderError))
+
+	errCount := 0
+	for _, h := range out.Headers {
+		if string(h.Key) == HeaderError {
+			errCount++
+		}
+	}
+	assert.Equal(t, 1, errCount, "stale failure headers should be replaced")
+}
+
+func TestDeadLetterPublisher(t *testing.T) {
+	var sent *sarama.ProducerMessage
+	p := &deadLetterPublisher{
+		group:   "g",
+		topicOf: suffixTopic(DefaultDeadLetterSuffix),
+		publish: func(msg *sarama.ProducerMessage) error {
+			sent = msg
+			return nil
+		},
+	}
+
+	msg := newKafkaMessage("g", &sarama.ConsumerMessage{Topic: "orders", Offset: 7, Value: []byte("x")}, 4)
+	require.NoError(t, p.OnDeadLetter(context.Background(), msg, errors.New("boom")))
+	require.NotNil(t, sent)
+	assert.Equal(t, "orders.DLT", sent.Topic)
+	assert.Equal(t, "4", headerValue(sent.Headers, HeaderAttempt))
+
+	p.publish = func(*sarama.ProducerMessage) error { return errors.New("broker down") }
+	assert.Error(t, p.OnDeadLetter(context.Background(), msg, errors.New("boom")))
+}
+
+func TestDeadLetter_ExhaustedPublishFailureNotCommitted(t *testing.T) {
+	handler := types.FuncHandler(func(context.Context, types.Message) error { return errors.New("boom") })
+	gh := newGroupHandler("g", &groupHandlerConf{
+		Handler: handler,
+		DeadLetter: &deadLetterPublisher{
+			group:   "g",
+			topicOf: suffixTopic(DefaultDeadLetterSuffix),
+			publish: func(*sarama.ProducerMessage) error { return errors.New("broker down") },
+		},
+		Logger: slog.Default(),
+	})
+
+	ch := consumerMessages("a")
+	close(ch)
+	session := newMockSession()
+	require.NoError(t, gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}))
+	assert.Empty(t, session.marks, "offset must not be committed when dead letter publish fails")
+}
+
+func TestReplayMessage(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Topic: "orders.DLT",
+		Key:   []byte("k"),
+		Value: []byte("hello"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(HeaderMessageID), Value: []byte("id-1")},
+			{Key: []byte(HeaderOriginalTopic), Value: []byte("orders")},
+			{Key: []byte(HeaderOriginalOffset), Value: []byte("42")},
+			{Key: []byte(HeaderAttempt), Value: []byte("4")},
+			{Key: []byte(HeaderError), Value: []byte("boom")},
+		},
+	}
+
+	out, ok := replayMessage(msg)
+	require.True(t, ok)
+	assert.Equal(t, "orders", out.Topic)
+	assert.Equal(t, sarama.ByteEncoder("k"), out.Key)
+	assert.Equal(t, []sarama.RecordHeader{{Key: []byte(HeaderMessageID), Value: []byte("id-1")}}, out.Headers,
+		"failure headers should be stripped")
+
+	_, ok = replayMessage(&sarama.ConsumerMessage{Value: []byte("no origin")})
+	assert.False(t, ok)
+}
+
+func TestDeadLetterReplayer_ReplayMessages(t *testing.T) {
+	r := NewDeadLetterReplayer([]string{"localhost:9092"})
+
+	ch := make(chan *sarama.ConsumerMessage, 4)
+	origin := []*sarama.RecordHeader{{Key: []byte(HeaderOriginalTopic), Value: []byte("orders")}}
+	ch <- &sarama.ConsumerMessage{Offset: 5, Value: []byte("a"), Headers: origin}
+	ch <- &sarama.ConsumerMessage{Offset: 6, Value: []byte("malformed")}
+	ch <- &sarama.ConsumerMessage{Offset: 7, Value: []byte("b"), Headers: origin}
+	ch <- &sarama.ConsumerMessage{Offset: 8, Value: []byte("after end"), Headers: origin}
+
+	var sent []string
+	var marked []int64
+	n, err := r.replayMessages(context.Background(), ch, 8, time.Second,
+		func(msg *sarama.ProducerMessage) error {
+			v, _ := msg.Value.Encode()
+			sent = append(sent, string(v))
+			return nil
+		},
+		func(offset int64) { marked = append(marked, offset) })
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"a", "b"}, sent, "replay should stop at the end offset captured at start")
+	assert.Equal(t, []int64{5, 6, 7}, marked, "malformed dead letters are skipped but committed")
+}
+
+func TestDeadLetterReplayer_ReplayMessagesStopsWhenIdle(t *testing.T) {
+	r := NewDeadLetterReplayer([]string{"localhost:9092"})
+
+	// offset 4 为事务提交标记，end=5 但最后投递的消息 offset 为 3
+	ch := make(chan *sarama.ConsumerMessage, 1)
+	ch <- &sarama.ConsumerMessage{Offset: 3, Value: []byte("a"), Headers: []*sarama.RecordHeader{
+		{Key: []byte(HeaderOriginalTopic), Value: []byte("orders")},
+	}}
+
+	var marked []int64
+	done := make(chan struct{})
+	var n int
+	var err error
+	go func() {
+		n, err = r.replayMessages(context.Background(), ch, 5, 20*time.Millisecond,
+			func(*sarama.ProducerMessage) error { return nil },
+			func(offset int64) { marked = append(marked, offset) })
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("replay should stop when no more records are delivered")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []int64{3}, marked, "undelivered control records are not committed")
+}
+
+func TestDeadLetterReplayer_ReplayMessagesSendError(t *testing.T) {
+	r := NewDeadLetterReplayer([]string{"localhost:9092"})
+
+	ch := make(chan *sarama.ConsumerMessage, 1)
+	ch <- &sarama.ConsumerMessage{Offset: 0, Headers: []*sarama.RecordHeader{
+		{Key: []byte(HeaderOriginalTopic), Value: []byte("orders")},
+	}}
+
+	var marked []int64
+	n, err := r.replayMessages(context.Background(), ch, 10, time.Second,
+		func(*sarama.ProducerMessage) error { return errors.New("broker down") },
+		func(offset int64) { marked = append(marked, offset) })
+
+	assert.Error(t, err)
+	assert.Zero(t, n)
+	assert.Empty(t, marked, "failed replay must not advance progress")
+}
@@ -0,0 +1,320 @@
+package kafka
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/kafka/internal"
+	"github.com/gomooth/xerror"
+)
+
+const (
+	// DefaultDelayTopic 默认延迟 topic
+	DefaultDelayTopic = "mq.delay"
+	// DefaultDelaySchedulerGroup 延迟调度器默认消费者组
+	DefaultDelaySchedulerGroup = "mq.delay.scheduler"
+
+	// HeaderDeliverAt 延迟消息的投递时间（毫秒时间戳）
+	HeaderDeliverAt = "mq-deliver-at"
+	// HeaderDelayTarget 延迟消息的目标 topic
+	HeaderDelayTarget = "mq-delay-target"
+)
+
+// routeDelayed 将延迟消息改写到延迟 topic，目标 topic 与投递时间写入 record headers
+func routeDelayed(msg *sarama.ProducerMessage, delayTopic string, at time.Time) {
+	msg.Headers = append(msg.Headers,
+		sarama.RecordHeader{Key: []byte(HeaderDelayTarget), Value: []byte(msg.Topic)},
+		sarama.RecordHeader{Key: []byte(HeaderDeliverAt), Value: []byte(strconv.FormatInt(at.UnixMilli(), 10))},
+	)
+	msg.Topic = delayTopic
+}
+
+// ==================== DelayScheduler ====================
+
+// DelaySchedulerOption 延迟调度器配置选项
+type DelaySchedulerOption func(*delaySchedulerConfig)
+
+// delaySchedulerConfig 延迟调度器配置（未导出）
+type delaySchedulerConfig struct {
+	logger       *slog.Logger
+	timeout      time.Duration
+	group        string
+	topics       []string
+	saramaConfig *sarama.Config
+}
+
+// WithSchedulerLogger 设置延迟调度器日志器
+func WithSchedulerLogger(l *slog.Logger) DelaySchedulerOption {
+	return func(c *delaySchedulerConfig) {
+		c.logger = l
+	}
+}
+
+// WithSchedulerGroup 设置延迟调度器的消费者组（默认 DefaultDelaySchedulerGroup）
+func WithSchedulerGroup(group string) DelaySchedulerOption {
+	return func(c *delaySchedulerConfig) {
+		c.group = group
+	}
+}
+
+// WithSchedulerTopics 设置延迟调度器消费的延迟 topic（默认 DefaultDelayTopic）
+func WithSchedulerTopics(topics ...string) DelaySchedulerOption {
+	return func(c *delaySchedulerConfig) {
+		c.topics = topics
+	}
+}
+
+// WithSchedulerSaramaConfig 设置自定义 sarama.Config，同时用于消费延迟 topic 与转发消息，
+// 须开启 Producer.Return.Successes（SyncProducer 要求）
+func WithSchedulerSaramaConfig(cfg *sarama.Config) DelaySchedulerOption {
+	return func(c *delaySchedulerConfig) {
+		c.saramaConfig = cfg
+	}
+}
+
+// DelayScheduler 延迟消息调度器：消费延迟 topic，到期后将消息转发到目标 topic。
+//
+// 同一分区内的消息按写入顺序转发，较早写入的长延迟消息会阻塞其后的短延迟消息；
+// 延迟差异较大的场景可为不同延迟使用不同的延迟 topic（见 WithProducerDelayTopic）。
+// 实现 app.IApp、app.HealthChecker 和 app.PreStopper 接口。
+type DelayScheduler struct {
+	engine.Base
+	brokers []string
+	opt     *delaySchedulerConfig
+
+	cg       sarama.ConsumerGroup
+	producer sarama.SyncProducer
+}
+
+// NewDelayScheduler 创建延迟消息调度器
+func NewDelayScheduler(brokers []string, opts ...DelaySchedulerOption) *DelayScheduler {
+	cfg := delaySchedulerConfig{
+		timeout: 5 * time.Second,
+		group:   DefaultDelaySchedulerGroup,
+		topics:  []string{DefaultDelayTopic},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	internal.InitSaramaLogger(logger)
+
+	return &DelayScheduler{
+		Base:    engine.Base{Logger: logger},
+		brokers: brokers,
+		opt:     &cfg,
+	}
+}
+
+// Start 连接 Kafka 并开始调度延迟消息
+func (s *DelayScheduler) Start(ctx context.Context) error {
+	if !s.TryStart() {
+		if s.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(xcode.ErrMQConsume, "delay scheduler already closed")
+	}
+
+	consumerConfig := s.opt.saramaConfig
+	producerConfig := s.opt.saramaConfig
+	if consumerConfig == nil {
+		consumerConfig = internal.BuildConsumerConfig(s.opt.timeout)
+		producerConfig = internal.BuildProducerConfig(s.opt.timeout)
+	}
+
+	producer, err := sarama.NewSyncProducer(s.brokers, producerConfig)
+	if err != nil {
+		s.State.Store(engine.Idle)
+		return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	cg, err := sarama.NewConsumerGroup(s.brokers, s.opt.group, consumerConfig)
+	if err != nil {
+		_ = producer.Close()
+		s.State.Store(engine.Idle)
+		return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	s.producer = producer
+	s.cg = cg
+
+	engineCtx, cancel := context.WithCancel(ctx)
+	s.CancelFunc = cancel
+
+	handler := &delayHandler{
+		logger: s.Logger,
+		forward: func(msg *sarama.ProducerMessage) error {
+			_, _, err := producer.SendMessage(msg)
+			return err
+		},
+	}
+
+	s.WG.Add(1)
+	s.SafeGo("delay-scheduler", func() {
+		defer s.WG.Done()
+		backoff := &retry.ExponentialDelay{Base: time.Second, Max: 30 * time.Second, Jitter: true}
+		attempt := uint(0)
+		for engineCtx.Err() == nil {
+			if err := cg.Consume(engineCtx, s.opt.topics, handler); err != nil {
+				s.Logger.Error("delay scheduler consume failed", "topics", s.opt.topics, "error", err)
+				select {
+				case <-engineCtx.Done():
+					return
+				case <-time.After(backoff.Delay(attempt)):
+				}
+				attempt++
+				continue
+			}
+			attempt = 0
+		}
+	}, nil)
+
+	return nil
+}
+
+// PreStop 暂停延迟 topic 的拉取，实现 app.PreStopper 接口
+func (s *DelayScheduler) PreStop(_ context.Context) error {
+	if s.State.Load() == engine.Running && s.cg != nil {
+		s.cg.PauseAll()
+	}
+	return nil
+}
+
+// Shutdown 停止调度并关闭连接，未到期的消息保留在延迟 topic 中，重启后继续调度
+func (s *DelayScheduler) Shutdown(ctx context.Context) error {
+	if !s.RequestShutdown() {
+		if s.State.Load() == engine.Idle {
+			s.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	if s.CancelFunc != nil {
+		s.CancelFunc()
+	}
+	var firstErr error
+	if err := s.cg.Close(); err != nil {
+		firstErr = err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.WG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	if err := s.producer.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	s.State.Store(engine.Closed)
+	return firstErr
+}
+
+// delayHandler 延迟 topic 的 sarama.ConsumerGroupHandler：按分区顺序等待消息到期后转发
+type delayHandler struct {
+	logger  *slog.Logger
+	forward func(msg *sarama.ProducerMessage) error
+}
+
+func (h *delayHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *delayHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *delayHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+	messages := claim.Messages()
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			if !h.dispatch(ctx, msg) {
+				return nil
+			}
+			session.MarkMessage(msg, "")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// dispatch 等待消息到期并转发到目标 topic，转发失败时退避重试。
+// 会话结束时返回 false，消息不提交，由下一次分配继续调度。
+func (h *delayHandler) dispatch(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	out, at, ok := delayedTarget(msg)
+	if !ok {
+		h.logger.Error("drop malformed delayed message",
+			"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+		return true
+	}
+
+	if wait := time.Until(at); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+
+	backoff := &retry.ExponentialDelay{Base: 100 * time.Millisecond, Max: 30 * time.Second, Jitter: true}
+	for attempt := uint(0); ; attempt++ {
+		err := h.forward(out)
+		if err == nil {
+			return true
+		}
+		h.logger.Warn("forward delayed message failed",
+			"target", out.Topic, "offset", msg.Offset, "attempt", attempt+1, "error", err)
+		select {
+		case <-time.After(backoff.Delay(attempt)):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// delayedTarget 从延迟 topic 的消息还原目标消息，并返回投递时间。
+// 缺少目标 topic 或投递时间的消息返回 ok=false。
+func delayedTarget(msg *sarama.ConsumerMessage) (out *sarama.ProducerMessage, at time.Time, ok bool) {
+	out = &sarama.ProducerMessage{
+		Value:     sarama.ByteEncoder(msg.Value),
+		Timestamp: msg.Timestamp,
+	}
+	if msg.Key != nil {
+		out.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	var deliverAt string
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		switch string(h.Key) {
+		case HeaderDelayTarget:
+			out.Topic = string(h.Value)
+		case HeaderDeliverAt:
+			deliverAt = string(h.Value)
+		default:
+			out.Headers = append(out.Headers, sarama.RecordHeader{Key: h.Key, Value: h.Value})
+		}
+	}
+
+	ms, err := strconv.ParseInt(deliverAt, 10, 64)
+	if out.Topic == "" || err != nil {
+		return nil, time.Time{}, false
+	}
+	return out, time.UnixMilli(ms), true
+}
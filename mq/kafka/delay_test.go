@@ -0,0 +1,162 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/pkg/mq/kafka/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func headerValue(headers []sarama.RecordHeader, key string) string {
+	for _, h := range headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestProducerEngine_ProduceDelayed(t *testing.T) {
+	cfg := internal.BuildProducerConfig(5 * time.Second)
+	mockProducer := mocks.NewSyncProducer(t, cfg)
+	defer mockProducer.Close()
+
+	at := time.Now().Add(time.Hour)
+	mockProducer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		if msg.Topic != "custom.delay" {
+			return errors.New("delayed message should be routed to delay topic, got " + msg.Topic)
+		}
+		if got := headerValue(msg.Headers, HeaderDelayTarget); got != "orders" {
+			return errors.New("unexpected delay target: " + got)
+		}
+		if got := headerValue(msg.Headers, HeaderDeliverAt); got != strconv.FormatInt(at.UnixMilli(), 10) {
+			return errors.New("unexpected deliver at: " + got)
+		}
+		return nil
+	})
+
+	eng := newProducerEngine([]string{"localhost:9092"}, &producerConfig{delayTopic: "custom.delay"})
+	eng.Base = engine.Base{Logger: slog.Default(), Metrics: metrics.NewProducerMetrics("kafka")}
+	eng.inner = mockProducer
+	eng.State.Store(engine.Running)
+
+	err := eng.Produce(context.Background(), "orders", []byte("hello"), types.WithDeliverAt(at))
+	assert.NoError(t, err)
+}
+
+func TestDelayedTarget(t *testing.T) {
+	at := time.Now().Add(time.Minute)
+	msg := &sarama.ConsumerMessage{
+		Topic: DefaultDelayTopic,
+		Key:   []byte("k"),
+		Value: []byte("hello"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(HeaderDelayTarget), Value: []byte("orders")},
+			{Key: []byte(HeaderDeliverAt), Value: []byte(strconv.FormatInt(at.UnixMilli(), 10))},
+			{Key: []byte(HeaderMessageID), Value: []byte("id-1")},
+		},
+	}
+
+	out, got, ok := delayedTarget(msg)
+	require.True(t, ok)
+	assert.Equal(t, "orders", out.Topic)
+	assert.Equal(t, at.UnixMilli(), got.UnixMilli())
+	assert.Equal(t, []sarama.RecordHeader{{Key: []byte(HeaderMessageID), Value: []byte("id-1")}}, out.Headers,
+		"delay headers should be stripped")
+
+	_, _, ok = delayedTarget(&sarama.ConsumerMessage{Value: []byte("no headers")})
+	assert.False(t, ok)
+}
+
+func TestDelayHandler_ForwardsWhenDue(t *testing.T) {
+	var forwarded []*sarama.ProducerMessage
+	h := &delayHandler{
+		logger: slog.Default(),
+		forward: func(msg *sarama.ProducerMessage) error {
+			forwarded = append(forwarded, msg)
+			return nil
+		},
+	}
+
+	deliverAt := time.Now().Add(100 * time.Millisecond)
+	claim := &mockClaim{closeAfterMessages: true, msgs: []*sarama.ConsumerMessage{
+		{Topic: DefaultDelayTopic, Offset: 1, Value: []byte("bad")},
+		{Topic: DefaultDelayTopic, Offset: 2, Value: []byte("hello"), Headers: []*sarama.RecordHeader{
+			{Key: []byte(HeaderDelayTarget), Value: []byte("orders")},
+			{Key: []byte(HeaderDeliverAt), Value: []byte(strconv.FormatInt(deliverAt.UnixMilli(), 10))},
+		}},
+	}}
+	session := newMockSession()
+
+	require.NoError(t, h.ConsumeClaim(session, claim))
+	assert.False(t, time.Now().Before(deliverAt.Truncate(time.Millisecond)), "message forwarded before it was due")
+	require.Len(t, forwarded, 1)
+	assert.Equal(t, "orders", forwarded[0].Topic)
+	assert.Len(t, session.marks, 2, "malformed message should be dropped and committed")
+}
+
+func TestDelayHandler_SessionEndsBeforeDue(t *testing.T) {
+	h := &delayHandler{
+		logger: slog.Default(),
+		forward: func(*sarama.ProducerMessage) error {
+			t.Error("message should not be forwarded before it is due")
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	claim := &mockClaim{msgs: []*sarama.ConsumerMessage{
+		{Topic: DefaultDelayTopic, Value: []byte("hello"), Headers: []*sarama.RecordHeader{
+			{Key: []byte(HeaderDelayTarget), Value: []byte("orders")},
+			{Key: []byte(HeaderDeliverAt), Value: []byte(strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10))},
+		}},
+	}}
+	session := &mockConsumerGroupSession{ctx: ctx}
+
+	require.NoError(t, h.ConsumeClaim(session, claim))
+	assert.Empty(t, session.marks, "undelivered message must not be committed")
+}
+
+func TestDelayHandler_RetriesForwardFailure(t *testing.T) {
+	calls := 0
+	h := &delayHandler{
+		logger: slog.Default(),
+		forward: func(*sarama.ProducerMessage) error {
+			calls++
+			if calls < 2 {
+				return errors.New("broker unavailable")
+			}
+			return nil
+		},
+	}
+
+	msg := &sarama.ConsumerMessage{Value: []byte("hello"), Headers: []*sarama.RecordHeader{
+		{Key: []byte(HeaderDelayTarget), Value: []byte("orders")},
+		{Key: []byte(HeaderDeliverAt), Value: []byte("0")},
+	}}
+	assert.True(t, h.dispatch(context.Background(), msg))
+	assert.Equal(t, 2, calls)
+}
+
+func TestDelayScheduler_Lifecycle(t *testing.T) {
+	s := NewDelayScheduler([]string{"localhost:9092"}, WithSchedulerGroup("g"), WithSchedulerTopics("d1", "d2"))
+	assert.Equal(t, "g", s.opt.group)
+	assert.Equal(t, []string{"d1", "d2"}, s.opt.topics)
+	assert.Error(t, s.HealthCheck(context.Background()))
+
+	// 未启动时关闭直接进入 Closed
+	require.NoError(t, s.Shutdown(context.Background()))
+	assert.Error(t, s.Start(context.Background()))
+}
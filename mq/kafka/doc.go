@@ -2,8 +2,22 @@
 //
 // 消费者支持同步和异步两种重试模式，异步模式可通过可插拔的 RetryStore
 // 选择内存水位线存储或 Redis 持久化存储。
+// WithRetryTopics 以分级重试 topic 代替进程内重试；WithDeadLetterTopic 将重试耗尽的消息写入死信 topic，
+// 可由 DeadLetterReplayer 重放回原始 topic。
+// 通过 mq.NewBatchHandler 注册的批量 handler 按分区凑批处理，失败的消息逐条进入重试流程。
+// WithKeyedParallelism 将分区内的消息按 key 分片并行处理，相同 key 保持顺序，offset 按水位线提交。
+// WithExactlyOnce 以 Kafka 事务处理每条消息，handler 经 TxFromContext 发送的消息与消费 offset 一并提交。
 //
 // 生产者支持单条和批量发送模式，可通过 WithOrderKey 选项实现有序发送，内置自动重连机制。
+// WithDelay / WithDeliverAt 发送的消息写入延迟 topic，由 DelayScheduler 到期后转发到目标 topic。
+// 子包 schemaregistry 以 Confluent wire format 编解码 Avro / JSON Schema / Protobuf 消息体，
+// WithProducerSchemas 在生产者启动时检查 schema 兼容性并注册。
+// NewTransactionalProducer 创建事务生产者，ProduceInTx 内发送的多条消息原子提交。
+//
+// TopicAdmin 按声明的 TopicSpec 创建 topic、增加分区并调整配置，Plan 只计算差异；
+// 默认拒绝重建 topic、缩短保留期等破坏性变更，副本数变更不自动执行。WithTopicAdmin / WithProducerTopicAdmin 在启动时执行预置。
 //
 // Consumer 和 Producer 均实现 app.IApp 接口，可通过 app.Manager 统一管理生命周期。
+// Consumer 同时实现 app.PreStopper 接口，排空阶段暂停全部分区拉取；
+// 实现 TopicPauser 接口，可在运行时暂停 / 恢复 topic。WithBackpressure 按分区重试积压或处理中消息数自动暂停与恢复拉取。
 package kafka
@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// HeaderMessageID 保存消息 ID 的 record header。
+// 消息信封的 Key、Timestamp 直接映射为 record 的 key 与 timestamp，其余消息头原样映射为 record headers。
+const HeaderMessageID = "mq-message-id"
+
+// newProducerMessage 按生产配置构造 sarama 消息：key、headers、timestamp 与消息 ID
+func newProducerMessage(topic string, value []byte, cfg *types.ProduceConfig) *sarama.ProducerMessage {
+	var envelope types.Message
+	cfg.Stamp(&envelope)
+
+	msg := &sarama.ProducerMessage{
+		Topic:     topic,
+		Value:     sarama.ByteEncoder(value),
+		Timestamp: envelope.Timestamp,
+		Headers:   make([]sarama.RecordHeader, 0, len(envelope.Headers)+1),
+	}
+	if envelope.Key != "" {
+		msg.Key = sarama.ByteEncoder(envelope.Key)
+	}
+	msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(HeaderMessageID), Value: []byte(envelope.ID)})
+	for k, v := range envelope.Headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	return msg
+}
+
+// newKafkaMessage 将消费到的 record 转换为统一消息，attempt 为第几次处理
+func newKafkaMessage(group string, msg *sarama.ConsumerMessage, attempt int) types.Message {
+	m := types.NewKafkaMessage(group, msg.Topic, msg.Value)
+	m.Partition = msg.Partition
+	m.Offset = msg.Offset
+	m.Key = string(msg.Key)
+	m.Timestamp = msg.Timestamp
+	m.Attempt = attempt
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		setEnvelopeHeader(&m, string(h.Key), h.Value)
+	}
+	return m
+}
+
+// retryItemMessage 将待重试消息转换为统一消息，Attempt 为已失败次数加一
+func retryItemMessage(group string, item *RetryItem) types.Message {
+	m := types.NewKafkaMessage(group, item.Topic, item.Value)
+	m.Partition = item.Partition
+	m.Offset = item.Offset
+	m.Key = string(item.Key)
+	m.Timestamp = item.Timestamp
+	m.Attempt = item.Attempt + 1
+	for _, h := range item.Headers {
+		setEnvelopeHeader(&m, h.Key, h.Value)
+	}
+	return m
+}
+
+// setEnvelopeHeader 将 record header 写入消息信封，HeaderMessageID 映射为 Message.ID
+func setEnvelopeHeader(m *types.Message, key string, value []byte) {
+	if key == HeaderMessageID {
+		m.ID = string(value)
+		return
+	}
+	if m.Headers == nil {
+		m.Headers = make(map[string]string)
+	}
+	m.Headers[key] = string(value)
+}
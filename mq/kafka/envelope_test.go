@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProducerMessage_MapsEnvelope(t *testing.T) {
+	cfg := types.ApplyProduceOptions([]types.ProduceOption{
+		types.WithMessageID("id-1"),
+		types.WithOrderKey("order-1"),
+		types.WithHeaders(map[string]string{"tenant": "t1"}),
+	})
+
+	msg := newProducerMessage("topic", []byte("hello"), cfg)
+	assert.Equal(t, "topic", msg.Topic)
+	assert.False(t, msg.Timestamp.IsZero())
+
+	key, err := msg.Key.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, "order-1", string(key))
+
+	headers := make(map[string]string)
+	for _, h := range msg.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	assert.Equal(t, map[string]string{HeaderMessageID: "id-1", "tenant": "t1"}, headers)
+}
+
+func TestNewKafkaMessage_FromRecord(t *testing.T) {
+	at := time.Now()
+	record := &sarama.ConsumerMessage{
+		Topic:     "topic",
+		Key:       []byte("order-1"),
+		Value:     []byte("hello"),
+		Timestamp: at,
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(HeaderMessageID), Value: []byte("id-1")},
+			{Key: []byte("traceparent"), Value: []byte("00-abc")},
+		},
+	}
+
+	msg := newKafkaMessage("g", record, 2)
+	assert.True(t, msg.IsKafka())
+	assert.Equal(t, "g", msg.Group)
+	assert.Equal(t, "topic", msg.Queue)
+	assert.Equal(t, []byte("hello"), msg.Data)
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "order-1", msg.Key)
+	assert.Equal(t, at, msg.Timestamp)
+	assert.Equal(t, 2, msg.Attempt)
+	assert.Equal(t, map[string]string{"traceparent": "00-abc"}, msg.Headers)
+}
+
+func TestRetryItemMessage(t *testing.T) {
+	item := &RetryItem{
+		Topic:   "topic",
+		Key:     []byte("order-1"),
+		Value:   []byte("hello"),
+		Headers: []HeaderKV{{Key: HeaderMessageID, Value: []byte("id-1")}},
+		Attempt: 2,
+	}
+
+	msg := retryItemMessage("g", item)
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "order-1", msg.Key)
+	assert.Equal(t, 3, msg.Attempt, "Attempt counts the current try")
+	assert.Nil(t, msg.Headers)
+}
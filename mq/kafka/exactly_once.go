@@ -0,0 +1,238 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/logutil"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+)
+
+// txProducerFactory 以指定 transactional.id 创建事务 producer
+type txProducerFactory func(transactionalID string) (sarama.SyncProducer, error)
+
+// errDeadLetterFailed 死信处理失败，事务中止且 offset 不提交
+var errDeadLetterFailed = xerror.NewXCode(xcode.ErrMQConsume, "dead letter handling failed")
+
+// exactlyOnceStrategy exactly-once 消费策略（consume-transform-produce）。
+// 每次处理在分区专属的事务 producer 上开启事务：handler 经 TxFromContext 发送的消息
+// 与消费 offset（AddMessageToTxn）一并提交，失败时中止事务并同步重试，不经过 session.MarkMessage。
+// 分区 producer 的 transactional.id 为 <prefix>-<group>-<topic>-<partition>，
+// 分区在 rebalance 后被其他实例接管时，新实例以相同 id 初始化即隔离旧实例未完成的事务。
+type exactlyOnceStrategy struct {
+	consumerGroup string
+	handler       types.IHandler
+	maxRetry      int
+	backoff       retry.BackoffStrategy
+	timeout       time.Duration
+	idPrefix      string
+	delayTopic    string
+	newProducer   txProducerFactory
+	logger        logutil.Logger
+	metrics       *metrics.ConsumerMetrics
+	failedHandler types.FailedHandlerFunc
+	deadLetter    types.DeadLetterHandler
+	retryIf       func(error) bool
+
+	mu        sync.Mutex
+	producers map[string]sarama.SyncProducer
+}
+
+func newExactlyOnceStrategy(
+	cg string,
+	handler types.IHandler,
+	maxRetry int,
+	backoff retry.BackoffStrategy,
+	handlerTimeout time.Duration,
+	idPrefix string,
+	newProducer txProducerFactory,
+	logger logutil.Logger,
+	metrics *metrics.ConsumerMetrics,
+) *exactlyOnceStrategy {
+	return &exactlyOnceStrategy{
+		consumerGroup: cg,
+		handler:       handler,
+		maxRetry:      maxRetry,
+		backoff:       backoff,
+		timeout:       handlerTimeout,
+		idPrefix:      idPrefix,
+		delayTopic:    DefaultDelayTopic,
+		newProducer:   newProducer,
+		logger:        logger,
+		metrics:       metrics,
+		producers:     make(map[string]sarama.SyncProducer),
+	}
+}
+
+// SetDelayTopic 设置事务内延迟消息写入的延迟 topic，为空时保持 DefaultDelayTopic
+func (s *exactlyOnceStrategy) SetDelayTopic(topic string) {
+	if topic != "" {
+		s.delayTopic = topic
+	}
+}
+
+// SetFailedHandler 设置失败处理器
+func (s *exactlyOnceStrategy) SetFailedHandler(fn types.FailedHandlerFunc) {
+	s.failedHandler = fn
+}
+
+// SetDeadLetterHandler 设置死信处理器（内置死信 topic 在同一事务内发布）
+func (s *exactlyOnceStrategy) SetDeadLetterHandler(h types.DeadLetterHandler) {
+	s.deadLetter = h
+}
+
+// SetRetryIf 设置错误重试谓词
+func (s *exactlyOnceStrategy) SetRetryIf(fn func(error) bool) {
+	s.retryIf = fn
+}
+
+func (s *exactlyOnceStrategy) OnMessage(ctx context.Context, session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
+	kafkaMsg := newKafkaMessage(s.consumerGroup, msg, 1)
+	var lastErr error
+
+	for attempt := 0; attempt <= s.maxRetry; attempt++ {
+		if ctx.Err() != nil {
+			return
+		}
+
+		kafkaMsg.Attempt = attempt + 1
+		err := s.inTx(ctx, msg, func(txCtx context.Context, _ Tx) error {
+			// handler 超时后中止事务，避免挂起的 handler 一直占用分区事务
+			if s.timeout > 0 {
+				var cancel context.CancelFunc
+				txCtx, cancel = context.WithTimeout(txCtx, s.timeout)
+				defer cancel()
+			}
+			return s.handler.Handle(txCtx, kafkaMsg)
+		})
+		if err == nil {
+			if s.metrics != nil {
+				s.metrics.OnConsume()
+			}
+			return
+		}
+
+		lastErr = err
+		if !mqretry.Retryable(err, s.retryIf) {
+			s.settle(ctx, msg, func(txCtx context.Context) exhaustedResult {
+				return handlePermanent(txCtx, kafkaMsg, err, s.deadLetter, s.failedHandler, s.logger, s.metrics)
+			})
+			return
+		}
+
+		if attempt < s.maxRetry {
+			if s.metrics != nil {
+				s.metrics.OnRetry()
+			}
+			select {
+			case <-time.After(s.backoff.Delay(uint(attempt))):
+			case <-session.Context().Done():
+				return
+			}
+		}
+	}
+
+	// 重试耗尽
+	s.settle(ctx, msg, func(txCtx context.Context) exhaustedResult {
+		return handleExhausted(txCtx, kafkaMsg, lastErr, s.deadLetter, s.failedHandler, s.logger, s.metrics)
+	})
+}
+
+// settle 在事务内执行死信流程并提交 offset；死信处理失败时中止事务，offset 不提交
+func (s *exactlyOnceStrategy) settle(ctx context.Context, msg *sarama.ConsumerMessage, deliver func(ctx context.Context) exhaustedResult) {
+	err := s.inTx(ctx, msg, func(txCtx context.Context, _ Tx) error {
+		if deliver(txCtx) == exhaustedFailed {
+			return errDeadLetterFailed
+		}
+		return nil
+	})
+	if err != nil && s.logger != nil {
+		s.logger.Error("exactly-once dead letter transaction failed, offset not committed",
+			"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "error", err)
+	}
+}
+
+// inTx 在分区 producer 上执行一次事务：fn 成功后将 msg 的 offset 加入事务并提交
+func (s *exactlyOnceStrategy) inTx(ctx context.Context, msg *sarama.ConsumerMessage, fn func(ctx context.Context, tx Tx) error) error {
+	id := s.transactionalID(msg.Topic, msg.Partition)
+	producer, err := s.producerFor(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = runTx(ctx, producer, s.delayTopic, fn, func() error {
+		return producer.AddMessageToTxn(msg, s.consumerGroup, nil)
+	})
+	if err != nil && txBroken(producer) {
+		if s.logger != nil {
+			s.logger.Warn("transactional producer in unrecoverable state, recreating",
+				"transactionalID", id, "error", err)
+		}
+		s.discard(id)
+	}
+	return err
+}
+
+// transactionalID 分区专属的 transactional.id
+func (s *exactlyOnceStrategy) transactionalID(topic string, partition int32) string {
+	return fmt.Sprintf("%s-%s-%s-%d", s.idPrefix, s.consumerGroup, topic, partition)
+}
+
+// producerFor 返回 transactional.id 对应的 producer，不存在时创建
+func (s *exactlyOnceStrategy) producerFor(id string) (sarama.SyncProducer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.producers[id]; ok {
+		return p, nil
+	}
+	p, err := s.newProducer(id)
+	if err != nil {
+		return nil, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	s.producers[id] = p
+	return p, nil
+}
+
+// discard 关闭并移除 producer，下次处理时以相同 transactional.id 重建
+func (s *exactlyOnceStrategy) discard(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.producers[id]; ok {
+		_ = p.Close()
+		delete(s.producers, id)
+	}
+}
+
+// closeProducers 关闭全部分区 producer
+func (s *exactlyOnceStrategy) closeProducers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, p := range s.producers {
+		if err := p.Close(); err != nil && s.logger != nil {
+			s.logger.Warn("close transactional producer failed", "transactionalID", id, "error", err)
+		}
+		delete(s.producers, id)
+	}
+}
+
+func (s *exactlyOnceStrategy) SetSession(sarama.ConsumerGroupSession) {}
+
+// ClearSession rebalance 时关闭分区 producer：分区可能被分配给其他实例
+func (s *exactlyOnceStrategy) ClearSession() {
+	s.closeProducers()
+}
+
+func (s *exactlyOnceStrategy) OnShutdown(_ context.Context) {
+	s.closeProducers()
+}
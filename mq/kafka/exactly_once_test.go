@@ -0,0 +1,253 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// txFactory 记录创建的分区事务 producer，setup 为每个新 producer 设置发送预期
+type txFactory struct {
+	t         *testing.T
+	setup     func(p *txRecordingProducer)
+	ids       []string
+	producers []*txRecordingProducer
+}
+
+func (f *txFactory) new(id string) (sarama.SyncProducer, error) {
+	p := newTxRecordingProducer(f.t)
+	if f.setup != nil {
+		f.setup(p)
+	}
+	f.ids = append(f.ids, id)
+	f.producers = append(f.producers, p)
+	return p, nil
+}
+
+func newTestExactlyOnce(handler types.IHandler, maxRetry int, f *txFactory) *exactlyOnceStrategy {
+	return newExactlyOnceStrategy("g1", handler, maxRetry,
+		&retry.ExponentialDelay{Base: time.Millisecond, Max: time.Millisecond}, 0,
+		"app", f.new, nil, nil)
+}
+
+func TestExactlyOnce_CommitsOutputWithOffset(t *testing.T) {
+	f := &txFactory{t: t, setup: func(p *txRecordingProducer) { p.ExpectSendMessageAndSucceed() }}
+	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+		tx, ok := TxFromContext(ctx)
+		require.True(t, ok)
+		return tx.Produce(ctx, "enriched", msg.Data)
+	})
+	s := newTestExactlyOnce(handler, 0, f)
+
+	session := newMockSession()
+	msg := &sarama.ConsumerMessage{Topic: "orders", Partition: 3, Offset: 42, Value: []byte("v")}
+	s.OnMessage(context.Background(), session, msg)
+
+	require.Len(t, f.producers, 1)
+	assert.Equal(t, []string{"app-g1-orders-3"}, f.ids)
+	commits, aborts, offsets := f.producers[0].stats()
+	assert.Equal(t, 1, commits)
+	assert.Zero(t, aborts)
+	assert.Equal(t, 1, offsets)
+	assert.Equal(t, []string{"g1"}, f.producers[0].groups)
+	assert.Empty(t, session.marks, "offsets are committed in the transaction, not via the session")
+}
+
+func TestExactlyOnce_DelayedOutputUsesConfiguredDelayTopic(t *testing.T) {
+	f := &txFactory{t: t, setup: func(p *txRecordingProducer) {
+		p.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(m *sarama.ProducerMessage) error {
+			if m.Topic != "custom.delay" {
+				return fmt.Errorf("delayed output should go to the configured delay topic, got %s", m.Topic)
+			}
+			if got := headerValue(m.Headers, HeaderDelayTarget); got != "enriched" {
+				return fmt.Errorf("unexpected delay target %s", got)
+			}
+			return nil
+		})
+	}}
+	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+		tx, _ := TxFromContext(ctx)
+		return tx.Produce(ctx, "enriched", msg.Data, types.WithDelay(time.Minute))
+	})
+	s := newTestExactlyOnce(handler, 0, f)
+	s.SetDelayTopic("custom.delay")
+
+	s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Offset: 1, Value: []byte("v")})
+
+	commits, aborts, _ := f.producers[0].stats()
+	assert.Equal(t, 1, commits)
+	assert.Zero(t, aborts)
+}
+
+func TestGroupHandler_ExactlyOnceDelayTopic(t *testing.T) {
+	build := func(opts ...ConsumerOption) *exactlyOnceStrategy {
+		cfg := &consumerConfig{}
+		for _, opt := range opts {
+			opt(cfg)
+		}
+		gh := newGroupHandler("g1", &groupHandlerConf{
+			Handler:               types.FuncHandler(func(context.Context, types.Message) error { return nil }),
+			TransactionalIDPrefix: "app",
+			DelayTopic:            cfg.delayTopic,
+		})
+		return gh.strategy.(*exactlyOnceStrategy)
+	}
+	assert.Equal(t, DefaultDelayTopic, build().delayTopic)
+	assert.Equal(t, "custom.delay", build(WithConsumerDelayTopic("custom.delay")).delayTopic)
+}
+
+func TestExactlyOnce_RetryAbortsFailedAttempts(t *testing.T) {
+	f := &txFactory{t: t}
+	calls := 0
+	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+		calls++
+		assert.Equal(t, calls, msg.Attempt)
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	s := newTestExactlyOnce(handler, 3, f)
+
+	s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Offset: 1})
+
+	commits, aborts, offsets := f.producers[0].stats()
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 1, commits)
+	assert.Equal(t, 2, aborts)
+	assert.Equal(t, 1, offsets)
+}
+
+func TestExactlyOnce_HandlerTimeoutAbortsTransaction(t *testing.T) {
+	f := &txFactory{t: t}
+	calls := 0
+	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+		calls++
+		if calls == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	s := newTestExactlyOnce(handler, 1, f)
+	s.timeout = 20 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Offset: 1})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler timeout should end the hanging attempt")
+	}
+
+	commits, aborts, offsets := f.producers[0].stats()
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 1, commits)
+	assert.Equal(t, 1, aborts, "timed-out attempt is aborted")
+	assert.Equal(t, 1, offsets)
+}
+
+func TestExactlyOnce_DeadLetterInTransaction(t *testing.T) {
+	f := &txFactory{t: t, setup: func(p *txRecordingProducer) {
+		p.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(m *sarama.ProducerMessage) error {
+			if m.Topic != "orders.DLT" {
+				return fmt.Errorf("unexpected topic %s", m.Topic)
+			}
+			return nil
+		})
+	}}
+	handler := types.FuncHandler(func(context.Context, types.Message) error {
+		return types.Permanent(errors.New("bad payload"))
+	})
+	s := newTestExactlyOnce(handler, 3, f)
+	s.SetDeadLetterHandler(&deadLetterPublisher{
+		group:   "g1",
+		topicOf: suffixTopic(DefaultDeadLetterSuffix),
+		publish: func(*sarama.ProducerMessage) error {
+			t.Fatal("dead letter must be published inside the transaction")
+			return nil
+		},
+	})
+
+	s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Offset: 7})
+
+	commits, aborts, offsets := f.producers[0].stats()
+	assert.Equal(t, 1, commits)
+	assert.Equal(t, 1, aborts, "only the handler attempt is aborted")
+	assert.Equal(t, 1, offsets)
+}
+
+func TestExactlyOnce_DeadLetterFailureKeepsOffset(t *testing.T) {
+	f := &txFactory{t: t}
+	handler := types.FuncHandler(func(context.Context, types.Message) error { return errors.New("fail") })
+	s := newTestExactlyOnce(handler, 0, f)
+	s.SetDeadLetterHandler(&failingDeadLetterHandler{})
+
+	s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Offset: 7})
+
+	commits, aborts, offsets := f.producers[0].stats()
+	assert.Zero(t, commits)
+	assert.Equal(t, 2, aborts)
+	assert.Zero(t, offsets)
+}
+
+func TestExactlyOnce_FatalErrorRecreatesProducer(t *testing.T) {
+	f := &txFactory{t: t}
+	calls := 0
+	handler := types.FuncHandler(func(context.Context, types.Message) error {
+		calls++
+		return nil
+	})
+	s := newTestExactlyOnce(handler, 1, f)
+	f.setup = func(p *txRecordingProducer) {
+		if len(f.producers) == 0 {
+			p.commitErr = sarama.ErrProducerFenced
+			p.fatal = true
+		}
+	}
+
+	s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Offset: 1})
+
+	// 提交失败的尝试计入重试，第二次在重建的 producer 上提交
+	assert.Equal(t, 2, calls)
+	require.Len(t, f.producers, 2)
+	assert.True(t, f.producers[0].closed)
+	assert.Equal(t, f.ids[0], f.ids[1], "recreated with the same transactional id")
+	commits, _, offsets := f.producers[1].stats()
+	assert.Equal(t, 1, commits)
+	assert.Equal(t, 1, offsets)
+}
+
+func TestExactlyOnce_ClearSessionClosesProducers(t *testing.T) {
+	f := &txFactory{t: t}
+	handler := types.FuncHandler(func(context.Context, types.Message) error { return nil })
+	s := newTestExactlyOnce(handler, 0, f)
+
+	s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Partition: 0})
+	s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Partition: 1})
+	s.OnMessage(context.Background(), newMockSession(), &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 1})
+	require.Len(t, f.producers, 2)
+
+	s.ClearSession()
+	assert.True(t, f.producers[0].closed)
+	assert.True(t, f.producers[1].closed)
+}
+
+func TestConsumerConfig_ExactlyOnceReadCommitted(t *testing.T) {
+	cfg := &consumerConfig{}
+	WithExactlyOnce("app")(cfg)
+	assert.Equal(t, sarama.ReadCommitted, cfg.consumerSaramaConfig().Consumer.IsolationLevel)
+
+	assert.Equal(t, sarama.ReadUncommitted, (&consumerConfig{}).consumerSaramaConfig().Consumer.IsolationLevel)
+}
@@ -0,0 +1,180 @@
+package kafka
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gomooth/pkg/mq/internal/logutil"
+)
+
+// partitionPauser 暂停 / 恢复分区拉取，由 sarama.ConsumerGroup 实现
+type partitionPauser interface {
+	Pause(partitions map[string][]int32)
+	Resume(partitions map[string][]int32)
+}
+
+// flowController 消费者组的分区拉取控制（未导出）。
+// 分区暂停有两个来源：手动暂停的 topic（PauseTopics）与背压自动暂停的分区，
+// 任一来源存在时分区保持暂停，全部解除后才恢复。
+// sarama 在每次 rebalance 后重建分区消费者，ConsumeClaim 开始时经 apply 重新应用暂停状态。
+type flowController struct {
+	pauser partitionPauser
+	logger logutil.Logger
+
+	mu     sync.Mutex
+	claims map[string][]int32
+	topics map[string]bool
+	auto   map[topicPartition]bool
+}
+
+func newFlowController(pauser partitionPauser, logger logutil.Logger) *flowController {
+	return &flowController{
+		pauser: pauser,
+		logger: logger,
+		topics: make(map[string]bool),
+		auto:   make(map[topicPartition]bool),
+	}
+}
+
+// setClaims 记录当前会话分配的分区
+func (f *flowController) setClaims(claims map[string][]int32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claims = claims
+}
+
+// clearSession 会话结束：分区消费者随会话销毁，自动暂停状态一并清除
+func (f *flowController) clearSession() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claims = nil
+	f.auto = make(map[topicPartition]bool)
+}
+
+// apply 分区开始消费时重新应用暂停状态
+func (f *flowController) apply(topic string, partition int32) {
+	f.mu.Lock()
+	paused := f.topics[topic] || f.auto[topicPartition{topic: topic, partition: partition}]
+	f.mu.Unlock()
+
+	if paused {
+		f.pauser.Pause(map[string][]int32{topic: {partition}})
+	}
+}
+
+// pauseTopics 手动暂停 topic 的全部已分配分区，未订阅的 topic 在分配后暂停
+func (f *flowController) pauseTopics(topics ...string) {
+	f.mu.Lock()
+	partitions := make(map[string][]int32)
+	for _, topic := range topics {
+		f.topics[topic] = true
+		if claimed, ok := f.claims[topic]; ok {
+			partitions[topic] = claimed
+		}
+	}
+	f.mu.Unlock()
+
+	if len(partitions) > 0 {
+		f.pauser.Pause(partitions)
+	}
+}
+
+// resumeTopics 解除 topic 的手动暂停，仍处于背压暂停的分区保持暂停
+func (f *flowController) resumeTopics(topics ...string) {
+	f.mu.Lock()
+	partitions := make(map[string][]int32)
+	for _, topic := range topics {
+		if !f.topics[topic] {
+			continue
+		}
+		delete(f.topics, topic)
+		for _, p := range f.claims[topic] {
+			if !f.auto[topicPartition{topic: topic, partition: p}] {
+				partitions[topic] = append(partitions[topic], p)
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if len(partitions) > 0 {
+		f.pauser.Resume(partitions)
+	}
+}
+
+// pausedTopics 返回手动暂停的 topic（按名称排序）
+func (f *flowController) pausedTopics() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	topics := make([]string, 0, len(f.topics))
+	for topic := range f.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// autoPause 背压暂停分区，已暂停时不重复操作
+func (f *flowController) autoPause(topic string, partition int32, backlog int) {
+	tp := topicPartition{topic: topic, partition: partition}
+
+	f.mu.Lock()
+	if f.auto[tp] {
+		f.mu.Unlock()
+		return
+	}
+	f.auto[tp] = true
+	manual := f.topics[topic]
+	f.mu.Unlock()
+
+	if f.logger != nil {
+		f.logger.Warn("backlog above high watermark, pausing partition",
+			"topic", topic, "partition", partition, "backlog", backlog)
+	}
+	if !manual {
+		f.pauser.Pause(map[string][]int32{topic: {partition}})
+	}
+}
+
+// autoResume 解除分区的背压暂停，topic 仍被手动暂停时保持暂停
+func (f *flowController) autoResume(topic string, partition int32, backlog int) {
+	tp := topicPartition{topic: topic, partition: partition}
+
+	f.mu.Lock()
+	if !f.auto[tp] {
+		f.mu.Unlock()
+		return
+	}
+	delete(f.auto, tp)
+	manual := f.topics[topic]
+	f.mu.Unlock()
+
+	if f.logger != nil {
+		f.logger.Info("backlog below low watermark, resuming partition",
+			"topic", topic, "partition", partition, "backlog", backlog)
+	}
+	if !manual {
+		f.pauser.Resume(map[string][]int32{topic: {partition}})
+	}
+}
+
+// backpressure 按分区积压自动暂停 / 恢复拉取（未导出）：
+// 积压达到 high 时暂停分区，回落到 low 及以下时恢复。
+// 积压为异步重试存储中的重试项，或分区内按 key 并行时处理中的消息数。
+type backpressure struct {
+	high    int
+	low     int
+	backlog BacklogStore
+	flow    *flowController
+}
+
+// check 在分区积压变化后检查水位
+func (b *backpressure) check(topic string, partition int32) {
+	n := b.backlog.Backlog(topic, partition)
+	switch {
+	case n >= b.high:
+		b.flow.autoPause(topic, partition, n)
+	case n <= b.low:
+		b.flow.autoResume(topic, partition, n)
+	}
+}
@@ -0,0 +1,191 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePauser 记录分区暂停状态
+type fakePauser struct {
+	mu     sync.Mutex
+	paused map[topicPartition]bool
+	calls  int
+}
+
+func newFakePauser() *fakePauser {
+	return &fakePauser{paused: make(map[topicPartition]bool)}
+}
+
+func (p *fakePauser) Pause(partitions map[string][]int32) {
+	p.set(partitions, true)
+}
+
+func (p *fakePauser) Resume(partitions map[string][]int32) {
+	p.set(partitions, false)
+}
+
+func (p *fakePauser) set(partitions map[string][]int32, paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			p.paused[topicPartition{topic: topic, partition: partition}] = paused
+		}
+	}
+}
+
+func (p *fakePauser) isPaused(topic string, partition int32) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused[topicPartition{topic: topic, partition: partition}]
+}
+
+func TestFlowController_PauseResumeTopics(t *testing.T) {
+	pauser := newFakePauser()
+	f := newFlowController(pauser, nil)
+	f.setClaims(map[string][]int32{"orders": {0, 1}, "audit": {0}})
+
+	f.pauseTopics("orders", "unknown")
+	assert.True(t, pauser.isPaused("orders", 0))
+	assert.True(t, pauser.isPaused("orders", 1))
+	assert.False(t, pauser.isPaused("audit", 0))
+	assert.Equal(t, []string{"orders", "unknown"}, f.pausedTopics())
+
+	f.resumeTopics("orders", "unknown")
+	assert.False(t, pauser.isPaused("orders", 0))
+	assert.Empty(t, f.pausedTopics())
+}
+
+func TestFlowController_ReappliesAfterRebalance(t *testing.T) {
+	pauser := newFakePauser()
+	f := newFlowController(pauser, nil)
+	f.pauseTopics("orders")
+
+	// 新会话的分区消费者开始消费时重新暂停
+	f.clearSession()
+	f.setClaims(map[string][]int32{"orders": {2}})
+	f.apply("orders", 2)
+	assert.True(t, pauser.isPaused("orders", 2))
+
+	f.apply("audit", 0)
+	assert.False(t, pauser.isPaused("audit", 0))
+}
+
+func TestFlowController_AutoAndManualPause(t *testing.T) {
+	pauser := newFakePauser()
+	f := newFlowController(pauser, nil)
+	f.setClaims(map[string][]int32{"orders": {0, 1}})
+
+	f.autoPause("orders", 0, 10)
+	f.autoPause("orders", 0, 11)
+	assert.True(t, pauser.isPaused("orders", 0))
+	assert.Equal(t, 1, pauser.calls, "auto pause is idempotent")
+
+	// 手动恢复不影响背压暂停中的分区
+	f.pauseTopics("orders")
+	f.resumeTopics("orders")
+	assert.True(t, pauser.isPaused("orders", 0))
+	assert.False(t, pauser.isPaused("orders", 1))
+
+	// 手动暂停期间背压恢复不恢复分区
+	f.pauseTopics("orders")
+	f.autoResume("orders", 0, 0)
+	assert.True(t, pauser.isPaused("orders", 0))
+	f.resumeTopics("orders")
+	assert.False(t, pauser.isPaused("orders", 0))
+}
+
+type fakeBacklog struct{ n atomic.Int64 }
+
+func (b *fakeBacklog) Backlog(string, int32) int { return int(b.n.Load()) }
+
+func TestBackpressure_Check(t *testing.T) {
+	pauser := newFakePauser()
+	backlog := &fakeBacklog{}
+	bp := &backpressure{high: 10, low: 5, backlog: backlog, flow: newFlowController(pauser, nil)}
+
+	backlog.n.Store(10)
+	bp.check("orders", 0)
+	assert.True(t, pauser.isPaused("orders", 0))
+
+	backlog.n.Store(7)
+	bp.check("orders", 0)
+	assert.True(t, pauser.isPaused("orders", 0), "stays paused between watermarks")
+
+	backlog.n.Store(5)
+	bp.check("orders", 0)
+	assert.False(t, pauser.isPaused("orders", 0))
+}
+
+func TestAsyncRetry_BackpressurePausesPartition(t *testing.T) {
+	var healthy atomic.Bool
+	handler := types.FuncHandler(func(context.Context, types.Message) error {
+		if healthy.Load() {
+			return nil
+		}
+		return errors.New("downstream unavailable")
+	})
+
+	pauser := newFakePauser()
+	engine := newAsyncRetryEngineWithStore("test-group", handler, 100,
+		&retry.FixedDelay{Wait: 5 * time.Millisecond},
+		0, 1, NewMemoryRetryStore(), nil, nil)
+	require.True(t, engine.SetBackpressure(2, 0, newFlowController(pauser, nil)))
+
+	session := newMockSession()
+	engine.SetSession(session)
+	defer engine.ClearSession()
+
+	engine.OnMessage(context.Background(), session, &sarama.ConsumerMessage{Topic: "orders", Offset: 1})
+	assert.False(t, pauser.isPaused("orders", 0))
+	engine.OnMessage(context.Background(), session, &sarama.ConsumerMessage{Topic: "orders", Offset: 2})
+	assert.True(t, pauser.isPaused("orders", 0))
+
+	healthy.Store(true)
+	assert.Eventually(t, func() bool { return !pauser.isPaused("orders", 0) }, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestAsyncRetry_BackpressureUnsupportedStore(t *testing.T) {
+	engine := newAsyncRetryEngineWithStore("test-group", types.FuncHandler(func(context.Context, types.Message) error { return nil }), 1,
+		&retry.ExponentialDelay{Base: time.Millisecond, Max: time.Second},
+		0, 1, &nonWatermarkMockStore{}, nil, nil)
+	assert.False(t, engine.SetBackpressure(2, 0, newFlowController(newFakePauser(), nil)))
+}
+
+func TestConsumerEngine_PauseTopics(t *testing.T) {
+	pauser := newFakePauser()
+	e := &consumerEngine{registrations: []consumerRegistration{
+		{group: "g1", flow: newFlowController(pauser, nil)},
+		{group: "g2", flow: newFlowController(pauser, nil)},
+	}}
+	e.registrations[0].flow.setClaims(map[string][]int32{"orders": {0}})
+
+	e.PauseTopics("orders")
+	assert.True(t, pauser.isPaused("orders", 0))
+	assert.Equal(t, []string{"orders"}, e.PausedTopics())
+
+	e.ResumeTopics("orders")
+	assert.False(t, pauser.isPaused("orders", 0))
+	assert.Empty(t, e.PausedTopics())
+}
+
+func TestWithBackpressure(t *testing.T) {
+	cfg := &consumerConfig{}
+	WithBackpressure(100, 20)(cfg)
+	assert.Equal(t, 100, cfg.backpressureHigh)
+	assert.Equal(t, 20, cfg.backpressureLow)
+
+	WithBackpressure(100, 200)(cfg)
+	assert.Equal(t, 50, cfg.backpressureLow)
+}
@@ -10,31 +10,63 @@ import (
 	"github.com/gomooth/pkg/framework/retry"
 	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/middleware"
 	"github.com/gomooth/pkg/mq/internal/types"
 )
 
 // groupHandler sarama.ConsumerGroupHandler 适配器（未导出）
 type groupHandler struct {
-	consumerGroup string
-	handler       types.IHandler
-	strategy      retryStrategy
-	logger        *slog.Logger
+	consumerGroup  string
+	handler        types.IHandler
+	strategy       retryStrategy
+	logger         *slog.Logger
+	handlerTimeout time.Duration
+	metrics        *metrics.ConsumerMetrics
+	flow           *flowController
+
+	// 分区内按 key 并行消费（WithKeyedParallelism 启用）
+	keyed *keyedDispatcher
+
+	// 批量消费（handler 由 types.NewBatchHandler 创建时启用）
+	batchHandler types.IBatchHandler
+	batchConfig  types.BatchConfig
 }
 
 // groupHandlerConf groupHandler 的配置
 type groupHandlerConf struct {
 	Logger                   *slog.Logger
 	Handler                  types.IHandler
+	Middlewares              []types.Middleware // 按顺序包装 Handler，mws[0] 位于最外层
+	PanicHandler             func(any)          // 批量 handler panic 时调用（可为 nil）
 	MaxRetry                 int
 	Backoff                  retry.BackoffStrategy
 	FailedHandler            types.FailedHandlerFunc
 	DeadLetter               types.DeadLetterHandler
+	RetryIf                  func(error) bool
 	RetryMode                types.RetryMode
 	RetryWorkers             int
 	RetryStore               RetryStore
 	Metrics                  interface{} // 使用 interface{} 避免循环导入
 	HandlerTimeout           time.Duration
 	SyncRetryMaxTotalTimeout time.Duration
+
+	// 重试 topic 链（设置后替代 RetryMode）
+	Topics      []string
+	RetryTopics []time.Duration
+	Publish     func(msg *sarama.ProducerMessage) error
+
+	// exactly-once 消费（设置后替代 RetryMode 与 RetryTopics）
+	TransactionalIDPrefix string
+	NewTxProducer         txProducerFactory
+	DelayTopic            string // 事务内延迟消息写入的延迟 topic，为空时使用 DefaultDelayTopic
+
+	// 分区拉取控制与背压（异步重试模式）
+	Flow             *flowController
+	BackpressureHigh int
+	BackpressureLow  int
+
+	// 分区内按 key 并行的 worker 数（大于 1 时启用，同步重试与重试 topic 链模式）
+	KeyWorkers int
 }
 
 func newGroupHandler(cg string, conf *groupHandlerConf) *groupHandler {
@@ -57,10 +89,28 @@ func newGroupHandler(cg string, conf *groupHandlerConf) *groupHandler {
 
 	m, _ := conf.Metrics.(*metrics.ConsumerMetrics)
 
+	// 重试策略调用组装好中间件链的 handler；批量接口仍在原始 handler 上识别
+	handler := types.Chain(conf.Handler, conf.Middlewares...)
+
 	var strategy retryStrategy
 
-	switch conf.RetryMode {
-	case types.RetryModeRequeue: // kafka maps Async to Requeue
+	switch {
+	case conf.TransactionalIDPrefix != "":
+		s := newExactlyOnceStrategy(cg, handler, conf.MaxRetry, backoff, conf.HandlerTimeout,
+			conf.TransactionalIDPrefix, conf.NewTxProducer, internalLogger, m)
+		s.SetDelayTopic(conf.DelayTopic)
+		s.SetFailedHandler(failedHandler)
+		s.SetDeadLetterHandler(conf.DeadLetter)
+		s.SetRetryIf(conf.RetryIf)
+		strategy = s
+	case len(conf.RetryTopics) > 0:
+		s := newRetryTopicStrategy(cg, handler, conf.Topics, conf.RetryTopics,
+			conf.HandlerTimeout, conf.Publish, internalLogger, m)
+		s.SetFailedHandler(failedHandler)
+		s.SetDeadLetterHandler(conf.DeadLetter)
+		s.SetRetryIf(conf.RetryIf)
+		strategy = s
+	case conf.RetryMode == types.RetryModeRequeue: // kafka maps Async to Requeue
 		store := conf.RetryStore
 		if store == nil {
 			// 默认使用 MemoryRetryStore（水位线模式）
@@ -73,10 +123,15 @@ func newGroupHandler(cg string, conf *groupHandlerConf) *groupHandler {
 				numWorkers = 1
 			}
 		}
-		engine := newAsyncRetryEngineWithStore(cg, conf.Handler, conf.MaxRetry, backoff,
+		engine := newAsyncRetryEngineWithStore(cg, handler, conf.MaxRetry, backoff,
 			conf.HandlerTimeout, numWorkers, store, internalLogger, m)
 		engine.SetFailedHandler(failedHandler)
 		engine.SetDeadLetterHandler(conf.DeadLetter)
+		engine.SetRetryIf(conf.RetryIf)
+		if conf.BackpressureHigh > 0 && conf.Flow != nil &&
+			!engine.SetBackpressure(conf.BackpressureHigh, conf.BackpressureLow, conf.Flow) {
+			logger.Warn("retry store does not report backlog, backpressure disabled", "group", cg)
+		}
 		strategy = engine
 	default: // RetryModeSync
 		if conf.MaxRetry > 1 && logger != nil {
@@ -84,32 +139,85 @@ func newGroupHandler(cg string, conf *groupHandlerConf) *groupHandler {
 				"consider using RetryModeAsync for production",
 				"maxRetry", conf.MaxRetry, "syncRetryMaxTotalTimeout", conf.SyncRetryMaxTotalTimeout)
 		}
-		s := newSyncRetryStrategy(cg, conf.Handler, conf.MaxRetry, backoff,
+		s := newSyncRetryStrategy(cg, handler, conf.MaxRetry, backoff,
 			conf.SyncRetryMaxTotalTimeout, internalLogger, m)
 		s.SetFailedHandler(failedHandler)
 		s.SetDeadLetterHandler(conf.DeadLetter)
+		s.SetRetryIf(conf.RetryIf)
 		strategy = s
 	}
 
-	return &groupHandler{
-		consumerGroup: cg,
-		handler:       conf.Handler,
-		strategy:      strategy,
-		logger:        logger,
+	g := &groupHandler{
+		consumerGroup:  cg,
+		handler:        handler,
+		strategy:       strategy,
+		logger:         logger,
+		handlerTimeout: conf.HandlerTimeout,
+		metrics:        m,
+		flow:           conf.Flow,
+	}
+	// exactly-once 模式下批量 handler 逐条处理，每条消息独立成事务；
+	// 整批调用与单条处理经过相同的中间件链
+	if bh, batchCfg, ok := types.BatchOf(conf.Handler); ok && conf.TransactionalIDPrefix == "" {
+		g.batchHandler = middleware.WrapBatch(bh, conf.Middlewares, logger, conf.PanicHandler)
+		g.batchConfig = batchCfg
 	}
+	if conf.KeyWorkers > 1 {
+		switch strategy.(type) {
+		case *syncRetryStrategy, *retryTopicStrategy:
+			if g.batchHandler != nil {
+				logger.Warn("batch handler consumes partitions serially, keyed parallelism disabled", "group", cg)
+				break
+			}
+			g.keyed = newKeyedDispatcher(conf.KeyWorkers, strategy, internalLogger)
+		default:
+			logger.Warn("keyed parallelism requires sync retry mode or retry topics, disabled", "group", cg)
+		}
+	}
+	// 背压：异步重试按重试积压（见上），分区内按 key 并行按处理中的消息数；逐条消费时处理中至多一条，不适用
+	if conf.BackpressureHigh > 0 && conf.Flow != nil {
+		if g.keyed != nil {
+			g.keyed.setBackpressure(conf.BackpressureHigh, conf.BackpressureLow, conf.Flow)
+		} else if _, async := strategy.(*asyncRetryEngine); !async {
+			logger.Warn("backpressure requires async retry mode or keyed parallelism, disabled", "group", cg)
+		}
+	}
+	return g
 }
 
 func (g *groupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	if g.flow != nil {
+		g.flow.setClaims(session.Claims())
+	}
 	g.strategy.SetSession(session)
 	return nil
 }
 
 func (g *groupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
 	g.strategy.ClearSession()
+	if g.flow != nil {
+		g.flow.clearSession()
+	}
 	return nil
 }
 
 func (g *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// 分区消费者在 Setup 之后创建，在此重新应用暂停状态
+	if g.flow != nil {
+		g.flow.apply(claim.Topic(), claim.Partition())
+	}
+
+	// 重试 topic 的消息需逐条等待到期，不参与批量处理
+	if g.batchHandler != nil && !g.isRetryTopic(claim.Topic()) {
+		return g.consumeBatch(session, claim)
+	}
+
+	if g.keyed != nil {
+		return g.keyed.consume(session, claim, func(msg *sarama.ConsumerMessage) {
+			g.recordLag(claim, msg)
+		})
+	}
+
 	for {
 		select {
 		case msg, ok := <-claim.Messages():
@@ -118,16 +226,112 @@ func (g *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim s
 			}
 			// P8 修复：此处不输出 "message claimed" 调试日志
 
-			// 从消息 headers 提取 trace context，创建消费者 Span
-			ctx, span := startConsumerSpan(session.Context(), msg)
-			g.strategy.OnMessage(ctx, session, msg)
-			span.End()
+			// 消费者 span 由 Trace 中间件在 handler 层创建
+			g.recordLag(claim, msg)
+			g.strategy.OnMessage(session.Context(), session, msg)
 		case <-session.Context().Done():
 			return nil
 		}
 	}
 }
 
+// recordLag 记录分区消费延迟：高水位（下一条待写入的 offset）与当前消息之后 offset 之差
+func (g *groupHandler) recordLag(claim sarama.ConsumerGroupClaim, msg *sarama.ConsumerMessage) {
+	if g.metrics == nil {
+		return
+	}
+	lag := claim.HighWaterMarkOffset() - msg.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	g.metrics.RecordLag(msg.Topic, msg.Partition, lag)
+}
+
+// isRetryTopic 报告 topic 是否为重试 topic 链中的重试 topic
+func (g *groupHandler) isRetryTopic(topic string) bool {
+	rt, ok := g.strategy.(*retryTopicStrategy)
+	return ok && rt.isRetryTopic(topic)
+}
+
+// consumeBatch 批量消费分区消息：凑满 BatchConfig.Size 条或首条消息等待 MaxWait 后整批处理。
+// 会话结束时未处理的消息不提交 offset，由下一次分配重新投递。
+func (g *groupHandler) consumeBatch(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	size := g.batchConfig.Size
+	if size <= 0 {
+		size = types.DefaultBatchSize
+	}
+	messages := claim.Messages()
+	batch := make([]*sarama.ConsumerMessage, 0, size)
+
+	timer := time.NewTimer(g.batchConfig.MaxWait)
+	timer.Stop()
+	defer timer.Stop()
+
+	flush := func() {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if len(batch) > 0 {
+			g.processBatch(session, batch)
+			batch = make([]*sarama.ConsumerMessage, 0, size)
+		}
+	}
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				flush()
+				return nil
+			}
+			g.recordLag(claim, msg)
+			if len(batch) == 0 {
+				timer.Reset(g.batchConfig.MaxWait)
+			}
+			batch = append(batch, msg)
+			if len(batch) >= size {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// processBatch 调用批量 handler，随后按分区顺序将每条消息连同其处理结果交给重试策略：
+// 成功的消息由策略提交 offset（异步模式经 CommitStrategy），失败的消息进入既有的重试/死信流程
+func (g *groupHandler) processBatch(session sarama.ConsumerGroupSession, batch []*sarama.ConsumerMessage) {
+	ctx, span := startBatchConsumerSpan(session.Context(), batch)
+	defer span.End()
+
+	msgs := make([]types.Message, len(batch))
+	for i, m := range batch {
+		msgs[i] = newKafkaMessage(g.consumerGroup, m, 1)
+	}
+
+	batchCtx, cancel := ctx, context.CancelFunc(func() {})
+	if g.handlerTimeout > 0 {
+		batchCtx, cancel = context.WithTimeout(ctx, g.handlerTimeout)
+	}
+	err := g.batchHandler.HandleBatch(batchCtx, msgs)
+	cancel()
+	if session.Context().Err() != nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	for i, m := range batch {
+		g.strategy.OnMessage(types.WithBatchResult(ctx, types.BatchItemError(err, i)), session, m)
+	}
+}
+
 // Shutdown 通知重试策略排空队列并关闭
 func (g *groupHandler) Shutdown(ctx context.Context) {
 	g.strategy.OnShutdown(ctx)
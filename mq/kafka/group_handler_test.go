@@ -0,0 +1,229 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/dedup"
+	"github.com/gomooth/pkg/mq/internal/middleware"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecorder 记录每次 HandleBatch 收到的消息
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	fail    string
+}
+
+func (r *batchRecorder) HandleBatch(_ context.Context, msgs []types.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	batch := make([]string, len(msgs))
+	be := types.NewBatchError()
+	for i, m := range msgs {
+		batch[i] = string(m.Data)
+		if string(m.Data) == r.fail {
+			be.Fail(i, errors.New("bad row"))
+		}
+	}
+	r.batches = append(r.batches, batch)
+	return be.Err()
+}
+
+func (r *batchRecorder) get() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]string(nil), r.batches...)
+}
+
+func consumerMessages(values ...string) chan *sarama.ConsumerMessage {
+	ch := make(chan *sarama.ConsumerMessage, len(values))
+	for i, v := range values {
+		ch <- &sarama.ConsumerMessage{Topic: "orders", Partition: 0, Offset: int64(i), Value: []byte(v)}
+	}
+	return ch
+}
+
+func TestGroupHandler_BatchPartialFailure(t *testing.T) {
+	rec := &batchRecorder{fail: "b"}
+	var failed []types.Message
+	gh := newGroupHandler("g", &groupHandlerConf{
+		Handler:  types.NewBatchHandler(rec, types.WithBatchSize(3), types.WithBatchMaxWait(time.Hour)),
+		MaxRetry: 1,
+		Backoff:  &retry.FixedDelay{Wait: time.Millisecond},
+		FailedHandler: func(_ context.Context, msg types.Message, _ error) {
+			failed = append(failed, msg)
+		},
+	})
+
+	ch := consumerMessages("a", "b", "c", "d")
+	close(ch)
+	session := newMockSession()
+	require.NoError(t, gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}))
+
+	// 首批满 3 条；失败消息以单条批次重试；channel 关闭时提交剩余消息
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"b"}, {"d"}}, rec.get())
+
+	require.Len(t, failed, 1)
+	assert.Equal(t, "b", string(failed[0].Data))
+	assert.Equal(t, 2, failed[0].Attempt)
+
+	// 所有消息按分区顺序提交 offset
+	var offsets []int64
+	for _, m := range session.marks {
+		offsets = append(offsets, m.Offset)
+	}
+	assert.Equal(t, []int64{0, 1, 2, 3}, offsets)
+}
+
+func TestGroupHandler_BatchMaxWait(t *testing.T) {
+	rec := &batchRecorder{}
+	gh := newGroupHandler("g", &groupHandlerConf{
+		Handler: types.NewBatchHandler(rec, types.WithBatchSize(10), types.WithBatchMaxWait(30*time.Millisecond)),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := &mockConsumerGroupSession{ctx: ctx}
+	ch := consumerMessages("a", "b")
+
+	done := make(chan error, 1)
+	go func() { done <- gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}) }()
+
+	require.Eventually(t, func() bool { return len(rec.get()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, rec.get()[0])
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestGroupHandler_BatchNotCommittedOnSessionEnd(t *testing.T) {
+	rec := &batchRecorder{}
+	gh := newGroupHandler("g", &groupHandlerConf{
+		Handler: types.NewBatchHandler(rec, types.WithBatchSize(10), types.WithBatchMaxWait(time.Hour)),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &mockConsumerGroupSession{ctx: ctx}
+	ch := consumerMessages("a")
+
+	done := make(chan error, 1)
+	go func() { done <- gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	assert.NoError(t, <-done)
+	assert.Empty(t, rec.get(), "partial batch should not be processed after session ends")
+	assert.Empty(t, session.marks)
+}
+
+func TestGroupHandler_BatchPanicRecovered(t *testing.T) {
+	var calls atomic.Int32
+	var seen []string
+	var failed []types.Message
+	gh := newGroupHandler("g", &groupHandlerConf{
+		Handler: types.NewBatchHandler(types.FuncBatchHandler(func(_ context.Context, msgs []types.Message) error {
+			if calls.Add(1) == 1 {
+				panic("boom")
+			}
+			if string(msgs[0].Data) == "b" {
+				return errors.New("still bad")
+			}
+			return nil
+		}), types.WithBatchSize(2), types.WithBatchMaxWait(time.Hour)),
+		Middlewares: append(middleware.Server(false, nil, nil, nil), func(next types.IHandler) types.IHandler {
+			return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+				seen = append(seen, string(msg.Data))
+				return next.Handle(ctx, msg)
+			})
+		}),
+		MaxRetry: 1,
+		Backoff:  &retry.FixedDelay{Wait: time.Millisecond},
+		FailedHandler: func(_ context.Context, msg types.Message, _ error) {
+			failed = append(failed, msg)
+		},
+	})
+
+	ch := consumerMessages("a", "b")
+	close(ch)
+	session := newMockSession()
+	require.NoError(t, gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}))
+
+	// 整批调用前每条消息经过中间件；panic 被恢复为整批失败，消息逐条重试
+	assert.Equal(t, []string{"a", "b"}, seen[:2])
+	assert.Equal(t, int32(3), calls.Load())
+	require.Len(t, failed, 1)
+	assert.Equal(t, "b", string(failed[0].Data))
+	assert.Len(t, session.marks, 2)
+}
+
+func TestGroupHandler_BatchMiddlewareDropsMessage(t *testing.T) {
+	rec := &batchRecorder{}
+	var dropped []string
+	gh := newGroupHandler("g", &groupHandlerConf{
+		Handler: types.NewBatchHandler(rec, types.WithBatchSize(3), types.WithBatchMaxWait(time.Hour)),
+		Middlewares: []types.Middleware{func(next types.IHandler) types.IHandler {
+			return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+				if string(msg.Data) == "skip" {
+					dropped = append(dropped, string(msg.Data))
+					return nil
+				}
+				return next.Handle(ctx, msg)
+			})
+		}},
+	})
+
+	ch := consumerMessages("a", "skip", "c")
+	close(ch)
+	session := newMockSession()
+	require.NoError(t, gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}))
+
+	// 被跳过的消息不交给 HandleBatch，由单条处理流程再次经过中间件后按其决定提交
+	assert.Equal(t, [][]string{{"a", "c"}}, rec.get())
+	assert.Equal(t, []string{"skip", "skip"}, dropped)
+	assert.Len(t, session.marks, 3)
+}
+
+func TestGroupHandler_DedupInProgressNotDeadLettered(t *testing.T) {
+	store := dedup.NewMemoryStore()
+	ctx := context.Background()
+	var calls atomic.Int32
+	var failed []types.Message
+	gh := newGroupHandler("g", &groupHandlerConf{
+		Handler: dedup.NewHandler(types.FuncHandler(func(context.Context, types.Message) error {
+			calls.Add(1)
+			return nil
+		}), store,
+			dedup.WithKeyFunc(func(msg types.Message) string { return string(msg.Data) }),
+			dedup.WithInProgressPoll(5*time.Millisecond)),
+		MaxRetry: 0, // kafka 默认不重试
+		FailedHandler: func(_ context.Context, msg types.Message, _ error) {
+			failed = append(failed, msg)
+		},
+	})
+
+	// 其他消费者正在处理同一消息，稍后完成
+	acquired, _, err := store.Acquire(ctx, "orders:g:a", dedup.DefaultProcessingTTL)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	time.AfterFunc(30*time.Millisecond, func() { _ = store.Complete(ctx, "orders:g:a", dedup.DefaultDoneTTL) })
+
+	ch := consumerMessages("a")
+	close(ch)
+	session := newMockSession()
+	require.NoError(t, gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}))
+
+	// 重复消息等待处理者完成后被确认，不进入失败回调
+	assert.Empty(t, failed)
+	assert.Zero(t, calls.Load())
+	assert.Len(t, session.marks, 1)
+}
@@ -14,6 +14,7 @@ type RetryItem struct {
 	Key       []byte
 	Value     []byte
 	Headers   []HeaderKV
+	Timestamp time.Time
 
 	Attempt     uint
 	NextRetryAt time.Time
@@ -54,3 +54,29 @@ func BuildProducerConfig(timeout time.Duration) *sarama.Config {
 
 	return cfg
 }
+
+// BuildAdminConfig 构建 topic 管理（ClusterAdmin）使用的 sarama.Config
+func BuildAdminConfig(timeout time.Duration) *sarama.Config {
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V3_6_0_0
+	cfg.ClientID = hostname()
+	cfg.Net.DialTimeout = timeout
+	cfg.Admin.Timeout = timeout
+
+	return cfg
+}
+
+// TransactionalProducerConfig 基于 base 复制一份事务生产者配置：开启幂等写入并设置 transactional.id。
+// 事务要求 acks=all、单连接串行请求且至少重试一次，base 本身不被修改。
+func TransactionalProducerConfig(base *sarama.Config, transactionalID string) *sarama.Config {
+	cfg := *base
+	cfg.Producer.Idempotent = true
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Transaction.ID = transactionalID
+	cfg.Net.MaxOpenRequests = 1
+	if cfg.Producer.Retry.Max < 1 {
+		cfg.Producer.Retry.Max = 1
+	}
+	return &cfg
+}
@@ -61,3 +61,32 @@ func TestBuildProducerConfig(t *testing.T) {
 		t.Errorf("expected 10ms retry backoff, got %v", cfg.Producer.Retry.Backoff)
 	}
 }
+
+func TestBuildAdminConfig(t *testing.T) {
+	cfg := BuildAdminConfig(3 * time.Second)
+
+	if cfg.Version != sarama.V3_6_0_0 {
+		t.Errorf("expected version V3_6_0_0")
+	}
+	if cfg.Admin.Timeout != 3*time.Second {
+		t.Errorf("expected admin timeout 3s, got %v", cfg.Admin.Timeout)
+	}
+}
+
+func TestTransactionalProducerConfig(t *testing.T) {
+	base := BuildProducerConfig(5 * time.Second)
+	cfg := TransactionalProducerConfig(base, "tx-1")
+
+	if cfg.Producer.Transaction.ID != "tx-1" || !cfg.Producer.Idempotent {
+		t.Errorf("expected idempotent transactional config")
+	}
+	if cfg.Net.MaxOpenRequests != 1 {
+		t.Errorf("expected MaxOpenRequests 1, got %d", cfg.Net.MaxOpenRequests)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+	if base.Producer.Transaction.ID != "" || base.Producer.Idempotent {
+		t.Errorf("base config must not be modified")
+	}
+}
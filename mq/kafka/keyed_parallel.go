@@ -0,0 +1,148 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/mq/internal/logutil"
+	"github.com/gomooth/pkg/mq/kafka/internal"
+)
+
+const (
+	// keyedQueueSize 每个 key 分片 worker 的待处理队列长度
+	keyedQueueSize = 64
+	// maxKeyWorkers 分区内并行 worker 数上限。
+	// 分区内未完成的消息最多 maxKeyWorkers*(keyedQueueSize+1) 条，保持在水位线 pending 集合容量以内
+	maxKeyWorkers = 128
+)
+
+// keyedDispatcher 分区内按消息 key 分片的并行消费（未导出）。
+// 相同 key 的消息固定由同一 worker 按 offset 顺序处理，不同 key 的消息并行处理；
+// 无 key 的消息按 offset 分散到各 worker，不保证顺序。
+// 消息分发前在 WatermarkTracker 中标记为 pending，处理完成后标记成功，
+// 只提交水位线以内（连续完成）的 offset，处理中的消息不会被后续 offset 的提交跳过。
+type keyedDispatcher struct {
+	workers      int
+	strategy     retryStrategy
+	tracker      *internal.WatermarkTracker
+	backpressure *backpressure
+}
+
+func newKeyedDispatcher(workers int, strategy retryStrategy, logger logutil.Logger) *keyedDispatcher {
+	if workers > maxKeyWorkers {
+		workers = maxKeyWorkers
+	}
+	return &keyedDispatcher{
+		workers:  workers,
+		strategy: strategy,
+		tracker:  internal.NewWatermarkTracker(logger),
+	}
+}
+
+// setBackpressure 设置背压控制：分区处理中（已分发未完成）的消息数达到 high 时暂停拉取，
+// 回落到 low 及以下时恢复
+func (d *keyedDispatcher) setBackpressure(high, low int, flow *flowController) {
+	d.backpressure = &backpressure{high: high, low: low, backlog: inFlightBacklog{d.tracker}, flow: flow}
+}
+
+// checkBackpressure 分区处理中的消息数变化后检查背压水位
+func (d *keyedDispatcher) checkBackpressure(topic string, partition int32) {
+	if d.backpressure != nil {
+		d.backpressure.check(topic, partition)
+	}
+}
+
+// consume 并行消费分区消息，onMessage 在分发前按分区顺序调用。
+// 返回前等待各 worker 退出；会话结束后队列中未处理的消息不提交 offset，由下一次分配重新投递。
+func (d *keyedDispatcher) consume(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim,
+	onMessage func(msg *sarama.ConsumerMessage)) error {
+	topic, partition := claim.Topic(), claim.Partition()
+	defer d.tracker.ResetPartition(topic, partition)
+
+	ks := keyedSession{ConsumerGroupSession: session}
+	queues := make([]chan *sarama.ConsumerMessage, d.workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan *sarama.ConsumerMessage, keyedQueueSize)
+		wg.Add(1)
+		go func(queue <-chan *sarama.ConsumerMessage) {
+			defer wg.Done()
+			for msg := range queue {
+				d.process(session, ks, msg)
+			}
+		}(queues[i])
+	}
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+		wg.Wait()
+	}()
+
+	ctx := session.Context()
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			onMessage(msg)
+			d.tracker.MarkPending(msg.Topic, msg.Partition, msg.Offset)
+			d.checkBackpressure(msg.Topic, msg.Partition)
+			select {
+			case queues[d.shard(msg)] <- msg:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// process 交给重试策略处理单条消息，完成后推进水位线并提交。
+// 与逐条消费一致，死信处理失败的消息不阻塞后续 offset 的提交。
+func (d *keyedDispatcher) process(session sarama.ConsumerGroupSession, ks keyedSession, msg *sarama.ConsumerMessage) {
+	ctx := session.Context()
+	if ctx.Err() != nil {
+		return
+	}
+	d.strategy.OnMessage(ctx, ks, msg)
+	if ctx.Err() != nil {
+		return
+	}
+
+	d.tracker.MarkSuccess(msg.Topic, msg.Partition, msg.Offset)
+	d.checkBackpressure(msg.Topic, msg.Partition)
+	if wm, ok := d.tracker.Watermark(msg.Topic, msg.Partition); ok {
+		session.MarkOffset(msg.Topic, msg.Partition, wm+1, "")
+	}
+}
+
+// shard 按消息 key 选择 worker
+func (d *keyedDispatcher) shard(msg *sarama.ConsumerMessage) int {
+	if len(msg.Key) == 0 {
+		return int(msg.Offset % int64(d.workers))
+	}
+	h := fnv.New32a()
+	h.Write(msg.Key)
+	return int(h.Sum32() % uint32(d.workers))
+}
+
+// inFlightBacklog 以水位线跟踪器中 pending 的消息数作为分区积压
+type inFlightBacklog struct {
+	tracker *internal.WatermarkTracker
+}
+
+func (b inFlightBacklog) Backlog(topic string, partition int32) int {
+	return b.tracker.PendingCount(topic, partition)
+}
+
+// keyedSession 屏蔽重试策略的逐条 offset 提交，改由 keyedDispatcher 按水位线提交
+type keyedSession struct {
+	sarama.ConsumerGroupSession
+}
+
+func (keyedSession) MarkMessage(*sarama.ConsumerMessage, string) {}
+func (keyedSession) MarkOffset(string, int32, int64, string)     {}
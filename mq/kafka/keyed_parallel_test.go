@@ -0,0 +1,236 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// offsetSession 并发安全地记录 MarkOffset / MarkMessage 的 mock 会话
+type offsetSession struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	offset int64
+	marks  int
+}
+
+func (s *offsetSession) Claims() map[string][]int32 { return nil }
+func (s *offsetSession) MemberID() string           { return "test-member" }
+func (s *offsetSession) GenerationID() int32        { return 1 }
+func (s *offsetSession) MarkOffset(_ string, _ int32, offset int64, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offset > s.offset {
+		s.offset = offset
+	}
+}
+func (s *offsetSession) Commit()                                  {}
+func (s *offsetSession) ResetOffset(string, int32, int64, string) {}
+func (s *offsetSession) MarkMessage(*sarama.ConsumerMessage, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marks++
+}
+func (s *offsetSession) Context() context.Context { return s.ctx }
+func (s *offsetSession) Close()                   {}
+
+func (s *offsetSession) committed() (offset int64, marks int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset, s.marks
+}
+
+func newKeyedGroupHandler(handler types.IHandler, workers int) *groupHandler {
+	return newGroupHandler("test-group", &groupHandlerConf{
+		Logger:     newTestSlogLogger(),
+		Handler:    handler,
+		Backoff:    &retry.ExponentialDelay{Base: time.Millisecond, Max: time.Millisecond},
+		KeyWorkers: workers,
+	})
+}
+
+func keyedMessage(key string, offset int64) *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{Topic: "test", Key: []byte(key), Offset: offset, Value: []byte(fmt.Sprint(offset))}
+}
+
+func TestKeyedParallel_PerKeyOrderAcrossWorkers(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string][]int64)
+	handler := types.FuncHandler(func(_ context.Context, msg types.Message) error {
+		if msg.Key == "slow" {
+			time.Sleep(2 * time.Millisecond)
+		}
+		mu.Lock()
+		seen[msg.Key] = append(seen[msg.Key], msg.Offset)
+		mu.Unlock()
+		return nil
+	})
+	gh := newKeyedGroupHandler(handler, 4)
+	require.NotNil(t, gh.keyed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := &offsetSession{ctx: ctx}
+	ch := make(chan *sarama.ConsumerMessage, 64)
+	keys := []string{"slow", "a", "b", "c"}
+	for i := int64(0); i < 40; i++ {
+		ch <- keyedMessage(keys[i%4], i)
+	}
+	close(ch)
+
+	require.NoError(t, gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}))
+
+	for _, key := range keys {
+		require.Len(t, seen[key], 10)
+		assert.IsIncreasing(t, seen[key], "key %s processed in offset order", key)
+	}
+	offset, marks := session.committed()
+	assert.Equal(t, int64(40), offset)
+	assert.Zero(t, marks, "strategy marks are replaced by watermark commits")
+}
+
+func TestKeyedParallel_CommitsOnlyContiguousOffsets(t *testing.T) {
+	release := make(chan struct{})
+	handler := types.FuncHandler(func(_ context.Context, msg types.Message) error {
+		if msg.Key == "blocked" {
+			<-release
+		}
+		return nil
+	})
+	gh := newKeyedGroupHandler(handler, 8)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := &offsetSession{ctx: ctx}
+	ch := make(chan *sarama.ConsumerMessage, 8)
+	ch <- keyedMessage("other", 10)
+	ch <- keyedMessage("blocked", 11)
+	for i := int64(12); i < 16; i++ {
+		ch <- keyedMessage(fmt.Sprintf("k%d", i), i)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}) }()
+
+	// 其他 key 的消息先完成，offset 停在阻塞消息处
+	assert.Eventually(t, func() bool {
+		offset, _ := session.committed()
+		return offset == 11
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	offset, _ := session.committed()
+	assert.Equal(t, int64(11), offset)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		offset, _ := session.committed()
+		return offset == 16
+	}, time.Second, time.Millisecond)
+
+	close(ch)
+	require.NoError(t, <-done)
+}
+
+func TestKeyedParallel_SessionEndSkipsQueued(t *testing.T) {
+	started := make(chan struct{})
+	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+		if msg.Offset == 1 {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		t.Errorf("queued message %d processed after session end", msg.Offset)
+		return nil
+	})
+	gh := newKeyedGroupHandler(handler, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &offsetSession{ctx: ctx}
+	ch := make(chan *sarama.ConsumerMessage, 4)
+	ch <- keyedMessage("k", 1)
+	ch <- keyedMessage("k", 2)
+
+	done := make(chan error, 1)
+	go func() { done <- gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}) }()
+	<-started
+	assert.Eventually(t, func() bool { return len(ch) == 0 }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	offset, _ := session.committed()
+	assert.Zero(t, offset)
+}
+
+func TestKeyedParallel_BackpressureOnInFlight(t *testing.T) {
+	release := make(chan struct{})
+	handler := types.FuncHandler(func(context.Context, types.Message) error {
+		<-release
+		return nil
+	})
+	pauser := newFakePauser()
+	gh := newGroupHandler("test-group", &groupHandlerConf{
+		Logger:           newTestSlogLogger(),
+		Handler:          handler,
+		KeyWorkers:       4,
+		Flow:             newFlowController(pauser, nil),
+		BackpressureHigh: 3,
+		BackpressureLow:  1,
+	})
+	require.NotNil(t, gh.keyed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := &offsetSession{ctx: ctx}
+	ch := make(chan *sarama.ConsumerMessage, 4)
+	done := make(chan error, 1)
+	go func() { done <- gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}) }()
+
+	ch <- keyedMessage("a", 0)
+	ch <- keyedMessage("b", 1)
+	assert.Never(t, func() bool { return pauser.isPaused("test", 0) }, 20*time.Millisecond, time.Millisecond)
+	ch <- keyedMessage("c", 2)
+	assert.Eventually(t, func() bool { return pauser.isPaused("test", 0) }, time.Second, time.Millisecond,
+		"in-flight messages reaching high pause the partition")
+
+	close(release)
+	assert.Eventually(t, func() bool { return !pauser.isPaused("test", 0) }, time.Second, time.Millisecond)
+
+	close(ch)
+	require.NoError(t, <-done)
+}
+
+func TestKeyedParallel_DisabledForAsyncRetry(t *testing.T) {
+	handler := types.FuncHandler(func(context.Context, types.Message) error { return nil })
+	gh := newGroupHandler("test-group", &groupHandlerConf{
+		Logger:     newTestSlogLogger(),
+		Handler:    handler,
+		RetryMode:  types.RetryModeRequeue,
+		KeyWorkers: 4,
+	})
+	defer gh.Shutdown(context.Background())
+	assert.Nil(t, gh.keyed)
+
+	assert.Nil(t, newKeyedGroupHandler(handler, 1).keyed, "single worker keeps serial consumption")
+}
+
+func TestKeyedDispatcher_Shard(t *testing.T) {
+	d := newKeyedDispatcher(1000, nil, nil)
+	assert.Equal(t, maxKeyWorkers, d.workers)
+
+	assert.Equal(t, d.shard(keyedMessage("order-1", 1)), d.shard(keyedMessage("order-1", 99)))
+	assert.Equal(t, 5, d.shard(&sarama.ConsumerMessage{Offset: 5}), "messages without key spread by offset")
+}
+
+func TestWithKeyedParallelism(t *testing.T) {
+	cfg := &consumerConfig{}
+	WithKeyedParallelism(8)(cfg)
+	assert.Equal(t, 8, cfg.keyWorkers)
+}
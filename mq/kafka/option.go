@@ -8,6 +8,7 @@ import (
 	"github.com/IBM/sarama"
 	"github.com/gomooth/pkg/framework/retry"
 	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/pkg/mq/kafka/internal"
 )
 
 // ==================== Consumer 选项 ====================
@@ -28,20 +29,58 @@ type consumerConfig struct {
 	handlerTimeout           time.Duration
 	syncRetryMaxTotalTimeout time.Duration
 	retryMode                types.RetryMode
+	retryIf                  func(error) bool
 	retryWorkers             int
 	retryStore               RetryStore
 
+	// 背压
+	backpressureHigh int
+	backpressureLow  int
+
+	// 分区内按 key 并行消费
+	keyWorkers int
+
 	// 失败处理
 	failedHandler       types.FailedHandlerFunc
 	groupFailedHandlers map[string]types.FailedHandlerFunc
+	deadLetterTopic     deadLetterTopicFunc
+	retryTopics         []time.Duration
+
+	// exactly-once 消费
+	transactionalIDPrefix string
+	delayTopic            string
+
+	// 启动时预置 topic
+	topicAdmin *TopicAdmin
 
 	// Panic 处理
 	panicHandler func(any)
 
+	// handler 中间件
+	middlewares          []types.Middleware
+	noDefaultMiddlewares bool
+
 	// 预注册消费者
 	consumers []ConsumerRegistration
 }
 
+// consumerSaramaConfig 消费者组使用的 sarama.Config：未自定义时按默认构建，
+// exactly-once 模式下仅读取已提交的事务消息
+func (c *consumerConfig) consumerSaramaConfig() *sarama.Config {
+	if c.saramaConfig != nil {
+		return c.saramaConfig
+	}
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	cfg := internal.BuildConsumerConfig(timeout)
+	if c.transactionalIDPrefix != "" {
+		cfg.Consumer.IsolationLevel = sarama.ReadCommitted
+	}
+	return cfg
+}
+
 // WithMaxRetry 设置最大重试次数（默认 0，即不重试）
 func WithMaxRetry(n int) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -63,13 +102,30 @@ func WithHandlerTimeout(d time.Duration) ConsumerOption {
 	}
 }
 
-// WithPanicHandler 设置 panic 恢复后的回调函数
+// WithPanicHandler 设置 panic 恢复后的回调函数（handler panic 由内置 Recover 中间件恢复后回调）
 func WithPanicHandler(fn func(any)) ConsumerOption {
 	return func(c *consumerConfig) {
 		c.panicHandler = fn
 	}
 }
 
+// WithMiddleware 追加服务级 handler 中间件，作用于该消费者注册的所有 handler。
+// 服务级中间件位于内置中间件（Trace → Recover）之内、注册级中间件（mq.WithMiddleware）之外；
+// 先传入的中间件位于外层。
+func WithMiddleware(mws ...types.Middleware) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithoutDefaultMiddlewares 禁用内置的 Trace 与 Recover 中间件，
+// 可通过 WithMiddleware 以 mq.Trace / mq.Recover 自定义顺序或替换实现
+func WithoutDefaultMiddlewares() ConsumerOption {
+	return func(c *consumerConfig) {
+		c.noDefaultMiddlewares = true
+	}
+}
+
 // WithRetryMode 设置重试模式（同步或异步）
 func WithRetryMode(mode types.RetryMode) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -77,6 +133,14 @@ func WithRetryMode(mode types.RetryMode) ConsumerOption {
 	}
 }
 
+// WithRetryIf 设置错误重试谓词：返回 false 的错误不再重试，直接进入死信处理器或失败回调（默认所有错误都重试）。
+// mq.Permanent 包装的错误与解码失败始终不重试。
+func WithRetryIf(fn func(error) bool) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryIf = fn
+	}
+}
+
 // WithRetryWorkers 设置异步重试的 worker 数量（默认 CPU 核数）
 func WithRetryWorkers(n int) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -91,6 +155,30 @@ func WithRetryMaxQueueSize(n int) ConsumerOption {
 	}
 }
 
+// WithBackpressure 启用背压控制：分区积压达到 high 时暂停该分区拉取，
+// 回落到 low 及以下时恢复（low 不小于 0 且小于 high，否则取 high/2）。
+// 异步重试模式下积压为重试积压（排队中与处理中），需要重试存储实现 BacklogStore（MemoryRetryStore）；
+// WithKeyedParallelism 下积压为分区内处理中（已分发未完成）的消息数。
+// 逐条同步消费时分区处理中至多一条消息，背压不适用，设置后仅记录告警。
+func WithBackpressure(high, low int) ConsumerOption {
+	return func(c *consumerConfig) {
+		if low < 0 || low >= high {
+			low = high / 2
+		}
+		c.backpressureHigh = high
+		c.backpressureLow = low
+	}
+}
+
+// WithKeyedParallelism 分区内按消息 key 分片到 n 个 worker 并行处理（n 最大 128）：
+// 相同 key 的消息保持 offset 顺序，不同 key 的消息并行，offset 按水位线只提交连续完成的部分。
+// 仅同步重试模式与重试 topic 链支持，异步重试、exactly-once 与批量 handler 下不生效。
+func WithKeyedParallelism(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.keyWorkers = n
+	}
+}
+
 // WithSyncRetryMaxTotalTimeout 设置同步重试的最大总超时时间
 func WithSyncRetryMaxTotalTimeout(d time.Duration) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -122,6 +210,69 @@ func WithConsumeGroupFailedHandler(group string, fn types.FailedHandlerFunc) Con
 	}
 }
 
+// WithDeadLetterTopic 启用内置死信 topic：重试耗尽的消息写入 <topic><suffix>（suffix 为空时使用 DefaultDeadLetterSuffix），
+// record headers 记录原始 topic/分区/offset、消费者组、处理次数与最后错误。
+// handler 自身实现 DeadLetterHandler 时优先使用 handler；启用后不再调用 FailedHandler。
+func WithDeadLetterTopic(suffix string) ConsumerOption {
+	return func(c *consumerConfig) {
+		if suffix == "" {
+			suffix = DefaultDeadLetterSuffix
+		}
+		c.deadLetterTopic = suffixTopic(suffix)
+	}
+}
+
+// WithDeadLetterTopicFunc 启用内置死信 topic，并由 fn 根据原始 topic 计算死信 topic
+func WithDeadLetterTopicFunc(fn func(topic string) string) ConsumerOption {
+	return func(c *consumerConfig) {
+		if fn != nil {
+			c.deadLetterTopic = fn
+		}
+	}
+}
+
+// WithRetryTopics 启用重试 topic 链，替代进程内重试（设置后忽略 WithRetryMode 与 WithMaxRetry）。
+// 处理失败的消息依次写入 <topic>.<group>.retry-<delay>（如 orders.order-group.retry-5s），
+// 重试 topic 按消费者组隔离，消费者组同时订阅本组的各级重试 topic，到期后再次处理；最后一级仍失败则进入死信流程。
+// 重试 topic 需预先创建或开启 broker 自动建 topic。
+func WithRetryTopics(delays ...time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryTopics = nil
+		for _, d := range delays {
+			if d > 0 {
+				c.retryTopics = append(c.retryTopics, d)
+			}
+		}
+	}
+}
+
+// WithExactlyOnce 启用 exactly-once 消费（consume-transform-produce）：每条消息在事务内处理，
+// handler 通过 TxFromContext 获取事务发送下游消息，消费 offset 随事务提交而非经 session 提交，
+// 处理失败时中止事务并按 WithMaxRetry / WithBackoff 同步重试，内置死信 topic 在同一事务内发布。
+// 每个分区使用独立的事务 producer，transactional.id 为 <prefix>-<group>-<topic>-<partition>；
+// 内置消费配置改为 read_committed，自定义 sarama.Config 需自行设置 Consumer.IsolationLevel。
+// 设置后忽略 WithRetryMode 与 WithRetryTopics，批量 handler 逐条处理。
+func WithExactlyOnce(transactionalIDPrefix string) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.transactionalIDPrefix = transactionalIDPrefix
+	}
+}
+
+// WithConsumerDelayTopic 设置 exactly-once 模式下 handler 经 TxFromContext 发送的延迟消息（WithDelay / WithDeliverAt）
+// 写入的延迟 topic（默认 DefaultDelayTopic），应与 DelayScheduler 消费的 topic 及生产者的 WithProducerDelayTopic 一致
+func WithConsumerDelayTopic(topic string) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.delayTopic = topic
+	}
+}
+
+// WithTopicAdmin 消费者启动前按 TopicAdmin 预置 topic（创建 / 扩容分区 / 修改配置），失败时 Start 返回错误
+func WithTopicAdmin(admin *TopicAdmin) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.topicAdmin = admin
+	}
+}
+
 // WithConsumers 批量预注册消费者
 func WithConsumers(regs ...ConsumerRegistration) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -172,6 +323,12 @@ type producerConfig struct {
 	logger       *slog.Logger
 	timeout      time.Duration
 	saramaConfig *sarama.Config
+	delayTopic   string
+	schemas      []SchemaRegistrant
+	topicAdmin   *TopicAdmin
+
+	// 事务模式，由 NewTransactionalProducer 设置
+	transactionalID string
 }
 
 // WithProducerTimeout 设置生产者连接超时时间（默认 5s）
@@ -195,6 +352,29 @@ func WithProducerSaramaConfig(cfg *sarama.Config) ProducerOption {
 	}
 }
 
+// WithProducerDelayTopic 设置延迟消息（WithDelay / WithDeliverAt）写入的延迟 topic（默认 DefaultDelayTopic）。
+// 延迟 topic 需由 DelayScheduler 消费并在到期后转发到目标 topic。
+func WithProducerDelayTopic(topic string) ProducerOption {
+	return func(c *producerConfig) {
+		c.delayTopic = topic
+	}
+}
+
+// WithProducerSchemas 设置生产者启动时注册的 schema（schemaregistry 的 Serde）：
+// 启动阶段逐个检查与 subject 最新版本的兼容性并注册，任一失败时 Start 返回错误
+func WithProducerSchemas(schemas ...SchemaRegistrant) ProducerOption {
+	return func(c *producerConfig) {
+		c.schemas = append(c.schemas, schemas...)
+	}
+}
+
+// WithProducerTopicAdmin 生产者启动前按 TopicAdmin 预置 topic，失败时 Start 返回错误
+func WithProducerTopicAdmin(admin *TopicAdmin) ProducerOption {
+	return func(c *producerConfig) {
+		c.topicAdmin = admin
+	}
+}
+
 // ==================== 辅助：适配旧 FailedHandlerFunc 签名 ====================
 
 // adaptFailedHandler 将旧版 kafka.FailedHandlerFunc(ctx, group, topic, message, err) 适配为统一 types.FailedHandlerFunc。
@@ -207,4 +387,4 @@ func adaptFailedHandler(fn func(ctx context.Context, consumerGroup string, topic
 		group, _ := msg.KafkaGroup()
 		fn(ctx, group, msg.Queue, msg.Data, err)
 	}
-}
\ No newline at end of file
+}
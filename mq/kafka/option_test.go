@@ -185,4 +185,23 @@ func TestAdaptFailedHandler_Adapts(t *testing.T) {
 	assert.Equal(t, "my-group", gotGroup)
 	assert.Equal(t, "my-topic", gotTopic)
 	assert.Equal(t, []byte("hello"), gotMessage)
-}
\ No newline at end of file
+}
+
+func TestConsumerOption_WithDeadLetterTopic(t *testing.T) {
+	cfg := consumerConfig{}
+	WithDeadLetterTopic("")(&cfg)
+	require.NotNil(t, cfg.deadLetterTopic)
+	assert.Equal(t, "orders.DLT", cfg.deadLetterTopic("orders"))
+
+	WithDeadLetterTopic("-dead")(&cfg)
+	assert.Equal(t, "orders-dead", cfg.deadLetterTopic("orders"))
+
+	WithDeadLetterTopicFunc(func(string) string { return "all.dlt" })(&cfg)
+	assert.Equal(t, "all.dlt", cfg.deadLetterTopic("orders"))
+}
+
+func TestConsumerOption_WithRetryTopics(t *testing.T) {
+	cfg := consumerConfig{}
+	WithRetryTopics(5*time.Second, 0, time.Minute)(&cfg)
+	assert.Equal(t, []time.Duration{5 * time.Second, time.Minute}, cfg.retryTopics)
+}
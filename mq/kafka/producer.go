@@ -10,7 +10,8 @@ import (
 // NewProducer 创建生产者实例
 func NewProducer(brokers []string, opts ...ProducerOption) types.IProducer {
 	cfg := producerConfig{
-		timeout: 5 * time.Second,
+		timeout:    5 * time.Second,
+		delayTopic: DefaultDelayTopic,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
@@ -20,13 +21,31 @@ func NewProducer(brokers []string, opts ...ProducerOption) types.IProducer {
 	return &producerImpl{engine: engine}
 }
 
+// NewTransactionalProducer 创建事务生产者实例：以 transactionalID 开启幂等写入与 Kafka 事务，
+// ProduceInTx 内发送的多条消息原子提交；Produce / ProduceBatch 各自在独立事务中发送。
+// 同一 transactionalID 同时只应有一个活跃实例，新实例启动后旧实例被 broker 隔离。
+// 自定义 sarama.Config（WithProducerSaramaConfig）会被复制后补齐事务所需配置。
+func NewTransactionalProducer(brokers []string, transactionalID string, opts ...ProducerOption) ITransactionalProducer {
+	cfg := producerConfig{
+		timeout:    5 * time.Second,
+		delayTopic: DefaultDelayTopic,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.transactionalID = transactionalID
+
+	engine := newProducerEngine(brokers, &cfg)
+	return &producerImpl{engine: engine}
+}
+
 // producerImpl 生产者实现，包装 producerEngine 并实现 IProducer 接口
 type producerImpl struct {
 	engine *producerEngine
 }
 
 // 编译时接口检查
-var _ types.IProducer = (*producerImpl)(nil)
+var _ ITransactionalProducer = (*producerImpl)(nil)
 
 func (p *producerImpl) Start(ctx context.Context) error {
 	return p.engine.Start(ctx)
@@ -42,4 +61,9 @@ func (p *producerImpl) Produce(ctx context.Context, dest string, message []byte,
 
 func (p *producerImpl) ProduceBatch(ctx context.Context, dest string, messages [][]byte, opts ...types.ProduceOption) error {
 	return p.engine.ProduceBatch(ctx, dest, messages, opts...)
+}
+
+// ProduceInTx 在一个 Kafka 事务内执行 fn，仅 NewTransactionalProducer 创建的实例可用
+func (p *producerImpl) ProduceInTx(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	return p.engine.ProduceInTx(ctx, fn)
 }
\ No newline at end of file
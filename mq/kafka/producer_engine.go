@@ -20,13 +20,20 @@ import (
 // producerEngine 生产者生命周期引擎（未导出）
 type producerEngine struct {
 	engine.Base
-	brokers []string
-	timeout time.Duration
+	brokers    []string
+	timeout    time.Duration
+	delayTopic string
+	schemas    []SchemaRegistrant
+	topicAdmin *TopicAdmin
 
 	mu     sync.RWMutex
 	inner  sarama.SyncProducer
 	config *sarama.Config
 
+	// 事务模式（NewTransactionalProducer）下串行执行事务
+	transactional bool
+	txMu          sync.Mutex
+
 	reconnectCh chan struct{}
 }
 
@@ -48,16 +55,28 @@ func newProducerEngine(brokers []string, cfg *producerConfig) *producerEngine {
 	if saramaConfig == nil {
 		saramaConfig = internal.BuildProducerConfig(timeout)
 	}
+	if cfg.transactionalID != "" {
+		saramaConfig = internal.TransactionalProducerConfig(saramaConfig, cfg.transactionalID)
+	}
+
+	delayTopic := cfg.delayTopic
+	if delayTopic == "" {
+		delayTopic = DefaultDelayTopic
+	}
 
 	return &producerEngine{
 		Base: engine.Base{
 			Logger:  logger,
 			Metrics: metrics.NewProducerMetrics("kafka"),
 		},
-		brokers:     brokers,
-		timeout:     timeout,
-		config:      saramaConfig,
-		reconnectCh: make(chan struct{}, 1),
+		brokers:       brokers,
+		timeout:       timeout,
+		delayTopic:    delayTopic,
+		schemas:       cfg.schemas,
+		topicAdmin:    cfg.topicAdmin,
+		config:        saramaConfig,
+		transactional: cfg.transactionalID != "",
+		reconnectCh:   make(chan struct{}, 1),
 	}
 }
 
@@ -69,6 +88,21 @@ func (e *producerEngine) Start(ctx context.Context) error {
 		return xerror.NewXCode(xcode.ErrMQPublish, "producer already closed")
 	}
 
+	// 连接前预置 topic，检查 schema 兼容性并注册，失败时不启动
+	if e.topicAdmin != nil {
+		if _, err := e.topicAdmin.Ensure(ctx); err != nil {
+			e.State.Store(engine.Idle)
+			return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+		}
+	}
+
+	for _, schema := range e.schemas {
+		if err := schema.Register(ctx); err != nil {
+			e.State.Store(engine.Idle)
+			return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+		}
+	}
+
 	// 初始连接
 	p, err := sarama.NewSyncProducer(e.brokers, e.config)
 	if err != nil {
@@ -119,16 +153,17 @@ func (e *producerEngine) Shutdown(ctx context.Context) error {
 }
 
 func (e *producerEngine) Produce(ctx context.Context, topic string, message []byte, opts ...types.ProduceOption) error {
-	produceCfg := types.ApplyProduceOptions(opts)
-
-	msgs := []*sarama.ProducerMessage{
-		{Topic: topic, Value: sarama.ByteEncoder(message)},
+	if e.transactional {
+		return e.ProduceInTx(ctx, func(ctx context.Context, tx Tx) error {
+			return tx.Produce(ctx, topic, message, opts...)
+		})
 	}
 
-	// If OrderKey is set, use ordered production logic (merged from ProduceOrdered)
-	if produceCfg.OrderKey != "" {
-		msgs[0].Key = sarama.ByteEncoder(produceCfg.OrderKey)
-	}
+	produceCfg := types.ApplyProduceOptions(opts)
+
+	// OrderKey 映射为 record key，用于有序生产（原 ProduceOrdered）
+	msgs := []*sarama.ProducerMessage{newProducerMessage(topic, message, produceCfg)}
+	e.routeDelayed(msgs, produceCfg)
 
 	ctx, span := injectProducerTrace(ctx, topic, msgs)
 	defer span.End()
@@ -147,20 +182,21 @@ func (e *producerEngine) ProduceBatch(ctx context.Context, topic string, message
 	if len(messages) == 0 {
 		return xerror.NewXCode(xcode.ErrMQPublish, "no messages")
 	}
+	if e.transactional {
+		return e.ProduceInTx(ctx, func(ctx context.Context, tx Tx) error {
+			return tx.ProduceBatch(ctx, topic, messages, opts...)
+		})
+	}
 
+	// 批量生产时每条消息独立生成消息 ID，OrderKey 与消息头作用于所有消息
 	produceCfg := types.ApplyProduceOptions(opts)
+	produceCfg.MessageID = ""
 
 	msgs := make([]*sarama.ProducerMessage, len(messages))
 	for i, msg := range messages {
-		msgs[i] = &sarama.ProducerMessage{
-			Topic: topic,
-			Value: sarama.ByteEncoder(msg),
-		}
-		// If OrderKey is set, apply to all messages in batch
-		if produceCfg.OrderKey != "" {
-			msgs[i].Key = sarama.ByteEncoder(produceCfg.OrderKey)
-		}
+		msgs[i] = newProducerMessage(topic, msg, produceCfg)
 	}
+	e.routeDelayed(msgs, produceCfg)
 
 	ctx, span := injectProducerTrace(ctx, topic, msgs)
 	defer span.End()
@@ -175,6 +211,58 @@ func (e *producerEngine) ProduceBatch(ctx context.Context, topic string, message
 	return err
 }
 
+// ProduceInTx 在一个 Kafka 事务内执行 fn：fn 经 tx 发送的消息在 fn 返回 nil 后原子提交；
+// fn 返回错误或提交失败时中止事务并返回错误。事务按调用顺序串行执行。
+// producer 进入致命错误状态（如被相同 transactional.id 的新实例隔离）时断开并触发重连。
+func (e *producerEngine) ProduceInTx(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error {
+	if !e.transactional {
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer is not transactional")
+	}
+	if err := ctx.Err(); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	e.txMu.Lock()
+	defer e.txMu.Unlock()
+
+	e.mu.RLock()
+	producer := e.inner
+	e.mu.RUnlock()
+
+	if producer == nil {
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer not connected")
+	}
+
+	sent, err := runTx(ctx, producer, e.delayTopic, fn, nil)
+	if err != nil {
+		if m, ok := e.Metrics.(*metrics.ProducerMetrics); ok && m != nil {
+			m.OnError()
+		}
+		if txBroken(producer) {
+			e.Logger.Error("transactional producer in unrecoverable state, reconnecting", "error", err)
+			e.markDisconnected()
+			e.triggerReconnect()
+		}
+		return err
+	}
+
+	if m, ok := e.Metrics.(*metrics.ProducerMetrics); ok && m != nil && sent > 0 {
+		m.OnProduce(sent)
+	}
+	return nil
+}
+
+// routeDelayed 设置了延迟投递时将消息改写到延迟 topic，由 DelayScheduler 到期后转发
+func (e *producerEngine) routeDelayed(msgs []*sarama.ProducerMessage, cfg *types.ProduceConfig) {
+	at, delayed := cfg.DeliveryTime()
+	if !delayed {
+		return
+	}
+	for _, msg := range msgs {
+		routeDelayed(msg, e.delayTopic, at)
+	}
+}
+
 func (e *producerEngine) send(ctx context.Context, msgs []*sarama.ProducerMessage) error {
 	if err := ctx.Err(); err != nil {
 		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
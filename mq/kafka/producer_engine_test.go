@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"testing"
@@ -158,6 +159,35 @@ func TestProducerEngine_StartStateTransition(t *testing.T) {
 	}
 }
 
+// schemaFunc 以函数实现 SchemaRegistrant
+type schemaFunc func(ctx context.Context) error
+
+func (f schemaFunc) Register(ctx context.Context) error { return f(ctx) }
+
+func TestProducerEngine_StartRegistersSchemas(t *testing.T) {
+	incompatible := errors.New("schema incompatible")
+	var registered []string
+	eng := newProducerEngine([]string{"localhost:9092"}, &producerConfig{
+		logger: newTestSlogLogger(),
+		schemas: []SchemaRegistrant{
+			schemaFunc(func(context.Context) error { registered = append(registered, "orders"); return nil }),
+			schemaFunc(func(context.Context) error { return incompatible }),
+			schemaFunc(func(context.Context) error { registered = append(registered, "audit"); return nil }),
+		},
+	})
+
+	err := eng.Start(context.Background())
+	if !errors.Is(err, incompatible) {
+		t.Fatalf("expected schema error, got %v", err)
+	}
+	if len(registered) != 1 {
+		t.Errorf("expected registration to stop at the failing schema, got %v", registered)
+	}
+	if eng.State.Load() != engine.Idle {
+		t.Errorf("expected state %d, got %d", engine.Idle, eng.State.Load())
+	}
+}
+
 func TestProducerEngine_ShutdownFromIdle(t *testing.T) {
 	eng := &producerEngine{
 		brokers:     []string{"localhost:9092"},
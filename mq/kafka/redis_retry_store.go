@@ -259,6 +259,9 @@ func (s *RedisRetryStore) toRedisFields(item *internal.RetryItem) map[string]int
 		"consumerGroup": item.ConsumerGroup,
 	}
 
+	if !item.Timestamp.IsZero() {
+		fields["timestamp"] = strconv.FormatInt(item.Timestamp.UnixMilli(), 10)
+	}
 	if len(item.Key) > 0 {
 		fields["key"] = base64.StdEncoding.EncodeToString(item.Key)
 	}
@@ -309,6 +312,14 @@ func (s *RedisRetryStore) fromRedisFields(fields map[string]string) (*internal.R
 		ConsumerGroup: fields["consumerGroup"],
 	}
 
+	if v, ok := fields["timestamp"]; ok {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp: %w", err)
+		}
+		item.Timestamp = time.UnixMilli(ms)
+	}
+
 	if v, ok := fields["key"]; ok {
 		decoded, err := base64.StdEncoding.DecodeString(v)
 		if err != nil {
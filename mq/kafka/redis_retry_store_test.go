@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/gomooth/pkg/mq/kafka/internal"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -671,3 +672,18 @@ func TestRedisRetryStore_RemoveWithRetryStoreError(t *testing.T) {
 	err := store.Remove(context.Background(), item)
 	assert.Error(t, err)
 }
+
+func TestRedisRetryStore_Fields_Timestamp(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+	at := time.UnixMilli(time.Now().UnixMilli())
+	item := &internal.RetryItem{Topic: "test", Attempt: 1, NextRetryAt: at, ConsumerGroup: "g", Timestamp: at}
+
+	raw := store.toRedisFields(item)
+	fields := make(map[string]string, len(raw))
+	for k, v := range raw {
+		fields[k] = v.(string)
+	}
+	got, err := store.fromRedisFields(fields)
+	require.NoError(t, err)
+	assert.True(t, at.Equal(got.Timestamp))
+}
@@ -0,0 +1,273 @@
+package kafka
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/kafka/internal"
+	"github.com/gomooth/xerror"
+)
+
+// DefaultReplayGroup 死信重放默认消费者组，用于记录各死信 topic 的重放进度
+const DefaultReplayGroup = "mq.dlt.replay"
+
+// ReplayOption 死信重放配置选项
+type ReplayOption func(*replayConfig)
+
+// replayConfig 死信重放配置（未导出）
+type replayConfig struct {
+	logger       *slog.Logger
+	timeout      time.Duration
+	idleTimeout  time.Duration
+	group        string
+	saramaConfig *sarama.Config
+}
+
+// WithReplayLogger 设置死信重放日志器
+func WithReplayLogger(l *slog.Logger) ReplayOption {
+	return func(c *replayConfig) {
+		c.logger = l
+	}
+}
+
+// WithReplayGroup 设置记录重放进度的消费者组（默认 DefaultReplayGroup）
+func WithReplayGroup(group string) ReplayOption {
+	return func(c *replayConfig) {
+		c.group = group
+	}
+}
+
+// WithReplayTimeout 设置连接与发送超时（默认 5s）
+func WithReplayTimeout(d time.Duration) ReplayOption {
+	return func(c *replayConfig) {
+		c.timeout = d
+	}
+}
+
+// WithReplayIdleTimeout 设置分区空闲判定时间（默认 2s）：超过该时间没有读到新的死信时结束该分区的重放。
+// 事务写入的死信 topic 末尾可能是事务控制记录或已中止的消息，二者都不会投递给消费者。
+func WithReplayIdleTimeout(d time.Duration) ReplayOption {
+	return func(c *replayConfig) {
+		c.idleTimeout = d
+	}
+}
+
+// WithReplaySaramaConfig 设置自定义 sarama.Config，同时用于读取死信与写回原始 topic，
+// 须开启 Producer.Return.Successes（SyncProducer 要求）；
+// 死信由 exactly-once 消费者写入时应设置 Consumer.IsolationLevel = sarama.ReadCommitted
+func WithReplaySaramaConfig(cfg *sarama.Config) ReplayOption {
+	return func(c *replayConfig) {
+		c.saramaConfig = cfg
+	}
+}
+
+// DeadLetterReplayer 死信重放工具：读取死信 topic 中尚未重放的消息，移除失败信息头后写回原始 topic。
+//
+// 重放进度按消费者组提交（默认 DefaultReplayGroup），重复执行只重放上次之后新增的死信；
+// 写回的消息处理次数从 1 重新计算。
+type DeadLetterReplayer struct {
+	brokers []string
+	opt     *replayConfig
+	logger  *slog.Logger
+}
+
+// NewDeadLetterReplayer 创建死信重放工具
+func NewDeadLetterReplayer(brokers []string, opts ...ReplayOption) *DeadLetterReplayer {
+	cfg := replayConfig{
+		timeout:     5 * time.Second,
+		idleTimeout: 2 * time.Second,
+		group:       DefaultReplayGroup,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	internal.InitSaramaLogger(logger)
+
+	return &DeadLetterReplayer{brokers: brokers, opt: &cfg, logger: logger}
+}
+
+// Replay 将 dltTopic 中截至调用时刻的死信重放到原始 topic，返回重放条数。
+// 缺少原始 topic 信息的消息记录日志后跳过；出错时已重放部分的进度仍会提交。
+func (r *DeadLetterReplayer) Replay(ctx context.Context, dltTopic string) (int, error) {
+	cfg := r.opt.saramaConfig
+	if cfg == nil {
+		cfg = internal.BuildProducerConfig(r.opt.timeout)
+		cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+		cfg.Consumer.Offsets.AutoCommit.Enable = false
+		// 只重放已提交事务中的死信，已中止事务（如 exactly-once 提交失败）写入的死信不重放
+		cfg.Consumer.IsolationLevel = sarama.ReadCommitted
+	}
+
+	client, err := sarama.NewClient(r.brokers, cfg)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	defer func() { _ = client.Close() }()
+
+	partitions, err := client.Partitions(dltTopic)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	defer func() { _ = producer.Close() }()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	defer func() { _ = consumer.Close() }()
+
+	om, err := sarama.NewOffsetManagerFromClient(r.opt.group, client)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	defer func() { _ = om.Close() }()
+
+	send := func(msg *sarama.ProducerMessage) error {
+		_, _, err := producer.SendMessage(msg)
+		return err
+	}
+
+	total := 0
+	for _, p := range partitions {
+		n, err := r.replayPartition(ctx, client, consumer, om, dltTopic, p, send)
+		total += n
+		om.Commit()
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// replayPartition 重放单个分区中从已提交 offset 到当前末尾的死信
+func (r *DeadLetterReplayer) replayPartition(
+	ctx context.Context,
+	client sarama.Client,
+	consumer sarama.Consumer,
+	om sarama.OffsetManager,
+	topic string,
+	partition int32,
+	send func(msg *sarama.ProducerMessage) error,
+) (int, error) {
+	end, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+
+	pom, err := om.ManagePartition(topic, partition)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	defer func() { _ = pom.Close() }()
+
+	next, _ := pom.NextOffset()
+	if next < oldest {
+		next = oldest
+	}
+	if next >= end {
+		return 0, nil
+	}
+
+	pc, err := consumer.ConsumePartition(topic, partition, next)
+	if err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+	}
+	defer func() { _ = pc.Close() }()
+
+	return r.replayMessages(ctx, pc.Messages(), end, r.opt.idleTimeout, send, func(offset int64) {
+		pom.MarkOffset(offset+1, "")
+	})
+}
+
+// replayMessages 逐条写回 messages 中 offset 小于 end 的死信，每条处理后调用 mark。
+// end 可能落在事务控制记录或已中止的消息之后（不会投递），idle 内没有新消息时同样结束；
+// 未投递的记录不提交进度，下次重放时重新读取。
+func (r *DeadLetterReplayer) replayMessages(
+	ctx context.Context,
+	messages <-chan *sarama.ConsumerMessage,
+	end int64,
+	idle time.Duration,
+	send func(msg *sarama.ProducerMessage) error,
+	mark func(offset int64),
+) (int, error) {
+	if idle <= 0 {
+		idle = 2 * time.Second
+	}
+	timer := time.NewTimer(idle)
+	defer timer.Stop()
+
+	n := 0
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return n, nil
+			}
+			if out, ok := replayMessage(msg); ok {
+				if err := send(out); err != nil {
+					return n, xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+				}
+				n++
+			} else {
+				r.logger.Error("skip dead letter without original topic",
+					"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+			}
+			mark(msg.Offset)
+			if msg.Offset+1 >= end {
+				return n, nil
+			}
+			timer.Reset(idle)
+		case <-timer.C:
+			return n, nil
+		case <-ctx.Done():
+			return n, ctx.Err()
+		}
+	}
+}
+
+// replayMessage 从死信还原写回原始 topic 的消息，移除死信 / 重试流程写入的消息头。
+// 缺少原始 topic 时返回 ok=false。
+func replayMessage(msg *sarama.ConsumerMessage) (out *sarama.ProducerMessage, ok bool) {
+	out = &sarama.ProducerMessage{
+		Value:     sarama.ByteEncoder(msg.Value),
+		Timestamp: msg.Timestamp,
+	}
+	if msg.Key != nil {
+		out.Key = sarama.ByteEncoder(msg.Key)
+	}
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		key := string(h.Key)
+		if key == HeaderOriginalTopic {
+			out.Topic = string(h.Value)
+			continue
+		}
+		if failureHeaders[key] {
+			continue
+		}
+		out.Headers = append(out.Headers, sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	if out.Topic == "" {
+		return nil, false
+	}
+	return out, true
+}
@@ -20,6 +20,7 @@ func toInternalRetryItem(item *RetryItem) *internal.RetryItem {
 		Key:           item.Key,
 		Value:         item.Value,
 		Headers:       headers,
+		Timestamp:     item.Timestamp,
 		Attempt:       uint(item.Attempt),
 		NextRetryAt:   item.NextRetryAt,
 		ConsumerGroup: item.ConsumerGroup,
@@ -42,6 +43,7 @@ func toPublicRetryItem(item *internal.RetryItem) *RetryItem {
 		Key:           item.Key,
 		Value:         item.Value,
 		Headers:       headers,
+		Timestamp:     item.Timestamp,
 		Attempt:       int(item.Attempt),
 		NextRetryAt:   item.NextRetryAt,
 		ConsumerGroup: item.ConsumerGroup,
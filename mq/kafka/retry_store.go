@@ -14,6 +14,7 @@ import (
 var (
 	_ RetryStore     = (*MemoryRetryStore)(nil)
 	_ WatermarkStore = (*MemoryRetryStore)(nil)
+	_ BacklogStore   = (*MemoryRetryStore)(nil)
 )
 
 // ErrRetryQueueFull 当重试队列已满时返回此错误
@@ -179,6 +180,11 @@ func (s *MemoryRetryStore) ResetPartition(topic string, partition int32) {
 	s.tracker.ResetPartition(topic, partition)
 }
 
+// Backlog 返回分区内尚未完成的重试数（排队中与处理中）
+func (s *MemoryRetryStore) Backlog(topic string, partition int32) int {
+	return s.tracker.PendingCount(topic, partition)
+}
+
 // Notify 返回通知通道，当有新的重试项加入时发送信号
 func (s *MemoryRetryStore) Notify() chan struct{} {
 	return s.notify
@@ -0,0 +1,236 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/mq/internal/logutil"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// 编译时接口检查
+var _ retryStrategy = (*retryTopicStrategy)(nil)
+
+// retryTopicName 返回原始 topic 在指定消费者组、延迟层级的重试 topic，如 orders.order-group.retry-5s。
+// 重试 topic 按消费者组隔离，某个组处理失败的消息不会被订阅同一 topic 的其他组重复处理。
+func retryTopicName(topic, group string, delay time.Duration) string {
+	return topic + "." + group + ".retry-" + formatDelay(delay)
+}
+
+// formatDelay 将延迟格式化为紧凑形式：5s、1m、1h30m、500ms
+func formatDelay(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// retryTopicsOf 返回 topics 在指定消费者组、各延迟层级的全部重试 topic
+func retryTopicsOf(topics []string, group string, tiers []time.Duration) []string {
+	out := make([]string, 0, len(topics)*len(tiers))
+	for _, t := range topics {
+		for _, d := range tiers {
+			out = append(out, retryTopicName(t, group, d))
+		}
+	}
+	return out
+}
+
+// retryTopicStrategy 重试 topic 链策略，替代进程内的异步重试堆。
+//
+// 处理失败的消息写入下一级重试 topic（<topic>.<group>.retry-<delay>）并提交原 offset；
+// 同一消费者组同时订阅各级重试 topic，消息到期后再次处理。
+// 最后一级仍失败时进入死信流程。重试 topic 的分区按写入顺序等待到期，各级延迟固定，不会相互阻塞。
+type retryTopicStrategy struct {
+	consumerGroup  string
+	handler        types.IHandler
+	tiers          []time.Duration
+	retryTopics    map[string]bool
+	handlerTimeout time.Duration
+	publish        func(msg *sarama.ProducerMessage) error
+	logger         logutil.Logger
+	metrics        *metrics.ConsumerMetrics
+	failedHandler  types.FailedHandlerFunc
+	deadLetter     types.DeadLetterHandler
+	retryIf        func(error) bool
+}
+
+func newRetryTopicStrategy(
+	cg string,
+	handler types.IHandler,
+	topics []string,
+	tiers []time.Duration,
+	handlerTimeout time.Duration,
+	publish func(msg *sarama.ProducerMessage) error,
+	logger logutil.Logger,
+	metrics *metrics.ConsumerMetrics,
+) *retryTopicStrategy {
+	retryTopics := make(map[string]bool)
+	for _, t := range retryTopicsOf(topics, cg, tiers) {
+		retryTopics[t] = true
+	}
+	return &retryTopicStrategy{
+		consumerGroup:  cg,
+		handler:        handler,
+		tiers:          tiers,
+		retryTopics:    retryTopics,
+		handlerTimeout: handlerTimeout,
+		publish:        publish,
+		logger:         logger,
+		metrics:        metrics,
+	}
+}
+
+// SetFailedHandler 设置失败处理器
+func (s *retryTopicStrategy) SetFailedHandler(fn types.FailedHandlerFunc) {
+	s.failedHandler = fn
+}
+
+// SetDeadLetterHandler 设置死信处理器
+func (s *retryTopicStrategy) SetDeadLetterHandler(h types.DeadLetterHandler) {
+	s.deadLetter = h
+}
+
+// SetRetryIf 设置错误重试谓词
+func (s *retryTopicStrategy) SetRetryIf(fn func(error) bool) {
+	s.retryIf = fn
+}
+
+// isRetryTopic 报告 topic 是否为本策略的重试 topic
+func (s *retryTopicStrategy) isRetryTopic(topic string) bool {
+	return s.retryTopics[topic]
+}
+
+func (s *retryTopicStrategy) OnMessage(ctx context.Context, session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
+	// 其他消费者组写入的重试消息（如手工转发到本组重试 topic）直接跳过，避免重复处理
+	if s.isRetryTopic(msg.Topic) && !s.ownsRecord(msg) {
+		if s.logger != nil {
+			s.logger.Warn("skip retry record of another consumer group",
+				"topic", msg.Topic, "offset", msg.Offset, "group", s.consumerGroup)
+		}
+		session.MarkMessage(msg, "")
+		return
+	}
+
+	kafkaMsg, dueAt := s.restore(msg)
+
+	// 重试消息等待到期，会话结束时不提交，由下一次分配继续等待
+	if wait := time.Until(dueAt); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+
+	msgCtx, cancel := ctx, context.CancelFunc(func() {})
+	if s.handlerTimeout > 0 {
+		msgCtx, cancel = context.WithTimeout(ctx, s.handlerTimeout)
+	}
+	err := s.handler.Handle(msgCtx, kafkaMsg)
+	cancel()
+
+	if err == nil {
+		session.MarkMessage(msg, "")
+		if s.metrics != nil {
+			s.metrics.OnConsume()
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	// 不可重试的错误不写入重试 topic，直接进入死信流程
+	if !mqretry.Retryable(err, s.retryIf) {
+		if handlePermanent(ctx, kafkaMsg, err, s.deadLetter, s.failedHandler, s.logger, s.metrics) == exhaustedHandled {
+			session.MarkMessage(msg, "")
+		}
+		return
+	}
+
+	// 写入下一级重试 topic；写入失败降级为重试耗尽处理
+	if tier := kafkaMsg.Attempt - 1; tier < len(s.tiers) {
+		delay := s.tiers[tier]
+		topic := retryTopicName(kafkaMsg.Queue, s.consumerGroup, delay)
+		out := failureRecord(topic, s.consumerGroup, kafkaMsg, err, kafkaMsg.Attempt+1)
+		out.Headers = append(out.Headers, sarama.RecordHeader{
+			Key:   []byte(HeaderDeliverAt),
+			Value: []byte(strconv.FormatInt(time.Now().Add(delay).UnixMilli(), 10)),
+		})
+		pubErr := s.publish(out)
+		if pubErr == nil {
+			if s.metrics != nil {
+				s.metrics.OnRetry()
+			}
+			session.MarkMessage(msg, "")
+			return
+		}
+		if s.logger != nil {
+			s.logger.Error("publish to retry topic failed, degrading to exhausted handling",
+				"topic", out.Topic, "offset", msg.Offset, "error", pubErr)
+		}
+	}
+
+	result := handleExhausted(ctx, kafkaMsg, err,
+		s.deadLetter, s.failedHandler, s.logger, s.metrics)
+	if result == exhaustedHandled {
+		session.MarkMessage(msg, "")
+	}
+}
+
+// ownsRecord 报告重试 record 是否由本消费者组写入；缺少消费者组头的 record 视为本组
+func (s *retryTopicStrategy) ownsRecord(msg *sarama.ConsumerMessage) bool {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == HeaderConsumerGroup {
+			return string(h.Value) == s.consumerGroup
+		}
+	}
+	return true
+}
+
+// restore 将 record 转换为统一消息。来自重试 topic 的消息还原原始 topic、分区、offset 与处理次数，
+// 并移除重试流程写入的消息头；返回消息的到期时间（主 topic 消息为零值）。
+func (s *retryTopicStrategy) restore(msg *sarama.ConsumerMessage) (types.Message, time.Time) {
+	m := newKafkaMessage(s.consumerGroup, msg, 1)
+	if !s.isRetryTopic(msg.Topic) {
+		return m, time.Time{}
+	}
+
+	h := m.Headers
+	if origin := h[HeaderOriginalTopic]; origin != "" {
+		m.Queue = origin
+	}
+	if p, err := strconv.ParseInt(h[HeaderOriginalPartition], 10, 32); err == nil {
+		m.Partition = int32(p)
+	}
+	if o, err := strconv.ParseInt(h[HeaderOriginalOffset], 10, 64); err == nil {
+		m.Offset = o
+	}
+	if a, err := strconv.Atoi(h[HeaderAttempt]); err == nil && a > 1 {
+		m.Attempt = a
+	}
+	var dueAt time.Time
+	if ms, err := strconv.ParseInt(h[HeaderDeliverAt], 10, 64); err == nil {
+		dueAt = time.UnixMilli(ms)
+	}
+	for k := range failureHeaders {
+		delete(h, k)
+	}
+	return m, dueAt
+}
+
+func (s *retryTopicStrategy) SetSession(sarama.ConsumerGroupSession) {}
+func (s *retryTopicStrategy) ClearSession()                          {}
+func (s *retryTopicStrategy) OnShutdown(_ context.Context)           {}
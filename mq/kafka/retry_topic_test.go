@@ -0,0 +1,226 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTopicName(t *testing.T) {
+	assert.Equal(t, "orders.g.retry-5s", retryTopicName("orders", "g", 5*time.Second))
+	assert.Equal(t, "orders.g.retry-1m", retryTopicName("orders", "g", time.Minute))
+	assert.Equal(t, "orders.g.retry-1h30m", retryTopicName("orders", "g", 90*time.Minute))
+	assert.Equal(t, "orders.g.retry-2h", retryTopicName("orders", "g", 2*time.Hour))
+	assert.Equal(t, "orders.g.retry-500ms", retryTopicName("orders", "g", 500*time.Millisecond))
+
+	assert.Equal(t,
+		[]string{"a.g.retry-5s", "a.g.retry-1m", "b.g.retry-5s", "b.g.retry-1m"},
+		retryTopicsOf([]string{"a", "b"}, "g", []time.Duration{5 * time.Second, time.Minute}))
+}
+
+// retryTopicFixture 记录重试 topic 策略发布的消息
+type retryTopicFixture struct {
+	strategy *retryTopicStrategy
+	sent     []*sarama.ProducerMessage
+	dead     []types.Message
+	handled  []types.Message
+}
+
+func newRetryTopicFixture(handlerErr error) *retryTopicFixture {
+	f := &retryTopicFixture{}
+	handler := types.FuncHandler(func(_ context.Context, msg types.Message) error {
+		f.handled = append(f.handled, msg)
+		return handlerErr
+	})
+	f.strategy = newRetryTopicStrategy("g", handler, []string{"orders"},
+		[]time.Duration{5 * time.Second, time.Minute}, 0,
+		func(msg *sarama.ProducerMessage) error {
+			f.sent = append(f.sent, msg)
+			return nil
+		}, slog.Default(), nil)
+	f.strategy.SetDeadLetterHandler(f)
+	return f
+}
+
+func (f *retryTopicFixture) OnDeadLetter(_ context.Context, msg types.Message, _ error) error {
+	f.dead = append(f.dead, msg)
+	return nil
+}
+
+// toConsumerMessage 将发布的重试消息转换为消费到的 record
+func toConsumerMessage(msg *sarama.ProducerMessage, offset int64) *sarama.ConsumerMessage {
+	value, _ := msg.Value.Encode()
+	out := &sarama.ConsumerMessage{Topic: msg.Topic, Offset: offset, Value: value}
+	for _, h := range msg.Headers {
+		out.Headers = append(out.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+	return out
+}
+
+func TestRetryTopicStrategy_Success(t *testing.T) {
+	f := newRetryTopicFixture(nil)
+	session := newMockSession()
+	msg := &sarama.ConsumerMessage{Topic: "orders", Offset: 1, Value: []byte("a")}
+
+	f.strategy.OnMessage(context.Background(), session, msg)
+
+	assert.Len(t, session.marks, 1)
+	assert.Empty(t, f.sent)
+}
+
+func TestRetryTopicStrategy_PublishesToNextTier(t *testing.T) {
+	f := newRetryTopicFixture(errors.New("boom"))
+	session := newMockSession()
+
+	f.strategy.OnMessage(context.Background(), session, &sarama.ConsumerMessage{
+		Topic: "orders", Partition: 3, Offset: 9, Value: []byte("a"),
+	})
+
+	require.Len(t, f.sent, 1)
+	out := f.sent[0]
+	assert.Equal(t, "orders.g.retry-5s", out.Topic)
+	assert.Equal(t, "2", headerValue(out.Headers, HeaderAttempt))
+	assert.Equal(t, "orders", headerValue(out.Headers, HeaderOriginalTopic))
+	assert.Equal(t, "3", headerValue(out.Headers, HeaderOriginalPartition))
+	assert.Equal(t, "9", headerValue(out.Headers, HeaderOriginalOffset))
+	deliverAt, err := strconv.ParseInt(headerValue(out.Headers, HeaderDeliverAt), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(5*time.Second).UnixMilli(), deliverAt, 1000)
+	assert.Len(t, session.marks, 1, "original offset should be committed once the retry is published")
+	assert.Empty(t, f.dead)
+}
+
+func TestRetryTopicStrategy_PermanentGoesToDeadLetter(t *testing.T) {
+	f := newRetryTopicFixture(types.Permanent(errors.New("invalid")))
+	session := newMockSession()
+
+	f.strategy.OnMessage(context.Background(), session, &sarama.ConsumerMessage{
+		Topic: "orders", Partition: 0, Offset: 1, Value: []byte("a"),
+	})
+
+	assert.Empty(t, f.sent, "permanent failures should not be published to retry topics")
+	assert.Len(t, f.dead, 1)
+	assert.Len(t, session.marks, 1)
+}
+
+func TestRetryTopicStrategy_RestoresOriginAndExhausts(t *testing.T) {
+	f := newRetryTopicFixture(errors.New("boom"))
+	session := newMockSession()
+
+	// 模拟最后一级重试 topic 中已到期的消息
+	origin := newKafkaMessage("g", &sarama.ConsumerMessage{
+		Topic: "orders", Partition: 3, Offset: 9, Value: []byte("a"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(HeaderMessageID), Value: []byte("id-1")},
+			{Key: []byte("trace"), Value: []byte("t1")},
+		},
+	}, 1)
+	first := failureRecord("orders.g.retry-1m", "g", origin, errors.New("boom"), 3)
+	first.Headers = append(first.Headers, sarama.RecordHeader{
+		Key: []byte(HeaderDeliverAt), Value: []byte(strconv.FormatInt(time.Now().Add(-time.Second).UnixMilli(), 10)),
+	})
+
+	f.strategy.OnMessage(context.Background(), session, toConsumerMessage(first, 0))
+
+	require.Len(t, f.handled, 1)
+	got := f.handled[0]
+	assert.Equal(t, "orders", got.Queue)
+	assert.Equal(t, int32(3), got.Partition)
+	assert.Equal(t, int64(9), got.Offset)
+	assert.Equal(t, 3, got.Attempt)
+	assert.Equal(t, "id-1", got.ID)
+	assert.Equal(t, map[string]string{"trace": "t1"}, got.Headers, "failure headers should be hidden from handler")
+
+	assert.Empty(t, f.sent, "final tier failures should not be republished")
+	require.Len(t, f.dead, 1)
+	assert.Equal(t, "orders", f.dead[0].Queue)
+	assert.Len(t, session.marks, 1)
+}
+
+func TestRetryTopicStrategy_IsolatesConsumerGroups(t *testing.T) {
+	// 两个消费者组消费同一 topic，共用一个 broker
+	var sent []*sarama.ProducerMessage
+	publish := func(msg *sarama.ProducerMessage) error {
+		sent = append(sent, msg)
+		return nil
+	}
+	handled := map[string]int{}
+	newStrategy := func(group string, handlerErr error) *retryTopicStrategy {
+		handler := types.FuncHandler(func(context.Context, types.Message) error {
+			handled[group]++
+			return handlerErr
+		})
+		return newRetryTopicStrategy(group, handler, []string{"orders"},
+			[]time.Duration{time.Millisecond}, 0, publish, slog.Default(), nil)
+	}
+	a := newStrategy("a", errors.New("boom"))
+	b := newStrategy("b", nil)
+
+	msg := &sarama.ConsumerMessage{Topic: "orders", Offset: 1, Value: []byte("x")}
+	a.OnMessage(context.Background(), newMockSession(), msg)
+	b.OnMessage(context.Background(), newMockSession(), msg)
+
+	require.Len(t, sent, 1)
+	assert.Equal(t, "orders.a.retry-1ms", sent[0].Topic)
+	assert.True(t, a.isRetryTopic(sent[0].Topic))
+	assert.False(t, b.isRetryTopic(sent[0].Topic), "group b does not subscribe to group a's retry topics")
+
+	// 即使其他组的重试 record 出现在本组重试 topic 中，也只提交不处理
+	foreign := toConsumerMessage(sent[0], 0)
+	foreign.Topic = "orders.b.retry-1ms"
+	session := newMockSession()
+	b.OnMessage(context.Background(), session, foreign)
+	assert.Equal(t, map[string]int{"a": 1, "b": 1}, handled)
+	assert.Len(t, session.marks, 1)
+}
+
+func TestRetryTopicStrategy_WaitsUntilDue(t *testing.T) {
+	f := newRetryTopicFixture(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &mockConsumerGroupSession{ctx: ctx}
+
+	origin := newKafkaMessage("g", &sarama.ConsumerMessage{Topic: "orders", Value: []byte("a")}, 1)
+	rec := failureRecord("orders.g.retry-5s", "g", origin, nil, 2)
+	rec.Headers = append(rec.Headers, sarama.RecordHeader{
+		Key: []byte(HeaderDeliverAt), Value: []byte(strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)),
+	})
+
+	done := make(chan struct{})
+	go func() {
+		f.strategy.OnMessage(ctx, session, toConsumerMessage(rec, 0))
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Empty(t, f.handled, "message should not be handled before it is due")
+	assert.Empty(t, session.marks)
+}
+
+func TestGroupHandler_RetryTopicBypassesBatch(t *testing.T) {
+	rec := &batchRecorder{}
+	gh := newGroupHandler("g", &groupHandlerConf{
+		Handler:     types.NewBatchHandler(rec, types.WithBatchSize(10), types.WithBatchMaxWait(time.Hour)),
+		Topics:      []string{"orders"},
+		RetryTopics: []time.Duration{5 * time.Second},
+		Publish:     func(*sarama.ProducerMessage) error { return nil },
+	})
+
+	ch := make(chan *sarama.ConsumerMessage, 1)
+	ch <- &sarama.ConsumerMessage{Topic: "orders.g.retry-5s", Value: []byte("a")}
+	close(ch)
+	session := newMockSession()
+	require.NoError(t, gh.ConsumeClaim(session, &mockClaimWithChannel{ch: ch}))
+
+	assert.Equal(t, [][]string{{"a"}}, rec.get(), "retry topic messages are handled one at a time")
+	assert.Len(t, session.marks, 1)
+}
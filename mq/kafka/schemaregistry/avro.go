@@ -0,0 +1,112 @@
+package schemaregistry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/hamba/avro/v2"
+)
+
+// AvroSerde Avro 编解码器：按注册的 schema 编码，按消息中的写入方 schema 解码。
+// 写入方 schema 与本地 schema 不同时，按 Avro schema 解析规则映射到本地 schema（字段增删、默认值）。
+type AvroSerde struct {
+	serde
+	avroSchema avro.Schema
+
+	mu      sync.RWMutex
+	readers map[int]avro.Schema // 写入方 schema ID -> 解码使用的 schema
+}
+
+// NewAvroSerde 创建 Avro 编解码器，schema 为 Avro schema JSON
+func NewAvroSerde(client Client, subject, schema string) (*AvroSerde, error) {
+	parsed, err := parseAvro(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &AvroSerde{
+		serde:      serde{client: client, subject: subject, schema: Schema{Type: Avro, Schema: schema}},
+		avroSchema: parsed,
+		readers:    make(map[int]avro.Schema),
+	}, nil
+}
+
+// Name 编解码器名称，写入消息头 mq.HeaderCodec
+func (*AvroSerde) Name() string { return "sr-avro" }
+
+// Marshal 按本地 schema 编码为 wire format，首次调用时注册 schema
+func (s *AvroSerde) Marshal(v any) ([]byte, error) {
+	id, err := s.register(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	payload, err := avro.Marshal(s.avroSchema, v)
+	if err != nil {
+		return nil, err
+	}
+	return encodeWire(id, payload), nil
+}
+
+// Unmarshal 按消息中 schema ID 对应的写入方 schema 解码到 v
+func (s *AvroSerde) Unmarshal(data []byte, v any) error {
+	id, payload, err := decodeWire(data)
+	if err != nil {
+		return err
+	}
+	reader, err := s.readerSchema(id)
+	if err != nil {
+		return err
+	}
+	return avro.Unmarshal(reader, payload, v)
+}
+
+// readerSchema 返回解码写入方 schema ID 的数据所用的 schema（缓存）
+func (s *AvroSerde) readerSchema(id int) (avro.Schema, error) {
+	s.mu.RLock()
+	reader, ok := s.readers[id]
+	s.mu.RUnlock()
+	if ok {
+		return reader, nil
+	}
+
+	writer, err := s.client.SchemaByID(context.Background(), id)
+	if err != nil {
+		err = fmt.Errorf("schemaregistry: fetch schema %d: %w", id, err)
+		// 注册中心暂不可用时消息仍可重试，不视为解码失败
+		if temporary(err) {
+			return nil, types.Transient(err)
+		}
+		return nil, err
+	}
+	if writer.Type != Avro {
+		return nil, fmt.Errorf("schemaregistry: schema %d is %s, not AVRO", id, writer.Type)
+	}
+	parsed, err := parseAvro(writer.Schema)
+	if err != nil {
+		return nil, err
+	}
+	reader = s.avroSchema
+	if parsed.Fingerprint() != s.avroSchema.Fingerprint() {
+		if reader, err = avro.NewSchemaCompatibility().Resolve(s.avroSchema, parsed); err != nil {
+			return nil, fmt.Errorf("schemaregistry: resolve schema %d against subject %q: %w", id, s.subject, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.readers[id] = reader
+	s.mu.Unlock()
+	return reader, nil
+}
+
+// parseAvro 以独立的缓存解析 schema，同名类型的不同版本互不覆盖
+func parseAvro(schema string) (avro.Schema, error) {
+	parsed, err := avro.ParseWithCache(schema, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("schemaregistry: parse avro schema: %w", err)
+	}
+	return parsed, nil
+}
+
+// 编译时接口检查
+var _ types.Codec = (*AvroSerde)(nil)
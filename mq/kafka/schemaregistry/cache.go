@@ -0,0 +1,77 @@
+package schemaregistry
+
+import (
+	"context"
+	"sync"
+)
+
+// cachedClient 带本地缓存的客户端（未导出）。
+// schema ID 全局唯一且不可变，按 ID 获取的 schema 与注册结果永久缓存；兼容性检查不缓存。
+type cachedClient struct {
+	inner Client
+
+	mu         sync.RWMutex
+	schemas    map[int]Schema
+	registered map[registration]int
+}
+
+// registration 注册结果的缓存键
+type registration struct {
+	subject string
+	typ     SchemaType
+	schema  string
+}
+
+// NewCachedClient 为自定义客户端增加本地缓存，NewClient 返回的客户端已带缓存
+func NewCachedClient(inner Client) Client {
+	if c, ok := inner.(*cachedClient); ok {
+		return c
+	}
+	return &cachedClient{
+		inner:      inner,
+		schemas:    make(map[int]Schema),
+		registered: make(map[registration]int),
+	}
+}
+
+func (c *cachedClient) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	key := registration{subject: subject, typ: schema.Type, schema: schema.Schema}
+	c.mu.RLock()
+	id, ok := c.registered[key]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := c.inner.Register(ctx, subject, schema)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.registered[key] = id
+	c.schemas[id] = schema
+	c.mu.Unlock()
+	return id, nil
+}
+
+func (c *cachedClient) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.schemas[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := c.inner.SchemaByID(ctx, id)
+	if err != nil {
+		return Schema{}, err
+	}
+	c.mu.Lock()
+	c.schemas[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+func (c *cachedClient) CheckCompatibility(ctx context.Context, subject string, schema Schema) (bool, error) {
+	return c.inner.CheckCompatibility(ctx, subject, schema)
+}
@@ -0,0 +1,219 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaType schema 类型
+type SchemaType string
+
+const (
+	Avro       SchemaType = "AVRO"
+	JSONSchema SchemaType = "JSON"
+	Protobuf   SchemaType = "PROTOBUF"
+)
+
+// Reference schema 引用（如 Protobuf import 的其他 schema）
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema 注册中心中的 schema
+type Schema struct {
+	Type       SchemaType
+	Schema     string
+	References []Reference
+}
+
+// Client 注册中心客户端接口
+type Client interface {
+	// Register 在 subject 下注册 schema，已注册时返回已有的全局 ID
+	Register(ctx context.Context, subject string, schema Schema) (int, error)
+	// SchemaByID 按全局 ID 获取 schema
+	SchemaByID(ctx context.Context, id int) (Schema, error)
+	// CheckCompatibility 检查 schema 与 subject 最新版本是否兼容，subject 尚无版本时视为兼容
+	CheckCompatibility(ctx context.Context, subject string, schema Schema) (bool, error)
+}
+
+// Error 注册中心返回的错误
+type Error struct {
+	StatusCode int    // HTTP 状态码
+	Code       int    // 注册中心错误码，如 40401（subject 不存在）
+	Message    string // 错误描述
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schemaregistry: status %d, error code %d: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// temporary 报告访问注册中心的错误是否为临时故障（网络错误、超时、限流、5xx），重试可能成功
+func temporary(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+	}
+	return true
+}
+
+// 注册中心错误码
+const (
+	codeSubjectNotFound = 40401
+	codeVersionNotFound = 40402
+)
+
+// contentType 注册中心 REST API 的请求与响应类型
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// ClientOption 客户端配置选项
+type ClientOption func(*httpClient)
+
+// WithHTTPClient 设置 HTTP 客户端（默认超时 10s）
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(h *httpClient) {
+		if c != nil {
+			h.http = c
+		}
+	}
+}
+
+// WithBasicAuth 设置 Basic 认证
+func WithBasicAuth(username, password string) ClientOption {
+	return func(h *httpClient) {
+		h.username = username
+		h.password = password
+	}
+}
+
+// NewClient 创建基于 REST API 的注册中心客户端，schema 与注册结果缓存在本地
+func NewClient(baseURL string, opts ...ClientOption) Client {
+	h := &httpClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return NewCachedClient(h)
+}
+
+// httpClient 注册中心 REST API 客户端（未导出）
+type httpClient struct {
+	baseURL  string
+	http     *http.Client
+	username string
+	password string
+}
+
+// schemaPayload 注册中心 schema 请求 / 响应体，Avro 类型省略 schemaType
+type schemaPayload struct {
+	Schema     string      `json:"schema"`
+	SchemaType SchemaType  `json:"schemaType,omitempty"`
+	References []Reference `json:"references,omitempty"`
+}
+
+func newSchemaPayload(s Schema) schemaPayload {
+	p := schemaPayload{Schema: s.Schema, References: s.References}
+	if s.Type != Avro && s.Type != "" {
+		p.SchemaType = s.Type
+	}
+	return p
+}
+
+func (h *httpClient) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if err := h.do(ctx, http.MethodPost, path, newSchemaPayload(schema), &resp); err != nil {
+		return 0, err
+	}
+	return resp.ID, nil
+}
+
+func (h *httpClient) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	var resp schemaPayload
+	if err := h.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &resp); err != nil {
+		return Schema{}, err
+	}
+	typ := resp.SchemaType
+	if typ == "" {
+		typ = Avro
+	}
+	return Schema{Type: typ, Schema: resp.Schema, References: resp.References}, nil
+}
+
+func (h *httpClient) CheckCompatibility(ctx context.Context, subject string, schema Schema) (bool, error) {
+	var resp struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	path := "/compatibility/subjects/" + url.PathEscape(subject) + "/versions/latest"
+	err := h.do(ctx, http.MethodPost, path, newSchemaPayload(schema), &resp)
+	if e, ok := err.(*Error); ok && (e.Code == codeSubjectNotFound || e.Code == codeVersionNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return resp.IsCompatible, nil
+}
+
+// do 发送请求并解码响应，非 2xx 响应解码为 *Error
+func (h *httpClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("schemaregistry: encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, h.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("schemaregistry: build request: %w", err)
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if h.username != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	resp, err := h.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("schemaregistry: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		e := &Error{StatusCode: resp.StatusCode}
+		var payload struct {
+			Code    int    `json:"error_code"`
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&payload) == nil {
+			e.Code, e.Message = payload.Code, payload.Message
+		}
+		if e.Message == "" {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+		return e
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("schemaregistry: decode response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_RegisterAndFetch(t *testing.T) {
+	r := newFakeRegistry(t)
+	c := NewClient(r.URL+"/", WithBasicAuth("user", "secret"))
+	ctx := context.Background()
+
+	ok, err := c.CheckCompatibility(ctx, "orders-value", Schema{Type: JSONSchema, Schema: `{}`})
+	require.NoError(t, err)
+	assert.True(t, ok, "subject without versions is compatible")
+
+	id, err := c.Register(ctx, "orders-value", Schema{Type: Protobuf, Schema: "syntax = \"proto3\";"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, "user:secret", r.auth)
+
+	r.mu.Lock()
+	assert.Equal(t, Protobuf, r.schemas[1].SchemaType)
+	r.mu.Unlock()
+
+	// 注册中心返回的 Avro schema 省略 schemaType
+	id, err = c.Register(ctx, "users-value", Schema{Type: Avro, Schema: `"string"`})
+	require.NoError(t, err)
+	schema, err := NewClient(r.URL).SchemaByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, Schema{Type: Avro, Schema: `"string"`}, schema)
+}
+
+func TestClient_Error(t *testing.T) {
+	r := newFakeRegistry(t)
+	_, err := NewClient(r.URL).SchemaByID(context.Background(), 42)
+
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, 404, e.StatusCode)
+	assert.Equal(t, 40403, e.Code)
+	assert.Equal(t, "Schema not found", e.Message)
+}
+
+func TestClient_Incompatible(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.compatible = func(string, schemaPayload) bool { return false }
+	c := NewClient(r.URL)
+	ctx := context.Background()
+
+	_, err := c.Register(ctx, "orders-value", Schema{Type: JSONSchema, Schema: `{}`})
+	require.NoError(t, err)
+	ok, err := c.CheckCompatibility(ctx, "orders-value", Schema{Type: JSONSchema, Schema: `{"type":"string"}`})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCachedClient_CachesSchemasAndRegistrations(t *testing.T) {
+	r := newFakeRegistry(t)
+	c := NewClient(r.URL)
+	ctx := context.Background()
+	schema := Schema{Type: JSONSchema, Schema: `{"type":"object"}`}
+
+	for range 3 {
+		id, err := c.Register(ctx, "orders-value", schema)
+		require.NoError(t, err)
+		assert.Equal(t, 1, id)
+	}
+	assert.Equal(t, 1, r.count("POST /subjects"))
+
+	// 注册结果同时缓存 ID -> schema
+	_, err := c.SchemaByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, r.count("GET /schemas"))
+
+	fresh := NewClient(r.URL)
+	for range 3 {
+		got, err := fresh.SchemaByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, schema, got)
+	}
+	assert.Equal(t, 1, r.count("GET /schemas"))
+
+	assert.Same(t, c, NewCachedClient(c), "already cached clients are not wrapped twice")
+}
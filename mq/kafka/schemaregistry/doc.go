@@ -0,0 +1,11 @@
+// Package schemaregistry 提供 Confluent Schema Registry 集成，以 Confluent wire format
+// （魔数字节 0 + 4 字节大端 schema ID + 负载）编解码 Kafka 消息体。
+//
+// Client 为可插拔的注册中心客户端接口，NewClient 基于 REST API 实现并带本地缓存，
+// 自定义实现可经 NewCachedClient 获得相同的缓存能力。
+//
+// AvroSerde、JSONSchemaSerde 与 ProtobufSerde 实现 mq.Codec，可直接用于 mq.NewTypedProducer
+// 与 mq.NewTypedHandler（经 mq.WithCodec）：生产端首次编码时检查兼容性并注册 schema，
+// 消费端按消息中的 schema ID 自动解码（Avro 按写入方 schema 解析后映射到读取方 schema）。
+// 传入 kafka.WithProducerSchemas 时在生产者启动阶段完成兼容性检查与注册，不兼容时启动失败。
+package schemaregistry
@@ -0,0 +1,52 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// JSONSchemaSerde JSON Schema 编解码器：消息体为 JSON，schema 用于注册与兼容性检查，不在本地校验负载
+type JSONSchemaSerde struct {
+	serde
+}
+
+// NewJSONSchemaSerde 创建 JSON Schema 编解码器，schema 为 JSON Schema 文档
+func NewJSONSchemaSerde(client Client, subject, schema string) (*JSONSchemaSerde, error) {
+	if !json.Valid([]byte(schema)) {
+		return nil, fmt.Errorf("schemaregistry: json schema of subject %q is not valid JSON", subject)
+	}
+	return &JSONSchemaSerde{
+		serde: serde{client: client, subject: subject, schema: Schema{Type: JSONSchema, Schema: schema}},
+	}, nil
+}
+
+// Name 编解码器名称，写入消息头 mq.HeaderCodec
+func (*JSONSchemaSerde) Name() string { return "sr-json" }
+
+// Marshal 编码为 wire format，首次调用时注册 schema
+func (s *JSONSchemaSerde) Marshal(v any) ([]byte, error) {
+	id, err := s.register(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return encodeWire(id, payload), nil
+}
+
+// Unmarshal 去除 wire format 头后按 JSON 解码
+func (s *JSONSchemaSerde) Unmarshal(data []byte, v any) error {
+	_, payload, err := decodeWire(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// 编译时接口检查
+var _ types.Codec = (*JSONSchemaSerde)(nil)
@@ -0,0 +1,99 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ProtobufSerde Protobuf 编解码器：wire format 头之后写入消息类型在 .proto 文件中的索引路径，再写入负载
+type ProtobufSerde struct {
+	serde
+}
+
+// NewProtobufSerde 创建 Protobuf 编解码器，schema 为 .proto 文件内容，refs 为其 import 的已注册 schema
+func NewProtobufSerde(client Client, subject, schema string, refs ...Reference) (*ProtobufSerde, error) {
+	if schema == "" {
+		return nil, fmt.Errorf("schemaregistry: protobuf schema of subject %q is empty", subject)
+	}
+	return &ProtobufSerde{
+		serde: serde{client: client, subject: subject, schema: Schema{Type: Protobuf, Schema: schema, References: refs}},
+	}, nil
+}
+
+// Name 编解码器名称，写入消息头 mq.HeaderCodec
+func (*ProtobufSerde) Name() string { return "sr-protobuf" }
+
+// Marshal 编码为 wire format，值须实现 proto.Message；首次调用时注册 schema
+func (s *ProtobufSerde) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("schemaregistry: protobuf serde requires proto.Message, got %T", v)
+	}
+	id, err := s.register(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return encodeWire(id, messageIndexes(m.ProtoReflect().Descriptor()), payload), nil
+}
+
+// Unmarshal 跳过 wire format 头与消息索引后解码到 v，支持 proto.Message 及指向其指针的指针
+func (s *ProtobufSerde) Unmarshal(data []byte, v any) error {
+	_, payload, err := decodeWire(data)
+	if err != nil {
+		return err
+	}
+	payload, err = skipMessageIndexes(payload)
+	if err != nil {
+		return err
+	}
+	return types.ProtobufCodec{}.Unmarshal(payload, v)
+}
+
+// messageIndexes 编码消息类型的索引路径（zigzag varint 数组）；
+// 文件中第一个顶层消息的路径 [0] 简写为单个 0
+func messageIndexes(desc protoreflect.MessageDescriptor) []byte {
+	var path []int
+	for d := protoreflect.Descriptor(desc); d != nil; d = d.Parent() {
+		if _, ok := d.(protoreflect.MessageDescriptor); !ok {
+			break
+		}
+		path = append([]int{d.Index()}, path...)
+	}
+	if len(path) == 1 && path[0] == 0 {
+		return []byte{0}
+	}
+
+	out := binary.AppendVarint(nil, int64(len(path)))
+	for _, i := range path {
+		out = binary.AppendVarint(out, int64(i))
+	}
+	return out
+}
+
+// skipMessageIndexes 跳过负载前的消息索引路径
+func skipMessageIndexes(data []byte) ([]byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 {
+		return nil, fmt.Errorf("%w: bad protobuf message indexes", ErrInvalidWireFormat)
+	}
+	data = data[n:]
+	for i := int64(0); i < count; i++ {
+		if _, n = binary.Varint(data); n <= 0 {
+			return nil, fmt.Errorf("%w: bad protobuf message indexes", ErrInvalidWireFormat)
+		}
+		data = data[n:]
+	}
+	return data, nil
+}
+
+// 编译时接口检查
+var _ types.Codec = (*ProtobufSerde)(nil)
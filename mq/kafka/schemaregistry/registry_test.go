@@ -0,0 +1,102 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry httptest 实现的注册中心，compatible 为 nil 时总是兼容
+type fakeRegistry struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	schemas    map[int]schemaPayload
+	subjects   map[string][]int
+	compatible func(subject string, schema schemaPayload) bool
+	requests   map[string]int // "METHOD /path前缀" -> 次数
+	auth       string
+	down       bool // 为 true 时所有请求返回 503
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{
+		schemas:  make(map[int]schemaPayload),
+		subjects: make(map[string][]int),
+		requests: make(map[string]int),
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *fakeRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w.Header().Set("Content-Type", contentType)
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	r.requests[req.Method+" /"+parts[0]]++
+	if user, pass, ok := req.BasicAuth(); ok {
+		r.auth = user + ":" + pass
+	}
+	if r.down {
+		writeError(w, http.StatusServiceUnavailable, 50301, "Service unavailable")
+		return
+	}
+
+	switch {
+	case req.Method == http.MethodPost && len(parts) == 3 && parts[0] == "subjects":
+		var p schemaPayload
+		_ = json.NewDecoder(req.Body).Decode(&p)
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": r.register(parts[1], p)})
+	case req.Method == http.MethodGet && len(parts) == 3 && parts[0] == "schemas":
+		id, _ := strconv.Atoi(parts[2])
+		p, ok := r.schemas[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, 40403, "Schema not found")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(p)
+	case req.Method == http.MethodPost && len(parts) == 5 && parts[0] == "compatibility":
+		if len(r.subjects[parts[2]]) == 0 {
+			writeError(w, http.StatusNotFound, 40401, "Subject not found")
+			return
+		}
+		var p schemaPayload
+		_ = json.NewDecoder(req.Body).Decode(&p)
+		ok := r.compatible == nil || r.compatible(parts[2], p)
+		_ = json.NewEncoder(w).Encode(map[string]bool{"is_compatible": ok})
+	default:
+		writeError(w, http.StatusNotFound, 404, "not found")
+	}
+}
+
+// register 在 subject 下注册 schema，相同内容复用已有 ID（调用方持有锁）
+func (r *fakeRegistry) register(subject string, p schemaPayload) int {
+	for id, existing := range r.schemas {
+		if existing.Schema == p.Schema && existing.SchemaType == p.SchemaType {
+			r.subjects[subject] = append(r.subjects[subject], id)
+			return id
+		}
+	}
+	id := len(r.schemas) + 1
+	r.schemas[id] = p
+	r.subjects[subject] = append(r.subjects[subject], id)
+	return id
+}
+
+func (r *fakeRegistry) count(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests[key]
+}
+
+func writeError(w http.ResponseWriter, status, code int, msg string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error_code": code, "message": msg})
+}
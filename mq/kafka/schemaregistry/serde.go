@@ -0,0 +1,55 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrIncompatible schema 与 subject 最新版本不兼容
+var ErrIncompatible = errors.New("schemaregistry: schema incompatible with latest version")
+
+// serde 各格式 Serde 共用的注册逻辑（未导出）
+type serde struct {
+	client  Client
+	subject string
+	schema  Schema
+
+	mu sync.Mutex
+	id int // 注册后的全局 ID，0 表示尚未注册
+}
+
+// Subject 返回 schema 注册的 subject
+func (s *serde) Subject() string {
+	return s.subject
+}
+
+// Register 检查 schema 与 subject 最新版本的兼容性并注册，成功后不再重复请求。
+// 生产者启动时调用（kafka.WithProducerSchemas），未调用时在首次编码时执行。
+func (s *serde) Register(ctx context.Context) error {
+	_, err := s.register(ctx)
+	return err
+}
+
+func (s *serde) register(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.id > 0 {
+		return s.id, nil
+	}
+
+	ok, err := s.client.CheckCompatibility(ctx, s.subject, s.schema)
+	if err != nil {
+		return 0, fmt.Errorf("schemaregistry: check compatibility of subject %q: %w", s.subject, err)
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: subject %q", ErrIncompatible, s.subject)
+	}
+	id, err := s.client.Register(ctx, s.subject, s.schema)
+	if err != nil {
+		return 0, fmt.Errorf("schemaregistry: register subject %q: %w", s.subject, err)
+	}
+	s.id = id
+	return id, nil
+}
@@ -0,0 +1,219 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const orderV1 = `{"type":"record","name":"Order","fields":[
+	{"name":"id","type":"string"},
+	{"name":"amount","type":"long"}]}`
+
+const orderV2 = `{"type":"record","name":"Order","fields":[
+	{"name":"id","type":"string"},
+	{"name":"amount","type":"long"},
+	{"name":"currency","type":"string","default":"CNY"}]}`
+
+type orderV1Record struct {
+	ID     string `avro:"id" json:"id"`
+	Amount int64  `avro:"amount" json:"amount"`
+}
+
+type orderV2Record struct {
+	ID       string `avro:"id"`
+	Amount   int64  `avro:"amount"`
+	Currency string `avro:"currency"`
+}
+
+func TestAvroSerde_RoundTrip(t *testing.T) {
+	r := newFakeRegistry(t)
+	s, err := NewAvroSerde(NewClient(r.URL), TopicSubject("orders"), orderV1)
+	require.NoError(t, err)
+	assert.Equal(t, "orders-value", s.Subject())
+
+	data, err := s.Marshal(orderV1Record{ID: "o-1", Amount: 100})
+	require.NoError(t, err)
+	id, err := SchemaID(data)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	var got orderV1Record
+	require.NoError(t, s.Unmarshal(data, &got))
+	assert.Equal(t, orderV1Record{ID: "o-1", Amount: 100}, got)
+
+	_, err = s.Marshal(orderV1Record{ID: "o-2"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.count("POST /subjects"), "schema registered once")
+	assert.Equal(t, 1, r.count("POST /compatibility"))
+}
+
+func TestAvroSerde_ResolvesWriterSchema(t *testing.T) {
+	r := newFakeRegistry(t)
+	writer, err := NewAvroSerde(NewClient(r.URL), "orders-value", orderV1)
+	require.NoError(t, err)
+	data, err := writer.Marshal(orderV1Record{ID: "o-1", Amount: 100})
+	require.NoError(t, err)
+
+	// 消费端使用新版本 schema，缺失字段取默认值
+	reader, err := NewAvroSerde(NewClient(r.URL), "orders-value", orderV2)
+	require.NoError(t, err)
+	var got orderV2Record
+	require.NoError(t, reader.Unmarshal(data, &got))
+	assert.Equal(t, orderV2Record{ID: "o-1", Amount: 100, Currency: "CNY"}, got)
+
+	require.NoError(t, reader.Unmarshal(data, &got))
+	assert.Equal(t, 1, r.count("GET /schemas"), "writer schema cached")
+}
+
+func TestAvroSerde_Errors(t *testing.T) {
+	r := newFakeRegistry(t)
+	_, err := NewAvroSerde(NewClient(r.URL), "orders-value", `{"type":"record"}`)
+	assert.Error(t, err)
+
+	s, err := NewAvroSerde(NewClient(r.URL), "orders-value", orderV1)
+	require.NoError(t, err)
+	var got orderV1Record
+	assert.ErrorIs(t, s.Unmarshal([]byte{1, 0, 0, 0, 1}, &got), ErrInvalidWireFormat)
+	assert.ErrorIs(t, s.Unmarshal([]byte{0, 1}, &got), ErrInvalidWireFormat)
+
+	var regErr *Error
+	assert.True(t, errors.As(s.Unmarshal([]byte{0, 0, 0, 0, 9}, &got), &regErr), "unknown schema id")
+}
+
+func TestSerde_RegisterChecksCompatibility(t *testing.T) {
+	r := newFakeRegistry(t)
+	client := NewClient(r.URL)
+	v1, err := NewAvroSerde(client, "orders-value", orderV1)
+	require.NoError(t, err)
+	require.NoError(t, v1.Register(context.Background()))
+
+	r.compatible = func(string, schemaPayload) bool { return false }
+	v2, err := NewAvroSerde(client, "orders-value", orderV2)
+	require.NoError(t, err)
+	assert.ErrorIs(t, v2.Register(context.Background()), ErrIncompatible)
+	_, err = v2.Marshal(orderV2Record{ID: "o-1"})
+	assert.ErrorIs(t, err, ErrIncompatible)
+	assert.Equal(t, 1, r.count("POST /subjects"), "incompatible schema is not registered")
+
+	r.compatible = nil
+	require.NoError(t, v2.Register(context.Background()))
+	require.NoError(t, v2.Register(context.Background()))
+	assert.Equal(t, 2, r.count("POST /subjects"))
+}
+
+func TestJSONSchemaSerde_RoundTrip(t *testing.T) {
+	r := newFakeRegistry(t)
+	_, err := NewJSONSchemaSerde(NewClient(r.URL), "orders-value", `{"type":`)
+	assert.Error(t, err)
+
+	s, err := NewJSONSchemaSerde(NewClient(r.URL), "orders-value", `{"type":"object"}`)
+	require.NoError(t, err)
+	data, err := s.Marshal(orderV1Record{ID: "o-1", Amount: 5})
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"o-1","amount":5}`, string(data[headerSize:]))
+
+	var got orderV1Record
+	require.NoError(t, s.Unmarshal(data, &got))
+	assert.Equal(t, orderV1Record{ID: "o-1", Amount: 5}, got)
+
+	r.mu.Lock()
+	assert.Equal(t, JSONSchema, r.schemas[1].SchemaType)
+	r.mu.Unlock()
+}
+
+func TestProtobufSerde_RoundTrip(t *testing.T) {
+	r := newFakeRegistry(t)
+	s, err := NewProtobufSerde(NewClient(r.URL), "names-value", `syntax = "proto3";`)
+	require.NoError(t, err)
+
+	data, err := s.Marshal(wrapperspb.String("hello"))
+	require.NoError(t, err)
+	// StringValue 为 wrappers.proto 的第 8 个顶层消息：索引数组 [7]
+	assert.Equal(t, []byte{2, 14}, data[headerSize:headerSize+2])
+
+	var got *wrapperspb.StringValue
+	require.NoError(t, s.Unmarshal(data, &got))
+	assert.Equal(t, "hello", got.GetValue())
+
+	_, err = s.Marshal("not a proto")
+	assert.Error(t, err)
+}
+
+func TestMessageIndexes(t *testing.T) {
+	assert.Equal(t, []byte{0}, messageIndexes((&wrapperspb.DoubleValue{}).ProtoReflect().Descriptor()))
+	// DescriptorProto.ExtensionRange：[2, 0]
+	assert.Equal(t, []byte{4, 4, 0}, messageIndexes((&descriptorpb.DescriptorProto_ExtensionRange{}).ProtoReflect().Descriptor()))
+
+	payload, err := skipMessageIndexes([]byte{4, 4, 0, 0xAA})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xAA}, payload)
+	_, err = skipMessageIndexes([]byte{4, 4})
+	assert.ErrorIs(t, err, ErrInvalidWireFormat)
+}
+
+func TestTypedHandler_DecodesWithSerde(t *testing.T) {
+	r := newFakeRegistry(t)
+	s, err := NewAvroSerde(NewClient(r.URL), "orders-value", orderV1)
+	require.NoError(t, err)
+	data, err := s.Marshal(orderV1Record{ID: "o-1", Amount: 100})
+	require.NoError(t, err)
+
+	var got orderV1Record
+	h := types.NewTypedHandler(func(_ context.Context, _ types.Message, v orderV1Record) error {
+		got = v
+		return nil
+	}, types.WithCodec(s))
+	require.NoError(t, h.Handle(context.Background(), types.Message{Data: data}))
+	assert.Equal(t, orderV1Record{ID: "o-1", Amount: 100}, got)
+
+	err = h.Handle(context.Background(), types.Message{Data: []byte(`{"id":"o-1"}`)})
+	var decodeErr *types.DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "sr-avro", decodeErr.Codec)
+}
+
+func TestTypedHandler_RegistryOutageIsRetryable(t *testing.T) {
+	r := newFakeRegistry(t)
+	producer, err := NewAvroSerde(NewClient(r.URL), "orders-value", orderV1)
+	require.NoError(t, err)
+	data, err := producer.Marshal(orderV1Record{ID: "o-1", Amount: 100})
+	require.NoError(t, err)
+
+	consumer, err := NewAvroSerde(NewClient(r.URL), "orders-value", orderV1)
+	require.NoError(t, err)
+	var got orderV1Record
+	h := types.NewTypedHandler(func(_ context.Context, _ types.Message, v orderV1Record) error {
+		got = v
+		return nil
+	}, types.WithCodec(consumer))
+
+	// 注册中心不可用：错误可重试，不视为解码失败
+	r.mu.Lock()
+	r.down = true
+	r.mu.Unlock()
+	err = h.Handle(context.Background(), types.Message{Data: data})
+	require.Error(t, err)
+	var decodeErr *types.DecodeError
+	assert.False(t, errors.As(err, &decodeErr))
+	assert.False(t, types.IsNonRetryable(err))
+	assert.True(t, types.IsTransient(err))
+
+	// 恢复后重试成功
+	r.mu.Lock()
+	r.down = false
+	r.mu.Unlock()
+	require.NoError(t, h.Handle(context.Background(), types.Message{Data: data}))
+	assert.Equal(t, orderV1Record{ID: "o-1", Amount: 100}, got)
+
+	// 未知 schema ID 属于消息内容错误，仍为解码失败
+	err = h.Handle(context.Background(), types.Message{Data: []byte{0, 0, 0, 0, 9}})
+	require.ErrorAs(t, err, &decodeErr)
+	assert.True(t, types.IsNonRetryable(err))
+}
@@ -0,0 +1,53 @@
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// magicByte Confluent wire format 的首字节
+const magicByte byte = 0
+
+// headerSize 魔数字节与 4 字节 schema ID
+const headerSize = 5
+
+// ErrInvalidWireFormat 消息体不是 Confluent wire format
+var ErrInvalidWireFormat = errors.New("schemaregistry: invalid wire format")
+
+// TopicSubject 按 TopicNameStrategy 返回 topic 消息体的 subject（<topic>-value）
+func TopicSubject(topic string) string {
+	return topic + "-value"
+}
+
+// SchemaID 返回 Confluent wire format 消息体中的 schema ID
+func SchemaID(data []byte) (int, error) {
+	id, _, err := decodeWire(data)
+	return id, err
+}
+
+// encodeWire 以 schema ID 与负载各部分组装 wire format 消息体
+func encodeWire(id int, parts ...[]byte) []byte {
+	size := headerSize
+	for _, p := range parts {
+		size += len(p)
+	}
+	out := make([]byte, headerSize, size)
+	out[0] = magicByte
+	binary.BigEndian.PutUint32(out[1:headerSize], uint32(id))
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// decodeWire 拆分 wire format 消息体为 schema ID 与负载
+func decodeWire(data []byte) (int, []byte, error) {
+	if len(data) < headerSize {
+		return 0, nil, fmt.Errorf("%w: %d bytes", ErrInvalidWireFormat, len(data))
+	}
+	if data[0] != magicByte {
+		return 0, nil, fmt.Errorf("%w: unknown magic byte %d", ErrInvalidWireFormat, data[0])
+	}
+	return int(binary.BigEndian.Uint32(data[1:headerSize])), data[headerSize:], nil
+}
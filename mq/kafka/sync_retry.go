@@ -8,6 +8,7 @@ import (
 	"github.com/gomooth/pkg/framework/retry"
 	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
 	"github.com/gomooth/pkg/mq/internal/types"
 )
 
@@ -38,6 +39,7 @@ type syncRetryStrategy struct {
 	metrics         *metrics.ConsumerMetrics
 	failedHandler   types.FailedHandlerFunc
 	deadLetter      types.DeadLetterHandler
+	retryIf         func(error) bool
 }
 
 func newSyncRetryStrategy(
@@ -70,9 +72,15 @@ func (s *syncRetryStrategy) SetDeadLetterHandler(h types.DeadLetterHandler) {
 	s.deadLetter = h
 }
 
+// SetRetryIf 设置错误重试谓词
+func (s *syncRetryStrategy) SetRetryIf(fn func(error) bool) {
+	s.retryIf = fn
+}
+
 func (s *syncRetryStrategy) OnMessage(ctx context.Context, session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
 	// P8 修复：移除 "message claimed" 重复日志
 
+	kafkaMsg := newKafkaMessage(s.consumerGroup, msg, 1)
 	var lastErr error
 	var startTime time.Time
 	if s.maxTotalTimeout > 0 {
@@ -98,7 +106,7 @@ func (s *syncRetryStrategy) OnMessage(ctx context.Context, session sarama.Consum
 			return
 		}
 
-		kafkaMsg := types.NewKafkaMessage(s.consumerGroup, msg.Topic, msg.Value)
+		kafkaMsg.Attempt = attempt + 1
 		err := s.handler.Handle(ctx, kafkaMsg)
 		if err == nil {
 			session.MarkMessage(msg, "")
@@ -109,6 +117,15 @@ func (s *syncRetryStrategy) OnMessage(ctx context.Context, session sarama.Consum
 		}
 
 		lastErr = err
+		// 不可重试的错误跳过剩余重试，直接进入死信流程
+		if !mqretry.Retryable(err, s.retryIf) {
+			result := handlePermanent(ctx, kafkaMsg, err,
+				s.deadLetter, s.failedHandler, s.logger, s.metrics)
+			if result == exhaustedHandled {
+				session.MarkMessage(msg, "")
+			}
+			return
+		}
 
 		if attempt < s.maxRetry {
 			if s.metrics != nil {
@@ -124,7 +141,7 @@ func (s *syncRetryStrategy) OnMessage(ctx context.Context, session sarama.Consum
 	}
 
 	// 重试耗尽
-	result := handleExhausted(ctx, s.consumerGroup, msg.Topic, msg.Value, lastErr,
+	result := handleExhausted(ctx, kafkaMsg, lastErr,
 		s.deadLetter, s.failedHandler, s.logger, s.metrics)
 	if result == exhaustedHandled {
 		session.MarkMessage(msg, "")
@@ -138,8 +155,7 @@ func (s *syncRetryStrategy) OnShutdown(_ context.Context)           {}
 // handleExhausted 处理重试耗尽的消息（公共逻辑，各策略共享）
 func handleExhausted(
 	ctx context.Context,
-	consumerGroup, topic string,
-	message []byte,
+	kafkaMsg types.Message,
 	lastErr error,
 	deadLetter types.DeadLetterHandler,
 	failedHandler types.FailedHandlerFunc,
@@ -149,13 +165,45 @@ func handleExhausted(
 	if metrics != nil {
 		metrics.OnDeadLetter()
 	}
+	return deliverDeadLetter(ctx, kafkaMsg, lastErr, deadLetter, failedHandler, logger)
+}
 
+// handlePermanent 处理不可重试的消息（公共逻辑，各策略共享）：以 permanent 原因记录指标与日志，
+// 再与重试耗尽相同地交给死信处理器或失败处理器
+func handlePermanent(
+	ctx context.Context,
+	kafkaMsg types.Message,
+	err error,
+	deadLetter types.DeadLetterHandler,
+	failedHandler types.FailedHandlerFunc,
+	logger logutil.Logger,
+	m *metrics.ConsumerMetrics,
+) exhaustedResult {
+	if m != nil {
+		m.OnPermanentFailure()
+	}
+	if logger != nil {
+		logger.Warn("non-retryable error, skipping retries",
+			"topic", kafkaMsg.Queue, "partition", kafkaMsg.Partition, "offset", kafkaMsg.Offset,
+			"attempt", kafkaMsg.Attempt, "reason", metrics.DeadLetterReasonPermanent, "error", err)
+	}
+	return deliverDeadLetter(ctx, kafkaMsg, err, deadLetter, failedHandler, logger)
+}
+
+// deliverDeadLetter 优先交给死信处理器，未设置时调用失败处理器
+func deliverDeadLetter(
+	ctx context.Context,
+	kafkaMsg types.Message,
+	lastErr error,
+	deadLetter types.DeadLetterHandler,
+	failedHandler types.FailedHandlerFunc,
+	logger logutil.Logger,
+) exhaustedResult {
 	if deadLetter != nil {
-		kafkaMsg := types.NewKafkaMessage(consumerGroup, topic, message)
 		if dlErr := deadLetter.OnDeadLetter(ctx, kafkaMsg, lastErr); dlErr != nil {
 			if logger != nil {
 				logger.Error("dead letter handler failed, offset not committed",
-					"topic", topic, "error", dlErr)
+					"topic", kafkaMsg.Queue, "error", dlErr)
 			}
 			return exhaustedFailed
 		}
@@ -163,7 +211,6 @@ func handleExhausted(
 	}
 
 	if failedHandler != nil {
-		kafkaMsg := types.NewKafkaMessage(consumerGroup, topic, message)
 		failedHandler(ctx, kafkaMsg, lastErr)
 	}
 	return exhaustedHandled
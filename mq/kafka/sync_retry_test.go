@@ -276,7 +276,7 @@ func TestSyncRetry_ExhaustedWithLogger(t *testing.T) {
 
 func TestHandleExhausted_WithDeadLetter_Success(t *testing.T) {
 	dl := &testDeadLetterHandler{}
-	result := handleExhausted(context.Background(), "g", "topic", []byte("msg"), errors.New("err"),
+	result := handleExhausted(context.Background(), types.NewKafkaMessage("g", "topic", []byte("msg")), errors.New("err"),
 		dl, nil, nil, nil)
 	assert.Equal(t, exhaustedHandled, result)
 	assert.True(t, dl.called.Load())
@@ -287,7 +287,7 @@ func TestHandleExhausted_WithDeadLetter_Fail(t *testing.T) {
 	var buf bytes.Buffer
 	logger := logutil.NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})))
 
-	result := handleExhausted(context.Background(), "g", "topic", []byte("msg"), errors.New("err"),
+	result := handleExhausted(context.Background(), types.NewKafkaMessage("g", "topic", []byte("msg")), errors.New("err"),
 		dl, nil, logger, nil)
 	assert.Equal(t, exhaustedFailed, result)
 }
@@ -297,7 +297,7 @@ func TestHandleExhausted_WithFailedHandler(t *testing.T) {
 	fh := types.FailedHandlerFunc(func(ctx context.Context, msg types.Message, err error) {
 		fhCalled = true
 	})
-	result := handleExhausted(context.Background(), "g", "topic", []byte("msg"), errors.New("err"),
+	result := handleExhausted(context.Background(), types.NewKafkaMessage("g", "topic", []byte("msg")), errors.New("err"),
 		nil, fh, nil, nil)
 	assert.Equal(t, exhaustedHandled, result)
 	assert.True(t, fhCalled)
@@ -308,7 +308,7 @@ func TestHandleExhausted_WithDefaultFailedHandler(t *testing.T) {
 	logger := logutil.NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})))
 
 	fh := DefaultFailedHandlerFunc(logger)
-	result := handleExhausted(context.Background(), "g", "topic", []byte("msg"), errors.New("err"),
+	result := handleExhausted(context.Background(), types.NewKafkaMessage("g", "topic", []byte("msg")), errors.New("err"),
 		nil, fh, logger, nil)
 	assert.Equal(t, exhaustedHandled, result)
 	assert.Contains(t, buf.String(), "message consume failed")
@@ -316,14 +316,52 @@ func TestHandleExhausted_WithDefaultFailedHandler(t *testing.T) {
 
 func TestHandleExhausted_WithMetrics(t *testing.T) {
 	m := metrics.NewConsumerMetrics("kafka")
-	result := handleExhausted(context.Background(), "g", "topic", []byte("msg"), errors.New("err"),
+	result := handleExhausted(context.Background(), types.NewKafkaMessage("g", "topic", []byte("msg")), errors.New("err"),
 		nil, nil, nil, m)
 	assert.Equal(t, exhaustedHandled, result)
 	// Metrics OnDeadLetter should have been called
 }
 
 func TestHandleExhausted_NoDeadLetterNoFailedHandlerNoLogger(t *testing.T) {
-	result := handleExhausted(context.Background(), "g", "topic", []byte("msg"), errors.New("err"),
+	result := handleExhausted(context.Background(), types.NewKafkaMessage("g", "topic", []byte("msg")), errors.New("err"),
 		nil, nil, nil, nil)
 	assert.Equal(t, exhaustedHandled, result)
-}
\ No newline at end of file
+}
+func TestSyncRetry_PermanentSkipsRetries(t *testing.T) {
+	var calls int
+	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+		calls++
+		return types.Permanent(errors.New("invalid"))
+	})
+	var failedErr error
+	strategy := newSyncRetryStrategy("test-group", handler, 3,
+		&retry.ExponentialDelay{Base: time.Millisecond, Max: time.Second},
+		0, nil, nil,
+	)
+	strategy.SetFailedHandler(func(_ context.Context, _ types.Message, err error) { failedErr = err })
+
+	session := newMockSession()
+	msg := &sarama.ConsumerMessage{Topic: "test", Partition: 0, Offset: 1, Value: []byte("hello")}
+	strategy.OnMessage(context.Background(), session, msg)
+
+	assert.Equal(t, 1, calls)
+	assert.True(t, types.IsNonRetryable(failedErr))
+	assert.Len(t, session.marks, 1)
+}
+
+func TestSyncRetry_RetryIf(t *testing.T) {
+	var calls int
+	handler := types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+		calls++
+		return errors.New("validation")
+	})
+	strategy := newSyncRetryStrategy("test-group", handler, 3,
+		&retry.ExponentialDelay{Base: time.Millisecond, Max: time.Second},
+		0, nil, nil,
+	)
+	strategy.SetRetryIf(func(error) bool { return false })
+
+	strategy.OnMessage(context.Background(), newMockSession(),
+		&sarama.ConsumerMessage{Topic: "test", Partition: 0, Offset: 1, Value: []byte("hello")})
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,543 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/mq/kafka/internal"
+	"github.com/gomooth/xerror"
+)
+
+// topicRecreatePollInterval 重建 topic 时等待删除完成的重试间隔
+const topicRecreatePollInterval = 500 * time.Millisecond
+
+// ErrDestructiveTopicChange 计划中包含可能丢失数据的配置变更，且未设置 WithAllowDestructiveTopicChanges
+var ErrDestructiveTopicChange = xerror.New("kafka: destructive topic changes require WithAllowDestructiveTopicChanges")
+
+// ErrTopicRecreateNotAllowed 计划中包含删除后重建 topic 的变更，且未设置 WithAllowTopicRecreate
+var ErrTopicRecreateNotAllowed = xerror.New("kafka: recreating topics requires WithAllowTopicRecreate")
+
+// ErrUnsupportedTopicChange 计划中包含无法自动执行的变更（如修改已有 topic 的副本数）
+var ErrUnsupportedTopicChange = xerror.New("kafka: unsupported topic change")
+
+// TopicSpec 期望的 topic 配置
+type TopicSpec struct {
+	Name              string
+	Partitions        int32             // 分区数，0 时创建使用 broker 默认值，已存在的 topic 不比较
+	ReplicationFactor int16             // 副本数，0 时创建使用 broker 默认值，已存在的 topic 不比较
+	Configs           map[string]string // topic 级配置（如 retention.ms、cleanup.policy），未声明的配置保持不变
+}
+
+// detail 创建 topic 的请求参数
+func (s TopicSpec) detail() *sarama.TopicDetail {
+	d := &sarama.TopicDetail{NumPartitions: -1, ReplicationFactor: -1}
+	if s.Partitions > 0 {
+		d.NumPartitions = s.Partitions
+	}
+	if s.ReplicationFactor > 0 {
+		d.ReplicationFactor = s.ReplicationFactor
+	}
+	if len(s.Configs) > 0 {
+		d.ConfigEntries = make(map[string]*string, len(s.Configs))
+		for k, v := range s.Configs {
+			d.ConfigEntries[k] = &v
+		}
+	}
+	return d
+}
+
+// TopicChangeKind topic 变更类型
+type TopicChangeKind string
+
+const (
+	TopicCreate        TopicChangeKind = "create"         // 创建 topic
+	TopicAddPartitions TopicChangeKind = "add-partitions" // 增加分区
+	TopicAlterConfig   TopicChangeKind = "alter-config"   // 修改单项配置
+	TopicRecreate      TopicChangeKind = "recreate"       // 减少分区：删除后重建，数据丢失
+	TopicReplication   TopicChangeKind = "replication"    // 副本数不一致：需通过分区重分配处理，不自动执行
+)
+
+// TopicChange topic 现状与期望配置之间的一项差异
+type TopicChange struct {
+	Topic       string
+	Kind        TopicChangeKind
+	Config      string // TopicAlterConfig 的配置项名称
+	From        string // 当前值，创建时为空
+	To          string // 期望值
+	Destructive bool   // 可能丢失数据：重建 topic、缩短保留期、修改清理策略
+}
+
+// Unsupported 报告变更是否无法由 TopicAdmin 执行
+func (c TopicChange) Unsupported() bool {
+	return c.Kind == TopicReplication
+}
+
+func (c TopicChange) String() string {
+	var b strings.Builder
+	b.WriteString(c.Topic)
+	b.WriteString(": ")
+	b.WriteString(string(c.Kind))
+	if c.Config != "" {
+		b.WriteString(" " + c.Config)
+	}
+	if c.From != "" {
+		b.WriteString(" " + c.From + " ->")
+	}
+	b.WriteString(" " + c.To)
+	if c.Destructive {
+		b.WriteString(" (destructive)")
+	}
+	if c.Unsupported() {
+		b.WriteString(" (unsupported)")
+	}
+	return b.String()
+}
+
+// TopicPlan 期望配置与集群现状的差异，按执行顺序排列
+type TopicPlan []TopicChange
+
+// Destructive 返回可能丢失数据的变更
+func (p TopicPlan) Destructive() TopicPlan {
+	return p.filter(func(c TopicChange) bool { return c.Destructive })
+}
+
+// Unsupported 返回无法自动执行的变更
+func (p TopicPlan) Unsupported() TopicPlan {
+	return p.filter(TopicChange.Unsupported)
+}
+
+func (p TopicPlan) filter(keep func(TopicChange) bool) TopicPlan {
+	var out TopicPlan
+	for _, c := range p {
+		if keep(c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// String 逐行输出变更，无变更时为 "no changes"
+func (p TopicPlan) String() string {
+	if len(p) == 0 {
+		return "no changes"
+	}
+	lines := make([]string, len(p))
+	for i, c := range p {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// clusterAdmin TopicAdmin 使用的 sarama.ClusterAdmin 子集（未导出）
+type clusterAdmin interface {
+	DescribeTopics(topics []string) ([]*sarama.TopicMetadata, error)
+	DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error)
+	CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error
+	CreatePartitions(topic string, count int32, assignment [][]int32, validateOnly bool) error
+	IncrementalAlterConfig(resourceType sarama.ConfigResourceType, name string,
+		entries map[string]sarama.IncrementalAlterConfigsEntry, validateOnly bool) error
+	DeleteTopic(topic string) error
+	Close() error
+}
+
+// TopicAdmin 按 TopicSpec 预置 topic：缺失时创建，分区不足时扩容，声明的配置不一致时修改。
+// 通过 WithTopicAdmin / WithProducerTopicAdmin 在消费者或生产者启动时执行，也可单独调用 Plan / Ensure。
+type TopicAdmin struct {
+	specs            []TopicSpec
+	dryRun           bool
+	allowDestructive bool
+	allowRecreate    bool
+	logger           *slog.Logger
+	connect          func() (clusterAdmin, error)
+}
+
+// TopicAdminOption topic 管理配置选项
+type TopicAdminOption func(*topicAdminConfig)
+
+// topicAdminConfig topic 管理配置（未导出）
+type topicAdminConfig struct {
+	timeout          time.Duration
+	saramaConfig     *sarama.Config
+	logger           *slog.Logger
+	dryRun           bool
+	allowDestructive bool
+	allowRecreate    bool
+}
+
+// WithTopicAdminDryRun 仅计算并记录差异，不修改集群
+func WithTopicAdminDryRun() TopicAdminOption {
+	return func(c *topicAdminConfig) {
+		c.dryRun = true
+	}
+}
+
+// WithAllowDestructiveTopicChanges 允许执行可能删除部分数据的配置变更（缩短保留期、修改清理策略）。
+// 未设置时计划中出现此类变更则不执行任何变更，Ensure 返回 ErrDestructiveTopicChange。
+// 不包括重建 topic，重建需单独设置 WithAllowTopicRecreate。
+func WithAllowDestructiveTopicChanges() TopicAdminOption {
+	return func(c *topicAdminConfig) {
+		c.allowDestructive = true
+	}
+}
+
+// WithAllowTopicRecreate 允许在期望分区数少于当前分区数时删除并重建 topic（丢失全部数据）。
+// 未设置时计划中出现重建则不执行任何变更，Ensure 返回 ErrTopicRecreateNotAllowed。
+func WithAllowTopicRecreate() TopicAdminOption {
+	return func(c *topicAdminConfig) {
+		c.allowRecreate = true
+	}
+}
+
+// WithTopicAdminLogger 设置日志器
+func WithTopicAdminLogger(l *slog.Logger) TopicAdminOption {
+	return func(c *topicAdminConfig) {
+		c.logger = l
+	}
+}
+
+// WithTopicAdminTimeout 设置连接与管理请求超时（默认 5s）
+func WithTopicAdminTimeout(d time.Duration) TopicAdminOption {
+	return func(c *topicAdminConfig) {
+		c.timeout = d
+	}
+}
+
+// WithTopicAdminSaramaConfig 设置自定义 sarama.Config（覆盖默认构建）
+func WithTopicAdminSaramaConfig(cfg *sarama.Config) TopicAdminOption {
+	return func(c *topicAdminConfig) {
+		c.saramaConfig = cfg
+	}
+}
+
+// NewTopicAdmin 创建 topic 管理器，执行时才连接集群
+func NewTopicAdmin(brokers []string, specs []TopicSpec, opts ...TopicAdminOption) *TopicAdmin {
+	cfg := &topicAdminConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	timeout := cfg.timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	saramaConfig := cfg.saramaConfig
+	if saramaConfig == nil {
+		saramaConfig = internal.BuildAdminConfig(timeout)
+	}
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &TopicAdmin{
+		specs:            specs,
+		dryRun:           cfg.dryRun,
+		allowDestructive: cfg.allowDestructive,
+		allowRecreate:    cfg.allowRecreate,
+		logger:           logger,
+		connect: func() (clusterAdmin, error) {
+			return sarama.NewClusterAdmin(brokers, saramaConfig)
+		},
+	}
+}
+
+// Plan 比较期望配置与集群现状，返回差异，不修改集群
+func (a *TopicAdmin) Plan(ctx context.Context) (TopicPlan, error) {
+	admin, err := a.connect()
+	if err != nil {
+		return nil, xerror.Wrap(err, "create cluster admin failed")
+	}
+	defer admin.Close()
+
+	return a.plan(ctx, admin)
+}
+
+// Ensure 计算差异并执行变更，返回执行（dry-run 时为计划）的变更。
+// 以下情况不执行任何变更：计划中包含无法执行的变更（ErrUnsupportedTopicChange）、
+// 未允许的重建（ErrTopicRecreateNotAllowed）或未允许的破坏性配置变更（ErrDestructiveTopicChange）。
+func (a *TopicAdmin) Ensure(ctx context.Context) (TopicPlan, error) {
+	admin, err := a.connect()
+	if err != nil {
+		return nil, xerror.Wrap(err, "create cluster admin failed")
+	}
+	defer admin.Close()
+
+	plan, err := a.plan(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan) == 0 {
+		a.logger.Debug("topics up to date", "topics", len(a.specs))
+		return plan, nil
+	}
+
+	if a.dryRun {
+		for _, c := range plan {
+			a.logger.Info("topic change planned (dry run)", "change", c.String(), "destructive", c.Destructive)
+		}
+		return plan, nil
+	}
+
+	if err := a.check(plan); err != nil {
+		return plan, err
+	}
+
+	for _, c := range plan {
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+		if err := a.apply(ctx, admin, c); err != nil {
+			return plan, err
+		}
+		a.logger.Info("topic change applied", "change", c.String())
+	}
+	return plan, nil
+}
+
+// check 检查计划能否执行，拒绝时记录被拒绝的变更
+func (a *TopicAdmin) check(plan TopicPlan) error {
+	refuse := func(changes TopicPlan, err error) error {
+		for _, c := range changes {
+			a.logger.Error("topic change refused", "change", c.String(), "error", err)
+		}
+		return err
+	}
+	if unsupported := plan.Unsupported(); len(unsupported) > 0 {
+		return refuse(unsupported, ErrUnsupportedTopicChange)
+	}
+	if !a.allowRecreate {
+		recreate := plan.filter(func(c TopicChange) bool { return c.Kind == TopicRecreate })
+		if len(recreate) > 0 {
+			return refuse(recreate, ErrTopicRecreateNotAllowed)
+		}
+	}
+	if !a.allowDestructive {
+		destructive := plan.filter(func(c TopicChange) bool { return c.Destructive && c.Kind != TopicRecreate })
+		if len(destructive) > 0 {
+			return refuse(destructive, ErrDestructiveTopicChange)
+		}
+	}
+	return nil
+}
+
+// plan 按 TopicSpec 顺序生成变更：创建、重建，或扩容分区后修改配置
+func (a *TopicAdmin) plan(ctx context.Context, admin clusterAdmin) (TopicPlan, error) {
+	names := make([]string, len(a.specs))
+	for i, spec := range a.specs {
+		if spec.Name == "" {
+			return nil, xerror.New("kafka: topic spec name must not be empty")
+		}
+		names[i] = spec.Name
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	metadata, err := admin.DescribeTopics(names)
+	if err != nil {
+		return nil, xerror.Wrap(err, "describe topics failed")
+	}
+	existing := make(map[string]*sarama.TopicMetadata, len(metadata))
+	for _, md := range metadata {
+		existing[md.Name] = md
+	}
+
+	var plan TopicPlan
+	for _, spec := range a.specs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		md := existing[spec.Name]
+		if md == nil || md.Err == sarama.ErrUnknownTopicOrPartition {
+			plan = append(plan, TopicChange{Topic: spec.Name, Kind: TopicCreate, To: layout(spec.Partitions, spec.ReplicationFactor)})
+			continue
+		}
+		if md.Err != sarama.ErrNoError {
+			return nil, xerror.Wrap(md.Err, fmt.Sprintf("describe topic %q failed", spec.Name))
+		}
+
+		partitions := int32(len(md.Partitions))
+		var replication int16
+		if len(md.Partitions) > 0 {
+			replication = int16(len(md.Partitions[0].Replicas))
+		}
+		// 分区数只能增加，减少分区只能重建（新 topic 同时使用期望的副本数）
+		if spec.Partitions > 0 && spec.Partitions < partitions {
+			plan = append(plan, TopicChange{
+				Topic:       spec.Name,
+				Kind:        TopicRecreate,
+				From:        layout(partitions, replication),
+				To:          layout(spec.Partitions, spec.ReplicationFactor),
+				Destructive: true,
+			})
+			continue
+		}
+		// 修改副本数需要分区重分配，不自动执行，也不以重建代替
+		if spec.ReplicationFactor > 0 && spec.ReplicationFactor != replication {
+			plan = append(plan, TopicChange{
+				Topic: spec.Name,
+				Kind:  TopicReplication,
+				From:  strconv.Itoa(int(replication)),
+				To:    strconv.Itoa(int(spec.ReplicationFactor)),
+			})
+		}
+		if spec.Partitions > partitions {
+			plan = append(plan, TopicChange{
+				Topic: spec.Name,
+				Kind:  TopicAddPartitions,
+				From:  strconv.Itoa(int(partitions)),
+				To:    strconv.Itoa(int(spec.Partitions)),
+			})
+		}
+
+		changes, err := configChanges(admin, spec)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, changes...)
+	}
+	return plan, nil
+}
+
+// configChanges 比较声明的配置项与当前值（按配置名排序）
+func configChanges(admin clusterAdmin, spec TopicSpec) (TopicPlan, error) {
+	if len(spec.Configs) == 0 {
+		return nil, nil
+	}
+	entries, err := admin.DescribeConfig(sarama.ConfigResource{Type: sarama.TopicResource, Name: spec.Name})
+	if err != nil {
+		return nil, xerror.Wrap(err, fmt.Sprintf("describe configs of topic %q failed", spec.Name))
+	}
+	current := make(map[string]string, len(entries))
+	for _, e := range entries {
+		current[e.Name] = e.Value
+	}
+
+	keys := make([]string, 0, len(spec.Configs))
+	for k := range spec.Configs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var changes TopicPlan
+	for _, k := range keys {
+		want, have := spec.Configs[k], current[k]
+		if want == have {
+			continue
+		}
+		changes = append(changes, TopicChange{
+			Topic:       spec.Name,
+			Kind:        TopicAlterConfig,
+			Config:      k,
+			From:        have,
+			To:          want,
+			Destructive: destructiveConfig(k, have, want),
+		})
+	}
+	return changes, nil
+}
+
+// destructiveConfig 报告配置修改是否可能删除已有数据：
+// 缩短 retention.ms / retention.bytes（-1 为不限）或修改 cleanup.policy
+func destructiveConfig(name, from, to string) bool {
+	switch name {
+	case "cleanup.policy":
+		return true
+	case "retention.ms", "retention.bytes":
+		f, errFrom := strconv.ParseInt(from, 10, 64)
+		t, errTo := strconv.ParseInt(to, 10, 64)
+		if errFrom != nil || errTo != nil {
+			return true
+		}
+		if t < 0 {
+			return false
+		}
+		return f < 0 || t < f
+	}
+	return false
+}
+
+// apply 执行单项变更
+func (a *TopicAdmin) apply(ctx context.Context, admin clusterAdmin, c TopicChange) error {
+	spec := a.spec(c.Topic)
+	switch c.Kind {
+	case TopicCreate:
+		// 其他实例可能同时创建
+		if err := admin.CreateTopic(spec.Name, spec.detail(), false); err != nil && !topicExists(err) {
+			return xerror.Wrap(err, fmt.Sprintf("create topic %q failed", spec.Name))
+		}
+	case TopicAddPartitions:
+		if err := admin.CreatePartitions(spec.Name, spec.Partitions, nil, false); err != nil {
+			return xerror.Wrap(err, fmt.Sprintf("add partitions to topic %q failed", spec.Name))
+		}
+	case TopicAlterConfig:
+		value := c.To
+		entries := map[string]sarama.IncrementalAlterConfigsEntry{
+			c.Config: {Operation: sarama.IncrementalAlterConfigsOperationSet, Value: &value},
+		}
+		if err := admin.IncrementalAlterConfig(sarama.TopicResource, spec.Name, entries, false); err != nil {
+			return xerror.Wrap(err, fmt.Sprintf("alter config %s of topic %q failed", c.Config, spec.Name))
+		}
+	case TopicRecreate:
+		return recreateTopic(ctx, admin, spec)
+	case TopicReplication:
+		return xerror.Wrap(ErrUnsupportedTopicChange, c.String())
+	}
+	return nil
+}
+
+// recreateTopic 删除后重建 topic。删除在 broker 上异步完成，期间创建返回 topic 已存在，按间隔重试直到 ctx 结束。
+func recreateTopic(ctx context.Context, admin clusterAdmin, spec TopicSpec) error {
+	if err := admin.DeleteTopic(spec.Name); err != nil {
+		return xerror.Wrap(err, fmt.Sprintf("delete topic %q failed", spec.Name))
+	}
+	for {
+		err := admin.CreateTopic(spec.Name, spec.detail(), false)
+		if err == nil {
+			return nil
+		}
+		if !topicExists(err) {
+			return xerror.Wrap(err, fmt.Sprintf("recreate topic %q failed", spec.Name))
+		}
+		select {
+		case <-time.After(topicRecreatePollInterval):
+		case <-ctx.Done():
+			return xerror.Wrap(ctx.Err(), fmt.Sprintf("recreate topic %q: deletion not completed", spec.Name))
+		}
+	}
+}
+
+// topicExists 报告创建 topic 的错误是否为 topic 已存在
+func topicExists(err error) bool {
+	return errors.Is(err, sarama.ErrTopicAlreadyExists)
+}
+
+// spec 按名称查找 TopicSpec
+func (a *TopicAdmin) spec(name string) TopicSpec {
+	for _, s := range a.specs {
+		if s.Name == name {
+			return s
+		}
+	}
+	return TopicSpec{Name: name}
+}
+
+// layout 描述分区数与副本数，0 表示 broker 默认值
+func layout(partitions int32, replication int16) string {
+	p, r := "default", "default"
+	if partitions > 0 {
+		p = strconv.Itoa(int(partitions))
+	}
+	if replication > 0 {
+		r = strconv.Itoa(int(replication))
+	}
+	return "partitions=" + p + " replication=" + r
+}
@@ -0,0 +1,295 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTopic fakeClusterAdmin 中的 topic
+type fakeTopic struct {
+	partitions  int32
+	replication int16
+	configs     map[string]string
+}
+
+// fakeClusterAdmin 内存中的集群管理实现，记录修改操作；
+// pendingDeletes 模拟删除异步完成：删除后的前 n 次创建返回 topic 已存在
+type fakeClusterAdmin struct {
+	mu             sync.Mutex
+	topics         map[string]*fakeTopic
+	ops            []string
+	pendingDeletes int
+	closed         bool
+}
+
+func newFakeClusterAdmin() *fakeClusterAdmin {
+	return &fakeClusterAdmin{topics: make(map[string]*fakeTopic)}
+}
+
+func (f *fakeClusterAdmin) DescribeTopics(topics []string) ([]*sarama.TopicMetadata, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]*sarama.TopicMetadata, 0, len(topics))
+	for _, name := range topics {
+		t, ok := f.topics[name]
+		if !ok {
+			out = append(out, &sarama.TopicMetadata{Name: name, Err: sarama.ErrUnknownTopicOrPartition})
+			continue
+		}
+		md := &sarama.TopicMetadata{Name: name}
+		for i := int32(0); i < t.partitions; i++ {
+			md.Partitions = append(md.Partitions, &sarama.PartitionMetadata{ID: i, Replicas: make([]int32, t.replication)})
+		}
+		out = append(out, md)
+	}
+	return out, nil
+}
+
+func (f *fakeClusterAdmin) DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var entries []sarama.ConfigEntry
+	for k, v := range f.topics[resource.Name].configs {
+		entries = append(entries, sarama.ConfigEntry{Name: k, Value: v})
+	}
+	return entries, nil
+}
+
+func (f *fakeClusterAdmin) CreateTopic(topic string, detail *sarama.TopicDetail, _ bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.topics[topic]; ok || f.pendingDeletes > 0 {
+		if f.pendingDeletes > 0 {
+			f.pendingDeletes--
+		}
+		return &sarama.TopicError{Err: sarama.ErrTopicAlreadyExists}
+	}
+	configs := make(map[string]string)
+	for k, v := range detail.ConfigEntries {
+		configs[k] = *v
+	}
+	f.topics[topic] = &fakeTopic{partitions: detail.NumPartitions, replication: detail.ReplicationFactor, configs: configs}
+	f.ops = append(f.ops, "create "+topic)
+	return nil
+}
+
+func (f *fakeClusterAdmin) CreatePartitions(topic string, count int32, _ [][]int32, _ bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.topics[topic].partitions = count
+	f.ops = append(f.ops, "add-partitions "+topic)
+	return nil
+}
+
+func (f *fakeClusterAdmin) IncrementalAlterConfig(_ sarama.ConfigResourceType, name string,
+	entries map[string]sarama.IncrementalAlterConfigsEntry, _ bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for k, e := range entries {
+		f.topics[name].configs[k] = *e.Value
+		f.ops = append(f.ops, "alter-config "+name+" "+k)
+	}
+	return nil
+}
+
+func (f *fakeClusterAdmin) DeleteTopic(topic string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.topics, topic)
+	f.ops = append(f.ops, "delete "+topic)
+	return nil
+}
+
+func (f *fakeClusterAdmin) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeClusterAdmin) operations() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ops...)
+}
+
+func newTestTopicAdmin(f *fakeClusterAdmin, specs []TopicSpec, opts ...TopicAdminOption) *TopicAdmin {
+	a := NewTopicAdmin([]string{"localhost:9092"}, specs, append([]TopicAdminOption{WithTopicAdminLogger(newTestSlogLogger())}, opts...)...)
+	a.connect = func() (clusterAdmin, error) { return f, nil }
+	return a
+}
+
+func TestTopicAdmin_Plan(t *testing.T) {
+	f := newFakeClusterAdmin()
+	f.topics["orders"] = &fakeTopic{partitions: 6, replication: 3, configs: map[string]string{
+		"retention.ms": "604800000", "cleanup.policy": "delete",
+	}}
+	f.topics["audit"] = &fakeTopic{partitions: 12, replication: 3, configs: map[string]string{}}
+	f.topics["events"] = &fakeTopic{partitions: 6, replication: 1, configs: map[string]string{}}
+
+	a := newTestTopicAdmin(f, []TopicSpec{
+		{Name: "payments", Partitions: 12, ReplicationFactor: 3, Configs: map[string]string{"retention.ms": "86400000"}},
+		{Name: "orders", Partitions: 12, Configs: map[string]string{
+			"retention.ms": "1209600000", "cleanup.policy": "delete",
+		}},
+		{Name: "audit", Partitions: 6},
+		{Name: "events", Partitions: 6, ReplicationFactor: 3},
+	})
+
+	plan, err := a.Plan(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, TopicPlan{
+		{Topic: "payments", Kind: TopicCreate, To: "partitions=12 replication=3"},
+		{Topic: "orders", Kind: TopicAddPartitions, From: "6", To: "12"},
+		{Topic: "orders", Kind: TopicAlterConfig, Config: "retention.ms", From: "604800000", To: "1209600000"},
+		{Topic: "audit", Kind: TopicRecreate, From: "partitions=12 replication=3", To: "partitions=6 replication=default", Destructive: true},
+		{Topic: "events", Kind: TopicReplication, From: "1", To: "3"},
+	}, plan)
+	assert.Equal(t, "orders: add-partitions 6 -> 12", plan[1].String())
+	assert.Equal(t, "audit: recreate partitions=12 replication=3 -> partitions=6 replication=default (destructive)", plan[3].String())
+	assert.Equal(t, "events: replication 1 -> 3 (unsupported)", plan[4].String())
+	assert.Len(t, plan.Destructive(), 1)
+	assert.Len(t, plan.Unsupported(), 1)
+	assert.Empty(t, f.operations(), "plan does not modify the cluster")
+	assert.True(t, f.closed)
+}
+
+func TestTopicAdmin_EnsureAppliesAndConverges(t *testing.T) {
+	f := newFakeClusterAdmin()
+	f.topics["orders"] = &fakeTopic{partitions: 6, replication: 3, configs: map[string]string{"retention.ms": "604800000"}}
+	specs := []TopicSpec{
+		{Name: "payments", Partitions: 3, ReplicationFactor: 1, Configs: map[string]string{"cleanup.policy": "compact"}},
+		{Name: "orders", Partitions: 12, Configs: map[string]string{"retention.ms": "-1"}},
+	}
+	a := newTestTopicAdmin(f, specs)
+
+	plan, err := a.Ensure(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, plan, 3)
+	assert.Equal(t, []string{"create payments", "add-partitions orders", "alter-config orders retention.ms"}, f.operations())
+	assert.Equal(t, "compact", f.topics["payments"].configs["cleanup.policy"])
+
+	plan, err = a.Ensure(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+	assert.Equal(t, "no changes", plan.String())
+}
+
+func TestTopicAdmin_DryRun(t *testing.T) {
+	f := newFakeClusterAdmin()
+	f.topics["orders"] = &fakeTopic{partitions: 12, replication: 3, configs: map[string]string{}}
+	a := newTestTopicAdmin(f, []TopicSpec{{Name: "payments"}, {Name: "orders", Partitions: 3}}, WithTopicAdminDryRun())
+
+	plan, err := a.Ensure(context.Background())
+	require.NoError(t, err, "dry run reports destructive changes without failing")
+	assert.Len(t, plan, 2)
+	assert.Empty(t, f.operations())
+}
+
+func TestTopicAdmin_RefusesDestructiveChanges(t *testing.T) {
+	f := newFakeClusterAdmin()
+	f.topics["orders"] = &fakeTopic{partitions: 6, replication: 3, configs: map[string]string{"cleanup.policy": "delete"}}
+	a := newTestTopicAdmin(f, []TopicSpec{
+		{Name: "payments"},
+		{Name: "orders", Configs: map[string]string{"cleanup.policy": "compact"}},
+	})
+
+	plan, err := a.Ensure(context.Background())
+	assert.ErrorIs(t, err, ErrDestructiveTopicChange)
+	assert.Len(t, plan, 2)
+	assert.Empty(t, f.operations(), "no change is applied when any change is destructive")
+}
+
+func TestTopicAdmin_RecreateRequiresOwnOption(t *testing.T) {
+	f := newFakeClusterAdmin()
+	f.topics["orders"] = &fakeTopic{partitions: 12, replication: 3, configs: map[string]string{}}
+	f.pendingDeletes = 1
+	specs := []TopicSpec{{Name: "orders", Partitions: 6, ReplicationFactor: 3}}
+
+	// 允许破坏性配置变更不包括重建 topic
+	_, err := newTestTopicAdmin(f, specs, WithAllowDestructiveTopicChanges()).Ensure(context.Background())
+	assert.ErrorIs(t, err, ErrTopicRecreateNotAllowed)
+	assert.Empty(t, f.operations())
+
+	_, err = newTestTopicAdmin(f, specs, WithAllowTopicRecreate()).Ensure(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"delete orders", "create orders"}, f.operations())
+	assert.Equal(t, int32(6), f.topics["orders"].partitions)
+}
+
+func TestTopicAdmin_ReplicationChangeUnsupported(t *testing.T) {
+	f := newFakeClusterAdmin()
+	f.topics["orders"] = &fakeTopic{partitions: 6, replication: 1, configs: map[string]string{}}
+	a := newTestTopicAdmin(f, []TopicSpec{{Name: "orders", Partitions: 12, ReplicationFactor: 3}},
+		WithAllowDestructiveTopicChanges(), WithAllowTopicRecreate())
+
+	plan, err := a.Ensure(context.Background())
+	assert.ErrorIs(t, err, ErrUnsupportedTopicChange)
+	assert.Len(t, plan, 2)
+	assert.Empty(t, f.operations(), "replication changes never delete the topic")
+}
+
+func TestTopicAdmin_Errors(t *testing.T) {
+	f := newFakeClusterAdmin()
+	_, err := newTestTopicAdmin(f, []TopicSpec{{}}).Plan(context.Background())
+	assert.Error(t, err)
+
+	connectErr := errors.New("no brokers")
+	a := newTestTopicAdmin(f, []TopicSpec{{Name: "orders"}})
+	a.connect = func() (clusterAdmin, error) { return nil, connectErr }
+	_, err = a.Ensure(context.Background())
+	assert.ErrorIs(t, err, connectErr)
+}
+
+func TestDestructiveConfig(t *testing.T) {
+	cases := []struct {
+		name, from, to string
+		want           bool
+	}{
+		{"retention.ms", "604800000", "86400000", true},
+		{"retention.ms", "86400000", "604800000", false},
+		{"retention.ms", "-1", "86400000", true},
+		{"retention.ms", "86400000", "-1", false},
+		{"retention.bytes", "", "1073741824", true},
+		{"cleanup.policy", "delete", "compact", true},
+		{"min.insync.replicas", "1", "2", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, destructiveConfig(c.name, c.from, c.to), "%s %s -> %s", c.name, c.from, c.to)
+	}
+}
+
+func TestConsumerEngine_StartEnsuresTopics(t *testing.T) {
+	f := newFakeClusterAdmin()
+	f.topics["orders"] = &fakeTopic{partitions: 12, replication: 3, configs: map[string]string{}}
+	admin := newTestTopicAdmin(f, []TopicSpec{{Name: "orders", Partitions: 3}})
+
+	e := newConsumerEngine([]string{"localhost:9092"}, &consumerConfig{logger: newTestSlogLogger(), topicAdmin: admin})
+	e.registrations = []consumerRegistration{{group: "g1", topics: []string{"orders"}}}
+
+	err := e.Start(context.Background())
+	assert.ErrorIs(t, err, ErrTopicRecreateNotAllowed)
+	assert.Empty(t, f.operations())
+}
+
+func TestProducerEngine_StartEnsuresTopics(t *testing.T) {
+	f := newFakeClusterAdmin()
+	admin := newTestTopicAdmin(f, []TopicSpec{{Name: "orders", Partitions: 3}})
+	registered := false
+	eng := newProducerEngine([]string{"localhost:9092"}, &producerConfig{
+		logger:     newTestSlogLogger(),
+		topicAdmin: admin,
+		schemas:    []SchemaRegistrant{schemaFunc(func(context.Context) error { registered = true; return nil })},
+	})
+
+	// 无可用 broker，topic 预置与 schema 注册在连接之前完成
+	require.Error(t, eng.Start(context.Background()))
+	assert.Equal(t, []string{"create orders"}, f.operations())
+	assert.True(t, registered)
+}
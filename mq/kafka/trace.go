@@ -87,21 +87,28 @@ func propagateFromMessage(ctx context.Context, msg *sarama.ConsumerMessage) cont
 	return otel.GetTextMapPropagator().Extract(ctx, carrier)
 }
 
-// startConsumerSpan 为消费者消息创建 SpanKindConsumer span，
-// 并将 trace context 从消息 headers 传播到返回的 context 中。
-func startConsumerSpan(ctx context.Context, msg *sarama.ConsumerMessage) (context.Context, trace.Span) {
-	ctx = propagateFromMessage(ctx, msg)
+// startBatchConsumerSpan 为一批消费消息创建 SpanKindConsumer span，
+// 批内各消息的 trace context 以 span link 关联
+func startBatchConsumerSpan(ctx context.Context, msgs []*sarama.ConsumerMessage) (context.Context, trace.Span) {
+	links := make([]trace.Link, 0, len(msgs))
+	for _, msg := range msgs {
+		sc := trace.SpanContextFromContext(propagateFromMessage(context.Background(), msg))
+		if sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	first := msgs[0]
 	tracer := telemetry.Tracer("mq.kafka.consumer")
-	ctx, span := tracer.Start(ctx, fmt.Sprintf("%s consume", msg.Topic),
+	return tracer.Start(ctx, fmt.Sprintf("%s consume batch", first.Topic),
 		trace.WithAttributes(
 			attribute.String("messaging.system", "kafka"),
-			attribute.String("messaging.destination", msg.Topic),
-			attribute.Int("messaging.partition", int(msg.Partition)),
-			attribute.Int64("messaging.offset", int64(msg.Offset)),
+			attribute.String("messaging.destination", first.Topic),
+			attribute.Int("messaging.partition", int(first.Partition)),
+			attribute.Int("messaging.batch.size", len(msgs)),
 		),
+		trace.WithLinks(links...),
 		trace.WithSpanKind(trace.SpanKindConsumer),
 	)
-	return ctx, span
 }
 
 // injectProducerTrace 为生产者消息创建 SpanKindProducer span，
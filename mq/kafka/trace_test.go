@@ -113,21 +113,6 @@ func TestPropagateFromMessage(t *testing.T) {
 	assert.NotNil(t, resultCtx)
 }
 
-func TestStartConsumerSpan(t *testing.T) {
-	msg := &sarama.ConsumerMessage{
-		Topic:     "test-topic",
-		Partition: 1,
-		Offset:    100,
-		Headers:   []*sarama.RecordHeader{},
-	}
-	ctx := context.Background()
-	resultCtx, span := startConsumerSpan(ctx, msg)
-	defer span.End()
-
-	assert.NotNil(t, resultCtx)
-	assert.NotNil(t, span)
-}
-
 func TestInjectProducerTrace(t *testing.T) {
 	msgs := []*sarama.ProducerMessage{
 		{Topic: "test-topic", Value: sarama.StringEncoder("hello")},
@@ -0,0 +1,163 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Tx 事务内的生产者视图。通过 Tx 发送的消息随所属事务原子提交或中止，
+// 中止后的消息对 read_committed 消费者不可见。Tx 仅在所属事务期间有效，事务结束后调用返回错误。
+type Tx interface {
+	// Produce 在事务内发送单条消息
+	Produce(ctx context.Context, topic string, message []byte, opts ...ProduceOption) error
+	// ProduceBatch 在事务内批量发送消息，每条消息独立生成消息 ID
+	ProduceBatch(ctx context.Context, topic string, messages [][]byte, opts ...ProduceOption) error
+}
+
+type txKey struct{}
+
+// withTx 将事务写入 context，供 handler 通过 TxFromContext 获取
+func withTx(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext 返回 context 所属的 Kafka 事务。
+// exactly-once 消费（WithExactlyOnce）的 handler 与 ProduceInTx 回调内可用，
+// 经该事务发送的消息与消费 offset 一并提交。
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(Tx)
+	return tx, ok
+}
+
+// producerTx 单个事务的发送视图（未导出），实现 Tx
+type producerTx struct {
+	producer   sarama.SyncProducer
+	delayTopic string
+
+	mu   sync.Mutex
+	done bool
+	sent int
+}
+
+var _ Tx = (*producerTx)(nil)
+
+func (t *producerTx) Produce(ctx context.Context, topic string, message []byte, opts ...ProduceOption) error {
+	produceCfg := types.ApplyProduceOptions(opts)
+	return t.send(ctx, topic, []*sarama.ProducerMessage{newProducerMessage(topic, message, produceCfg)}, produceCfg)
+}
+
+func (t *producerTx) ProduceBatch(ctx context.Context, topic string, messages [][]byte, opts ...ProduceOption) error {
+	if len(messages) == 0 {
+		return xerror.NewXCode(xcode.ErrMQPublish, "no messages")
+	}
+
+	produceCfg := types.ApplyProduceOptions(opts)
+	produceCfg.MessageID = ""
+
+	msgs := make([]*sarama.ProducerMessage, len(messages))
+	for i, msg := range messages {
+		msgs[i] = newProducerMessage(topic, msg, produceCfg)
+	}
+	return t.send(ctx, topic, msgs, produceCfg)
+}
+
+// publish 在事务内发送已构造好的 record（死信 topic 等内部流程使用）
+func (t *producerTx) publish(msg *sarama.ProducerMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return xerror.NewXCode(xcode.ErrMQPublish, "transaction already finished")
+	}
+	if _, _, err := t.producer.SendMessage(msg); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	t.sent++
+	return nil
+}
+
+func (t *producerTx) send(ctx context.Context, topic string, msgs []*sarama.ProducerMessage, cfg *types.ProduceConfig) error {
+	if err := ctx.Err(); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	if at, delayed := cfg.DeliveryTime(); delayed {
+		for _, msg := range msgs {
+			routeDelayed(msg, t.delayTopic, at)
+		}
+	}
+
+	_, span := injectProducerTrace(ctx, topic, msgs)
+	defer span.End()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		err := xerror.NewXCode(xcode.ErrMQPublish, "transaction already finished")
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := t.producer.SendMessages(msgs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+	t.sent += len(msgs)
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
+
+// finish 结束事务视图，返回事务内发送的消息数
+func (t *producerTx) finish() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = true
+	return t.sent
+}
+
+// runTx 在 producer 上开启事务并执行 fn；fn 与 beforeCommit（可为 nil）均成功后提交，否则中止。
+// 返回已提交的消息数。失败后应通过 txBroken 判断 producer 是否仍可复用。
+func runTx(
+	ctx context.Context,
+	producer sarama.SyncProducer,
+	delayTopic string,
+	fn func(ctx context.Context, tx Tx) error,
+	beforeCommit func() error,
+) (int, error) {
+	if err := producer.BeginTxn(); err != nil {
+		return 0, xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	tx := &producerTx{producer: producer, delayTopic: delayTopic}
+	err := fn(withTx(ctx, tx), tx)
+	sent := tx.finish()
+
+	if err == nil && beforeCommit != nil {
+		if err = beforeCommit(); err != nil {
+			err = xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+		}
+	}
+	if err == nil {
+		if err = producer.CommitTxn(); err == nil {
+			return sent, nil
+		}
+		err = xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	// 致命错误状态下无法中止，由调用方关闭并重建 producer
+	if producer.TxnStatus()&sarama.ProducerTxnFlagFatalError == 0 {
+		_ = producer.AbortTxn()
+	}
+	return 0, err
+}
+
+// txBroken 报告事务 producer 是否处于不可恢复状态（致命错误或中止失败），需关闭后以相同 transactional.id 重建
+func txBroken(producer sarama.SyncProducer) bool {
+	return producer.TxnStatus()&(sarama.ProducerTxnFlagFatalError|sarama.ProducerTxnFlagInError) != 0
+}
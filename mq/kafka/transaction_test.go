@@ -0,0 +1,187 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/pkg/mq/kafka/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// txRecordingProducer 记录事务操作的 mock 事务生产者，可注入提交失败与致命错误
+type txRecordingProducer struct {
+	*mocks.SyncProducer
+
+	mu        sync.Mutex
+	offsets   []*sarama.ConsumerMessage
+	groups    []string
+	commits   int
+	aborts    int
+	commitErr error
+	fatal     bool
+	closed    bool
+}
+
+func newTxRecordingProducer(t *testing.T) *txRecordingProducer {
+	cfg := internal.TransactionalProducerConfig(internal.BuildProducerConfig(5*time.Second), "test-tx")
+	return &txRecordingProducer{SyncProducer: mocks.NewSyncProducer(t, cfg)}
+}
+
+func (p *txRecordingProducer) AddMessageToTxn(msg *sarama.ConsumerMessage, groupID string, metadata *string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.offsets = append(p.offsets, msg)
+	p.groups = append(p.groups, groupID)
+	return p.SyncProducer.AddMessageToTxn(msg, groupID, metadata)
+}
+
+func (p *txRecordingProducer) CommitTxn() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.commitErr != nil {
+		return p.commitErr
+	}
+	p.commits++
+	return p.SyncProducer.CommitTxn()
+}
+
+func (p *txRecordingProducer) AbortTxn() error {
+	p.mu.Lock()
+	p.aborts++
+	p.mu.Unlock()
+	return p.SyncProducer.AbortTxn()
+}
+
+func (p *txRecordingProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fatal {
+		return sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagFatalError
+	}
+	return p.SyncProducer.TxnStatus()
+}
+
+func (p *txRecordingProducer) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	return p.SyncProducer.Close()
+}
+
+func (p *txRecordingProducer) stats() (commits, aborts, offsets int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.commits, p.aborts, len(p.offsets)
+}
+
+func newTxProducerEngine(t *testing.T, producer sarama.SyncProducer) *producerEngine {
+	cfg := &producerConfig{logger: newTestSlogLogger(), transactionalID: "test-tx"}
+	eng := newProducerEngine([]string{"localhost:9092"}, cfg)
+	eng.Metrics = metrics.NewProducerMetrics("kafka")
+	eng.inner = producer
+	eng.State.Store(engine.Running)
+	return eng
+}
+
+func TestProducerEngine_ProduceInTxCommit(t *testing.T) {
+	p := newTxRecordingProducer(t)
+	p.ExpectSendMessageAndSucceed()
+	p.ExpectSendMessageAndSucceed()
+	p.ExpectSendMessageAndSucceed()
+	eng := newTxProducerEngine(t, p)
+
+	err := eng.ProduceInTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		_, ok := TxFromContext(ctx)
+		assert.True(t, ok)
+		if err := tx.Produce(ctx, "orders", []byte("a"), types.WithOrderKey("k")); err != nil {
+			return err
+		}
+		return tx.ProduceBatch(ctx, "audit", [][]byte{[]byte("b"), []byte("c")})
+	})
+	require.NoError(t, err)
+
+	commits, aborts, _ := p.stats()
+	assert.Equal(t, 1, commits)
+	assert.Zero(t, aborts)
+	assert.Equal(t, sarama.ProducerTxnFlagReady, p.TxnStatus())
+}
+
+func TestProducerEngine_ProduceInTxAbort(t *testing.T) {
+	p := newTxRecordingProducer(t)
+	p.ExpectSendMessageAndSucceed()
+	eng := newTxProducerEngine(t, p)
+
+	fnErr := errors.New("transform failed")
+	var leaked Tx
+	err := eng.ProduceInTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		leaked = tx
+		if err := tx.Produce(ctx, "orders", []byte("a")); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	assert.ErrorIs(t, err, fnErr)
+
+	commits, aborts, _ := p.stats()
+	assert.Zero(t, commits)
+	assert.Equal(t, 1, aborts)
+	assert.Error(t, leaked.Produce(context.Background(), "orders", []byte("late")), "tx unusable after finish")
+	assert.NotNil(t, eng.inner, "abortable failure keeps the producer")
+}
+
+func TestProducerEngine_ProduceInTxFatalReconnects(t *testing.T) {
+	p := newTxRecordingProducer(t)
+	p.commitErr = sarama.ErrProducerFenced
+	p.fatal = true
+	eng := newTxProducerEngine(t, p)
+
+	err := eng.ProduceInTx(context.Background(), func(context.Context, Tx) error { return nil })
+	require.Error(t, err)
+
+	_, aborts, _ := p.stats()
+	assert.Zero(t, aborts, "fatal state cannot abort")
+	assert.Nil(t, eng.inner)
+	select {
+	case <-eng.reconnectCh:
+	default:
+		t.Fatal("expected reconnect to be triggered")
+	}
+}
+
+func TestProducerEngine_ProduceWrapsTransaction(t *testing.T) {
+	p := newTxRecordingProducer(t)
+	p.ExpectSendMessageAndSucceed()
+	p.ExpectSendMessageAndSucceed()
+	eng := newTxProducerEngine(t, p)
+
+	require.NoError(t, eng.Produce(context.Background(), "orders", []byte("a")))
+	require.NoError(t, eng.ProduceBatch(context.Background(), "orders", [][]byte{[]byte("b")}))
+
+	commits, _, _ := p.stats()
+	assert.Equal(t, 2, commits)
+}
+
+func TestProducerEngine_ProduceInTxNotTransactional(t *testing.T) {
+	p := NewProducer([]string{"localhost:9092"}, WithProducerLogger(newTestSlogLogger())).(*producerImpl)
+	err := p.ProduceInTx(context.Background(), func(context.Context, Tx) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestNewTransactionalProducer(t *testing.T) {
+	custom := internal.BuildProducerConfig(time.Second)
+	p := NewTransactionalProducer([]string{"localhost:9092"}, "orders-tx",
+		WithProducerSaramaConfig(custom)).(*producerImpl)
+
+	assert.True(t, p.engine.transactional)
+	assert.Equal(t, "orders-tx", p.engine.config.Producer.Transaction.ID)
+	assert.Empty(t, custom.Producer.Transaction.ID, "custom config is copied")
+}
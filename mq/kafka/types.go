@@ -18,6 +18,12 @@ type DeadLetterHandler = types.DeadLetterHandler
 // FuncHandler 函数适配器，将函数转换为 IHandler
 type FuncHandler = types.FuncHandler
 
+// IBatchHandler 批量消息处理器接口，通过 mq.NewBatchHandler 包装后注册
+type IBatchHandler = types.IBatchHandler
+
+// FuncBatchHandler 函数适配器，将函数转换为 IBatchHandler
+type FuncBatchHandler = types.FuncBatchHandler
+
 // IConsumeServer 消费者服务接口
 type IConsumeServer = types.IConsumeServer
 
@@ -33,6 +39,15 @@ type ConsumerRegistration struct {
 // IProducer 生产者接口（集成 app.IApp 生命周期）
 type IProducer = types.IProducer
 
+// ITransactionalProducer 事务生产者接口，由 NewTransactionalProducer 创建
+type ITransactionalProducer interface {
+	IProducer
+
+	// ProduceInTx 在一个 Kafka 事务内执行 fn：fn 经 tx 发送的消息在 fn 返回 nil 后原子提交，
+	// fn 返回错误或提交失败时中止事务并返回错误
+	ProduceInTx(ctx context.Context, fn func(ctx context.Context, tx Tx) error) error
+}
+
 // ==================== 重试模式 ====================
 
 // RetryMode 重试模式
@@ -86,6 +101,32 @@ type WatermarkStore interface {
 	Notify() chan struct{}
 }
 
+// BacklogStore 可选的重试存储扩展接口：报告分区内尚未完成的重试数，供 WithBackpressure 自动暂停分区。
+// MemoryRetryStore 实现该接口。
+type BacklogStore interface {
+	Backlog(topic string, partition int32) int
+}
+
+// TopicPauser 运行时暂停 / 恢复 topic 拉取，NewConsumer 返回的实例实现该接口：
+//
+//	if p, ok := consumer.(kafka.TopicPauser); ok {
+//		p.PauseTopics("orders")
+//	}
+type TopicPauser interface {
+	// PauseTopics 暂停 topic 在所有消费者组中的拉取，已拉取的消息继续处理；rebalance 后保持暂停
+	PauseTopics(topics ...string)
+	// ResumeTopics 恢复 topic 的拉取，背压暂停中的分区待积压回落后恢复
+	ResumeTopics(topics ...string)
+	// PausedTopics 返回手动暂停的 topic
+	PausedTopics() []string
+}
+
+// SchemaRegistrant 生产者启动时注册的 schema，schemaregistry 的各 Serde 实现该接口
+type SchemaRegistrant interface {
+	// Register 检查 schema 与 subject 最新版本的兼容性并注册
+	Register(ctx context.Context) error
+}
+
 // RetryItem 待重试消息的完整表示（公开类型）
 type RetryItem struct {
 	Topic         string
@@ -94,6 +135,7 @@ type RetryItem struct {
 	Key           []byte
 	Value         []byte
 	Headers       []HeaderKV
+	Timestamp     time.Time // 原始消息的生产时间
 	Attempt       int
 	NextRetryAt   time.Time
 	ConsumerGroup string
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Broker 进程内消息代理，保存全部内存队列。
+// 同一 Broker 上的生产者与消费者共享队列，不同 Broker 之间相互隔离。
+type Broker struct {
+	mu     sync.Mutex
+	queues map[string]*queue
+	timers map[*time.Timer]struct{}
+}
+
+// NewBroker 创建进程内消息代理
+func NewBroker() *Broker {
+	return &Broker{
+		queues: make(map[string]*queue),
+		timers: make(map[*time.Timer]struct{}),
+	}
+}
+
+// Len 返回队列中待消费的消息数（不含未到期的延迟消息）
+func (b *Broker) Len(name string) int {
+	return b.queue(name).len()
+}
+
+// Close 取消全部未到期的延迟消息。已入队的消息保留，不影响消费者继续消费。
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for t := range b.timers {
+		t.Stop()
+	}
+	clear(b.timers)
+}
+
+// queue 返回指定名称的队列，不存在时创建
+func (b *Broker) queue(name string) *queue {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[name]
+	if !ok {
+		q = &queue{ready: make(chan struct{}, 1)}
+		b.queues[name] = q
+	}
+	return q
+}
+
+// push 将消息追加到队列尾部
+func (b *Broker) push(name string, items ...[]byte) {
+	b.queue(name).push(items...)
+}
+
+// pushAt 在指定时间将消息追加到队列尾部
+func (b *Broker) pushAt(name string, at time.Time, items ...[]byte) {
+	q := b.queue(name)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var t *time.Timer
+	t = time.AfterFunc(time.Until(at), func() {
+		b.mu.Lock()
+		_, pending := b.timers[t]
+		delete(b.timers, t)
+		b.mu.Unlock()
+		if pending {
+			q.push(items...)
+		}
+	})
+	b.timers[t] = struct{}{}
+}
+
+// queue 单个 FIFO 内存队列
+type queue struct {
+	mu    sync.Mutex
+	items [][]byte
+	ready chan struct{} // 容量为 1，有消息可取时通知等待中的消费者
+}
+
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *queue) push(items ...[]byte) {
+	q.mu.Lock()
+	q.items = append(q.items, items...)
+	q.mu.Unlock()
+	q.notify()
+}
+
+// pop 取出队首消息，队列为空时阻塞直到有消息或 ctx 结束
+func (q *queue) pop(ctx context.Context) ([]byte, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			item := q.items[0]
+			q.items[0] = nil
+			q.items = q.items[1:]
+			remaining := len(q.items)
+			q.mu.Unlock()
+			// 仍有剩余消息时继续唤醒其他等待的消费者
+			if remaining > 0 {
+				q.notify()
+			}
+			return item, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+func (q *queue) notify() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker_FIFO(t *testing.T) {
+	b := NewBroker()
+	b.push("q", []byte("a"), []byte("b"))
+	b.push("q", []byte("c"))
+	assert.Equal(t, 3, b.Len("q"))
+	assert.Equal(t, 0, b.Len("other"))
+
+	q := b.queue("q")
+	for _, want := range []string{"a", "b", "c"} {
+		got, ok := q.pop(context.Background())
+		require.True(t, ok)
+		assert.Equal(t, want, string(got))
+	}
+	assert.Equal(t, 0, b.Len("q"))
+}
+
+func TestBroker_PopBlocksUntilPush(t *testing.T) {
+	b := NewBroker()
+	q := b.queue("q")
+
+	got := make(chan string, 1)
+	go func() {
+		data, _ := q.pop(context.Background())
+		got <- string(data)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	b.push("q", []byte("hello"))
+
+	select {
+	case v := <-got:
+		assert.Equal(t, "hello", v)
+	case <-time.After(time.Second):
+		t.Fatal("pop was not woken up by push")
+	}
+}
+
+func TestBroker_PopContextDone(t *testing.T) {
+	b := NewBroker()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, ok := b.queue("q").pop(ctx)
+	assert.False(t, ok)
+}
+
+func TestBroker_PushAt(t *testing.T) {
+	b := NewBroker()
+	b.pushAt("q", time.Now().Add(50*time.Millisecond), []byte("later"))
+	assert.Equal(t, 0, b.Len("q"), "delayed message must not be visible before due")
+
+	assert.Eventually(t, func() bool { return b.Len("q") == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestBroker_CloseCancelsDelayed(t *testing.T) {
+	b := NewBroker()
+	b.pushAt("q", time.Now().Add(30*time.Millisecond), []byte("later"))
+	b.Close()
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, 0, b.Len("q"))
+}
@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// NewConsumer 创建消费者服务实例，消费 broker 中的内存队列
+func NewConsumer(broker *Broker, opts ...ConsumerOption) types.IConsumeServer {
+	cfg := consumerConfig{
+		maxRetry: 3,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return newConsumerEngine(broker, &cfg)
+}
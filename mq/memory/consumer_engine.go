@@ -0,0 +1,243 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/framework/telemetry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/consume"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/logutil"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/middleware"
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+)
+
+// queueConsumer 单个队列消费者
+type queueConsumer struct {
+	queueName string
+	handler   types.IHandler
+	strategy  *retryStrategy
+}
+
+// consumerEngine 消费者生命周期引擎（未导出）
+type consumerEngine struct {
+	engine.Base
+	broker *Broker
+	opt    *consumerConfig
+
+	registrations []queueConsumer
+	regMu         sync.Mutex
+}
+
+// 编译时接口检查
+var _ types.IConsumeServer = (*consumerEngine)(nil)
+
+func newConsumerEngine(broker *Broker, cfg *consumerConfig) *consumerEngine {
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	eng := &consumerEngine{
+		Base: engine.Base{
+			Logger:       logger,
+			Metrics:      metrics.NewConsumerMetrics("memory"),
+			PanicHandler: cfg.panicHandler,
+		},
+		broker: broker,
+		opt:    cfg,
+	}
+
+	// 预注册配置中的消费者
+	for _, reg := range cfg.consumers {
+		eng.createRegistration(reg.Queue, reg.Handler, nil)
+	}
+
+	return eng
+}
+
+func (e *consumerEngine) Register(queue string, handler types.IHandler, opts ...types.RegisterOption) error {
+	e.regMu.Lock()
+	defer e.regMu.Unlock()
+
+	if e.State.Load() != engine.Idle {
+		return xerror.NewXCode(xcode.ErrMQConsume, "cannot register after consumer started")
+	}
+
+	// 解析选项，内存队列不支持 WithGroup
+	cfg := types.ApplyRegisterOptions(opts)
+	if cfg.Group != "" {
+		return xerror.NewXCode(xcode.ErrMQConsume, "memory does not support WithGroup option")
+	}
+
+	e.createRegistration(queue, handler, cfg.Middlewares)
+	return nil
+}
+
+func (e *consumerEngine) Count() uint {
+	e.regMu.Lock()
+	defer e.regMu.Unlock()
+	return uint(len(e.registrations))
+}
+
+func (e *consumerEngine) createRegistration(queueName string, handler types.IHandler, mws []types.Middleware) {
+	if len(queueName) == 0 {
+		e.Logger.Error("queue name must not be empty")
+		return
+	}
+	if handler == nil {
+		e.Logger.Error("handler must not be nil", "queue", queueName)
+		return
+	}
+
+	backoff := e.opt.backoff
+	if backoff == nil {
+		backoff = &retry.ExponentialDelay{Base: time.Second, Max: 5 * time.Minute}
+	}
+	backoffFn := mqretry.BackoffDelayFunc(func(attempt uint) time.Duration {
+		return backoff.Delay(attempt)
+	})
+
+	m := e.Metrics.(*metrics.ConsumerMetrics)
+
+	// 注入默认 failedHandler（若用户未设置）
+	failedHandler := e.opt.failedHandler
+	if failedHandler == nil {
+		failedHandler = DefaultFailedHandlerFunc(logutil.NewSlogLogger(e.Logger))
+	}
+	deadLetter, _ := handler.(types.DeadLetterHandler)
+
+	var inner mqretry.RetryStrategy
+	switch e.opt.retryMode {
+	case types.RetryModeRequeue:
+		inner = mqretry.NewRequeueStrategy(mqretry.RequeueConfig{
+			MaxRetry:      e.opt.maxRetry,
+			Backoff:       backoffFn,
+			Metrics:       m,
+			FailedHandler: failedHandler,
+			DeadLetter:    deadLetter,
+			Timeout:       e.opt.handlerTimeout,
+			RetryIf:       e.opt.retryIf,
+			Logger:        logutil.NewSlogLogger(e.Logger),
+			Requeue: func(_ context.Context, msg types.Message) error {
+				e.broker.push(queueName, types.MarshalWire(msg))
+				return nil
+			},
+		})
+	default: // RetryModeSync
+		inner = mqretry.NewSyncStrategy(mqretry.SyncConfig{
+			MaxRetry:      e.opt.maxRetry,
+			Backoff:       backoffFn,
+			Metrics:       m,
+			FailedHandler: failedHandler,
+			DeadLetter:    deadLetter,
+			Timeout:       e.opt.handlerTimeout,
+			RetryIf:       e.opt.retryIf,
+			Logger:        logutil.NewSlogLogger(e.Logger),
+		})
+	}
+
+	// 重试策略调用组装好中间件链的 handler；死信与批量接口仍在原始 handler 上识别
+	e.registrations = append(e.registrations, queueConsumer{
+		queueName: queueName,
+		handler:   handler,
+		strategy:  &retryStrategy{inner: inner, handler: e.wrapHandler(handler, mws)},
+	})
+}
+
+// wrapHandler 按 内置 → 服务级 → 注册级 的顺序为 handler 组装中间件链
+func (e *consumerEngine) wrapHandler(handler types.IHandler, mws []types.Middleware) types.IHandler {
+	server := middleware.Server(e.opt.noDefaultMiddlewares, e.Logger, e.opt.panicHandler, e.opt.middlewares)
+	return middleware.Wrap(handler, server, mws)
+}
+
+func (e *consumerEngine) Start(ctx context.Context) error {
+	if !e.TryStart() {
+		if e.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(xcode.ErrMQConsume, "consumer already closed")
+	}
+
+	if e.broker == nil {
+		e.State.Store(engine.Idle)
+		return xerror.NewXCode(xcode.ErrMQConsume, "memory broker is required")
+	}
+
+	engineCtx, cancel := context.WithCancel(ctx)
+	e.CancelFunc = cancel
+
+	e.regMu.Lock()
+	regs := make([]queueConsumer, len(e.registrations))
+	copy(regs, e.registrations)
+	e.regMu.Unlock()
+
+	if len(regs) == 0 {
+		cancel()
+		e.State.Store(engine.Idle)
+		return xerror.NewXCode(xcode.ErrMQConsume, "no consumers registered")
+	}
+
+	// 启动消费循环
+	for _, reg := range regs {
+		r := reg
+		e.WG.Add(1)
+		e.SafeGo(fmt.Sprintf("consume-%s", r.queueName), func() {
+			defer e.WG.Done()
+			e.consumeLoop(engineCtx, r)
+		}, e.opt.panicHandler)
+	}
+
+	return nil
+}
+
+func (e *consumerEngine) Shutdown(ctx context.Context) error {
+	if !e.RequestShutdown() {
+		if e.State.Load() == engine.Idle {
+			e.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	if e.CancelFunc != nil {
+		e.CancelFunc()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.WG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	e.State.Store(engine.Closed)
+	return nil
+}
+
+// consumeLoop 单个队列的消费循环
+func (e *consumerEngine) consumeLoop(ctx context.Context, qc queueConsumer) {
+	fetcher := newMemoryFetcher(e.broker.queue(qc.queueName))
+
+	cfg := consume.LoopConfig{
+		MQSystem:  "memory",
+		QueueName: qc.queueName,
+		Tracer:    telemetry.Tracer("mq.memory.consumer"),
+		Logger:    e.Logger,
+		Drain:     e.Draining(),
+		Metrics:   e.Metrics.(*metrics.ConsumerMetrics),
+	}
+
+	consume.ConsumeLoop(ctx, cfg, fetcher, qc.strategy)
+}
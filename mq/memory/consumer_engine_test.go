@@ -0,0 +1,339 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadLetterHandler 始终失败并记录死信的测试 handler
+type deadLetterHandler struct {
+	mu       sync.Mutex
+	attempts []int
+	dead     []types.Message
+	lastErr  error
+}
+
+func (h *deadLetterHandler) Handle(_ context.Context, msg types.Message) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.attempts = append(h.attempts, msg.Attempt)
+	return errors.New("boom")
+}
+
+func (h *deadLetterHandler) OnDeadLetter(_ context.Context, msg types.Message, lastErr error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dead = append(h.dead, msg)
+	h.lastErr = lastErr
+	return nil
+}
+
+func (h *deadLetterHandler) deadCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.dead)
+}
+
+func startPair(t *testing.T, opts ...ConsumerOption) (IConsumeServer, IProducer) {
+	t.Helper()
+	b := NewBroker()
+	t.Cleanup(b.Close)
+
+	c := NewConsumer(b, opts...)
+	p := NewProducer(b)
+	ctx := context.Background()
+	require.NoError(t, c.Start(ctx))
+	require.NoError(t, p.Start(ctx))
+	t.Cleanup(func() {
+		_ = c.Shutdown(context.Background())
+		_ = p.Shutdown(context.Background())
+	})
+	return c, p
+}
+
+func fastBackoff() retry.BackoffStrategy {
+	return &retry.FixedDelay{Wait: time.Millisecond}
+}
+
+func TestConsumer_Register(t *testing.T) {
+	c := NewConsumer(NewBroker(), WithConsumer("q1", FuncHandler(func(context.Context, Message) error { return nil })))
+	assert.Equal(t, uint(1), c.Count())
+
+	require.NoError(t, c.Register("q2", FuncHandler(func(context.Context, Message) error { return nil })))
+	assert.Error(t, c.Register("q3", FuncHandler(func(context.Context, Message) error { return nil }), types.WithGroup("g")))
+	assert.Equal(t, uint(2), c.Count())
+
+	assert.Error(t, NewConsumer(NewBroker()).Start(context.Background()), "no consumers registered")
+}
+
+func TestConsumer_ReceivesEnvelope(t *testing.T) {
+	got := make(chan Message, 1)
+	_, p := startPair(t, WithConsumer("orders", FuncHandler(func(_ context.Context, msg Message) error {
+		got <- msg
+		return nil
+	})))
+
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("hello"),
+		types.WithMessageID("id-1"), types.WithHeaders(map[string]string{"k": "v"})))
+
+	select {
+	case msg := <-got:
+		assert.True(t, msg.IsMemory())
+		assert.Equal(t, "orders", msg.Queue)
+		assert.Equal(t, "hello", string(msg.Data))
+		assert.Equal(t, "id-1", msg.ID)
+		assert.Equal(t, "v", msg.Header("k"))
+		assert.Equal(t, 1, msg.Attempt)
+	case <-time.After(time.Second):
+		t.Fatal("message not consumed")
+	}
+}
+
+func TestConsumer_SyncRetryThenDeadLetter(t *testing.T) {
+	h := &deadLetterHandler{}
+	_, p := startPair(t,
+		WithConsumer("q", h),
+		WithMaxRetry(2),
+		WithBackoff(fastBackoff()),
+	)
+
+	require.NoError(t, p.Produce(context.Background(), "q", []byte("x")))
+	require.Eventually(t, func() bool { return h.deadCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	assert.Equal(t, []int{1, 2, 3}, h.attempts, "first try + MaxRetry retries")
+	assert.Equal(t, 3, h.dead[0].Attempt)
+	assert.EqualError(t, h.lastErr, "boom")
+}
+
+func TestConsumer_RequeueRetryThenFailedHandler(t *testing.T) {
+	var calls atomic.Int32
+	failed := make(chan Message, 1)
+	_, p := startPair(t,
+		WithConsumer("q", FuncHandler(func(context.Context, Message) error {
+			calls.Add(1)
+			return errors.New("boom")
+		})),
+		WithRetryMode(RetryModeRequeue),
+		WithMaxRetry(3),
+		WithBackoff(fastBackoff()),
+		WithFailedHandler(func(_ context.Context, msg Message, err error) {
+			failed <- msg
+		}),
+	)
+
+	require.NoError(t, p.Produce(context.Background(), "q", []byte("x")))
+
+	select {
+	case msg := <-failed:
+		assert.Equal(t, int32(3), calls.Load(), "requeue mode stops after MaxRetry attempts")
+		assert.Equal(t, 3, msg.Attempt)
+	case <-time.After(time.Second):
+		t.Fatal("failed handler not called")
+	}
+}
+
+func TestConsumer_RetrySucceeds(t *testing.T) {
+	var calls atomic.Int32
+	failed := make(chan struct{}, 1)
+	_, p := startPair(t,
+		WithConsumer("q", FuncHandler(func(context.Context, Message) error {
+			if calls.Add(1) < 2 {
+				return errors.New("transient")
+			}
+			return nil
+		})),
+		WithBackoff(fastBackoff()),
+		WithFailedHandler(func(context.Context, Message, error) { failed <- struct{}{} }),
+	)
+
+	require.NoError(t, p.Produce(context.Background(), "q", []byte("x")))
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, 5*time.Millisecond)
+
+	select {
+	case <-failed:
+		t.Fatal("failed handler must not be called when a retry succeeds")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestConsumer_HandlerTimeout(t *testing.T) {
+	errs := make(chan error, 1)
+	_, p := startPair(t,
+		WithConsumer("q", FuncHandler(func(ctx context.Context, _ Message) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})),
+		WithMaxRetry(0),
+		WithHandlerTimeout(20*time.Millisecond),
+		WithFailedHandler(func(_ context.Context, _ Message, err error) { errs <- err }),
+	)
+
+	require.NoError(t, p.Produce(context.Background(), "q", []byte("x")))
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("handler timeout not applied")
+	}
+}
+
+func TestConsumer_DelayedDelivery(t *testing.T) {
+	got := make(chan time.Time, 1)
+	_, p := startPair(t, WithConsumer("q", FuncHandler(func(context.Context, Message) error {
+		got <- time.Now()
+		return nil
+	})))
+
+	start := time.Now()
+	require.NoError(t, p.Produce(context.Background(), "q", []byte("x"), types.WithDelay(50*time.Millisecond)))
+
+	select {
+	case at := <-got:
+		assert.GreaterOrEqual(t, at.Sub(start), 50*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("delayed message not delivered")
+	}
+}
+
+func TestConsumer_PreStop(t *testing.T) {
+	c, _ := startPair(t, WithConsumer("q", FuncHandler(func(context.Context, Message) error { return nil })))
+
+	preStopper, ok := c.(interface{ PreStop(context.Context) error })
+	require.True(t, ok, "consumer should implement app.PreStopper")
+
+	// 排空会中断阻塞中的拉取，消费循环应立即退出
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, preStopper.PreStop(ctx))
+}
+
+func TestConsumer_Lifecycle(t *testing.T) {
+	c := NewConsumer(NewBroker(), WithConsumer("q", FuncHandler(func(context.Context, Message) error { return nil })))
+	ctx := context.Background()
+
+	assert.Error(t, c.HealthCheck(ctx))
+	require.NoError(t, c.Start(ctx))
+	assert.NoError(t, c.HealthCheck(ctx))
+	require.NoError(t, c.Shutdown(ctx))
+	assert.Error(t, c.Start(ctx), "closed consumer cannot restart")
+	assert.Error(t, c.Register("q2", FuncHandler(func(context.Context, Message) error { return nil })))
+}
+
+func TestConsumer_MiddlewareOrder(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	mw := func(name string) types.Middleware {
+		return func(next types.IHandler) types.IHandler {
+			return FuncHandler(func(ctx context.Context, msg Message) error {
+				mu.Lock()
+				calls = append(calls, name)
+				mu.Unlock()
+				return next.Handle(ctx, msg)
+			})
+		}
+	}
+
+	b := NewBroker()
+	t.Cleanup(b.Close)
+	c := NewConsumer(b, WithMiddleware(mw("server-1"), mw("server-2")))
+	p := NewProducer(b)
+
+	done := make(chan struct{})
+	require.NoError(t, c.Register("q", FuncHandler(func(context.Context, Message) error {
+		close(done)
+		return nil
+	}), types.WithMiddleware(mw("register"))))
+	require.NoError(t, c.Start(context.Background()))
+	require.NoError(t, p.Start(context.Background()))
+	t.Cleanup(func() {
+		_ = c.Shutdown(context.Background())
+		_ = p.Shutdown(context.Background())
+	})
+
+	require.NoError(t, p.Produce(context.Background(), "q", []byte("x")))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("message not consumed")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"server-1", "server-2", "register"}, calls)
+}
+
+func TestConsumer_PanicRecoveredAsFailure(t *testing.T) {
+	var panics atomic.Int32
+	h := &panicThenDeadLetter{}
+	_, p := startPair(t,
+		WithConsumer("q", h),
+		WithMaxRetry(1),
+		WithBackoff(fastBackoff()),
+		WithPanicHandler(func(any) { panics.Add(1) }),
+	)
+
+	require.NoError(t, p.Produce(context.Background(), "q", []byte("x")))
+	require.Eventually(t, func() bool { return h.dead.Load() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), panics.Load(), "each attempt recovered by the Recover middleware")
+}
+
+// panicThenDeadLetter 每次处理均 panic 的测试 handler
+type panicThenDeadLetter struct {
+	dead atomic.Int32
+}
+
+func (h *panicThenDeadLetter) Handle(context.Context, Message) error {
+	panic("boom")
+}
+
+func (h *panicThenDeadLetter) OnDeadLetter(context.Context, Message, error) error {
+	h.dead.Add(1)
+	return nil
+}
+
+func TestConsumer_TypedHandlerDecodeFailureSkipsRetry(t *testing.T) {
+	type order struct {
+		ID int `json:"id"`
+	}
+	got := make(chan order, 1)
+	var failed atomic.Int32
+	var failedErr atomic.Value
+	_, p := startPair(t,
+		WithConsumer("orders", types.NewTypedHandler(func(_ context.Context, _ Message, v order) error {
+			got <- v
+			return nil
+		})),
+		WithMaxRetry(3),
+		WithBackoff(fastBackoff()),
+		WithFailedHandler(func(_ context.Context, _ Message, err error) {
+			failedErr.Store(err)
+			failed.Add(1)
+		}),
+	)
+
+	tp := types.NewTypedProducer[order](p)
+	require.NoError(t, tp.Produce(context.Background(), "orders", order{ID: 1}))
+	select {
+	case v := <-got:
+		assert.Equal(t, 1, v.ID)
+	case <-time.After(time.Second):
+		t.Fatal("message not consumed")
+	}
+
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("not json")))
+	require.Eventually(t, func() bool { return failed.Load() == 1 }, time.Second, 5*time.Millisecond)
+	var de *types.DecodeError
+	assert.ErrorAs(t, failedErr.Load().(error), &de)
+}
@@ -0,0 +1,12 @@
+// Package memory 提供基于进程内内存队列的消费者和生产者实现，适用于单元测试和单进程应用。
+//
+// 消费者与 redis/httpsqs 共用消费循环和重试策略，最大重试、退避、死信处理器与
+// handler 超时的行为与真实 MQ 一致，可在不依赖外部服务的情况下验证 handler 逻辑。
+// 消息以与 redis/httpsqs 相同的线上格式存储，消息信封与 trace context 完整传递。
+//
+// 生产者与消费者通过同一个 Broker 共享队列；消息不持久化，进程退出即丢失。
+// 延迟消息（WithDelay / WithDeliverAt）由定时器到期后追加到队列尾部。
+//
+// Consumer 和 Producer 均实现 app.IApp 接口，可通过 app.Manager 统一管理生命周期。
+// Consumer 同时实现 app.PreStopper 接口，排空阶段停止拉取新消息并等待处理中的消息完成。
+package memory
@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/gomooth/pkg/mq/internal/consume"
+)
+
+// memoryFetcher 实现 consume.Fetcher 接口，从内存队列拉取消息
+type memoryFetcher struct {
+	queue *queue
+}
+
+func newMemoryFetcher(q *queue) *memoryFetcher {
+	return &memoryFetcher{queue: q}
+}
+
+// Fetch 从内存队列拉取一条消息，队列为空时阻塞等待，ctx 结束时返回空结果
+func (f *memoryFetcher) Fetch(ctx context.Context) consume.FetchResult {
+	data, ok := f.queue.pop(ctx)
+	if !ok {
+		return consume.FetchResult{Empty: true}
+	}
+	return consume.FetchResult{Data: string(data)}
+}
@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// ==================== Consumer 选项 ====================
+
+// ConsumerOption 消费者配置选项
+type ConsumerOption func(*consumerConfig)
+
+// consumerConfig 消费者引擎配置（未导出）
+type consumerConfig struct {
+	logger               *slog.Logger
+	maxRetry             int
+	backoff              retry.BackoffStrategy
+	retryMode            types.RetryMode
+	retryIf              func(error) bool
+	handlerTimeout       time.Duration
+	failedHandler        types.FailedHandlerFunc
+	panicHandler         func(any)
+	middlewares          []types.Middleware
+	noDefaultMiddlewares bool
+	consumers            []ConsumerRegistration
+}
+
+// WithMaxRetry 设置最大重试次数（默认 3，0=不重试）
+func WithMaxRetry(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.maxRetry = n
+	}
+}
+
+// WithBackoff 设置退避策略（默认 ExponentialDelay{Base:1s, Max:5min}）
+func WithBackoff(b retry.BackoffStrategy) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.backoff = b
+	}
+}
+
+// WithRetryMode 设置重试模式（默认 RetryModeSync）
+func WithRetryMode(mode types.RetryMode) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryMode = mode
+	}
+}
+
+// WithRetryIf 设置错误重试谓词：返回 false 的错误不再重试，直接进入死信处理器或失败回调（默认所有错误都重试）。
+// mq.Permanent 包装的错误与解码失败始终不重试。
+func WithRetryIf(fn func(error) bool) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryIf = fn
+	}
+}
+
+// WithHandlerTimeout 设置单次 handler 调用的超时时间（默认 0，不限）
+func WithHandlerTimeout(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.handlerTimeout = d
+	}
+}
+
+// WithPanicHandler 设置 panic 恢复后的回调函数（handler panic 由内置 Recover 中间件恢复后回调）
+func WithPanicHandler(fn func(any)) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.panicHandler = fn
+	}
+}
+
+// WithMiddleware 追加服务级 handler 中间件，作用于该消费者注册的所有 handler。
+// 服务级中间件位于内置中间件（Trace → Recover）之内、注册级中间件（mq.WithMiddleware）之外；
+// 先传入的中间件位于外层。
+func WithMiddleware(mws ...types.Middleware) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithoutDefaultMiddlewares 禁用内置的 Trace 与 Recover 中间件，
+// 可通过 WithMiddleware 以 mq.Trace / mq.Recover 自定义顺序或替换实现
+func WithoutDefaultMiddlewares() ConsumerOption {
+	return func(c *consumerConfig) {
+		c.noDefaultMiddlewares = true
+	}
+}
+
+// WithFailedHandler 设置重试耗尽后的失败处理回调
+func WithFailedHandler(fn types.FailedHandlerFunc) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.failedHandler = fn
+	}
+}
+
+// WithConsumers 批量预注册消费者
+func WithConsumers(regs ...ConsumerRegistration) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.consumers = append(c.consumers, regs...)
+	}
+}
+
+// WithConsumer 预注册单个消费者
+func WithConsumer(queue string, handler types.IHandler) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.consumers = append(c.consumers, ConsumerRegistration{
+			Queue:   queue,
+			Handler: handler,
+		})
+	}
+}
+
+// WithConsumerLogger 设置消费者日志器
+func WithConsumerLogger(l *slog.Logger) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.logger = l
+	}
+}
+
+// ==================== Producer 选项 ====================
+
+// ProducerOption 生产者配置选项
+type ProducerOption func(*producerConfig)
+
+// producerConfig 生产者引擎配置（未导出）
+type producerConfig struct {
+	logger *slog.Logger
+}
+
+// WithProducerLogger 设置生产者日志器
+func WithProducerLogger(l *slog.Logger) ProducerOption {
+	return func(c *producerConfig) {
+		c.logger = l
+	}
+}
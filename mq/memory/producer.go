@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// NewProducer 创建生产者实例，消息写入 broker 中的内存队列
+func NewProducer(broker *Broker, opts ...ProducerOption) types.IProducer {
+	cfg := producerConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return newProducerEngine(broker, &cfg)
+}
@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/gomooth/pkg/framework/telemetry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	mqtraceutil "github.com/gomooth/pkg/mq/internal/traceutil"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// producerEngine 生产者生命周期引擎（未导出）
+type producerEngine struct {
+	engine.Base
+	broker *Broker
+	opt    *producerConfig
+}
+
+// 编译时接口检查
+var _ types.IProducer = (*producerEngine)(nil)
+
+func newProducerEngine(broker *Broker, cfg *producerConfig) *producerEngine {
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &producerEngine{
+		Base: engine.Base{
+			Logger:  logger,
+			Metrics: metrics.NewProducerMetrics("memory"),
+		},
+		broker: broker,
+		opt:    cfg,
+	}
+}
+
+func (e *producerEngine) Start(_ context.Context) error {
+	if !e.TryStart() {
+		if e.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer already closed")
+	}
+
+	if e.broker == nil {
+		e.State.Store(engine.Idle)
+		return xerror.NewXCode(xcode.ErrMQPublish, "memory broker is required")
+	}
+	return nil
+}
+
+func (e *producerEngine) Shutdown(_ context.Context) error {
+	if !e.RequestShutdown() {
+		if e.State.Load() == engine.Idle {
+			e.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	e.State.Store(engine.Closed)
+	return nil
+}
+
+func (e *producerEngine) Produce(ctx context.Context, queue string, message []byte, opts ...types.ProduceOption) error {
+	return e.produce(ctx, queue, [][]byte{message}, opts, false)
+}
+
+func (e *producerEngine) ProduceBatch(ctx context.Context, queue string, messages [][]byte, opts ...types.ProduceOption) error {
+	if len(messages) == 0 {
+		return xerror.NewXCode(xcode.ErrMQPublish, "no messages")
+	}
+	return e.produce(ctx, queue, messages, opts, true)
+}
+
+// produce 为每条消息生成信封并写入内存队列，批量发送时每条消息独立生成消息 ID
+func (e *producerEngine) produce(ctx context.Context, queue string, messages [][]byte, opts []types.ProduceOption, batch bool) error {
+	if err := ctx.Err(); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	spanName := fmt.Sprintf("%s produce", queue)
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "memory"),
+		attribute.String("messaging.destination", queue),
+	}
+	if batch {
+		spanName = fmt.Sprintf("%s produce batch", queue)
+		attrs = append(attrs, attribute.Int("messaging.batch.size", len(messages)))
+	}
+
+	// Create producer Span
+	tracer := telemetry.Tracer("mq.memory.producer")
+	ctx, span := tracer.Start(ctx, spanName,
+		trace.WithAttributes(attrs...),
+		trace.WithSpanKind(trace.SpanKindProducer),
+	)
+	defer span.End()
+
+	m, _ := e.Metrics.(*metrics.ProducerMetrics)
+	if e.State.Load() != engine.Running {
+		span.RecordError(fmt.Errorf("producer not started"))
+		span.SetStatus(codes.Error, "producer not started")
+		m.OnError()
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer not started")
+	}
+
+	produceCfg := types.ApplyProduceOptions(opts)
+	if batch {
+		produceCfg.MessageID = ""
+	}
+
+	payloads := make([][]byte, 0, len(messages))
+	for _, data := range messages {
+		msg := types.NewMemoryMessage(queue, data)
+		produceCfg.Stamp(&msg)
+		mqtraceutil.InjectHeaders(ctx, msg.Headers)
+		payloads = append(payloads, types.MarshalWire(msg))
+	}
+
+	if at, delayed := produceCfg.DeliveryTime(); delayed {
+		e.broker.pushAt(queue, at, payloads...)
+	} else {
+		e.broker.push(queue, payloads...)
+	}
+
+	m.OnProduce(len(messages))
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProducer_NotStarted(t *testing.T) {
+	p := NewProducer(NewBroker())
+	assert.Error(t, p.Produce(context.Background(), "q", []byte("x")))
+}
+
+func TestProducer_Produce(t *testing.T) {
+	b := NewBroker()
+	p := NewProducer(b)
+	ctx := context.Background()
+	require.NoError(t, p.Start(ctx))
+	defer p.Shutdown(ctx)
+
+	require.NoError(t, p.Produce(ctx, "q", []byte("hello"),
+		types.WithMessageID("id-1"),
+		types.WithOrderKey("user-1"),
+		types.WithHeaders(map[string]string{"tenant": "t1"}),
+	))
+	require.Equal(t, 1, b.Len("q"))
+
+	raw, ok := b.queue("q").pop(ctx)
+	require.True(t, ok)
+	msg := types.NewMemoryMessage("q", nil)
+	types.UnmarshalWire(&msg, raw)
+	assert.Equal(t, "hello", string(msg.Data))
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "user-1", msg.Key)
+	assert.Equal(t, "t1", msg.Header("tenant"))
+	assert.False(t, msg.Timestamp.IsZero())
+}
+
+func TestProducer_ProduceBatch(t *testing.T) {
+	b := NewBroker()
+	p := NewProducer(b)
+	ctx := context.Background()
+	require.NoError(t, p.Start(ctx))
+	defer p.Shutdown(ctx)
+
+	assert.Error(t, p.ProduceBatch(ctx, "q", nil))
+	require.NoError(t, p.ProduceBatch(ctx, "q", [][]byte{[]byte("a"), []byte("b")}, types.WithMessageID("fixed")))
+	require.Equal(t, 2, b.Len("q"))
+
+	ids := map[string]bool{}
+	for range 2 {
+		raw, _ := b.queue("q").pop(ctx)
+		msg := types.NewMemoryMessage("q", nil)
+		types.UnmarshalWire(&msg, raw)
+		ids[msg.ID] = true
+	}
+	assert.Len(t, ids, 2, "batch messages should get distinct ids")
+	assert.False(t, ids["fixed"])
+}
+
+func TestProducer_ProduceDelayed(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+	p := NewProducer(b)
+	ctx := context.Background()
+	require.NoError(t, p.Start(ctx))
+
+	require.NoError(t, p.Produce(ctx, "q", []byte("later"), types.WithDelay(50*time.Millisecond)))
+	assert.Equal(t, 0, b.Len("q"))
+	assert.Eventually(t, func() bool { return b.Len("q") == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestProducer_Lifecycle(t *testing.T) {
+	p := NewProducer(NewBroker())
+	ctx := context.Background()
+	require.NoError(t, p.Start(ctx))
+	require.NoError(t, p.Start(ctx), "repeated Start should be a no-op")
+	require.NoError(t, p.Shutdown(ctx))
+	assert.Error(t, p.Start(ctx), "closed producer cannot restart")
+	assert.Error(t, p.Produce(ctx, "q", []byte("x")))
+
+	assert.Error(t, NewProducer(nil).Start(ctx), "broker is required")
+}
@@ -23,3 +23,13 @@ func NewKafkaMessage(group, topic string, data []byte) Message {
 func NewHttpsqSMessage(queue string, data []byte, pos int64) Message {
 	return types.NewHttpsqSMessage(queue, data, pos)
 }
+
+// NewMemoryMessage 创建进程内内存队列消息
+func NewMemoryMessage(queue string, data []byte) Message {
+	return types.NewMemoryMessage(queue, data)
+}
+
+// NewAMQPMessage 创建 AMQP 队列消息
+func NewAMQPMessage(queue string, data []byte) Message {
+	return types.NewAMQPMessage(queue, data)
+}
@@ -0,0 +1,16 @@
+package mq
+
+import (
+	"github.com/gomooth/pkg/mq/internal/middleware"
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// Middleware re-export
+type Middleware = types.Middleware
+
+var Chain = types.Chain
+var WithMiddleware = types.WithMiddleware
+
+// 内置中间件 re-export
+var Trace = middleware.Trace
+var Recover = middleware.Recover
@@ -0,0 +1,9 @@
+// Package outbox 提供基于 GORM 的事务发件箱（transactional outbox）。
+//
+// Outbox.Add 在业务事务中将消息写入发件箱表，与业务数据同时提交或回滚；
+// Relay 轮询发件箱表，通过任意 mq.IProducer 发布消息，投递后删除或在保留期后清理。
+// 同一 OrderKey 的消息按写入顺序投递，发布失败的 key 按退避策略重试，不影响其他 key。
+//
+// Relay 实现 app.IApp、app.HealthChecker 和 app.PreStopper 接口，可通过 app.Manager 统一管理生命周期，
+// 并上报已发布、发布失败、积压消息数与最早未投递消息滞留时长等指标。
+package outbox
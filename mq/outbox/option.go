@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+)
+
+// ==================== Outbox 选项 ====================
+
+// Option 发件箱配置选项
+type Option func(*config)
+
+// config 发件箱配置（未导出）
+type config struct {
+	table string
+}
+
+// WithTable 设置发件箱表名（默认 DefaultTable）
+func WithTable(table string) Option {
+	return func(c *config) {
+		if table != "" {
+			c.table = table
+		}
+	}
+}
+
+// ==================== Relay 选项 ====================
+
+// RelayOption 中继配置选项
+type RelayOption func(*relayConfig)
+
+// relayConfig 中继配置（未导出）
+type relayConfig struct {
+	logger          *slog.Logger
+	table           string
+	interval        time.Duration
+	batchSize       int
+	backoff         retry.BackoffStrategy
+	lock            bool
+	retention       time.Duration
+	cleanupInterval time.Duration
+	panicHandler    func(any)
+}
+
+// WithRelayLogger 设置中继日志器
+func WithRelayLogger(l *slog.Logger) RelayOption {
+	return func(c *relayConfig) {
+		c.logger = l
+	}
+}
+
+// WithRelayTable 设置发件箱表名（默认 DefaultTable），须与写入端的 WithTable 一致
+func WithRelayTable(table string) RelayOption {
+	return func(c *relayConfig) {
+		if table != "" {
+			c.table = table
+		}
+	}
+}
+
+// WithRelayInterval 设置轮询间隔（默认 1s）；一轮取满 batchSize 条时立即开始下一轮
+func WithRelayInterval(d time.Duration) RelayOption {
+	return func(c *relayConfig) {
+		if d > 0 {
+			c.interval = d
+		}
+	}
+}
+
+// WithRelayBatchSize 设置每轮最多读取的消息数（默认 100）
+func WithRelayBatchSize(n int) RelayOption {
+	return func(c *relayConfig) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithRelayBackoff 设置发布失败后的退避策略（默认指数退避 1s ~ 1m）
+func WithRelayBackoff(b retry.BackoffStrategy) RelayOption {
+	return func(c *relayConfig) {
+		c.backoff = b
+	}
+}
+
+// WithRelayLock 每轮在事务中以 SELECT ... FOR UPDATE 锁定读取的行，
+// 多个中继实例并存时互斥处理，保证同一 key 的投递顺序。
+// 需要数据库支持行锁（MySQL / PostgreSQL），SQLite 不支持。
+func WithRelayLock() RelayOption {
+	return func(c *relayConfig) {
+		c.lock = true
+	}
+}
+
+// WithRelayRetention 设置已投递消息的保留时长（默认 0，投递后立即删除）。
+// 大于 0 时已投递的行标记投递时间，由清理任务在保留期后删除。
+func WithRelayRetention(d time.Duration) RelayOption {
+	return func(c *relayConfig) {
+		if d >= 0 {
+			c.retention = d
+		}
+	}
+}
+
+// WithRelayCleanupInterval 设置清理已投递消息的间隔（默认 1m，仅保留时长大于 0 时生效）
+func WithRelayCleanupInterval(d time.Duration) RelayOption {
+	return func(c *relayConfig) {
+		if d > 0 {
+			c.cleanupInterval = d
+		}
+	}
+}
+
+// WithRelayPanicHandler 设置中继 goroutine panic 恢复后的回调
+func WithRelayPanicHandler(fn func(any)) RelayOption {
+	return func(c *relayConfig) {
+		c.panicHandler = fn
+	}
+}
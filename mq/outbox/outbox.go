@@ -0,0 +1,93 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/traceutil"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	"github.com/gomooth/xerror/xcode"
+	"gorm.io/gorm"
+)
+
+// Outbox 发件箱写入端：在业务事务中写入待投递消息，由 Relay 异步发布到 MQ。
+type Outbox struct {
+	opt *config
+}
+
+// New 创建发件箱写入端
+func New(opts ...Option) *Outbox {
+	cfg := config{table: DefaultTable}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Outbox{opt: &cfg}
+}
+
+// Migrate 创建或更新发件箱表
+func (o *Outbox) Migrate(ctx context.Context, db *gorm.DB) error {
+	if db == nil {
+		return xerror.NewXCode(xcode.DBRequestParamError, "outbox: Migrate called with nil *gorm.DB")
+	}
+	if err := db.WithContext(ctx).Table(o.opt.table).AutoMigrate(&Record{}); err != nil {
+		return xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	return nil
+}
+
+// Add 在 tx 所在事务中写入一条待投递消息，事务提交后由 Relay 发布到 dest。
+//
+// 支持的生产选项：WithOrderKey（同一 key 的消息按写入顺序投递）、WithMessageID、
+// WithHeaders、WithDelay / WithDeliverAt（换算为绝对时间，发布时透传给生产者）。
+// 当前 trace context 写入消息头，消费端与写入事务处于同一条链路。
+func (o *Outbox) Add(ctx context.Context, tx *gorm.DB, dest string, message []byte, opts ...types.ProduceOption) error {
+	return o.AddBatch(ctx, tx, dest, [][]byte{message}, opts...)
+}
+
+// AddBatch 在 tx 所在事务中批量写入待投递消息，每条消息自动生成消息 ID（忽略 WithMessageID）
+func (o *Outbox) AddBatch(ctx context.Context, tx *gorm.DB, dest string, messages [][]byte, opts ...types.ProduceOption) error {
+	if tx == nil {
+		return xerror.NewXCode(xcode.DBRequestParamError, "outbox: Add called with nil *gorm.DB")
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	cfg := types.ApplyProduceOptions(opts)
+	now := time.Now()
+	var deliverAt *time.Time
+	if at, delayed := cfg.DeliveryTime(); delayed {
+		deliverAt = &at
+	}
+
+	records := make([]Record, len(messages))
+	for i, message := range messages {
+		var env types.Message
+		cfg.Stamp(&env)
+		if len(messages) > 1 {
+			env.ID = types.NewMessageID()
+		}
+		traceutil.InjectHeaders(ctx, env.Headers)
+
+		headers, err := json.Marshal(env.Headers)
+		if err != nil {
+			return xerror.Wrap(err, "outbox: marshal headers")
+		}
+		records[i] = Record{
+			MessageID: env.ID,
+			Dest:      dest,
+			OrderKey:  env.Key,
+			Payload:   message,
+			Headers:   string(headers),
+			DeliverAt: deliverAt,
+			CreatedAt: now,
+		}
+	}
+
+	if err := tx.WithContext(ctx).Table(o.opt.table).Create(&records).Error; err != nil {
+		return xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/framework/dbrepo"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s_%d?mode=memory&cache=shared",
+		strings.ReplaceAll(t.Name(), "/", "_"), time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, New().Migrate(context.Background(), db))
+	return db
+}
+
+func addInTx(t *testing.T, db *gorm.DB, dest, data string, opts ...types.ProduceOption) {
+	t.Helper()
+	err := dbrepo.RunInTx(context.Background(), db, func(tx *gorm.DB) error {
+		return New().Add(context.Background(), tx, dest, []byte(data), opts...)
+	})
+	require.NoError(t, err)
+}
+
+func countRows(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var n int64
+	require.NoError(t, db.Table(DefaultTable).Count(&n).Error)
+	return n
+}
+
+func TestOutbox_AddInTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	ob := New()
+
+	addInTx(t, db, "orders", "a", types.WithOrderKey("o-1"), types.WithMessageID("id-1"),
+		types.WithHeaders(map[string]string{"tenant": "t1"}))
+
+	var rec Record
+	require.NoError(t, db.Table(DefaultTable).First(&rec).Error)
+	assert.Equal(t, "orders", rec.Dest)
+	assert.Equal(t, "o-1", rec.OrderKey)
+	assert.Equal(t, "id-1", rec.MessageID)
+	assert.Equal(t, []byte("a"), rec.Payload)
+	assert.Contains(t, rec.Headers, `"tenant":"t1"`)
+	assert.Nil(t, rec.DeliveredAt)
+
+	// 事务回滚时消息一并回滚
+	err := dbrepo.RunInTx(ctx, db, func(tx *gorm.DB) error {
+		if err := ob.Add(ctx, tx, "orders", []byte("b")); err != nil {
+			return err
+		}
+		return errors.New("business failed")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), countRows(t, db))
+
+	assert.Error(t, ob.Add(ctx, nil, "orders", []byte("c")))
+}
+
+func TestOutbox_AddBatch(t *testing.T) {
+	db := setupTestDB(t)
+	ob := New()
+
+	at := time.Now().Add(time.Hour)
+	require.NoError(t, ob.AddBatch(context.Background(), db, "orders",
+		[][]byte{[]byte("a"), []byte("b")}, types.WithMessageID("ignored"), types.WithDeliverAt(at)))
+
+	var recs []Record
+	require.NoError(t, db.Table(DefaultTable).Order("id").Find(&recs).Error)
+	require.Len(t, recs, 2)
+	assert.NotEqual(t, recs[0].MessageID, recs[1].MessageID)
+	assert.NotEqual(t, "ignored", recs[0].MessageID)
+	require.NotNil(t, recs[0].DeliverAt)
+	assert.WithinDuration(t, at, *recs[0].DeliverAt, time.Millisecond)
+}
@@ -0,0 +1,23 @@
+package outbox
+
+import "time"
+
+// DefaultTable 发件箱默认表名
+const DefaultTable = "mq_outbox"
+
+// Record 发件箱表的一行，对应一条待投递消息。
+// 表名默认 DefaultTable，可通过 WithTable / WithRelayTable 修改。
+type Record struct {
+	ID            uint64     `gorm:"primaryKey;autoIncrement"`
+	MessageID     string     `gorm:"size:64;not null"`
+	Dest          string     `gorm:"size:255;not null"`
+	OrderKey      string     `gorm:"size:255;not null;default:'';index"` // 有序投递键，同一 key 按写入顺序投递
+	Payload       []byte     `gorm:"not null"`
+	Headers       string     `gorm:"type:text"` // JSON 编码的消息头
+	DeliverAt     *time.Time // 定时投递时间，透传给生产者
+	Attempts      int        `gorm:"not null;default:0"` // 已失败的发布次数
+	LastError     string     `gorm:"type:text"`          // 最后一次发布错误
+	NextAttemptAt *time.Time // 发布失败后的下次尝试时间
+	CreatedAt     time.Time  `gorm:"not null"` // 写入时间，用于计算中继滞后
+	DeliveredAt   *time.Time `gorm:"index"`    // 投递时间，为空表示未投递
+}
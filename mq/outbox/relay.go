@@ -0,0 +1,337 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/gomooth/pkg/framework/dbrepo"
+	"github.com/gomooth/pkg/framework/retry"
+	pkgxcode "github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/traceutil"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	"github.com/gomooth/xerror/xcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxErrorLength 写入 last_error 的错误信息最大长度
+const maxErrorLength = 1024
+
+// Relay 发件箱中继：轮询发件箱表，按写入顺序将消息发布到 IProducer，投递后删除或标记已投递。
+//
+// 同一 OrderKey 的消息严格按写入顺序发布：某条消息发布失败时，同一 key 的后续消息等待其退避到期并发布成功后再投递；
+// 未设置 key 的消息互不影响。投递语义为至少一次，消费端可按消息 ID 去重。
+//
+// 中继不管理 producer 的生命周期，producer 需另行启动（如同样注册到 app.Manager）。
+// 实现 app.IApp、app.HealthChecker 和 app.PreStopper 接口。
+type Relay struct {
+	engine.Base
+	db       *gorm.DB
+	producer types.IProducer
+	opt      *relayConfig
+	metrics  *metrics.OutboxMetrics
+}
+
+// NewRelay 创建发件箱中继
+func NewRelay(db *gorm.DB, producer types.IProducer, opts ...RelayOption) *Relay {
+	cfg := relayConfig{
+		table:           DefaultTable,
+		interval:        time.Second,
+		batchSize:       100,
+		cleanupInterval: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.backoff == nil {
+		cfg.backoff = &retry.ExponentialDelay{Base: time.Second, Max: time.Minute, Jitter: true}
+	}
+
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Relay{
+		Base:     engine.Base{Logger: logger, PanicHandler: cfg.panicHandler},
+		db:       db,
+		producer: producer,
+		opt:      &cfg,
+		metrics:  metrics.NewOutboxMetrics(),
+	}
+}
+
+// Start 启动轮询；保留时长大于 0 时同时启动已投递消息的清理任务
+func (r *Relay) Start(ctx context.Context) error {
+	if r.db == nil || r.producer == nil {
+		return xerror.NewXCode(xcode.DBRequestParamError, "outbox: relay requires *gorm.DB and producer")
+	}
+	if !r.TryStart() {
+		if r.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(pkgxcode.ErrMQPublish, "outbox relay already closed")
+	}
+
+	engineCtx, cancel := context.WithCancel(ctx)
+	r.CancelFunc = cancel
+
+	r.WG.Add(1)
+	r.SafeGo("outbox-relay", func() {
+		defer r.WG.Done()
+		r.run(engineCtx)
+	}, nil)
+
+	if r.opt.retention > 0 {
+		r.WG.Add(1)
+		r.SafeGo("outbox-cleanup", func() {
+			defer r.WG.Done()
+			r.cleanupLoop(engineCtx)
+		}, nil)
+	}
+	return nil
+}
+
+// Shutdown 停止轮询，等待当前一轮发布结束或 ctx 超时；未投递的消息保留在表中，重启后继续投递
+func (r *Relay) Shutdown(ctx context.Context) error {
+	if !r.RequestShutdown() {
+		if r.State.Load() == engine.Idle {
+			r.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	if r.CancelFunc != nil {
+		r.CancelFunc()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.WG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	r.State.Store(engine.Closed)
+	return nil
+}
+
+// run 轮询循环：一轮发布了满批消息时立即开始下一轮，否则等待轮询间隔。
+// 排空阶段完成当前一轮后退出。
+func (r *Relay) run(ctx context.Context) {
+	for {
+		read, published, err := r.relayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.Logger.Error("outbox relay round failed", "table", r.opt.table, "error", err)
+		}
+		r.recordLag(ctx)
+
+		wait := r.opt.interval
+		if err == nil && read >= r.opt.batchSize && published > 0 {
+			wait = 0
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-r.Draining():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// relayOnce 执行一轮投递，返回读取与成功发布的消息数
+func (r *Relay) relayOnce(ctx context.Context) (read, published int, err error) {
+	if !r.opt.lock {
+		return r.process(ctx, r.db)
+	}
+	err = dbrepo.RunInTx(ctx, r.db, func(tx *gorm.DB) error {
+		var txErr error
+		read, published, txErr = r.process(ctx, tx)
+		return txErr
+	})
+	return read, published, err
+}
+
+// process 读取一批可投递的消息并按 ID 顺序发布。
+// 处于退避中的 key 整体跳过；本轮发布失败的 key，其后续消息留到下一轮。
+func (r *Relay) process(ctx context.Context, db *gorm.DB) (read, published int, err error) {
+	now := time.Now()
+	table := r.opt.table
+
+	waiting := db.Table(table).Select("order_key").
+		Where("delivered_at IS NULL AND order_key <> '' AND next_attempt_at > ?", now)
+	q := db.WithContext(ctx).Table(table).
+		Where("delivered_at IS NULL").
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+		Where("order_key = '' OR order_key NOT IN (?)", waiting).
+		Order("id").
+		Limit(r.opt.batchSize)
+	if r.opt.lock {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var rows []Record
+	if err := q.Find(&rows).Error; err != nil {
+		return 0, 0, xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+
+	failedKeys := make(map[string]bool)
+	for i := range rows {
+		row := &rows[i]
+		if row.OrderKey != "" && failedKeys[row.OrderKey] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return len(rows), published, ctx.Err()
+		}
+
+		if pubErr := r.publish(ctx, row); pubErr != nil {
+			r.metrics.OnError()
+			r.Logger.Warn("outbox publish failed",
+				"id", row.ID, "dest", row.Dest, "attempts", row.Attempts+1, "error", pubErr)
+			if row.OrderKey != "" {
+				failedKeys[row.OrderKey] = true
+			}
+			if err := r.markFailed(ctx, db, row, pubErr); err != nil {
+				return len(rows), published, err
+			}
+			continue
+		}
+
+		published++
+		r.metrics.OnRelay(1)
+		// 标记失败时消息会在下一轮重复发布（至少一次）
+		if err := r.markDelivered(ctx, db, row); err != nil {
+			return len(rows), published, err
+		}
+	}
+	return len(rows), published, nil
+}
+
+// publish 以写入时的消息 ID、消息头与 trace context 发布一条消息
+func (r *Relay) publish(ctx context.Context, row *Record) error {
+	headers := make(map[string]string)
+	if row.Headers != "" {
+		if err := json.Unmarshal([]byte(row.Headers), &headers); err != nil {
+			return xerror.Wrap(err, "outbox: unmarshal headers")
+		}
+	}
+	ctx = traceutil.ExtractMessage(ctx, headers, row.Payload)
+
+	opts := []types.ProduceOption{types.WithMessageID(row.MessageID), types.WithHeaders(headers)}
+	if row.OrderKey != "" {
+		opts = append(opts, types.WithOrderKey(row.OrderKey))
+	}
+	if row.DeliverAt != nil {
+		opts = append(opts, types.WithDeliverAt(*row.DeliverAt))
+	}
+	return r.producer.Produce(ctx, row.Dest, row.Payload, opts...)
+}
+
+// markDelivered 投递成功：保留时长为 0 时删除，否则记录投递时间
+func (r *Relay) markDelivered(ctx context.Context, db *gorm.DB, row *Record) error {
+	q := db.WithContext(ctx).Table(r.opt.table).Where("id = ?", row.ID)
+	var err error
+	if r.opt.retention > 0 {
+		err = q.Update("delivered_at", time.Now()).Error
+	} else {
+		err = q.Delete(&Record{}).Error
+	}
+	if err != nil {
+		return xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	return nil
+}
+
+// markFailed 投递失败：累加失败次数并按退避策略设置下次尝试时间
+func (r *Relay) markFailed(ctx context.Context, db *gorm.DB, row *Record, pubErr error) error {
+	msg := pubErr.Error()
+	if len(msg) > maxErrorLength {
+		msg = msg[:maxErrorLength]
+	}
+	next := time.Now().Add(r.opt.backoff.Delay(uint(row.Attempts)))
+	err := db.WithContext(ctx).Table(r.opt.table).Where("id = ?", row.ID).Updates(map[string]any{
+		"attempts":        row.Attempts + 1,
+		"last_error":      msg,
+		"next_attempt_at": next,
+	}).Error
+	if err != nil {
+		return xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	return nil
+}
+
+// recordLag 记录未投递消息数与最早未投递消息的滞留时长
+func (r *Relay) recordLag(ctx context.Context) {
+	pending, lag, err := r.Lag(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.Logger.Warn("outbox lag query failed", "table", r.opt.table, "error", err)
+		}
+		return
+	}
+	r.metrics.RecordLag(pending, lag)
+}
+
+// Lag 返回未投递消息数与最早未投递消息的滞留时长，无积压时滞留时长为 0
+func (r *Relay) Lag(ctx context.Context) (pending int64, lag time.Duration, err error) {
+	q := r.db.WithContext(ctx).Table(r.opt.table).Where("delivered_at IS NULL")
+	if err := q.Count(&pending).Error; err != nil {
+		return 0, 0, xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	if pending == 0 {
+		return 0, 0, nil
+	}
+
+	var oldest Record
+	err = r.db.WithContext(ctx).Table(r.opt.table).
+		Where("delivered_at IS NULL").Order("id").Limit(1).Find(&oldest).Error
+	if err != nil {
+		return 0, 0, xerror.WrapWithXCode(err, xcode.DBFailed)
+	}
+	if !oldest.CreatedAt.IsZero() {
+		lag = max(time.Since(oldest.CreatedAt), 0)
+	}
+	return pending, lag, nil
+}
+
+// cleanupLoop 定期删除超过保留时长的已投递消息
+func (r *Relay) cleanupLoop(ctx context.Context) {
+	ticker := time.NewTicker(r.opt.cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.Draining():
+			return
+		case <-ticker.C:
+			if _, err := r.Cleanup(ctx); err != nil && ctx.Err() == nil {
+				r.Logger.Error("outbox cleanup failed", "table", r.opt.table, "error", err)
+			}
+		}
+	}
+}
+
+// Cleanup 删除投递时间早于保留时长的消息，返回删除条数
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	res := r.db.WithContext(ctx).Table(r.opt.table).
+		Where("delivered_at IS NOT NULL AND delivered_at < ?", time.Now().Add(-r.opt.retention)).
+		Delete(&Record{})
+	if res.Error != nil {
+		return 0, xerror.WrapWithXCode(res.Error, xcode.DBFailed)
+	}
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,189 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sentMessage 记录一次 Produce 调用
+type sentMessage struct {
+	dest string
+	data string
+	cfg  *types.ProduceConfig
+}
+
+// fakeProducer 记录发布的消息，fail 返回 true 时发布失败
+type fakeProducer struct {
+	mu   sync.Mutex
+	sent []sentMessage
+	fail func(data string) bool
+}
+
+func (p *fakeProducer) Start(context.Context) error    { return nil }
+func (p *fakeProducer) Shutdown(context.Context) error { return nil }
+
+func (p *fakeProducer) Produce(_ context.Context, dest string, message []byte, opts ...types.ProduceOption) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail != nil && p.fail(string(message)) {
+		return errors.New("broker down")
+	}
+	p.sent = append(p.sent, sentMessage{dest: dest, data: string(message), cfg: types.ApplyProduceOptions(opts)})
+	return nil
+}
+
+func (p *fakeProducer) ProduceBatch(ctx context.Context, dest string, messages [][]byte, opts ...types.ProduceOption) error {
+	for _, m := range messages {
+		if err := p.Produce(ctx, dest, m, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *fakeProducer) data() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]string, len(p.sent))
+	for i, m := range p.sent {
+		out[i] = m.data
+	}
+	return out
+}
+
+func TestRelay_PublishesInOrderAndDeletes(t *testing.T) {
+	db := setupTestDB(t)
+	addInTx(t, db, "orders", "a", types.WithOrderKey("k1"), types.WithMessageID("id-a"))
+	addInTx(t, db, "orders", "b", types.WithOrderKey("k2"))
+	addInTx(t, db, "payments", "c", types.WithOrderKey("k1"))
+
+	producer := &fakeProducer{}
+	relay := NewRelay(db, producer)
+	read, published, err := relay.relayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, read)
+	assert.Equal(t, 3, published)
+
+	assert.Equal(t, []string{"a", "b", "c"}, producer.data())
+	first := producer.sent[0]
+	assert.Equal(t, "orders", first.dest)
+	assert.Equal(t, "id-a", first.cfg.MessageID)
+	assert.Equal(t, "k1", first.cfg.OrderKey)
+	assert.Equal(t, "payments", producer.sent[2].dest)
+	assert.Zero(t, countRows(t, db), "delivered rows should be deleted")
+}
+
+func TestRelay_FailureBlocksSameKey(t *testing.T) {
+	db := setupTestDB(t)
+	addInTx(t, db, "orders", "k1-1", types.WithOrderKey("k1"))
+	addInTx(t, db, "orders", "k1-2", types.WithOrderKey("k1"))
+	addInTx(t, db, "orders", "k2-1", types.WithOrderKey("k2"))
+	addInTx(t, db, "orders", "nokey")
+
+	failing := true
+	producer := &fakeProducer{fail: func(data string) bool { return failing && data == "k1-1" }}
+	relay := NewRelay(db, producer, WithRelayBackoff(&retry.FixedDelay{Wait: time.Hour}))
+
+	_, _, err := relay.relayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k2-1", "nokey"}, producer.data(), "k1-2 must wait for k1-1")
+
+	var rec Record
+	require.NoError(t, db.Table(DefaultTable).Where("payload = ?", []byte("k1-1")).First(&rec).Error)
+	assert.Equal(t, 1, rec.Attempts)
+	assert.Equal(t, "broker down", rec.LastError)
+	require.NotNil(t, rec.NextAttemptAt)
+
+	// 退避期间同一 key 的消息均不投递
+	failing = false
+	_, published, err := relay.relayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, published)
+
+	// 退避到期后按写入顺序投递
+	require.NoError(t, db.Table(DefaultTable).Where("id = ?", rec.ID).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	_, _, err = relay.relayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k2-1", "nokey", "k1-1", "k1-2"}, producer.data())
+}
+
+func TestRelay_RetentionAndCleanup(t *testing.T) {
+	db := setupTestDB(t)
+	addInTx(t, db, "orders", "a")
+
+	producer := &fakeProducer{}
+	relay := NewRelay(db, producer, WithRelayRetention(time.Hour))
+	_, _, err := relay.relayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), countRows(t, db), "delivered row kept during retention")
+
+	pending, _, err := relay.Lag(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+
+	// 已投递的行不再发布
+	_, _, err = relay.relayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, producer.data(), 1)
+
+	n, err := relay.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, db.Table(DefaultTable).Where("1 = 1").
+		Update("delivered_at", time.Now().Add(-2*time.Hour)).Error)
+	n, err = relay.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestRelay_Lag(t *testing.T) {
+	db := setupTestDB(t)
+	relay := NewRelay(db, &fakeProducer{})
+
+	pending, lag, err := relay.Lag(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+	assert.Zero(t, lag)
+
+	addInTx(t, db, "orders", "a")
+	addInTx(t, db, "orders", "b")
+	require.NoError(t, db.Table(DefaultTable).Where("payload = ?", []byte("a")).
+		Update("created_at", time.Now().Add(-time.Minute)).Error)
+
+	pending, lag, err = relay.Lag(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pending)
+	assert.InDelta(t, time.Minute.Seconds(), lag.Seconds(), 5)
+}
+
+func TestRelay_StartShutdown(t *testing.T) {
+	db := setupTestDB(t)
+	producer := &fakeProducer{}
+	relay := NewRelay(db, producer, WithRelayInterval(10*time.Millisecond))
+
+	require.NoError(t, relay.Start(context.Background()))
+	assert.NoError(t, relay.HealthCheck(context.Background()))
+
+	addInTx(t, db, "orders", "a")
+	require.Eventually(t, func() bool { return len(producer.data()) == 1 }, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, relay.PreStop(ctx))
+	require.NoError(t, relay.Shutdown(ctx))
+	assert.Error(t, relay.Start(context.Background()), "closed relay cannot restart")
+}
+
+func TestRelay_StartRequiresDependencies(t *testing.T) {
+	assert.Error(t, NewRelay(nil, &fakeProducer{}).Start(context.Background()))
+}
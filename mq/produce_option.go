@@ -8,3 +8,10 @@ type ProduceConfig = types.ProduceConfig
 
 var WithOrderKey = types.WithOrderKey
 var ApplyProduceOptions = types.ApplyProduceOptions
+var WithMessageID = types.WithMessageID
+var WithHeaders = types.WithHeaders
+var WithDelay = types.WithDelay
+var WithDeliverAt = types.WithDeliverAt
+
+// ErrDelayNotSupported 表示当前 MQ 实现不支持延迟投递。
+var ErrDelayNotSupported = types.ErrDelayNotSupported
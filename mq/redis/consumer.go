@@ -9,9 +9,11 @@ import (
 // NewConsumer 创建消费者服务实例
 func NewConsumer(addr string, opts ...ConsumerOption) types.IConsumeServer {
 	cfg := consumerConfig{
-		maxRetry:        3,
-		emptyQueueSleep: time.Second,
-		queuePrefix:     "queue:",
+		maxRetry:          3,
+		emptyQueueSleep:   time.Second,
+		queuePrefix:       "queue:",
+		delayPollInterval: time.Second,
+		concurrency:       1,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
@@ -14,6 +14,7 @@ import (
 	"github.com/gomooth/pkg/mq/internal/engine"
 	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/middleware"
 	"github.com/gomooth/pkg/mq/internal/types"
 	"github.com/gomooth/pkg/mq/redis/internal"
 	"github.com/gomooth/xerror"
@@ -26,6 +27,7 @@ const maxConsumeErrors = 50
 type queueConsumer struct {
 	queueName string
 	handler   types.IHandler
+	mws       []types.Middleware // 注册级中间件，批量调用组装中间件链时使用
 	client    *redis.Client
 	strategy  retryStrategy
 }
@@ -63,7 +65,7 @@ func newConsumerEngine(addr string, cfg *consumerConfig) *consumerEngine {
 
 	// 预注册配置中的消费者
 	for _, reg := range cfg.consumers {
-		eng.createRegistration(reg.Queue, reg.Handler)
+		eng.createRegistration(reg.Queue, reg.Handler, nil)
 	}
 
 	return eng
@@ -83,7 +85,7 @@ func (e *consumerEngine) Register(queue string, handler types.IHandler, opts ...
 		return xerror.NewXCode(xcode.ErrMQConsume, "redis does not support WithGroup option")
 	}
 
-	e.createRegistration(queue, handler)
+	e.createRegistration(queue, handler, cfg.Middlewares)
 	return nil
 }
 
@@ -93,7 +95,7 @@ func (e *consumerEngine) Count() uint {
 	return uint(len(e.registrations))
 }
 
-func (e *consumerEngine) createRegistration(queueName string, handler types.IHandler) {
+func (e *consumerEngine) createRegistration(queueName string, handler types.IHandler, mws []types.Middleware) {
 	if len(queueName) == 0 {
 		e.Logger.Error("queue name must not be empty")
 		return
@@ -128,33 +130,51 @@ func (e *consumerEngine) createRegistration(queueName string, handler types.IHan
 		failedHandler = DefaultFailedHandlerFunc(intLogger)
 	}
 
+	// 重试策略调用组装好中间件链的 handler；死信与批量接口仍在原始 handler 上识别
+	wrapped := e.wrapHandler(handler, mws)
+
 	switch e.opt.retryMode {
 	case types.RetryModeRequeue:
-		s := newRequeueRetryStrategy(handler, e.opt.maxRetry, backoff, client, e.opt.queuePrefix, intLogger, m)
+		s := newRequeueRetryStrategy(wrapped, e.opt.maxRetry, backoff, client, e.opt.queuePrefix, intLogger, m)
 		s.SetFailedHandler(failedHandler)
 		if dl, ok := handler.(types.DeadLetterHandler); ok {
 			s.SetDeadLetterHandler(dl)
 		}
 		s.SetTimeout(e.opt.handlerTimeout)
+		s.SetRetryIf(e.opt.retryIf)
 		strategy = s
 	default: // RetryModeSync
-		s := newSyncRetryStrategy(handler, e.opt.maxRetry, backoff, intLogger, m)
+		s := newSyncRetryStrategy(wrapped, e.opt.maxRetry, backoff, intLogger, m)
 		s.SetFailedHandler(failedHandler)
 		if dl, ok := handler.(types.DeadLetterHandler); ok {
 			s.SetDeadLetterHandler(dl)
 		}
 		s.SetTimeout(e.opt.handlerTimeout)
+		s.SetRetryIf(e.opt.retryIf)
 		strategy = s
 	}
 
 	e.registrations = append(e.registrations, queueConsumer{
 		queueName: queueName,
 		handler:   handler,
+		mws:       mws,
 		client:    client,
 		strategy:  strategy,
 	})
 }
 
+// wrapHandler 按 内置 → 服务级 → 注册级 的顺序为 handler 组装中间件链
+func (e *consumerEngine) wrapHandler(handler types.IHandler, mws []types.Middleware) types.IHandler {
+	server := middleware.Server(e.opt.noDefaultMiddlewares, e.Logger, e.opt.panicHandler, e.opt.middlewares)
+	return middleware.Wrap(handler, server, mws)
+}
+
+// wrapBatchHandler 为整批 HandleBatch 调用组装与单条处理相同的中间件链
+func (e *consumerEngine) wrapBatchHandler(bh types.IBatchHandler, mws []types.Middleware) types.IBatchHandler {
+	server := middleware.Server(e.opt.noDefaultMiddlewares, e.Logger, e.opt.panicHandler, e.opt.middlewares)
+	return middleware.WrapBatch(bh, append(server, mws...), e.Logger, e.opt.panicHandler)
+}
+
 func (e *consumerEngine) Start(ctx context.Context) error {
 	if !e.TryStart() {
 		if e.State.Load() == engine.Running {
@@ -186,13 +206,25 @@ func (e *consumerEngine) Start(ctx context.Context) error {
 		}
 	}
 
-	// 启动消费循环
+	// 恢复上次退出时未处理完成的消息
+	for _, reg := range regs {
+		e.recoverBackup(ctx, reg)
+	}
+
+	// 启动消费循环（每个队列 concurrency 个 worker）与延迟消息搬运协程
 	for _, reg := range regs {
 		r := reg
+		for i := range e.opt.concurrency {
+			e.WG.Add(1)
+			e.SafeGo(fmt.Sprintf("consume-%s-%d", r.queueName, i), func() {
+				defer e.WG.Done()
+				e.consumeLoop(engineCtx, r)
+			}, e.opt.panicHandler)
+		}
 		e.WG.Add(1)
-		e.SafeGo(fmt.Sprintf("consume-%s", r.queueName), func() {
+		e.SafeGo(fmt.Sprintf("delay-mover-%s", r.queueName), func() {
 			defer e.WG.Done()
-			e.consumeLoop(engineCtx, r)
+			e.delayMover(engineCtx, r)
 		}, e.opt.panicHandler)
 	}
 
@@ -211,22 +243,6 @@ func (e *consumerEngine) Shutdown(ctx context.Context) error {
 		e.CancelFunc()
 	}
 
-	// 关闭 Redis 客户端
-	e.regMu.Lock()
-	regs := make([]queueConsumer, len(e.registrations))
-	copy(regs, e.registrations)
-	e.regMu.Unlock()
-
-	for _, reg := range regs {
-		if reg.client != nil {
-			_ = reg.client.Close()
-		}
-		// 关闭 requeueRetryStrategy 的 AttemptTracker
-		if closer, ok := reg.strategy.(interface{ Close() }); ok {
-			closer.Close()
-		}
-	}
-
 	done := make(chan struct{})
 	go func() {
 		e.WG.Wait()
@@ -238,6 +254,18 @@ func (e *consumerEngine) Shutdown(ctx context.Context) error {
 	case <-ctx.Done():
 	}
 
+	// 等待 worker 退出后再关闭 Redis 客户端，避免 worker 使用关闭中的客户端
+	e.regMu.Lock()
+	regs := make([]queueConsumer, len(e.registrations))
+	copy(regs, e.registrations)
+	e.regMu.Unlock()
+
+	for _, reg := range regs {
+		if reg.client != nil {
+			_ = reg.client.Close()
+		}
+	}
+
 	e.State.Store(engine.Closed)
 	return nil
 }
@@ -255,7 +283,82 @@ func (e *consumerEngine) consumeLoop(ctx context.Context, qc queueConsumer) {
 		EmptySleep: e.opt.emptyQueueSleep,
 		MaxErrors:  maxConsumeErrors,
 		Tracer:     telemetry.Tracer("mq.redis.consumer"),
+		Logger:     e.Logger,
+		Drain:      e.Draining(),
+		Metrics:    e.Metrics.(*metrics.ConsumerMetrics),
+	}
+
+	// 批量 handler 使用批量消费循环，失败的消息交给单条重试策略
+	if bh, batchCfg, ok := types.BatchOf(qc.handler); ok {
+		strategy := consume.NewBatchStrategy(e.wrapBatchHandler(bh, qc.mws), decodeMessage, qc.strategy, e.opt.handlerTimeout)
+		consume.ConsumeBatchLoop(ctx, cfg, batchCfg, fetcher, strategy)
+		return
 	}
 
 	consume.ConsumeLoop(ctx, cfg, fetcher, qc.strategy)
 }
+
+// decodeMessage 将线上数据解析为 redis 消息
+func decodeMessage(queue string, data []byte) types.Message {
+	msg := types.NewRedisMessage(queue, nil)
+	types.UnmarshalWire(&msg, data)
+	return msg
+}
+
+// recoverBackup 将 backup 列表中残留的消息移回主队列。
+// backup 列表记录处理中的消息，启动时残留的消息来自上次未正常完成处理的进程。
+// 同一队列仅应由一个消费者进程消费，否则会重复投递其他进程正在处理的消息。
+func (e *consumerEngine) recoverBackup(ctx context.Context, qc queueConsumer) {
+	queueKey := fmt.Sprintf("%s%s", e.opt.queuePrefix, qc.queueName)
+	backupKey := fmt.Sprintf("%s_backup", queueKey)
+	n, err := internal.RecoverScript.Run(ctx, qc.client, []string{queueKey, backupKey}).Int()
+	if err != nil {
+		e.Logger.Warn("recover backup messages failed", "queue", qc.queueName, "error", err)
+		return
+	}
+	if n > 0 {
+		e.Logger.Info("recovered unfinished messages from backup", "queue", qc.queueName, "count", n)
+	}
+}
+
+// delayMoveBatch 单次搬运的最大延迟消息数
+const delayMoveBatch = 100
+
+// delayMover 定期将到期的延迟消息从 Sorted Set 移入主队列。
+// 单次搬运达到上限时立即继续，避免积压的到期消息等待下一个检查周期。
+// 排空开始后停止搬运，未搬运的消息保留在 Sorted Set 中由下次启动继续处理。
+func (e *consumerEngine) delayMover(ctx context.Context, qc queueConsumer) {
+	queueKey := fmt.Sprintf("%s%s", e.opt.queuePrefix, qc.queueName)
+	keys := []string{queueKey + internal.DelayKeySuffix, queueKey}
+
+	interval := e.opt.delayPollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	drain := e.Draining()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-drain:
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			moved, err := internal.MoveDueScript.Run(ctx, qc.client, keys, time.Now().UnixMilli(), delayMoveBatch).Int()
+			if err != nil {
+				if ctx.Err() == nil {
+					e.Logger.Warn("move delayed messages failed", "queue", qc.queueName, "error", err)
+				}
+				break
+			}
+			if moved < delayMoveBatch {
+				break
+			}
+		}
+	}
+}
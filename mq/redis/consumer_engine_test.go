@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"errors"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -216,3 +217,371 @@ func miniredisClientForEngine(t *testing.T, mr *miniredis.Miniredis) *redis.Clie
 	t.Helper()
 	return redis.NewClient(&redis.Options{Addr: mr.Addr()})
 }
+
+func TestConsumer_PreStop(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("q", types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			return nil
+		})),
+		WithEmptyQueueSleep(time.Hour),
+	)
+	require.NoError(t, consumer.Start(context.Background()))
+
+	preStopper, ok := consumer.(interface{ PreStop(context.Context) error })
+	require.True(t, ok, "consumer should implement app.PreStopper")
+
+	// 排空会中断空队列休眠，消费循环应立即退出
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.NoError(t, preStopper.PreStop(ctx))
+
+	assert.NoError(t, consumer.Shutdown(context.Background()))
+}
+
+func TestConsumer_ReceivesEnvelope(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	received := make(chan types.Message, 16)
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("envelope-queue", types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			received <- msg
+			return nil
+		})),
+		WithEmptyQueueSleep(50*time.Millisecond),
+	)
+	ctx := context.Background()
+	require.NoError(t, consumer.Start(ctx))
+	defer func() { _ = consumer.Shutdown(context.Background()) }()
+
+	producer := NewProducer(mr.Addr())
+	require.NoError(t, producer.Start(ctx))
+	defer func() { _ = producer.Shutdown(context.Background()) }()
+
+	// 旧格式的原始消息体仍可被消费
+	client := miniredisClientForEngine(t, mr)
+	require.NoError(t, client.LPush(ctx, "queue:envelope-queue", "raw").Err())
+	require.NoError(t, producer.Produce(ctx, "envelope-queue", []byte("hello"),
+		types.WithMessageID("id-1"), types.WithHeaders(map[string]string{"tenant": "t1"})))
+
+	got := make(map[string]types.Message)
+	for len(got) < 2 {
+		select {
+		case msg := <-received:
+			got[string(msg.Data)] = msg
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timeout, received: %d", len(got))
+		}
+	}
+
+	raw := got["raw"]
+	assert.Empty(t, raw.ID)
+	assert.Equal(t, 1, raw.Attempt)
+
+	msg := got["hello"]
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "t1", msg.Header("tenant"))
+	assert.False(t, msg.Timestamp.IsZero())
+	assert.Equal(t, 1, msg.Attempt)
+}
+
+func TestConsumer_DelayedDelivery(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	received := make(chan time.Time, 1)
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("delay-queue", types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			received <- time.Now()
+			return nil
+		})),
+		WithEmptyQueueSleep(20*time.Millisecond),
+		WithDelayPollInterval(20*time.Millisecond),
+	)
+	ctx := context.Background()
+	require.NoError(t, consumer.Start(ctx))
+	defer func() { _ = consumer.Shutdown(context.Background()) }()
+
+	producer := NewProducer(mr.Addr())
+	require.NoError(t, producer.Start(ctx))
+	defer func() { _ = producer.Shutdown(context.Background()) }()
+
+	sentAt := time.Now()
+	require.NoError(t, producer.Produce(ctx, "delay-queue", []byte("later"), types.WithDelay(300*time.Millisecond)))
+
+	select {
+	case at := <-received:
+		assert.GreaterOrEqual(t, at.Sub(sentAt), 300*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("delayed message not delivered")
+	}
+
+	client := miniredisClientForEngine(t, mr)
+	assert.Equal(t, int64(0), client.ZCard(ctx, "queue:delay-queue_delayed").Val())
+}
+
+func TestConsumer_DelayedDuplicateBodies(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	var mu sync.Mutex
+	var bodies []string
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("delay-queue", types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			mu.Lock()
+			bodies = append(bodies, string(msg.Data))
+			mu.Unlock()
+			return nil
+		})),
+		WithEmptyQueueSleep(20*time.Millisecond),
+		WithDelayPollInterval(20*time.Millisecond),
+	)
+	ctx := context.Background()
+	require.NoError(t, consumer.Start(ctx))
+	defer func() { _ = consumer.Shutdown(context.Background()) }()
+
+	legacy := NewProducer(mr.Addr(), WithProducerLegacyPayload())
+	require.NoError(t, legacy.Start(ctx))
+	defer func() { _ = legacy.Shutdown(context.Background()) }()
+	producer := NewProducer(mr.Addr())
+	require.NoError(t, producer.Start(ctx))
+	defer func() { _ = producer.Shutdown(context.Background()) }()
+
+	// 相同内容、相同投递时间的延迟消息各自保留
+	at := time.Now().Add(100 * time.Millisecond)
+	require.NoError(t, legacy.Produce(ctx, "delay-queue", []byte("same"), types.WithDeliverAt(at)))
+	require.NoError(t, legacy.Produce(ctx, "delay-queue", []byte("same"), types.WithDeliverAt(at)))
+	require.NoError(t, producer.ProduceBatch(ctx, "delay-queue", [][]byte{[]byte("dup"), []byte("dup")}, types.WithDeliverAt(at)))
+	require.NoError(t, producer.Produce(ctx, "delay-queue", []byte("id"), types.WithDeliverAt(at), types.WithMessageID("id-1")))
+	require.NoError(t, producer.Produce(ctx, "delay-queue", []byte("id"), types.WithDeliverAt(at), types.WithMessageID("id-1")))
+
+	client := miniredisClientForEngine(t, mr)
+	assert.Equal(t, int64(6), client.ZCard(ctx, "queue:delay-queue_delayed").Val())
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(bodies) == 6
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"same", "same", "dup", "dup", "id", "id"}, bodies)
+	mu.Unlock()
+}
+
+func TestConsumer_AckRemovesBackup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	var consumed atomic.Int32
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("q", types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			consumed.Add(1)
+			return nil
+		})),
+		WithEmptyQueueSleep(20*time.Millisecond),
+	)
+	ctx := context.Background()
+	require.NoError(t, consumer.Start(ctx))
+
+	client := miniredisClientForEngine(t, mr)
+	require.NoError(t, client.LPush(ctx, "queue:q", "m1", "m2").Err())
+
+	require.Eventually(t, func() bool { return consumed.Load() == 2 }, 3*time.Second, 10*time.Millisecond)
+	// 处理完成的消息不得被再次投递
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(2), consumed.Load())
+	assert.False(t, mr.Exists("queue:q_backup"), "backup list should be empty after ack")
+
+	require.NoError(t, consumer.Shutdown(ctx))
+}
+
+func TestConsumer_RecoversBackupOnStart(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	// 模拟上次进程处理中途退出：消息残留在 backup 列表
+	_, err := mr.Push("queue:q_backup", "unfinished")
+	require.NoError(t, err)
+
+	got := make(chan string, 1)
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("q", types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			got <- string(msg.Data)
+			return nil
+		})),
+		WithEmptyQueueSleep(20*time.Millisecond),
+	)
+	ctx := context.Background()
+	require.NoError(t, consumer.Start(ctx))
+	defer consumer.Shutdown(ctx)
+
+	select {
+	case v := <-got:
+		assert.Equal(t, "unfinished", v)
+	case <-time.After(3 * time.Second):
+		t.Fatal("backup message not recovered")
+	}
+}
+
+func TestConsumer_Concurrency(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	const workers = 3
+	var inFlight, peak, consumed atomic.Int32
+	release := make(chan struct{})
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("q", types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			inFlight.Add(-1)
+			consumed.Add(1)
+			return nil
+		})),
+		WithConcurrency(workers),
+		WithEmptyQueueSleep(10*time.Millisecond),
+	)
+	ctx := context.Background()
+	require.NoError(t, consumer.Start(ctx))
+
+	client := miniredisClientForEngine(t, mr)
+	require.NoError(t, client.LPush(ctx, "queue:q", "m1", "m2", "m3", "m4", "m5").Err())
+
+	require.Eventually(t, func() bool { return inFlight.Load() == workers }, 3*time.Second, 10*time.Millisecond)
+	close(release)
+	require.Eventually(t, func() bool { return consumed.Load() == 5 }, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(workers), peak.Load(), "in-flight messages must not exceed concurrency")
+	// handler 返回后才确认 backup；空闲 worker 的阻塞拉取（5 秒）可能排在确认之前，需等待其超时
+	assert.Eventually(t, func() bool { return !mr.Exists("queue:q_backup") }, 7*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, consumer.Shutdown(ctx))
+}
+
+func TestConsumer_BatchHandler(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	var mu sync.Mutex
+	var batches [][]string
+	failed := make(chan types.Message, 1)
+	h := types.NewBatchHandler(types.FuncBatchHandler(func(ctx context.Context, msgs []types.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batch := make([]string, len(msgs))
+		be := types.NewBatchError()
+		for i, m := range msgs {
+			batch[i] = string(m.Data)
+			if string(m.Data) == "bad" {
+				be.Fail(i, errors.New("bad row"))
+			}
+		}
+		batches = append(batches, batch)
+		return be.Err()
+	}), types.WithBatchSize(3), types.WithBatchMaxWait(50*time.Millisecond))
+
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("q", h),
+		WithMaxRetry(1),
+		WithBackoff(&retry.FixedDelay{Wait: time.Millisecond}),
+		WithEmptyQueueSleep(10*time.Millisecond),
+		WithFailedHandler(func(_ context.Context, msg types.Message, _ error) { failed <- msg }),
+	)
+	ctx := context.Background()
+
+	client := miniredisClientForEngine(t, mr)
+	require.NoError(t, client.RPush(ctx, "queue:q", "a", "bad", "c", "d").Err())
+	require.NoError(t, consumer.Start(ctx))
+	defer consumer.Shutdown(ctx)
+
+	select {
+	case msg := <-failed:
+		assert.Equal(t, "bad", string(msg.Data))
+		assert.Equal(t, 2, msg.Attempt)
+	case <-time.After(3 * time.Second):
+		t.Fatal("failed handler not called")
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(batches) == 3
+	}, 3*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	// 首批满 3 条；失败消息以单条批次重试；剩余消息在 MaxWait 到期后提交
+	assert.Equal(t, []string{"a", "bad", "c"}, batches[0])
+	assert.Equal(t, []string{"bad"}, batches[1])
+	assert.Equal(t, []string{"d"}, batches[2])
+	mu.Unlock()
+
+	assert.Eventually(t, func() bool { return !mr.Exists("queue:q_backup") }, time.Second, 10*time.Millisecond,
+		"batch messages should be acked")
+}
+
+func TestConsumer_BatchHandlerPanicRecovered(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	var calls, panics, seen atomic.Int32
+	consumed := make(chan string, 4)
+	h := types.NewBatchHandler(types.FuncBatchHandler(func(ctx context.Context, msgs []types.Message) error {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		for _, m := range msgs {
+			consumed <- string(m.Data)
+		}
+		return nil
+	}), types.WithBatchSize(2), types.WithBatchMaxWait(50*time.Millisecond))
+
+	consumer := NewConsumer(mr.Addr(),
+		WithConsumer("q", h),
+		WithMaxRetry(1),
+		WithBackoff(&retry.FixedDelay{Wait: time.Millisecond}),
+		WithEmptyQueueSleep(10*time.Millisecond),
+		WithPanicHandler(func(any) { panics.Add(1) }),
+		WithMiddleware(func(next types.IHandler) types.IHandler {
+			return types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+				seen.Add(1)
+				return next.Handle(ctx, msg)
+			})
+		}),
+	)
+	ctx := context.Background()
+
+	client := miniredisClientForEngine(t, mr)
+	require.NoError(t, client.RPush(ctx, "queue:q", "a", "b").Err())
+	require.NoError(t, consumer.Start(ctx))
+	defer consumer.Shutdown(ctx)
+
+	// 首批 panic 被恢复，两条消息以单条批次重试；消费循环继续处理后续消息
+	got := make([]string, 0, 2)
+	for len(got) < 2 {
+		select {
+		case v := <-consumed:
+			got = append(got, v)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("messages not retried after panic, got %v", got)
+		}
+	}
+	assert.ElementsMatch(t, []string{"a", "b"}, got)
+	assert.Equal(t, int32(1), panics.Load())
+	assert.GreaterOrEqual(t, seen.Load(), int32(2), "service middleware should run for the batch call")
+
+	require.NoError(t, client.RPush(ctx, "queue:q", "c").Err())
+	select {
+	case v := <-consumed:
+		assert.Equal(t, "c", v)
+	case <-time.After(3 * time.Second):
+		t.Fatal("consume loop stopped after batch panic")
+	}
+}
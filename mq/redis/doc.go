@@ -1,8 +1,13 @@
 // Package redis 提供统一的 Redis 队列消费者和生产者实现。
 //
 // 消费者支持同步重试和再入队重试两种模式，通过可配置的退避策略控制重试节奏。
+// 处理中的消息记录在 backup 列表，处理结束后移除；启动时残留的消息移回主队列重新处理。
+// WithConcurrency 可为每个队列启动多个 worker 并行处理。
+// 通过 mq.NewBatchHandler 注册的批量 handler 批量出队处理，失败的消息逐条进入重试流程。
 //
 // 生产者支持单条和批量推送，内置 Pipeline 优化。
+// 延迟消息（WithDelay / WithDeliverAt）写入 Sorted Set，由消费者的搬运协程到期后移入主队列。
 //
 // Consumer 和 Producer 均实现 app.IApp 接口，可通过 app.Manager 统一管理生命周期。
+// Consumer 同时实现 app.PreStopper 接口，排空阶段停止拉取新消息并等待处理中的消息完成。
 package redis
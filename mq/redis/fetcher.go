@@ -27,7 +27,8 @@ func newRedisFetcher(client *redis.Client, queueKey string, popTimeout int64) *r
 	}
 }
 
-// Fetch 从 Redis 队列拉取一条消息
+// Fetch 从 Redis 队列拉取一条消息，消息同时写入 backup 列表，
+// 返回的 Ack 在处理完成后将其从 backup 列表移除
 func (f *redisFetcher) Fetch(ctx context.Context) consume.FetchResult {
 	timeout := time.Duration(f.popTimeout) * time.Second
 	val, err := internal.PopScript.Run(ctx, f.client, []string{f.queueKey, f.backupKey}, timeout.Seconds()).Text()
@@ -45,5 +46,32 @@ func (f *redisFetcher) Fetch(ctx context.Context) consume.FetchResult {
 	if val == "" {
 		return consume.FetchResult{Empty: true}
 	}
-	return consume.FetchResult{Data: val}
+	return consume.FetchResult{
+		Data: val,
+		Ack: func(ctx context.Context) error {
+			return f.client.LRem(ctx, f.backupKey, 1, val).Err()
+		},
+	}
+}
+
+// FetchBatch 非阻塞地批量拉取至多 max 条消息，消息同时写入 backup 列表，
+// 每条消息的 Ack 在处理完成后将其从 backup 列表移除
+func (f *redisFetcher) FetchBatch(ctx context.Context, max int) ([]consume.FetchResult, error) {
+	vals, err := internal.BatchPopScript.Run(ctx, f.client, []string{f.queueKey, f.backupKey}, max).StringSlice()
+	if err != nil {
+		if err == redis.Nil || ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	results := make([]consume.FetchResult, len(vals))
+	for i, val := range vals {
+		results[i] = consume.FetchResult{
+			Data: val,
+			Ack: func(ctx context.Context) error {
+				return f.client.LRem(ctx, f.backupKey, 1, val).Err()
+			},
+		}
+	}
+	return results, nil
 }
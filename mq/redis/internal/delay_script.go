@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// DelayKeySuffix 延迟队列（Sorted Set）键名后缀，score 为投递时间的毫秒时间戳
+const DelayKeySuffix = "_delayed"
+
+// DelayMember 生成延迟消息在 Sorted Set 中的成员：<32 位十六进制随机串>:<payload>。
+// Sorted Set 成员唯一，随机前缀避免相同内容的延迟消息（如未封装信封的原始消息体）相互覆盖；
+// 前缀由 MoveDueScript 移入主队列时去除。
+func DelayMember(payload []byte) []byte {
+	id := types.NewMessageID()
+	member := make([]byte, 0, len(id)+1+len(payload))
+	member = append(member, id...)
+	member = append(member, ':')
+	return append(member, payload...)
+}
+
+// MoveDueScript 原子性地将到期的延迟消息从 Sorted Set 移入主队列，并去除 DelayMember 添加的前缀。
+// 多个消费者并发执行时每条消息只会被移动一次。返回本次移动的消息数。
+var MoveDueScript = redis.NewScript(`
+local delay_key = KEYS[1]
+local main_key = KEYS[2]
+local now = ARGV[1]
+local limit = tonumber(ARGV[2])
+
+local items = redis.call('ZRANGEBYSCORE', delay_key, '-inf', now, 'LIMIT', 0, limit)
+for _, item in ipairs(items) do
+    -- 成员格式为 <32 位十六进制前缀>:<payload>
+    redis.call('LPUSH', main_key, string.sub(item, 34))
+    redis.call('ZREM', delay_key, item)
+end
+return #items
+`)
@@ -2,21 +2,44 @@ package internal
 
 import "github.com/redis/go-redis/v9"
 
-// PopScript 原子性 Pop 脚本：先从 backup 列表取数据，backup 为空则从主队列阻塞取并写入 backup
+// PopScript 原子性 Pop 脚本：从主队列取出消息并写入 backup 列表。
+// backup 列表记录处理中的消息，处理完成后由消费者通过 LREM 移除。
 // 使用 BLMOVE 替代已废弃的 BRPOPLPUSH（Redis 6.2+ 废弃）
 var PopScript = redis.NewScript(`
 local backup_key = KEYS[2]
 local main_key = KEYS[1]
 local timeout = tonumber(ARGV[1])
 
--- 先尝试从 backup 获取
-local bak = redis.call('RPOP', backup_key)
-if bak then
-    return bak
-end
-
--- backup 为空，从主队列阻塞获取
 -- BLMOVE source destination LEFT RIGHT timeout 等价于 BRPOPLPUSH source destination timeout
 local result = redis.call('BLMOVE', main_key, backup_key, 'LEFT', 'RIGHT', timeout)
 return result
 `)
+
+// RecoverScript 将 backup 列表中的消息移回主队列头部（下一个被取出的位置），返回移动的条数。
+// 消费者启动时执行，恢复上次进程退出时未处理完成的消息。
+var RecoverScript = redis.NewScript(`
+local main_key = KEYS[1]
+local backup_key = KEYS[2]
+local n = 0
+while redis.call('LMOVE', backup_key, main_key, 'RIGHT', 'LEFT') do
+    n = n + 1
+end
+return n
+`)
+
+// BatchPopScript 批量 Pop 脚本：非阻塞地从主队列取出至多 ARGV[1] 条消息并写入 backup 列表。
+// 队列为空时返回空列表，由消费循环按空队列休眠间隔轮询。
+var BatchPopScript = redis.NewScript(`
+local main_key = KEYS[1]
+local backup_key = KEYS[2]
+local limit = tonumber(ARGV[1])
+local items = {}
+for i = 1, limit do
+    local v = redis.call('LMOVE', main_key, backup_key, 'LEFT', 'RIGHT')
+    if not v then
+        break
+    end
+    items[#items + 1] = v
+end
+return items
+`)
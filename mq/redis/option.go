@@ -24,10 +24,13 @@ type consumerConfig struct {
 	backoff        retry.BackoffStrategy
 	handlerTimeout time.Duration
 	retryMode      types.RetryMode
+	retryIf        func(error) bool
 
 	// 队列配置
-	emptyQueueSleep time.Duration
-	queuePrefix     string
+	emptyQueueSleep   time.Duration
+	queuePrefix       string
+	delayPollInterval time.Duration
+	concurrency       int
 
 	// 失败处理
 	failedHandler types.FailedHandlerFunc
@@ -35,6 +38,10 @@ type consumerConfig struct {
 	// Panic 处理
 	panicHandler func(any)
 
+	// handler 中间件
+	middlewares          []types.Middleware
+	noDefaultMiddlewares bool
+
 	// 预注册消费者
 	consumers []ConsumerRegistration
 }
@@ -67,13 +74,38 @@ func WithRetryMode(mode types.RetryMode) ConsumerOption {
 	}
 }
 
-// WithPanicHandler 设置 panic 恢复后的回调函数
+// WithRetryIf 设置错误重试谓词：返回 false 的错误不再重试，直接进入死信处理器或失败回调（默认所有错误都重试）。
+// mq.Permanent 包装的错误与解码失败始终不重试。
+func WithRetryIf(fn func(error) bool) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryIf = fn
+	}
+}
+
+// WithPanicHandler 设置 panic 恢复后的回调函数（handler panic 由内置 Recover 中间件恢复后回调）
 func WithPanicHandler(fn func(any)) ConsumerOption {
 	return func(c *consumerConfig) {
 		c.panicHandler = fn
 	}
 }
 
+// WithMiddleware 追加服务级 handler 中间件，作用于该消费者注册的所有 handler。
+// 服务级中间件位于内置中间件（Trace → Recover）之内、注册级中间件（mq.WithMiddleware）之外；
+// 先传入的中间件位于外层。
+func WithMiddleware(mws ...types.Middleware) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithoutDefaultMiddlewares 禁用内置的 Trace 与 Recover 中间件，
+// 可通过 WithMiddleware 以 mq.Trace / mq.Recover 自定义顺序或替换实现
+func WithoutDefaultMiddlewares() ConsumerOption {
+	return func(c *consumerConfig) {
+		c.noDefaultMiddlewares = true
+	}
+}
+
 // WithEmptyQueueSleep 设置队列为空时的休眠时间（默认 1s）
 func WithEmptyQueueSleep(d time.Duration) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -83,6 +115,25 @@ func WithEmptyQueueSleep(d time.Duration) ConsumerOption {
 	}
 }
 
+// WithConcurrency 设置每个队列并行处理消息的 worker 数（默认 1）。
+// 并行处理时同一队列的消息不再保证按顺序处理。
+func WithConcurrency(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithDelayPollInterval 设置延迟消息到期检查间隔（默认 1s），决定延迟投递的时间精度
+func WithDelayPollInterval(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		if d > 0 {
+			c.delayPollInterval = d
+		}
+	}
+}
+
 // WithFailedHandler 设置重试耗尽后的失败处理回调
 func WithFailedHandler(fn types.FailedHandlerFunc) ConsumerOption {
 	return func(c *consumerConfig) {
@@ -138,6 +189,7 @@ type producerConfig struct {
 	logger       *slog.Logger
 	redisOptions *redis.Options
 	queuePrefix  string
+	legacyWire   bool
 }
 
 // WithProducerLogger 设置生产者日志器
@@ -160,3 +212,11 @@ func WithProducerQueuePrefix(prefix string) ProducerOption {
 		c.queuePrefix = prefix
 	}
 }
+
+// WithProducerLegacyPayload 不使用消息信封，直接写入原始消息体（trace context 注入 JSON 消息体）。
+// 用于滚动升级期间兼容尚未升级的消费者，此时消息头、消息 ID 等信封字段不会被传递。
+func WithProducerLegacyPayload() ProducerOption {
+	return func(c *producerConfig) {
+		c.legacyWire = true
+	}
+}
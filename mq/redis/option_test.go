@@ -68,6 +68,14 @@ func TestWithQueuePrefix(t *testing.T) {
 	assert.Equal(t, "myqueue:", cfg.queuePrefix)
 }
 
+func TestWithConcurrency(t *testing.T) {
+	cfg := &consumerConfig{concurrency: 1}
+	WithConcurrency(0)(cfg)
+	assert.Equal(t, 1, cfg.concurrency, "non-positive value should be ignored")
+	WithConcurrency(4)(cfg)
+	assert.Equal(t, 4, cfg.concurrency)
+}
+
 func TestWithProducerLogger(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 	cfg := &producerConfig{}
@@ -109,8 +109,8 @@ func (e *producerEngine) Produce(ctx context.Context, queue string, message []by
 	)
 	defer span.End()
 
-	// Inject trace context into message
-	injectedMsg := mqtraceutil.InjectTraceContext(ctx, string(message))
+	produceCfg := types.ApplyProduceOptions(opts)
+	payload := e.encode(ctx, queue, message, produceCfg)
 
 	e.mu.RLock()
 	client := e.client
@@ -123,7 +123,14 @@ func (e *producerEngine) Produce(ctx context.Context, queue string, message []by
 	}
 
 	queueKey := fmt.Sprintf("%s%s", e.opt.queuePrefix, queue)
-	if err := client.LPush(ctx, queueKey, []byte(injectedMsg)).Err(); err != nil {
+	var err error
+	if at, delayed := produceCfg.DeliveryTime(); delayed {
+		// 延迟消息写入 Sorted Set，由消费者的搬运协程到期后移入主队列
+		err = client.ZAdd(ctx, queueKey+internal.DelayKeySuffix, redis.Z{Score: float64(at.UnixMilli()), Member: internal.DelayMember(payload)}).Err()
+	} else {
+		err = client.LPush(ctx, queueKey, payload).Err()
+	}
+	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		if m, ok := e.Metrics.(*metrics.ProducerMetrics); ok && m != nil {
@@ -172,11 +179,18 @@ func (e *producerEngine) ProduceBatch(ctx context.Context, queue string, message
 
 	queueKey := fmt.Sprintf("%s%s", e.opt.queuePrefix, queue)
 
-	// 使用 Pipeline 批量推送，注入 trace context
+	// 使用 Pipeline 批量推送，每条消息独立生成信封
+	produceCfg := types.ApplyProduceOptions(opts)
+	produceCfg.MessageID = ""
+	at, delayed := produceCfg.DeliveryTime()
 	pipe := client.Pipeline()
 	for _, msg := range messages {
-		injectedMsg := mqtraceutil.InjectTraceContext(ctx, string(msg))
-		pipe.LPush(ctx, queueKey, []byte(injectedMsg))
+		payload := e.encode(ctx, queue, msg, produceCfg)
+		if delayed {
+			pipe.ZAdd(ctx, queueKey+internal.DelayKeySuffix, redis.Z{Score: float64(at.UnixMilli()), Member: internal.DelayMember(payload)})
+			continue
+		}
+		pipe.LPush(ctx, queueKey, payload)
 	}
 	if _, err := pipe.Exec(ctx); err != nil {
 		span.RecordError(err)
@@ -193,3 +207,16 @@ func (e *producerEngine) ProduceBatch(ctx context.Context, queue string, message
 	span.SetStatus(codes.Ok, "")
 	return nil
 }
+
+// encode 生成写入队列的数据：填充消息信封并注入 trace context，编码为线上格式。
+// 启用 WithProducerLegacyPayload 时写入原始消息体，trace context 注入 JSON 消息体。
+func (e *producerEngine) encode(ctx context.Context, queue string, data []byte, cfg *types.ProduceConfig) []byte {
+	if e.opt.legacyWire {
+		return []byte(mqtraceutil.InjectTraceContext(ctx, string(data)))
+	}
+
+	msg := types.NewRedisMessage(queue, data)
+	cfg.Stamp(&msg)
+	mqtraceutil.InjectHeaders(ctx, msg.Headers)
+	return types.MarshalWire(msg)
+}
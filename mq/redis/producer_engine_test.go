@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/gomooth/pkg/mq/internal/types"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -43,15 +44,45 @@ func TestProducer_Produce(t *testing.T) {
 	require.NoError(t, err)
 
 	ctx := context.Background()
-	err = producer.Produce(ctx, "test-queue", []byte("hello"))
+	err = producer.Produce(ctx, "test-queue", []byte("hello"),
+		types.WithMessageID("id-1"), types.WithOrderKey("k"), types.WithHeaders(map[string]string{"tenant": "t1"}))
 	assert.NoError(t, err)
 
-	// 验证消息在 Redis 中
+	// 验证消息在 Redis 中，以信封格式写入
 	client := miniredisProducerClient(t, mr)
 	val, err := client.LRange(ctx, "queue:test-queue", 0, -1).Result()
 	require.NoError(t, err)
 	assert.Equal(t, 1, len(val))
-	assert.Equal(t, "hello", val[0])
+
+	msg := types.NewRedisMessage("test-queue", nil)
+	types.UnmarshalWire(&msg, []byte(val[0]))
+	assert.Equal(t, []byte("hello"), msg.Data)
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "k", msg.Key)
+	assert.Equal(t, "t1", msg.Header("tenant"))
+	assert.False(t, msg.Timestamp.IsZero())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = producer.Shutdown(shutdownCtx)
+}
+
+func TestProducer_LegacyPayload(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	producer := NewProducer(mr.Addr(), WithProducerLegacyPayload())
+	err := producer.Start(context.Background())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = producer.Produce(ctx, "test-queue", []byte("hello"))
+	assert.NoError(t, err)
+
+	client := miniredisProducerClient(t, mr)
+	val, err := client.LRange(ctx, "queue:test-queue", 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, val)
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -163,3 +194,25 @@ func miniredisProducerClient(t *testing.T, mr *miniredis.Miniredis) *redis.Clien
 	t.Helper()
 	return redis.NewClient(&redis.Options{Addr: mr.Addr()})
 }
+
+func TestProducer_ProduceDelayed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	producer := NewProducer(mr.Addr())
+	require.NoError(t, producer.Start(context.Background()))
+	defer func() { _ = producer.Shutdown(context.Background()) }()
+
+	ctx := context.Background()
+	at := time.Now().Add(time.Hour)
+	require.NoError(t, producer.Produce(ctx, "delay-queue", []byte("later"), types.WithDeliverAt(at)))
+	require.NoError(t, producer.ProduceBatch(ctx, "delay-queue", [][]byte{[]byte("a"), []byte("b")}, types.WithDelay(time.Hour)))
+
+	// 延迟消息写入 Sorted Set，不进入主队列
+	client := miniredisProducerClient(t, mr)
+	assert.Equal(t, int64(0), client.LLen(ctx, "queue:delay-queue").Val())
+	members, err := client.ZRangeWithScores(ctx, "queue:delay-queue_delayed", 0, -1).Result()
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+	assert.Equal(t, float64(at.UnixMilli()), members[0].Score)
+}
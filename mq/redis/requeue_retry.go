@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/gomooth/pkg/framework/retry"
-	"github.com/gomooth/pkg/mq/internal/attempt_tracker"
 	mqretry "github.com/gomooth/pkg/mq/internal/retry"
 	"github.com/gomooth/pkg/mq/internal/logutil"
 	"github.com/gomooth/pkg/mq/internal/metrics"
@@ -26,18 +25,16 @@ func newRequeueRetryStrategy(
 	backoff retry.BackoffStrategy,
 	client *redis.Client,
 	queuePrefix string,
-	_ logutil.Logger,
+	logger logutil.Logger,
 	m *metrics.ConsumerMetrics,
 ) *requeueRetryStrategy {
 	backoffFn := mqretry.BackoffDelayFunc(func(attempt uint) time.Duration {
 		return backoff.Delay(attempt)
 	})
 
-	tracker := attempt_tracker.NewAttemptTracker()
-
 	requeueFn := func(ctx context.Context, msg types.Message) error {
 		queueKey := fmt.Sprintf("%s%s", queuePrefix, msg.Queue)
-		return client.RPush(ctx, queueKey, msg.Data).Err()
+		return client.RPush(ctx, queueKey, types.MarshalWire(msg)).Err()
 	}
 
 	return &requeueRetryStrategy{
@@ -45,8 +42,8 @@ func newRequeueRetryStrategy(
 		inner: mqretry.NewRequeueStrategy(mqretry.RequeueConfig{
 			MaxRetry: maxRetry,
 			Backoff:  backoffFn,
-			Tracker:  tracker,
 			Metrics:  m,
+			Logger:   logger,
 			Requeue:  requeueFn,
 		}),
 	}
@@ -64,8 +61,13 @@ func (s *requeueRetryStrategy) SetTimeout(d time.Duration) {
 	s.inner.SetTimeout(d)
 }
 
+func (s *requeueRetryStrategy) SetRetryIf(fn func(error) bool) {
+	s.inner.SetRetryIf(fn)
+}
+
 func (s *requeueRetryStrategy) OnMessage(ctx context.Context, queue string, data []byte) error {
-	msg := types.NewRedisMessage(queue, data)
+	msg := types.NewRedisMessage(queue, nil)
+	types.UnmarshalWire(&msg, data)
 	err := s.inner.OnMessage(ctx, msg, s.handler.Handle)
 	// 兼容旧行为：上下文取消时返回错误，其他情况返回 nil
 	if err != nil && ctx.Err() != nil {
@@ -73,8 +75,3 @@ func (s *requeueRetryStrategy) OnMessage(ctx context.Context, queue string, data
 	}
 	return nil
 }
-
-// Close 停止 AttemptTracker 的后台清理 goroutine
-func (s *requeueRetryStrategy) Close() {
-	s.inner.Close()
-}
@@ -105,6 +105,50 @@ func TestRequeueRetryStrategy_Exhausted(t *testing.T) {
 	assert.Equal(t, 0, len(val), "exhausted message should not be requeued")
 }
 
+// TestRequeueRetryStrategy_AttemptSurvivesRestart 验证重试次数随消息信封传递：
+// 每次由新的策略实例（模拟重启 / 其他消费实例）处理再入队的消息，仍在 maxRetry 次处理后耗尽
+func TestRequeueRetryStrategy_AttemptSurvivesRestart(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	client := miniredisClient(t, mr)
+	ctx := context.Background()
+
+	var handled, failed atomic.Int32
+	newStrategy := func() *requeueRetryStrategy {
+		s := newRequeueRetryStrategy(
+			types.FuncHandler(func(ctx context.Context, msg types.Message) error {
+				handled.Add(1)
+				return errors.New("always fail")
+			}),
+			3,
+			&retry.FixedDelay{Wait: time.Millisecond},
+			client,
+			"queue:",
+			logutil.NewSlogLogger(nilLogger()),
+			nil,
+		)
+		s.SetFailedHandler(func(ctx context.Context, msg types.Message, err error) {
+			failed.Add(1)
+			assert.Equal(t, 3, msg.Attempt)
+		})
+		return s
+	}
+
+	data := []byte("hello")
+	for range 5 {
+		require.NoError(t, newStrategy().OnMessage(ctx, "test", data))
+		next, err := client.LPop(ctx, "queue:test").Bytes()
+		if errors.Is(err, redis.Nil) {
+			break
+		}
+		require.NoError(t, err)
+		data = next
+	}
+	assert.Equal(t, int32(3), handled.Load())
+	assert.Equal(t, int32(1), failed.Load())
+}
+
 func TestRequeueRetryStrategy_ContextCancel(t *testing.T) {
 	mr := miniredis.RunT(t)
 	defer mr.Close()
@@ -134,22 +134,11 @@ func BenchmarkRequeueRetry_OnMessage_Fail_Requeue(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		// 每次使用不同的消息，避免 AttemptTracker 重复 key
 		data := []byte(time.Now().String())
 		_ = strategy.OnMessage(context.Background(), "test-queue", data)
 	}
 }
 
-// ============================================================
-// P2: AttemptTracker 操作 — 通过 requeueRetryStrategy 间接测试
-// 直接基准测试通过 internal 包的导出函数进行
-// ============================================================
-
-// Note: AttemptTracker is in the internal package and cannot be directly
-// benchmarked from outside. The tracker operations are covered indirectly
-// through the requeueRetryStrategy benchmarks above.
-// For direct benchmarking, see mq/redis/internal/ tests.
-
 // ============================================================
 // P2: BackoffStrategy — 退避策略计算
 // ============================================================
@@ -15,6 +15,12 @@ type DeadLetterHandler = types.DeadLetterHandler
 // FuncHandler 函数适配器，将函数转换为 IHandler
 type FuncHandler = types.FuncHandler
 
+// IBatchHandler 批量消息处理器接口，通过 mq.NewBatchHandler 包装后注册
+type IBatchHandler = types.IBatchHandler
+
+// FuncBatchHandler 函数适配器，将函数转换为 IBatchHandler
+type FuncBatchHandler = types.FuncBatchHandler
+
 // IConsumeServer 消费者服务接口
 type IConsumeServer = types.IConsumeServer
 
@@ -0,0 +1,25 @@
+package redisstream
+
+import (
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// NewConsumer 创建消费者服务实例
+func NewConsumer(addr string, opts ...ConsumerOption) types.IConsumeServer {
+	cfg := consumerConfig{
+		maxRetry:        3,
+		emptyQueueSleep: 100 * time.Millisecond,
+		streamPrefix:    "stream:",
+		group:           "mq",
+		consumerName:    defaultConsumerName(),
+		blockTimeout:    5 * time.Second,
+		claimMinIdle:    time.Minute,
+		claimInterval:   30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return newConsumerEngine(addr, &cfg)
+}
@@ -0,0 +1,289 @@
+package redisstream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/framework/telemetry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/consume"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/logutil"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	"github.com/gomooth/pkg/mq/internal/middleware"
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	"github.com/redis/go-redis/v9"
+)
+
+const maxConsumeErrors = 50
+
+// streamConsumer 单个 stream + 消费者组的消费者
+type streamConsumer struct {
+	stream   string
+	group    string
+	handler  types.IHandler
+	client   *redis.Client
+	strategy *retryStrategy
+}
+
+// consumerEngine 消费者生命周期引擎（未导出）
+type consumerEngine struct {
+	engine.Base
+	addr string
+	opt  *consumerConfig
+
+	registrations []streamConsumer
+	regMu         sync.Mutex
+}
+
+// 编译时接口检查
+var _ types.IConsumeServer = (*consumerEngine)(nil)
+
+func newConsumerEngine(addr string, cfg *consumerConfig) *consumerEngine {
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	eng := &consumerEngine{
+		Base: engine.Base{
+			Logger:       logger,
+			Metrics:      metrics.NewConsumerMetrics("redisstream"),
+			PanicHandler: cfg.panicHandler,
+		},
+		addr: addr,
+		opt:  cfg,
+	}
+
+	// 预注册配置中的消费者
+	for _, reg := range cfg.consumers {
+		eng.createRegistration(reg.Stream, reg.Group, reg.Handler, nil)
+	}
+
+	return eng
+}
+
+// Register 注册 stream 消费者，WithGroup 指定消费者组（默认使用 WithConsumerGroup 的配置）。
+// 同一 stream 注册多个消费者组时，每个组各自收到全部消息。
+func (e *consumerEngine) Register(stream string, handler types.IHandler, opts ...types.RegisterOption) error {
+	e.regMu.Lock()
+	defer e.regMu.Unlock()
+
+	if e.State.Load() != engine.Idle {
+		return xerror.NewXCode(xcode.ErrMQConsume, "cannot register after consumer started")
+	}
+
+	cfg := types.ApplyRegisterOptions(opts)
+	e.createRegistration(stream, cfg.Group, handler, cfg.Middlewares)
+	return nil
+}
+
+func (e *consumerEngine) Count() uint {
+	e.regMu.Lock()
+	defer e.regMu.Unlock()
+	return uint(len(e.registrations))
+}
+
+func (e *consumerEngine) createRegistration(stream, group string, handler types.IHandler, mws []types.Middleware) {
+	if len(stream) == 0 {
+		e.Logger.Error("stream name must not be empty")
+		return
+	}
+	if handler == nil {
+		e.Logger.Error("handler must not be nil", "stream", stream)
+		return
+	}
+	if group == "" {
+		group = e.opt.group
+	}
+
+	// 创建 Redis 客户端，XREADGROUP 阻塞期间独占连接
+	opts := e.opt.redisOptions
+	if opts == nil {
+		opts = buildClientOptions(e.addr)
+	}
+	opts.Addr = e.addr
+	client := redis.NewClient(opts)
+
+	backoff := e.opt.backoff
+	if backoff == nil {
+		backoff = &retry.ExponentialDelay{Base: time.Second, Max: 5 * time.Minute}
+	}
+	backoffFn := mqretry.BackoffDelayFunc(func(attempt uint) time.Duration {
+		return backoff.Delay(attempt)
+	})
+
+	m := e.Metrics.(*metrics.ConsumerMetrics)
+
+	// 注入默认 failedHandler（若用户未设置）
+	failedHandler := e.opt.failedHandler
+	if failedHandler == nil {
+		failedHandler = DefaultFailedHandlerFunc(logutil.NewSlogLogger(e.Logger))
+	}
+	deadLetter, _ := handler.(types.DeadLetterHandler)
+
+	var inner mqretry.RetryStrategy
+	switch e.opt.retryMode {
+	case types.RetryModeRequeue:
+		key := e.opt.streamPrefix + stream
+		inner = mqretry.NewRequeueStrategy(mqretry.RequeueConfig{
+			MaxRetry:      e.opt.maxRetry,
+			Backoff:       backoffFn,
+			Metrics:       m,
+			FailedHandler: failedHandler,
+			DeadLetter:    deadLetter,
+			Timeout:       e.opt.handlerTimeout,
+			RetryIf:       e.opt.retryIf,
+			Logger:        logutil.NewSlogLogger(e.Logger),
+			Requeue: func(ctx context.Context, msg types.Message) error {
+				// 写入新条目后由消费循环确认原条目
+				return client.XAdd(ctx, &redis.XAddArgs{
+					Stream: key,
+					MaxLen: e.opt.maxLen,
+					Approx: e.opt.maxLen > 0,
+					Values: map[string]any{payloadField: types.MarshalWire(msg)},
+				}).Err()
+			},
+		})
+	default: // RetryModeSync
+		inner = mqretry.NewSyncStrategy(mqretry.SyncConfig{
+			MaxRetry:      e.opt.maxRetry,
+			Backoff:       backoffFn,
+			Metrics:       m,
+			FailedHandler: failedHandler,
+			DeadLetter:    deadLetter,
+			Timeout:       e.opt.handlerTimeout,
+			RetryIf:       e.opt.retryIf,
+			Logger:        logutil.NewSlogLogger(e.Logger),
+		})
+	}
+
+	// 重试策略调用组装好中间件链的 handler；死信与批量接口仍在原始 handler 上识别
+	e.registrations = append(e.registrations, streamConsumer{
+		stream:   stream,
+		group:    group,
+		handler:  handler,
+		client:   client,
+		strategy: &retryStrategy{inner: inner, handler: e.wrapHandler(handler, mws)},
+	})
+}
+
+// wrapHandler 按 内置 → 服务级 → 注册级 的顺序为 handler 组装中间件链
+func (e *consumerEngine) wrapHandler(handler types.IHandler, mws []types.Middleware) types.IHandler {
+	server := middleware.Server(e.opt.noDefaultMiddlewares, e.Logger, e.opt.panicHandler, e.opt.middlewares)
+	return middleware.Wrap(handler, server, mws)
+}
+
+func (e *consumerEngine) Start(ctx context.Context) error {
+	if !e.TryStart() {
+		if e.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(xcode.ErrMQConsume, "consumer already closed")
+	}
+
+	engineCtx, cancel := context.WithCancel(ctx)
+	e.CancelFunc = cancel
+
+	e.regMu.Lock()
+	regs := make([]streamConsumer, len(e.registrations))
+	copy(regs, e.registrations)
+	e.regMu.Unlock()
+
+	if len(regs) == 0 {
+		cancel()
+		e.State.Store(engine.Idle)
+		return xerror.NewXCode(xcode.ErrMQConsume, "no consumers registered")
+	}
+
+	// 创建消费者组（同时验证连接）
+	for _, reg := range regs {
+		if err := ensureGroup(ctx, reg.client, e.opt.streamPrefix+reg.stream, reg.group); err != nil {
+			cancel()
+			e.State.Store(engine.Idle)
+			return xerror.WrapWithXCode(err, xcode.ErrMQConsume)
+		}
+	}
+
+	// 启动消费循环
+	for _, reg := range regs {
+		r := reg
+		e.WG.Add(1)
+		e.SafeGo(fmt.Sprintf("consume-%s-%s", r.stream, r.group), func() {
+			defer e.WG.Done()
+			e.consumeLoop(engineCtx, r)
+		}, e.opt.panicHandler)
+	}
+
+	return nil
+}
+
+func (e *consumerEngine) Shutdown(ctx context.Context) error {
+	if !e.RequestShutdown() {
+		if e.State.Load() == engine.Idle {
+			e.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	if e.CancelFunc != nil {
+		e.CancelFunc()
+	}
+
+	// 关闭 Redis 客户端，中断阻塞中的 XREADGROUP；未确认的条目保留在待确认列表中
+	e.regMu.Lock()
+	regs := make([]streamConsumer, len(e.registrations))
+	copy(regs, e.registrations)
+	e.regMu.Unlock()
+
+	for _, reg := range regs {
+		_ = reg.client.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.WG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	e.State.Store(engine.Closed)
+	return nil
+}
+
+// consumeLoop 单个 stream + 消费者组的消费循环
+func (e *consumerEngine) consumeLoop(ctx context.Context, sc streamConsumer) {
+	fetcher := &streamFetcher{
+		client:        sc.client,
+		logger:        e.Logger,
+		key:           e.opt.streamPrefix + sc.stream,
+		group:         sc.group,
+		consumer:      e.opt.consumerName,
+		block:         e.opt.blockTimeout,
+		claimMinIdle:  e.opt.claimMinIdle,
+		claimInterval: e.opt.claimInterval,
+	}
+
+	cfg := consume.LoopConfig{
+		MQSystem:   "redisstream",
+		QueueName:  sc.stream,
+		EmptySleep: e.opt.emptyQueueSleep,
+		MaxErrors:  maxConsumeErrors,
+		Tracer:     telemetry.Tracer("mq.redisstream.consumer"),
+		Logger:     e.Logger,
+		Drain:      e.Draining(),
+		Metrics:    e.Metrics.(*metrics.ConsumerMetrics),
+	}
+
+	consume.ConsumeLoop(ctx, cfg, fetcher, sc.strategy)
+}
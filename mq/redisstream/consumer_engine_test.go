@@ -0,0 +1,226 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector 记录已处理消息的测试 handler
+type collector struct {
+	mu   sync.Mutex
+	msgs []types.Message
+}
+
+func (c *collector) Handle(_ context.Context, msg types.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, msg)
+	return nil
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.msgs)
+}
+
+func startConsumer(t *testing.T, mr *miniredis.Miniredis, opts ...ConsumerOption) IConsumeServer {
+	t.Helper()
+	opts = append([]ConsumerOption{
+		WithBlockTimeout(50 * time.Millisecond),
+		WithEmptyQueueSleep(10 * time.Millisecond),
+		WithBackoff(&retry.FixedDelay{Wait: time.Millisecond}),
+	}, opts...)
+	c := NewConsumer(mr.Addr(), opts...)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Shutdown(context.Background()) })
+	return c
+}
+
+func pendingCount(t *testing.T, mr *miniredis.Miniredis, key, group string) int64 {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	p, err := client.XPending(context.Background(), key, group).Result()
+	require.NoError(t, err)
+	return p.Count
+}
+
+func TestConsumer_Register(t *testing.T) {
+	c := NewConsumer("localhost:0", WithConsumer("s1", &collector{}))
+	require.NoError(t, c.Register("s2", &collector{}, types.WithGroup("g2")))
+	assert.Equal(t, uint(2), c.Count())
+
+	assert.Error(t, NewConsumer("localhost:0").Start(context.Background()), "no consumers registered")
+}
+
+func TestConsumer_ConsumeAndAck(t *testing.T) {
+	mr := miniredis.RunT(t)
+	h := &collector{}
+	startConsumer(t, mr, WithConsumer("orders", h))
+	p := startProducer(t, mr)
+
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("hello"), types.WithMessageID("id-1")))
+	require.Eventually(t, func() bool { return h.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	h.mu.Lock()
+	msg := h.msgs[0]
+	h.mu.Unlock()
+	assert.True(t, msg.IsRedis())
+	assert.Equal(t, "orders", msg.Queue)
+	assert.Equal(t, "hello", string(msg.Data))
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, 1, msg.Attempt)
+
+	assert.Eventually(t, func() bool { return pendingCount(t, mr, "stream:orders", "mq") == 0 },
+		time.Second, 10*time.Millisecond, "processed entry should be acknowledged")
+}
+
+func TestConsumer_ConsumesEntriesWrittenBeforeGroup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	p := startProducer(t, mr)
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("early")))
+
+	h := &collector{}
+	startConsumer(t, mr, WithConsumer("orders", h))
+	assert.Eventually(t, func() bool { return h.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestConsumer_GroupsFanOut_InstancesShare(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	// 同组两个实例竞争消费
+	a, b := &collector{}, &collector{}
+	startConsumer(t, mr, WithConsumer("orders", a), WithConsumerName("a"))
+	startConsumer(t, mr, WithConsumer("orders", b), WithConsumerName("b"))
+
+	// 另一个组独立收到全部消息
+	other := &collector{}
+	c := NewConsumer(mr.Addr(), WithBlockTimeout(50*time.Millisecond), WithEmptyQueueSleep(10*time.Millisecond))
+	require.NoError(t, c.Register("orders", other, types.WithGroup("audit")))
+	require.NoError(t, c.Start(context.Background()))
+	defer c.Shutdown(context.Background())
+
+	p := startProducer(t, mr)
+	const n = 20
+	for range n {
+		require.NoError(t, p.Produce(context.Background(), "orders", []byte("x")))
+	}
+
+	require.Eventually(t, func() bool { return a.count()+b.count() == n && other.count() == n },
+		3*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, n, a.count()+b.count(), "each entry is delivered to exactly one instance of a group")
+}
+
+func TestConsumer_ReclaimsPendingFromDeadConsumer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	// 模拟已崩溃的实例：读取消息后未确认
+	p := startProducer(t, mr)
+	require.NoError(t, p.Produce(ctx, "orders", []byte("orphan")))
+	require.NoError(t, client.XGroupCreateMkStream(ctx, "stream:orders", "mq", "0").Err())
+	_, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "mq", Consumer: "dead", Streams: []string{"stream:orders", ">"}, Count: 1,
+	}).Result()
+	require.NoError(t, err)
+
+	h := &collector{}
+	startConsumer(t, mr, WithConsumer("orders", h),
+		WithConsumerName("alive"),
+		WithClaimMinIdle(50*time.Millisecond),
+		WithClaimInterval(20*time.Millisecond),
+	)
+
+	require.Eventually(t, func() bool { return h.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	h.mu.Lock()
+	assert.Equal(t, "orphan", string(h.msgs[0].Data))
+	h.mu.Unlock()
+	assert.Eventually(t, func() bool { return pendingCount(t, mr, "stream:orders", "mq") == 0 },
+		time.Second, 10*time.Millisecond)
+}
+
+func TestConsumer_SyncRetryThenFailedHandler(t *testing.T) {
+	mr := miniredis.RunT(t)
+	var calls atomic.Int32
+	failed := make(chan types.Message, 1)
+	startConsumer(t, mr,
+		WithConsumer("orders", types.FuncHandler(func(context.Context, types.Message) error {
+			calls.Add(1)
+			return errors.New("boom")
+		})),
+		WithMaxRetry(2),
+		WithFailedHandler(func(_ context.Context, msg types.Message, _ error) { failed <- msg }),
+	)
+	p := startProducer(t, mr)
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("x")))
+
+	select {
+	case msg := <-failed:
+		assert.Equal(t, int32(3), calls.Load())
+		assert.Equal(t, 3, msg.Attempt)
+	case <-time.After(2 * time.Second):
+		t.Fatal("failed handler not called")
+	}
+	assert.Eventually(t, func() bool { return pendingCount(t, mr, "stream:orders", "mq") == 0 },
+		time.Second, 10*time.Millisecond, "exhausted entry should be acknowledged")
+}
+
+func TestConsumer_RequeueRetry(t *testing.T) {
+	mr := miniredis.RunT(t)
+	var attempts []int
+	var mu sync.Mutex
+	done := make(chan struct{})
+	startConsumer(t, mr,
+		WithConsumer("orders", types.FuncHandler(func(_ context.Context, msg types.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts = append(attempts, msg.Attempt)
+			if msg.Attempt < 2 {
+				return errors.New("transient")
+			}
+			close(done)
+			return nil
+		})),
+		WithRetryMode(RetryModeRequeue),
+	)
+	p := startProducer(t, mr)
+	require.NoError(t, p.Produce(context.Background(), "orders", []byte("x")))
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("requeued message not redelivered")
+	}
+	mu.Lock()
+	assert.Equal(t, []int{1, 2}, attempts)
+	mu.Unlock()
+	assert.Eventually(t, func() bool { return pendingCount(t, mr, "stream:orders", "mq") == 0 },
+		time.Second, 10*time.Millisecond)
+}
+
+func TestConsumer_PreStop(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := startConsumer(t, mr, WithConsumer("orders", &collector{}))
+
+	preStopper, ok := c.(interface{ PreStop(context.Context) error })
+	require.True(t, ok, "consumer should implement app.PreStopper")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.NoError(t, preStopper.PreStop(ctx))
+}
@@ -0,0 +1,12 @@
+// Package redisstream 提供基于 Redis Streams 消费者组的消费者和生产者实现。
+//
+// 与基于 List 的 mq/redis 不同，消息通过 XREADGROUP 投递给消费者组内的某一个实例，
+// 处理完成后 XACK 确认；同一消费者组可部署多个实例水平扩展，均获得至少一次投递保证。
+// 崩溃或下线实例未确认的消息，由其他实例通过 XAUTOCLAIM 在空闲超时后认领并重新处理。
+//
+// 消费者支持同步重试和再入队重试两种模式，与 redis/httpsqs 共用消费循环和重试策略。
+// 生产者通过 XADD 写入消息，可使用 MAXLEN 近似裁剪限制 stream 长度；不支持延迟投递。
+//
+// Consumer 和 Producer 均实现 app.IApp 接口，可通过 app.Manager 统一管理生命周期。
+// Consumer 同时实现 app.PreStopper 接口，排空阶段停止拉取新消息并等待处理中的消息完成。
+package redisstream
@@ -0,0 +1,150 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gomooth/pkg/mq/internal/consume"
+	"github.com/redis/go-redis/v9"
+)
+
+// claimBatch 单次 XAUTOCLAIM 认领的最大条目数
+const claimBatch = 10
+
+// streamFetcher 实现 consume.Fetcher 接口，通过消费者组从 stream 拉取消息。
+// 优先返回通过 XAUTOCLAIM 从失效实例认领的待确认条目，其次读取新条目。
+// 仅由单个消费循环调用，不需要加锁。
+type streamFetcher struct {
+	client   *redis.Client
+	logger   *slog.Logger
+	key      string
+	group    string
+	consumer string
+	block    time.Duration
+
+	claimMinIdle  time.Duration
+	claimInterval time.Duration
+	claimCursor   string
+	lastClaim     time.Time
+	claimed       []redis.XMessage
+}
+
+// Fetch 拉取一条消息，返回的 Ack 在处理完成后确认条目（XACK）
+func (f *streamFetcher) Fetch(ctx context.Context) consume.FetchResult {
+	for {
+		msg, ok, err := f.next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return consume.FetchResult{Empty: true}
+			}
+			if isNoGroup(err) {
+				// stream 或消费者组被删除，重建后由消费循环退避重试
+				if gErr := ensureGroup(ctx, f.client, f.key, f.group); gErr != nil {
+					f.logger.Warn("recreate consumer group failed", "stream", f.key, "group", f.group, "error", gErr)
+				}
+			}
+			return consume.FetchResult{Err: err}
+		}
+		if !ok {
+			return consume.FetchResult{Empty: true}
+		}
+
+		data, valid := msg.Values[payloadField].(string)
+		if !valid {
+			// 非本包写入或已被裁剪的条目，直接确认跳过
+			f.logger.Warn("skip malformed stream entry", "stream", f.key, "id", msg.ID)
+			_ = f.ack(ctx, msg.ID)
+			continue
+		}
+
+		id := msg.ID
+		return consume.FetchResult{
+			Data: data,
+			Ack:  func(ctx context.Context) error { return f.ack(ctx, id) },
+		}
+	}
+}
+
+// next 返回下一条待处理的条目，没有可用条目时 ok=false
+func (f *streamFetcher) next(ctx context.Context) (msg redis.XMessage, ok bool, err error) {
+	if len(f.claimed) == 0 && f.claimMinIdle > 0 && time.Since(f.lastClaim) >= f.claimInterval {
+		if err := f.claim(ctx); err != nil {
+			return msg, false, err
+		}
+	}
+	if len(f.claimed) > 0 {
+		msg, f.claimed = f.claimed[0], f.claimed[1:]
+		return msg, true, nil
+	}
+
+	streams, err := f.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    f.group,
+		Consumer: f.consumer,
+		Streams:  []string{f.key, ">"},
+		Count:    1,
+		Block:    f.block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return msg, false, nil
+		}
+		return msg, false, err
+	}
+	for _, s := range streams {
+		if len(s.Messages) > 0 {
+			return s.Messages[0], true, nil
+		}
+	}
+	return msg, false, nil
+}
+
+// claim 认领空闲超过 claimMinIdle 的待确认条目（来自崩溃或已下线的实例）。
+// 游标未回到起点时不等待间隔，下次拉取继续扫描。
+func (f *streamFetcher) claim(ctx context.Context) error {
+	start := f.claimCursor
+	if start == "" {
+		start = "0-0"
+	}
+	msgs, cursor, err := f.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   f.key,
+		Group:    f.group,
+		Consumer: f.consumer,
+		MinIdle:  f.claimMinIdle,
+		Start:    start,
+		Count:    claimBatch,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	f.claimCursor = cursor
+	if cursor == "0-0" {
+		f.lastClaim = time.Now()
+	}
+	f.claimed = msgs
+	if len(msgs) > 0 {
+		f.logger.Info("claimed pending stream entries", "stream", f.key, "group", f.group, "count", len(msgs))
+	}
+	return nil
+}
+
+func (f *streamFetcher) ack(ctx context.Context, id string) error {
+	return f.client.XAck(ctx, f.key, f.group, id).Err()
+}
+
+// ensureGroup 创建消费者组（stream 不存在时一并创建），组已存在时忽略。
+// 新建的组从 stream 起点开始消费，已写入但尚未有消费者组的消息不会丢失。
+func ensureGroup(ctx context.Context, client *redis.Client, key, group string) error {
+	err := client.XGroupCreateMkStream(ctx, key, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+func isNoGroup(err error) bool {
+	return strings.HasPrefix(err.Error(), "NOGROUP")
+}
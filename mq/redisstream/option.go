@@ -0,0 +1,270 @@
+package redisstream
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/gomooth/pkg/framework/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// ==================== Consumer 选项 ====================
+
+// ConsumerOption 消费者配置选项
+type ConsumerOption func(*consumerConfig)
+
+// consumerConfig 消费者引擎配置（未导出）
+type consumerConfig struct {
+	logger               *slog.Logger
+	maxRetry             int
+	backoff              retry.BackoffStrategy
+	retryMode            types.RetryMode
+	retryIf              func(error) bool
+	handlerTimeout       time.Duration
+	emptyQueueSleep      time.Duration
+	failedHandler        types.FailedHandlerFunc
+	panicHandler         func(any)
+	middlewares          []types.Middleware
+	noDefaultMiddlewares bool
+	consumers            []ConsumerRegistration
+	redisOptions         *redis.Options
+	streamPrefix         string
+	group                string        // 默认消费者组
+	consumerName         string        // 组内消费者名称，同一组内各实例必须唯一
+	blockTimeout         time.Duration // XREADGROUP 阻塞时长
+	claimMinIdle         time.Duration // 待确认消息空闲超过该时长后被认领
+	claimInterval        time.Duration // XAUTOCLAIM 检查间隔
+	maxLen               int64         // 再入队时的 MAXLEN（近似裁剪），0=不裁剪
+}
+
+// WithMaxRetry 设置最大重试次数（默认 3，0=不重试）
+func WithMaxRetry(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.maxRetry = n
+	}
+}
+
+// WithBackoff 设置退避策略（默认 ExponentialDelay{Base:1s, Max:5min}）
+func WithBackoff(b retry.BackoffStrategy) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.backoff = b
+	}
+}
+
+// WithHandlerTimeout 设置单次 handler 调用的超时时间（默认 0，不限）
+func WithHandlerTimeout(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.handlerTimeout = d
+	}
+}
+
+// WithRetryMode 设置重试模式（默认 RetryModeSync）
+func WithRetryMode(mode types.RetryMode) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryMode = mode
+	}
+}
+
+// WithRetryIf 设置错误重试谓词：返回 false 的错误不再重试，直接进入死信处理器或失败回调（默认所有错误都重试）。
+// mq.Permanent 包装的错误与解码失败始终不重试。
+func WithRetryIf(fn func(error) bool) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.retryIf = fn
+	}
+}
+
+// WithPanicHandler 设置 panic 恢复后的回调函数（handler panic 由内置 Recover 中间件恢复后回调）
+func WithPanicHandler(fn func(any)) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.panicHandler = fn
+	}
+}
+
+// WithMiddleware 追加服务级 handler 中间件，作用于该消费者注册的所有 handler。
+// 服务级中间件位于内置中间件（Trace → Recover）之内、注册级中间件（mq.WithMiddleware）之外；
+// 先传入的中间件位于外层。
+func WithMiddleware(mws ...types.Middleware) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.middlewares = append(c.middlewares, mws...)
+	}
+}
+
+// WithoutDefaultMiddlewares 禁用内置的 Trace 与 Recover 中间件，
+// 可通过 WithMiddleware 以 mq.Trace / mq.Recover 自定义顺序或替换实现
+func WithoutDefaultMiddlewares() ConsumerOption {
+	return func(c *consumerConfig) {
+		c.noDefaultMiddlewares = true
+	}
+}
+
+// WithEmptyQueueSleep 设置阻塞读取超时后的休眠时间（默认 100ms）
+func WithEmptyQueueSleep(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		if d > 0 {
+			c.emptyQueueSleep = d
+		}
+	}
+}
+
+// WithFailedHandler 设置重试耗尽后的失败处理回调
+func WithFailedHandler(fn types.FailedHandlerFunc) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.failedHandler = fn
+	}
+}
+
+// WithConsumers 批量预注册消费者
+func WithConsumers(regs ...ConsumerRegistration) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.consumers = append(c.consumers, regs...)
+	}
+}
+
+// WithConsumer 预注册单个消费者，使用默认消费者组
+func WithConsumer(stream string, handler types.IHandler) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.consumers = append(c.consumers, ConsumerRegistration{
+			Stream:  stream,
+			Handler: handler,
+		})
+	}
+}
+
+// WithConsumerLogger 设置消费者日志器
+func WithConsumerLogger(l *slog.Logger) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.logger = l
+	}
+}
+
+// WithConsumerRedisConfig 设置消费者 Redis 连接配置
+func WithConsumerRedisConfig(opt *redis.Options) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.redisOptions = opt
+	}
+}
+
+// WithStreamPrefix 设置 stream 键名前缀（默认 "stream:"）
+func WithStreamPrefix(prefix string) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.streamPrefix = prefix
+	}
+}
+
+// WithConsumerGroup 设置默认消费者组（默认 "mq"），可通过注册选项 WithGroup 按 stream 覆盖
+func WithConsumerGroup(group string) ConsumerOption {
+	return func(c *consumerConfig) {
+		if group != "" {
+			c.group = group
+		}
+	}
+}
+
+// WithConsumerName 设置组内消费者名称（默认 "<hostname>-<pid>"）。
+// 同一消费者组内的各实例必须使用不同名称，否则会共享待确认列表。
+func WithConsumerName(name string) ConsumerOption {
+	return func(c *consumerConfig) {
+		if name != "" {
+			c.consumerName = name
+		}
+	}
+}
+
+// WithBlockTimeout 设置 XREADGROUP 的阻塞时长（默认 5s）
+func WithBlockTimeout(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		if d > 0 {
+			c.blockTimeout = d
+		}
+	}
+}
+
+// WithClaimMinIdle 设置待确认消息被其他实例认领前的最小空闲时长（默认 1min，0=不认领）。
+// 应大于 handler 的最长处理时间（含同步重试），否则处理中的消息可能被重复投递。
+func WithClaimMinIdle(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.claimMinIdle = d
+	}
+}
+
+// WithClaimInterval 设置 XAUTOCLAIM 检查待确认消息的间隔（默认 30s）
+func WithClaimInterval(d time.Duration) ConsumerOption {
+	return func(c *consumerConfig) {
+		if d > 0 {
+			c.claimInterval = d
+		}
+	}
+}
+
+// WithMaxLen 设置再入队重试写入 stream 时的最大长度（MAXLEN ~，默认 0 不裁剪）
+func WithMaxLen(n int64) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.maxLen = n
+	}
+}
+
+// ==================== Producer 选项 ====================
+
+// ProducerOption 生产者配置选项
+type ProducerOption func(*producerConfig)
+
+// producerConfig 生产者引擎配置（未导出）
+type producerConfig struct {
+	logger       *slog.Logger
+	redisOptions *redis.Options
+	streamPrefix string
+	maxLen       int64
+}
+
+// WithProducerLogger 设置生产者日志器
+func WithProducerLogger(l *slog.Logger) ProducerOption {
+	return func(c *producerConfig) {
+		c.logger = l
+	}
+}
+
+// WithProducerRedisConfig 设置生产者 Redis 连接配置
+func WithProducerRedisConfig(opt *redis.Options) ProducerOption {
+	return func(c *producerConfig) {
+		c.redisOptions = opt
+	}
+}
+
+// WithProducerStreamPrefix 设置生产者 stream 键名前缀（默认 "stream:"）
+func WithProducerStreamPrefix(prefix string) ProducerOption {
+	return func(c *producerConfig) {
+		c.streamPrefix = prefix
+	}
+}
+
+// WithProducerMaxLen 设置 stream 的最大长度（XADD MAXLEN ~，默认 0 不裁剪）。
+// 采用近似裁剪，实际长度可能略大于 n；超出部分无论是否已被消费都会被删除。
+func WithProducerMaxLen(n int64) ProducerOption {
+	return func(c *producerConfig) {
+		c.maxLen = n
+	}
+}
+
+// buildClientOptions 构建 Redis 连接配置，XREADGROUP 需要长阻塞，不设读超时
+func buildClientOptions(addr string) *redis.Options {
+	return &redis.Options{
+		Addr:         addr,
+		ReadTimeout:  0,
+		WriteTimeout: 0,
+		PoolSize:     runtime.GOMAXPROCS(0) * 2,
+		MinIdleConns: 2,
+	}
+}
+
+// defaultConsumerName 生成默认的组内消费者名称
+func defaultConsumerName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
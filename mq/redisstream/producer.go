@@ -0,0 +1,16 @@
+package redisstream
+
+import (
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// NewProducer 创建生产者实例
+func NewProducer(addr string, opts ...ProducerOption) types.IProducer {
+	cfg := producerConfig{
+		streamPrefix: "stream:",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return newProducerEngine(addr, &cfg)
+}
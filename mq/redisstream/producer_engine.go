@@ -0,0 +1,179 @@
+package redisstream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/gomooth/pkg/framework/telemetry"
+	"github.com/gomooth/pkg/framework/xcode"
+	"github.com/gomooth/pkg/mq/internal/engine"
+	"github.com/gomooth/pkg/mq/internal/metrics"
+	mqtraceutil "github.com/gomooth/pkg/mq/internal/traceutil"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/gomooth/xerror"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// producerEngine 生产者生命周期引擎（未导出）
+type producerEngine struct {
+	engine.Base
+	addr string
+	opt  *producerConfig
+
+	mu     sync.RWMutex
+	client redis.UniversalClient
+}
+
+// 编译时接口检查
+var _ types.IProducer = (*producerEngine)(nil)
+
+func newProducerEngine(addr string, cfg *producerConfig) *producerEngine {
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &producerEngine{
+		Base: engine.Base{
+			Logger:  logger,
+			Metrics: metrics.NewProducerMetrics("redisstream"),
+		},
+		addr: addr,
+		opt:  cfg,
+	}
+}
+
+func (e *producerEngine) Start(ctx context.Context) error {
+	if !e.TryStart() {
+		if e.State.Load() == engine.Running {
+			return nil
+		}
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer already closed")
+	}
+
+	opts := e.opt.redisOptions
+	if opts == nil {
+		opts = buildClientOptions(e.addr)
+	}
+	opts.Addr = e.addr
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		e.State.Store(engine.Idle)
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	e.mu.Lock()
+	e.client = client
+	e.mu.Unlock()
+
+	return nil
+}
+
+func (e *producerEngine) Shutdown(_ context.Context) error {
+	if !e.RequestShutdown() {
+		if e.State.Load() == engine.Idle {
+			e.State.Store(engine.Closed)
+		}
+		return nil
+	}
+
+	e.mu.Lock()
+	if e.client != nil {
+		_ = e.client.Close()
+		e.client = nil
+	}
+	e.mu.Unlock()
+
+	e.State.Store(engine.Closed)
+	return nil
+}
+
+// Produce 将消息追加到 stream（XADD）。不支持延迟投递，设置 WithDelay / WithDeliverAt 时返回 ErrDelayNotSupported。
+func (e *producerEngine) Produce(ctx context.Context, stream string, message []byte, opts ...types.ProduceOption) error {
+	return e.produce(ctx, stream, [][]byte{message}, opts, false)
+}
+
+// ProduceBatch 通过 Pipeline 批量追加消息，每条消息独立生成消息 ID
+func (e *producerEngine) ProduceBatch(ctx context.Context, stream string, messages [][]byte, opts ...types.ProduceOption) error {
+	if len(messages) == 0 {
+		return xerror.NewXCode(xcode.ErrMQPublish, "no messages")
+	}
+	return e.produce(ctx, stream, messages, opts, true)
+}
+
+func (e *producerEngine) produce(ctx context.Context, stream string, messages [][]byte, opts []types.ProduceOption, batch bool) error {
+	if err := ctx.Err(); err != nil {
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	produceCfg := types.ApplyProduceOptions(opts)
+	if _, delayed := produceCfg.DeliveryTime(); delayed {
+		return xerror.WrapWithXCode(types.ErrDelayNotSupported, xcode.ErrMQPublish)
+	}
+	if batch {
+		produceCfg.MessageID = ""
+	}
+
+	spanName := fmt.Sprintf("%s produce", stream)
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "redisstream"),
+		attribute.String("messaging.destination", stream),
+	}
+	if batch {
+		spanName = fmt.Sprintf("%s produce batch", stream)
+		attrs = append(attrs, attribute.Int("messaging.batch.size", len(messages)))
+	}
+
+	// Create producer Span
+	tracer := telemetry.Tracer("mq.redisstream.producer")
+	ctx, span := tracer.Start(ctx, spanName,
+		trace.WithAttributes(attrs...),
+		trace.WithSpanKind(trace.SpanKindProducer),
+	)
+	defer span.End()
+
+	e.mu.RLock()
+	client := e.client
+	e.mu.RUnlock()
+
+	if client == nil {
+		span.RecordError(fmt.Errorf("producer not connected"))
+		span.SetStatus(codes.Error, "producer not connected")
+		return xerror.NewXCode(xcode.ErrMQPublish, "producer not connected")
+	}
+
+	key := e.opt.streamPrefix + stream
+	pipe := client.Pipeline()
+	for _, data := range messages {
+		msg := types.NewRedisMessage(stream, data)
+		produceCfg.Stamp(&msg)
+		mqtraceutil.InjectHeaders(ctx, msg.Headers)
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: key,
+			MaxLen: e.opt.maxLen,
+			Approx: e.opt.maxLen > 0,
+			Values: map[string]any{payloadField: types.MarshalWire(msg)},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if m, ok := e.Metrics.(*metrics.ProducerMetrics); ok && m != nil {
+			m.OnError()
+		}
+		return xerror.WrapWithXCode(err, xcode.ErrMQPublish)
+	}
+
+	if m, ok := e.Metrics.(*metrics.ProducerMetrics); ok && m != nil {
+		m.OnProduce(len(messages))
+	}
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
@@ -0,0 +1,94 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomooth/pkg/mq/internal/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startProducer(t *testing.T, mr *miniredis.Miniredis, opts ...ProducerOption) IProducer {
+	t.Helper()
+	p := NewProducer(mr.Addr(), opts...)
+	require.NoError(t, p.Start(context.Background()))
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+	return p
+}
+
+func decodeEntry(t *testing.T, entry redis.XMessage) types.Message {
+	t.Helper()
+	data, ok := entry.Values[payloadField].(string)
+	require.True(t, ok, "entry should carry payload field")
+	msg := types.NewRedisMessage("", nil)
+	types.UnmarshalWire(&msg, []byte(data))
+	return msg
+}
+
+func TestProducer_Produce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	p := startProducer(t, mr)
+	ctx := context.Background()
+
+	require.NoError(t, p.Produce(ctx, "orders", []byte("hello"),
+		types.WithMessageID("id-1"), types.WithOrderKey("user-1")))
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	entries, err := client.XRange(ctx, "stream:orders", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	msg := decodeEntry(t, entries[0])
+	assert.Equal(t, "hello", string(msg.Data))
+	assert.Equal(t, "id-1", msg.ID)
+	assert.Equal(t, "user-1", msg.Key)
+}
+
+func TestProducer_ProduceBatchWithMaxLen(t *testing.T) {
+	mr := miniredis.RunT(t)
+	p := startProducer(t, mr, WithProducerStreamPrefix("s:"), WithProducerMaxLen(2))
+	ctx := context.Background()
+
+	require.NoError(t, p.ProduceBatch(ctx, "orders", [][]byte{[]byte("a"), []byte("b"), []byte("c")}))
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	entries, err := client.XRange(ctx, "s:orders", "-", "+").Result()
+	require.NoError(t, err)
+	// MAXLEN ~ 为近似裁剪，长度不超过写入数即可；最新的消息一定保留
+	require.NotEmpty(t, entries)
+	assert.LessOrEqual(t, len(entries), 3)
+	assert.Equal(t, "c", string(decodeEntry(t, entries[len(entries)-1]).Data))
+
+	assert.Error(t, p.ProduceBatch(ctx, "orders", nil))
+}
+
+func TestProducer_DelayNotSupported(t *testing.T) {
+	mr := miniredis.RunT(t)
+	p := startProducer(t, mr)
+
+	err := p.Produce(context.Background(), "orders", []byte("x"), types.WithDelay(time.Second))
+	assert.True(t, errors.Is(err, types.ErrDelayNotSupported))
+	assert.False(t, mr.Exists("stream:orders"))
+}
+
+func TestProducer_Lifecycle(t *testing.T) {
+	mr := miniredis.RunT(t)
+	p := NewProducer(mr.Addr())
+	ctx := context.Background()
+
+	assert.Error(t, p.Produce(ctx, "q", []byte("x")), "not started")
+	require.NoError(t, p.Start(ctx))
+	require.NoError(t, p.Shutdown(ctx))
+	assert.Error(t, p.Start(ctx))
+
+	addr := mr.Addr()
+	mr.Close()
+	assert.Error(t, NewProducer(addr).Start(ctx), "unreachable redis")
+}
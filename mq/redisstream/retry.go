@@ -0,0 +1,26 @@
+package redisstream
+
+import (
+	"context"
+
+	mqretry "github.com/gomooth/pkg/mq/internal/retry"
+	"github.com/gomooth/pkg/mq/internal/types"
+)
+
+// retryStrategy 重试策略适配器（未导出），与 consume.RetryStrategy 兼容。
+// 将拉取到的线上数据解析为消息后，委托给共享的 SyncStrategy / RequeueStrategy。
+type retryStrategy struct {
+	inner   mqretry.RetryStrategy
+	handler types.IHandler
+}
+
+func (s *retryStrategy) OnMessage(ctx context.Context, stream string, data []byte) error {
+	msg := types.NewRedisMessage(stream, nil)
+	types.UnmarshalWire(&msg, data)
+	err := s.inner.OnMessage(ctx, msg, s.handler.Handle)
+	// 上下文取消时返回错误（条目不确认，由 XAUTOCLAIM 重新投递），其他情况返回 nil
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	return nil
+}
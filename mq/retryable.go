@@ -0,0 +1,15 @@
+package mq
+
+import "github.com/gomooth/pkg/mq/internal/types"
+
+// 不可重试错误 re-export
+type PermanentError = types.PermanentError
+
+var Permanent = types.Permanent
+var IsNonRetryable = types.IsNonRetryable
+
+// 临时错误 re-export
+type TransientError = types.TransientError
+
+var Transient = types.Transient
+var IsTransient = types.IsTransient
\ No newline at end of file